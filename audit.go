@@ -0,0 +1,222 @@
+package epp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord is a record of a command and its response.
+type AuditRecord struct {
+	Time                   time.Time `json:"time"`
+	SessionID              string    `json:"session_id"`
+	ClientID               string    `json:"client_id,omitempty"`
+	RemoteAddr             string    `json:"remote_addr"`
+	CertificateFingerprint string    `json:"certificate_fingerprint,omitempty"`
+	Command                string    `json:"command,omitempty"`
+	ClientTransactionID    string    `json:"cltrid,omitempty"`
+	ServerTransactionID    string    `json:"svtrid,omitempty"`
+	ResultCode             string    `json:"result_code,omitempty"`
+
+	// Request and Response holds the redacted XML for the command and the
+	// response. Response is empty if no response was written.
+	Request  string `json:"request"`
+	Response string `json:"response,omitempty"`
+}
+
+// AuditSink receives a record for each command handled by a session. Sinks
+// must be safe to use from multiple goroutines.
+type AuditSink interface {
+	Audit(record AuditRecord) error
+}
+
+// redactedUnparsable replaces messages that can't be parsed and therefore
+// can't be redacted.
+const redactedUnparsable = "REDACTED: message could not be parsed"
+
+// newAuditRecord creates a record for the request and the response, with the
+// values from their summaries, with the messages redacted by redactor.
+func newAuditRecord(redactor *Redactor, request, response []byte, command, result messageSummary) AuditRecord {
+	record := AuditRecord{
+		Time:                time.Now().UTC(),
+		Command:             command.command,
+		ClientTransactionID: command.clientTransactionID,
+		Request:             redactMessage(redactor, request),
+	}
+
+	if response != nil {
+		record.Response = redactMessage(redactor, response)
+		record.ResultCode = result.resultCode
+		record.ServerTransactionID = result.serverTransactionID
+	}
+
+	return record
+}
+
+func redactMessage(redactor *Redactor, data []byte) string {
+	redacted, err := redactor.Redact(data)
+	if err != nil {
+		return redactedUnparsable
+	}
+
+	return string(redacted)
+}
+
+// MemoryAuditSink is an AuditSink keeping all records in memory.
+type MemoryAuditSink struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+// Audit implements AuditSink.
+func (s *MemoryAuditSink) Audit(record AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, record)
+
+	return nil
+}
+
+// Records returns all records in the order they were added.
+func (s *MemoryAuditSink) Records() []AuditRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]AuditRecord{}, s.records...)
+}
+
+// FileAuditSink is an AuditSink writing each record as a line of JSON to a
+// file. When the file would exceed MaxSize it's rotated by renaming it with
+// the suffix .1, renaming existing backups to the next number and removing
+// backups exceeding MaxBackups.
+type FileAuditSink struct {
+	// MaxSize is the maximum size of the file in bytes before it's rotated.
+	// If zero the file is never rotated.
+	MaxSize int64
+
+	// MaxBackups is the maximum number of rotated files to keep. If zero no
+	// rotated files are kept.
+	MaxBackups int
+
+	mu   sync.Mutex
+	path string
+	file *os.File
+	size int64
+}
+
+// NewFileAuditSink creates a new FileAuditSink appending records to the file
+// at path, creating it if it doesn't exist.
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	s := &FileAuditSink{
+		path: path,
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileAuditSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+
+		return err
+	}
+
+	s.file = file
+	s.size = info.Size()
+
+	return nil
+}
+
+// Audit implements AuditSink.
+func (s *FileAuditSink) Audit(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return os.ErrClosed
+	}
+
+	if s.MaxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.MaxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+
+	return err
+}
+
+// rotate will close the current file, rename it and the backups and open a
+// new file.
+func (s *FileAuditSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	s.file = nil
+
+	if s.MaxBackups <= 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return s.open()
+	}
+
+	if err := os.Remove(s.backupPath(s.MaxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for i := s.MaxBackups - 1; i > 0; i-- {
+		if err := os.Rename(s.backupPath(i), s.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.Rename(s.path, s.backupPath(1)); err != nil {
+		return err
+	}
+
+	return s.open()
+}
+
+func (s *FileAuditSink) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", s.path, n)
+}
+
+// Close closes the file.
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+
+	return err
+}
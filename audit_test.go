@@ -0,0 +1,220 @@
+package epp
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor(t *testing.T) {
+	cases := []struct {
+		description string
+		rules       []string
+		data        string
+		want        string
+	}{
+		{
+			description: "password and new password",
+			rules:       DefaultRedactionRules,
+			data:        `<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><command><login><clID>foo</clID><pw>secret</pw><newPW>other</newPW></login></command></epp>`,
+			want:        `<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><command><login><clID>foo</clID><pw>REDACTED</pw><newPW>REDACTED</newPW></login></command></epp>`,
+		},
+		{
+			description: "auth info with nested password",
+			rules:       DefaultRedactionRules,
+			data:        `<d:authInfo xmlns:d="urn:ietf:params:xml:ns:domain-1.0"><d:pw roid="X">secret</d:pw></d:authInfo>`,
+			want:        `<d:authInfo xmlns:d="urn:ietf:params:xml:ns:domain-1.0">REDACTED</d:authInfo>`,
+		},
+		{
+			description: "self closing and empty elements are kept",
+			rules:       DefaultRedactionRules,
+			data:        `<a><pw/><pw></pw></a>`,
+			want:        `<a><pw/><pw></pw></a>`,
+		},
+		{
+			description: "namespace alias",
+			rules:       ContactPostalInfoRedactionRules,
+			data:        `<a xmlns:c="urn:ietf:params:xml:ns:contact-1.0" xmlns:o="urn:other"><c:postalInfo type="loc"><c:name>Name</c:name></c:postalInfo><o:postalInfo>kept</o:postalInfo></a>`,
+			want:        `<a xmlns:c="urn:ietf:params:xml:ns:contact-1.0" xmlns:o="urn:other"><c:postalInfo type="loc">REDACTED</c:postalInfo><o:postalInfo>kept</o:postalInfo></a>`,
+		},
+		{
+			description: "absolute path and namespace in braces",
+			rules:       []string{"/a/b", "//{urn:other}c"},
+			data:        `<a><b>x</b><d><b>y</b></d><c xmlns="urn:other">z</c><c>w</c></a>`,
+			want:        `<a><b>REDACTED</b><d><b>y</b></d><c xmlns="urn:other">REDACTED</c><c>w</c></a>`,
+		},
+		{
+			description: "wildcard",
+			rules:       []string{"/a/*/b"},
+			data:        `<a><d><b>y</b></d><b>x</b></a>`,
+			want:        `<a><d><b>REDACTED</b></d><b>x</b></a>`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			redactor, err := NewRedactor(tc.rules...)
+			require.Nil(t, err)
+
+			redacted, err := redactor.Redact([]byte(tc.data))
+			require.Nil(t, err)
+
+			assert.Equal(t, tc.want, string(redacted))
+		})
+	}
+
+	for _, rule := range []string{"pw", "//", "//unknown:pw", "//{urn:x"} {
+		_, err := NewRedactor(rule)
+		assert.NotNil(t, err, rule)
+	}
+
+	_, err := DefaultRedactor.Redact([]byte("<a><pw>secret</a>"))
+	assert.NotNil(t, err)
+}
+
+func TestFileAuditSink(t *testing.T) {
+	dir, err := os.MkdirTemp("", "epp-audit")
+	require.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+
+	sink, err := NewFileAuditSink(path)
+	require.Nil(t, err)
+
+	sink.MaxBackups = 2
+
+	record := AuditRecord{SessionID: "1", Request: "<epp/>"}
+
+	line, err := json.Marshal(record)
+	require.Nil(t, err)
+
+	// Fit two records in each file.
+	sink.MaxSize = int64(2 * (len(line) + 1))
+
+	for i := 0; i < 7; i++ {
+		require.Nil(t, sink.Audit(record))
+	}
+
+	require.Nil(t, sink.Close())
+	assert.Equal(t, os.ErrClosed, sink.Audit(record))
+
+	for file, want := range map[string]int{"audit.log": 1, "audit.log.1": 2, "audit.log.2": 2} {
+		f, err := os.Open(filepath.Join(dir, file))
+		require.Nil(t, err)
+
+		var lines int
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var got AuditRecord
+
+			require.Nil(t, json.Unmarshal(scanner.Bytes(), &got))
+			assert.Equal(t, record, got)
+
+			lines++
+		}
+
+		_ = f.Close()
+
+		assert.Equal(t, want, lines, file)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "audit.log.3"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestServerAudit(t *testing.T) {
+	l := newPipeListener()
+	sink := &MemoryAuditSink{}
+
+	srv := Server{
+		AllowPlaintext: true,
+		Logger:         NopLogger{},
+		SessionConfig: SessionConfig{
+			IdleTimeout:    10 * time.Minute,
+			SessionTimeout: 10 * time.Minute,
+			AuditSink:      sink,
+			AuditRedactor:  MustRedactor(append(DefaultRedactionRules, ContactPostalInfoRedactionRules...)...),
+			Handler: func(s *Session, in []byte) ([]byte, error) {
+				if summarizeCommand(in).command == "command/login" {
+					require.Nil(t, s.Login("foobar"))
+				}
+
+				return Encode(types.Response{
+					Result: []types.Result{
+						{
+							Code:    EppOk.Code(),
+							Message: EppOk.Message(),
+						},
+					},
+					TransactionID: types.TransactionID{
+						ClientTransactionID: ClientTransactionID(in),
+						ServerTransactionID: "SRV-1",
+					},
+				}, ServerXMLAttributes())
+			},
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+	}
+
+	served := make(chan error)
+
+	go func() {
+		served <- srv.Serve(l)
+	}()
+
+	conn, err := l.Dial()
+	require.Nil(t, err)
+
+	framer := NewFramer(conn)
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	for _, file := range []string{"login.xml", "create-contact.xml"} {
+		request, err := os.ReadFile(filepath.Join("xml", "commands", file))
+		require.Nil(t, err)
+
+		require.Nil(t, framer.WriteMessage(request))
+
+		_, err = framer.ReadMessage()
+		require.Nil(t, err)
+	}
+
+	srv.Stop()
+	require.Nil(t, conn.Close())
+	require.Nil(t, <-served)
+
+	records := sink.Records()
+	require.Len(t, records, 2)
+
+	login, create := records[0], records[1]
+
+	assert.NotEmpty(t, login.SessionID)
+	assert.Equal(t, "pipe", login.RemoteAddr)
+	assert.Equal(t, "foobar", login.ClientID)
+	assert.Equal(t, "command/login", login.Command)
+	assert.Equal(t, "ABC-12345", login.ClientTransactionID)
+	assert.Equal(t, "SRV-1", login.ServerTransactionID)
+	assert.Equal(t, "1000", login.ResultCode)
+	assert.Contains(t, login.Request, "<pw>REDACTED</pw>")
+	assert.NotContains(t, login.Request, "password")
+	assert.Contains(t, login.Response, "SRV-1")
+	assert.False(t, login.Time.IsZero())
+
+	assert.Equal(t, "command/create/contact", create.Command)
+	assert.True(t, strings.Contains(create.Request, `<contact:postalInfo type="loc">REDACTED</contact:postalInfo>`))
+	assert.True(t, strings.Contains(create.Request, `<contact:authInfo>REDACTED</contact:authInfo>`))
+}
@@ -1,6 +1,7 @@
 package epp
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
 	"time"
@@ -14,54 +15,244 @@ type Client struct {
 	// to an EPP server.
 	TLSConfig *tls.Config
 
+	// MaxFrameSize is the maximum size of a frame, including the four byte
+	// length header, accepted from the server. If the server sends a larger
+	// frame a *FrameSizeError is returned and the connection is closed. If
+	// zero DefaultMaxFrameSize is used.
+	MaxFrameSize int
+
+	// Logger is used to log messages from the client. Each command is logged
+	// at debug level with the command path, the transaction IDs, the result
+	// code and the latency. If nil, messages are written with the standard
+	// logger from the log package.
+	Logger Logger
+
+	// Metrics is used to record the round trip for each command. If nil no
+	// metrics are recorded.
+	Metrics Metrics
+
+	// Tracer is used to create a span for each command sent. If nil no spans
+	// are created.
+	Tracer Tracer
+
+	// TracePropagator is used to carry the span context for each command to
+	// the server, e.g. in the clTRID with ClientTransactionIDPropagator. If
+	// nil the span context isn't sent to the server.
+	TracePropagator TracePropagator
+
+	// Transcript is used to record the greeting and each command and response.
+	// If nil no transcript is written.
+	Transcript *TranscriptWriter
+
 	// conn holds the TCP connection to the server.
 	conn net.Conn
+
+	// framer reads and writes frames on the connection.
+	framer *Framer
+
+	// greeting holds the greeting received from the server when connecting.
+	greeting *types.EPPGreeting
+
+	// server and clientID holds the server address and the client ID used
+	// when logging in, added to each log message.
+	server   string
+	clientID string
 }
 
-// Connect will connect to the server passed as argument.
+// Connect will connect to the server passed as argument. The greeting from the
+// server is returned and also decoded and stored on the client so the server
+// capabilities can be inspected.
 func (c *Client) Connect(server string) ([]byte, error) {
 	if c.TLSConfig == nil {
 		c.TLSConfig = &tls.Config{}
 	}
 
+	c.server = server
+
 	conn, err := tls.Dial("tcp", server, c.TLSConfig)
 	if err != nil {
+		c.log(LogLevelError, "could not connect to server", Field{Key: FieldError, Value: err})
+
 		return nil, err
 	}
 
+	framer := NewFramer(conn)
+	framer.MaxFrameSize = c.MaxFrameSize
+
 	// Read the greeting.
-	greeting, err := ReadMessage(conn)
+	greeting, err := framer.ReadMessage()
 	if err != nil {
+		c.log(LogLevelError, "could not read greeting", Field{Key: FieldError, Value: err})
+		_ = conn.Close()
+
+		return nil, err
+	}
+
+	c.record(TranscriptGreeting, greeting)
+
+	eppGreeting := types.EPPGreeting{}
+
+	if err := Decode(greeting, &eppGreeting); err != nil {
+		_ = conn.Close()
+
 		return nil, err
 	}
 
 	c.conn = conn
+	c.framer = framer
+	c.greeting = &eppGreeting
+
+	c.log(LogLevelDebug, "connected to server")
 
 	return greeting, nil
 }
 
+// Greeting returns the greeting received from the server when connecting or
+// nil if the client isn't connected.
+func (c *Client) Greeting() *types.Greeting {
+	if c.greeting == nil {
+		return nil
+	}
+
+	return &c.greeting.Greeting
+}
+
+// Versions returns the protocol versions supported by the server.
+func (c *Client) Versions() []string {
+	if c.greeting == nil {
+		return nil
+	}
+
+	return c.greeting.Greeting.Versions()
+}
+
+// SupportsObject returns true if the server announced support for the object
+// namespace in the greeting.
+func (c *Client) SupportsObject(ns string) bool {
+	if c.greeting == nil {
+		return false
+	}
+
+	return c.greeting.Greeting.SupportsObject(ns)
+}
+
+// SupportsExtension returns true if the server announced support for the
+// extension namespace in the greeting.
+func (c *Client) SupportsExtension(ns string) bool {
+	if c.greeting == nil {
+		return false
+	}
+
+	return c.greeting.Greeting.SupportsExtension(ns)
+}
+
+// DCP returns the data collection policy declared by the server in the
+// greeting.
+func (c *Client) DCP() *types.DCP {
+	if c.greeting == nil {
+		return nil
+	}
+
+	return &c.greeting.Greeting.DCP
+}
+
 // Send will send data to the server.
 func (c *Client) Send(data []byte) ([]byte, error) {
-	err := WriteMessage(c.conn, data)
+	return c.SendContext(context.Background(), data)
+}
+
+// SendContext will send data to the server. If a tracer is configured the
+// command is sent within a span that is a child of the span in ctx.
+func (c *Client) SendContext(ctx context.Context, data []byte) (response []byte, err error) {
+	// The command and the response are only parsed once and the summaries
+	// are shared by logging, metrics and tracing.
+	var (
+		command = summarizeCommand(data)
+		result  messageSummary
+	)
+
+	if c.Tracer != nil {
+		name := command.command
+		if name == "" {
+			name = "unknown"
+		}
+
+		_, span := c.Tracer.Start(ctx, name, c.fields()...)
+
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+			} else {
+				span.SetAttributes(result.fields()...)
+			}
+
+			span.End()
+		}()
+
+		if c.TracePropagator != nil {
+			data, err = c.TracePropagator.Inject(data, span.SpanContext())
+			if err != nil {
+				return nil, err
+			}
+
+			// The propagator may have changed the client transaction ID.
+			command = summarizeCommand(data)
+		}
+
+		span.SetAttributes(command.fields()...)
+	}
+
+	started := time.Now()
+
+	err = c.framer.WriteMessage(data)
 	if err != nil {
+		c.log(LogLevelError, "could not send command", append(command.fields(), Field{Key: FieldError, Value: err})...)
 		_ = c.conn.Close()
 
+		defaultMetrics(c.Metrics).ClientRoundTrip(command.command, "", time.Since(started))
+
 		return nil, err
 	}
 
+	c.record(TranscriptRequest, data)
+
 	_ = c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
-	msg, err := ReadMessage(c.conn)
+	msg, err := c.framer.ReadMessage()
 	if err != nil {
+		c.log(LogLevelError, "could not read response", append(command.fields(), Field{Key: FieldError, Value: err})...)
 		_ = c.conn.Close()
 
+		defaultMetrics(c.Metrics).ClientRoundTrip(command.command, "", time.Since(started))
+
 		return nil, err
 	}
 
+	latency := time.Since(started)
+
+	c.record(TranscriptResponse, msg)
+
+	result = summarizeResponse(msg)
+
+	c.log(
+		LogLevelDebug,
+		"command completed",
+		append(commandFields(command, result), Field{Key: FieldLatency, Value: latency})...,
+	)
+
+	defaultMetrics(c.Metrics).ClientRoundTrip(command.command, result.resultCode, latency)
+
 	return msg, nil
 }
 
 // Login will perform a login to an EPP server.
 func (c *Client) Login(username, password string) ([]byte, error) {
+	return c.LoginContext(context.Background(), username, password)
+}
+
+// LoginContext will perform a login to an EPP server. If a tracer is
+// configured the login is sent within a span that is a child of the span in
+// ctx.
+func (c *Client) LoginContext(ctx context.Context, username, password string) ([]byte, error) {
 	login := types.Login{
 		ClientID: username,
 		Password: password,
@@ -89,5 +280,36 @@ func (c *Client) Login(username, password string) ([]byte, error) {
 		return nil, err
 	}
 
-	return c.Send(encoded)
+	c.clientID = username
+
+	return c.SendContext(ctx, encoded)
+}
+
+// log will log the message with the fields identifying the client followed by
+// fields.
+func (c *Client) log(level LogLevel, msg string, fields ...Field) {
+	defaultLogger(c.Logger).Log(level, msg, append(c.fields(), fields...)...)
+}
+
+// record will write the message to the transcript, if any.
+func (c *Client) record(kind TranscriptEntryKind, data []byte) {
+	if c.Transcript == nil {
+		return
+	}
+
+	if err := c.Transcript.Write(kind, data); err != nil {
+		c.log(LogLevelError, "could not write transcript", Field{Key: FieldError, Value: err})
+	}
+}
+
+// fields returns the fields identifying the client, that is the server
+// address and the client ID if logged in.
+func (c *Client) fields() []Field {
+	fields := []Field{{Key: FieldRemoteAddr, Value: c.server}}
+
+	if c.clientID != "" {
+		fields = append(fields, Field{Key: FieldClientID, Value: c.clientID})
+	}
+
+	return fields
 }
@@ -0,0 +1,72 @@
+package epp
+
+import (
+	"testing"
+
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testGreeting = []byte(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <greeting>
+    <svID>Example EPP server epp.example.com</svID>
+    <svDate>2000-06-08T22:00:00.0Z</svDate>
+    <svcMenu>
+      <version>1.0</version>
+      <lang>en</lang>
+      <lang>fr</lang>
+      <objURI>urn:ietf:params:xml:ns:obj1</objURI>
+      <objURI>urn:ietf:params:xml:ns:obj2</objURI>
+      <objURI>urn:ietf:params:xml:ns:obj3</objURI>
+      <svcExtension>
+        <extURI>http://custom/obj1ext-1.0</extURI>
+        <extURI>urn:ietf:params:xml:ns:secDNS-1.1</extURI>
+      </svcExtension>
+    </svcMenu>
+    <dcp>
+      <access><all/></access>
+      <statement>
+        <purpose><admin/><prov/></purpose>
+        <recipient><ours/><public/></recipient>
+        <retention><stated/></retention>
+      </statement>
+    </dcp>
+  </greeting>
+</epp>`)
+
+func TestGreeting(t *testing.T) {
+	greeting := types.EPPGreeting{}
+
+	require.Nil(t, Decode(testGreeting, &greeting))
+
+	g := greeting.Greeting
+
+	assert.Equal(t, "Example EPP server epp.example.com", g.ServerID)
+	assert.Equal(t, []string{"1.0"}, g.Versions())
+	assert.Equal(t, []string{"en", "fr"}, g.Languages())
+
+	assert.True(t, g.SupportsObject("urn:ietf:params:xml:ns:obj2"))
+	assert.False(t, g.SupportsObject(types.NameSpaceDomain))
+
+	assert.True(t, g.SupportsExtension("http://custom/obj1ext-1.0"))
+	assert.True(t, g.SupportsExtension(types.NameSpaceDNSSEC11))
+	assert.False(t, g.SupportsExtension(types.NameSpaceIIS12))
+
+	assert.NotNil(t, g.DCP.Access.All)
+	assert.NotNil(t, g.DCP.Statement.Purpose.Admin)
+	assert.NotNil(t, g.DCP.Statement.Recipient.Public)
+	assert.NotNil(t, g.DCP.Statement.Retention.Stated)
+	assert.Nil(t, g.DCP.Statement.Retention.Legal)
+}
+
+func TestClientWithoutGreeting(t *testing.T) {
+	client := &Client{}
+
+	assert.Nil(t, client.Greeting())
+	assert.Nil(t, client.Versions())
+	assert.Nil(t, client.DCP())
+	assert.False(t, client.SupportsObject(types.NameSpaceDomain))
+	assert.False(t, client.SupportsExtension(types.NameSpaceDNSSEC11))
+}
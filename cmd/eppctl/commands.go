@@ -0,0 +1,533 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/types"
+)
+
+// Requests without any data to encode.
+const (
+	helloRequest = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<epp xmlns="` + types.NameSpaceEPP10 + `"><hello/></epp>`
+	logoutRequest = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<epp xmlns="` + types.NameSpaceEPP10 + `"><command><logout/></command></epp>`
+)
+
+// errUsage is returned when a command is called with invalid arguments.
+var errUsage = errors.New("invalid arguments")
+
+// command is a command that can be run from the command line or the REPL.
+type command struct {
+	// name is the name of the command, e.g. "domain check".
+	name string
+
+	// usage describes the arguments to the command.
+	usage string
+
+	// help is a short description of the command.
+	help string
+
+	// build creates the requests to send from the arguments.
+	build func(args []string) ([][]byte, error)
+}
+
+// commands holds all commands by their name.
+var commands = map[string]command{}
+
+func init() {
+	for _, c := range []command{
+		{"hello", "", "send a hello and print the greeting", buildHello},
+		{"raw", "file...", "send XML files as is, - reads from stdin", buildRaw},
+		{"domain check", "name...", "check if domains are available", buildDomainCheck},
+		{"domain info", "[-auth pw] [-hosts all|del|sub|none] name", "show information about a domain", buildDomainInfo},
+		{"domain create", "[-period years] [-registrant id] [-admin id] [-tech id] [-billing id] [-ns host,...] [-auth pw] name", "create a domain", buildDomainCreate},
+		{"domain delete", "name", "delete a domain", buildDomainDelete},
+		{"contact check", "id...", "check if contact IDs are available", buildContactCheck},
+		{"contact info", "[-auth pw] id", "show information about a contact", buildContactInfo},
+		{"contact create", "-file file", "create a contact from a file with XML or JSON", buildContactCreate},
+		{"contact delete", "id", "delete a contact", buildContactDelete},
+		{"host check", "name...", "check if hosts are available", buildHostCheck},
+		{"host info", "name", "show information about a host", buildHostInfo},
+		{"host create", "[-addr ip] name", "create a host", buildHostCreate},
+		{"host update", "[-add ip,...] [-rem ip,...] [-name new-name] name", "update the addresses or name of a host", buildHostUpdate},
+		{"host delete", "name", "delete a host", buildHostDelete},
+		{"poll req", "", "request the oldest message in the queue", buildPollRequest},
+		{"poll ack", "msgID", "acknowledge a message in the queue", buildPollAcknowledge},
+	} {
+		commands[c.name] = c
+	}
+}
+
+// lookupCommand returns the command named by the first one or two arguments
+// and the remaining arguments.
+func lookupCommand(args []string) (command, []string, bool) {
+	if len(args) >= 2 {
+		if c, ok := commands[args[0]+" "+args[1]]; ok {
+			return c, args[2:], true
+		}
+	}
+
+	if len(args) >= 1 {
+		if c, ok := commands[args[0]]; ok {
+			return c, args[1:], true
+		}
+	}
+
+	return command{}, nil, false
+}
+
+// printUsage prints the usage for all commands.
+func printUsage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		c := commands[name]
+
+		fmt.Fprintf(w, "  %s %s\n", c.name, c.usage)
+		fmt.Fprintf(w, "        %s\n", c.help)
+	}
+}
+
+// parseFlags parses the flags in args allowing flags and positional arguments
+// to be mixed, e.g. domain info example.se -auth secret. The positional
+// arguments are returned.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+
+	var positional []string
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %s", errUsage, err.Error())
+		}
+
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// splitList splits a comma separated list, ignoring empty values.
+func splitList(s string) []string {
+	var values []string
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// encode encodes the command v as a request.
+func encode(v interface{}) ([][]byte, error) {
+	data, err := epp.Encode(v, epp.ClientXMLAttributes())
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{data}, nil
+}
+
+// names returns the positional arguments, requiring at least one.
+func names(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(positional) == 0 {
+		return nil, fmt.Errorf("%w: missing name", errUsage)
+	}
+
+	return positional, nil
+}
+
+// name returns the positional argument, requiring exactly one.
+func name(fs *flag.FlagSet, args []string) (string, error) {
+	positional, err := names(fs, args)
+	if err != nil {
+		return "", err
+	}
+
+	if len(positional) > 1 {
+		return "", fmt.Errorf("%w: expected one name, got %d", errUsage, len(positional))
+	}
+
+	return positional[0], nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet(name, flag.ContinueOnError)
+}
+
+func buildHello(args []string) ([][]byte, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%w: hello takes no arguments", errUsage)
+	}
+
+	return [][]byte{[]byte(helloRequest)}, nil
+}
+
+func buildRaw(args []string) ([][]byte, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%w: missing file", errUsage)
+	}
+
+	requests := make([][]byte, 0, len(args))
+
+	for _, file := range args {
+		data, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		requests = append(requests, bytes.TrimSpace(data))
+	}
+
+	return requests, nil
+}
+
+// readFile reads the file or stdin if file is -.
+func readFile(file string) ([]byte, error) {
+	if file == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+
+	return os.ReadFile(file)
+}
+
+func buildDomainCheck(args []string) ([][]byte, error) {
+	domains, err := names(newFlagSet("domain check"), args)
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(types.DomainCheckType{
+		Check: types.DomainCheck{
+			Names: domains,
+		},
+	})
+}
+
+func buildDomainInfo(args []string) ([][]byte, error) {
+	fs := newFlagSet("domain info")
+	authInfo := fs.String("auth", "", "authorization information")
+	hosts := fs.String("hosts", "", "hosts to show, all, del, sub or none")
+
+	domain, err := name(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	info := types.DomainInfo{
+		Name: types.DomainInfoName{
+			Name:  domain,
+			Hosts: types.DomainHostsType(*hosts),
+		},
+	}
+
+	if *authInfo != "" {
+		info.AuthInfo = &types.AuthInfo{Password: *authInfo}
+	}
+
+	return encode(types.DomainInfoType{Info: info})
+}
+
+func buildDomainCreate(args []string) ([][]byte, error) {
+	fs := newFlagSet("domain create")
+	period := fs.Int("period", 0, "registration period in years")
+	registrant := fs.String("registrant", "", "registrant contact ID")
+	admin := fs.String("admin", "", "admin contact ID")
+	tech := fs.String("tech", "", "tech contact ID")
+	billing := fs.String("billing", "", "billing contact ID")
+	nameServers := fs.String("ns", "", "comma separated list of name servers")
+	authInfo := fs.String("auth", "", "authorization information")
+
+	domain, err := name(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	create := types.DomainCreate{
+		Name:       domain,
+		Registrant: *registrant,
+		NameServer: types.NameServer{
+			HostObject: splitList(*nameServers),
+		},
+		AuthInfo: &types.AuthInfo{Password: *authInfo},
+	}
+
+	if *period > 0 {
+		create.Period = types.Period{Value: *period, Unit: "y"}
+	}
+
+	for _, c := range []types.Contact{
+		{Type: "admin", Name: *admin},
+		{Type: "tech", Name: *tech},
+		{Type: "billing", Name: *billing},
+	} {
+		if c.Name != "" {
+			create.Contacts = append(create.Contacts, c)
+		}
+	}
+
+	return encode(types.DomainCreateType{Create: create})
+}
+
+func buildDomainDelete(args []string) ([][]byte, error) {
+	domain, err := name(newFlagSet("domain delete"), args)
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(types.DomainDeleteType{
+		Delete: types.DomainDelete{Name: domain},
+	})
+}
+
+func buildContactCheck(args []string) ([][]byte, error) {
+	ids, err := names(newFlagSet("contact check"), args)
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(types.ContactCheckType{
+		Check: types.ContactCheck{Names: ids},
+	})
+}
+
+func buildContactInfo(args []string) ([][]byte, error) {
+	fs := newFlagSet("contact info")
+	authInfo := fs.String("auth", "", "authorization information")
+
+	id, err := name(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(types.ContactInfoType{
+		Info: types.ContactInfo{
+			Name:     id,
+			AuthInfo: types.AuthInfo{Password: *authInfo},
+		},
+	})
+}
+
+// buildContactCreate creates a contact create command from a file. If the
+// file contains XML it's sent as is, otherwise it's decoded as JSON to a
+// types.ContactCreate.
+func buildContactCreate(args []string) ([][]byte, error) {
+	fs := newFlagSet("contact create")
+	file := fs.String("file", "", "file with the contact as XML or JSON, - reads from stdin")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if *file == "" || len(positional) > 0 {
+		return nil, fmt.Errorf("%w: contact create requires -file and no other arguments", errUsage)
+	}
+
+	data, err := readFile(*file)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("<")) {
+		return [][]byte{data}, nil
+	}
+
+	var create types.ContactCreate
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+
+	if err := d.Decode(&create); err != nil {
+		return nil, fmt.Errorf("invalid contact in %s: %w", *file, err)
+	}
+
+	return encode(types.ContactCreateType{Create: create})
+}
+
+func buildContactDelete(args []string) ([][]byte, error) {
+	id, err := name(newFlagSet("contact delete"), args)
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(types.ContactDeleteType{
+		Delete: types.ContactDelete{Name: id},
+	})
+}
+
+func buildHostCheck(args []string) ([][]byte, error) {
+	hosts, err := names(newFlagSet("host check"), args)
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(types.HostCheckType{
+		Check: types.HostCheck{Names: hosts},
+	})
+}
+
+func buildHostInfo(args []string) ([][]byte, error) {
+	host, err := name(newFlagSet("host info"), args)
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(types.HostInfoType{
+		Info: types.HostInfo{Name: host},
+	})
+}
+
+func buildHostCreate(args []string) ([][]byte, error) {
+	fs := newFlagSet("host create")
+	addr := fs.String("addr", "", "IP address of the host")
+
+	host, err := name(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	create := types.HostCreate{Name: host}
+
+	if *addr != "" {
+		addresses, err := hostAddresses(*addr)
+		if err != nil {
+			return nil, err
+		}
+
+		create.Address = addresses[0]
+	}
+
+	return encode(types.HostCreateType{Create: create})
+}
+
+func buildHostUpdate(args []string) ([][]byte, error) {
+	fs := newFlagSet("host update")
+	add := fs.String("add", "", "comma separated list of IP addresses to add")
+	rem := fs.String("rem", "", "comma separated list of IP addresses to remove")
+	newName := fs.String("name", "", "new name of the host")
+
+	host, err := name(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if *add == "" && *rem == "" && *newName == "" {
+		return nil, fmt.Errorf("%w: nothing to update, use -add, -rem or -name", errUsage)
+	}
+
+	update := types.HostUpdate{
+		Name:   host,
+		Change: *newName,
+	}
+
+	if *add != "" {
+		addresses, err := hostAddresses(*add)
+		if err != nil {
+			return nil, err
+		}
+
+		update.Add = &types.HostAddRemove{Address: addresses}
+	}
+
+	if *rem != "" {
+		addresses, err := hostAddresses(*rem)
+		if err != nil {
+			return nil, err
+		}
+
+		update.Remove = &types.HostAddRemove{Address: addresses}
+	}
+
+	return encode(types.HostUpdateType{Update: update})
+}
+
+// hostAddresses parses a comma separated list of IP addresses.
+func hostAddresses(s string) ([]types.HostAddress, error) {
+	var addresses []types.HostAddress
+
+	for _, v := range splitList(s) {
+		ip := net.ParseIP(v)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", v)
+		}
+
+		address := types.HostAddress{
+			Address: ip.String(),
+			IP:      types.HostIPv6,
+		}
+
+		if ip.To4() != nil {
+			address.IP = types.HostIPv4
+		}
+
+		addresses = append(addresses, address)
+	}
+
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("%w: missing IP address", errUsage)
+	}
+
+	return addresses, nil
+}
+
+func buildHostDelete(args []string) ([][]byte, error) {
+	host, err := name(newFlagSet("host delete"), args)
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(types.HostDeleteType{
+		Delete: types.HostDelete{Name: host},
+	})
+}
+
+func buildPollRequest(args []string) ([][]byte, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%w: poll req takes no arguments", errUsage)
+	}
+
+	return encode(types.Poll{
+		Poll: types.PollCommand{Operation: types.PollOperationRequest},
+	})
+}
+
+func buildPollAcknowledge(args []string) ([][]byte, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%w: poll ack requires a message ID", errUsage)
+	}
+
+	return encode(types.Poll{
+		Poll: types.PollCommand{
+			Operation: types.PollOperationAcknowledge,
+			MessageID: args[0],
+		},
+	})
+}
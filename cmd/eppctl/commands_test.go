@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommands(t *testing.T) {
+	validator, err := epp.NewDefaultValidator()
+	require.Nil(t, err)
+
+	defer validator.Free()
+
+	contact, err := json.Marshal(map[string]interface{}{
+		"ID": "sh8013",
+		"PostalInfo": []map[string]interface{}{
+			{
+				"Type": "int",
+				"Name": "John Doe",
+				"Address": map[string]interface{}{
+					"City":        "Dulles",
+					"CountryCode": "US",
+				},
+			},
+		},
+		"Email":    "jdoe@example.com",
+		"AuthInfo": map[string]string{"Password": "2fooBAR"},
+		"Disclose": map[string]interface{}{
+			"Flag":         false,
+			"Name":         map[string]string{"Type": "int"},
+			"Organization": map[string]string{"Type": "int"},
+			"Address":      map[string]string{"Type": "int"},
+		},
+	})
+	require.Nil(t, err)
+
+	contactFile := filepath.Join(t.TempDir(), "contact.json")
+	require.Nil(t, os.WriteFile(contactFile, contact, 0o600))
+
+	cases := []struct {
+		args     string
+		contains []string
+	}{
+		{"hello", []string{"<hello"}},
+		{"domain check example.se example.com", []string{">example.se<", ">example.com<"}},
+		{"domain info example.se -auth secret -hosts all", []string{`hosts="all"`, ">secret<"}},
+		{"domain create -period 2 -registrant reg -admin adm -ns ns1.example.se,ns2.example.se -auth secret example.se", []string{`unit="y"`, `type="admin"`, ">ns2.example.se<"}},
+		{"domain delete example.se", []string{"delete"}},
+		{"contact check sh8013", []string{">sh8013<"}},
+		{"contact info sh8013 -auth secret", []string{">secret<"}},
+		{"contact create -file " + contactFile, []string{">John Doe<", ">jdoe@example.com<"}},
+		{"contact delete sh8013", []string{">sh8013<"}},
+		{"host check ns1.example.se", []string{">ns1.example.se<"}},
+		{"host info ns1.example.se", []string{">ns1.example.se<"}},
+		{"host create ns1.example.se -addr 2001:db8::1", []string{`ip="v6"`}},
+		{"host update ns1.example.se -add 192.0.2.1,192.0.2.2 -rem 2001:db8::1 -name ns2.example.se", []string{`ip="v4"`, ">192.0.2.2<", ">ns2.example.se<"}},
+		{"host delete ns1.example.se", []string{">ns1.example.se<"}},
+		{"poll req", []string{`op="req"`}},
+		{"poll ack 12345", []string{`op="ack"`, `msgID="12345"`}},
+		{"raw ../../xml/commands/info-domain.xml", []string{"<domain:info"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.args, func(t *testing.T) {
+			c, args, ok := lookupCommand(strings.Fields(tc.args))
+			require.True(t, ok)
+
+			requests, err := c.build(args)
+			require.Nil(t, err)
+			require.Len(t, requests, 1)
+
+			assert.Nil(t, validator.Validate(requests[0]), string(requests[0]))
+
+			for _, s := range tc.contains {
+				assert.Contains(t, string(requests[0]), s)
+			}
+		})
+	}
+}
+
+func TestCommandsUsage(t *testing.T) {
+	for _, args := range []string{
+		"domain check",
+		"domain info a.se b.se",
+		"domain info -unknown a.se",
+		"contact create",
+		"host update ns1.example.se",
+		"host create -addr invalid ns1.example.se",
+		"poll ack",
+	} {
+		c, rest, ok := lookupCommand(strings.Fields(args))
+		require.True(t, ok)
+
+		_, err := c.build(rest)
+		assert.NotNil(t, err, args)
+
+		if !strings.Contains(args, "invalid") {
+			assert.True(t, errors.Is(err, errUsage), args)
+		}
+	}
+
+	_, _, ok := lookupCommand([]string{"domain", "unknown"})
+	assert.False(t, ok)
+}
+
+func TestRunWithoutConnecting(t *testing.T) {
+	// Nothing listens on the address so connecting would fail with another
+	// error than errUsage.
+	s := &session{config: &config{Server: "127.0.0.1:1"}}
+
+	for _, args := range [][]string{
+		{"domian", "info", "example.se"},
+		{"domain", "info"},
+	} {
+		err := s.run(args)
+		assert.True(t, errors.Is(err, errUsage), err)
+	}
+
+	assert.Nil(t, s.client)
+}
+
+func TestSplitLine(t *testing.T) {
+	args, err := splitLine(`domain info  example.se -auth "my secret" ''`)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"domain", "info", "example.se", "-auth", "my secret", ""}, args)
+
+	_, err = splitLine(`domain info "example.se`)
+	assert.NotNil(t, err)
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	h, err := loadHistory(path)
+	require.Nil(t, err)
+
+	for _, line := range []string{"domain check a.se", "poll req", "poll req"} {
+		require.Nil(t, h.add(line))
+	}
+
+	h, err = loadHistory(path)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"domain check a.se", "poll req"}, h.lines)
+
+	line, err := h.expand("!1")
+	require.Nil(t, err)
+	assert.Equal(t, "domain check a.se", line)
+
+	line, err = h.expand("!!")
+	require.Nil(t, err)
+	assert.Equal(t, "poll req", line)
+
+	_, err = h.expand("!3")
+	assert.NotNil(t, err)
+}
+
+// testLineReader returns the lines and errors in order and records the lines
+// added to the history.
+type testLineReader struct {
+	lines   []interface{}
+	history []string
+}
+
+func (r *testLineReader) readLine() (string, error) {
+	if len(r.lines) == 0 {
+		return "", io.EOF
+	}
+
+	next := r.lines[0]
+	r.lines = r.lines[1:]
+
+	if err, ok := next.(error); ok {
+		return "", err
+	}
+
+	return next.(string), nil
+}
+
+func (r *testLineReader) addHistory(line string) {
+	r.history = append(r.history, line)
+}
+
+func (r *testLineReader) close() error {
+	return nil
+}
+
+func TestREPL(t *testing.T) {
+	h, err := loadHistory("")
+	require.Nil(t, err)
+
+	require.Nil(t, h.add("poll req"))
+
+	in := &testLineReader{
+		lines: []interface{}{"domain check a.se", errInterrupted, "  ", "!1", "!!", "!9", "history"},
+	}
+
+	var (
+		out bytes.Buffer
+		ran [][]string
+	)
+
+	r := &repl{
+		in:      in,
+		out:     &out,
+		history: h,
+		run: func(args []string) error {
+			ran = append(ran, args)
+			return nil
+		},
+	}
+
+	require.Nil(t, r.loop())
+
+	assert.Equal(t, [][]string{
+		{"domain", "check", "a.se"},
+		{"poll", "req"},
+		{"poll", "req"},
+	}, ran)
+	assert.Equal(t, []string{"domain check a.se", "poll req", "poll req", "history"}, in.history)
+	assert.Equal(t, []string{"poll req", "domain check a.se", "poll req", "history"}, h.lines)
+	assert.Contains(t, out.String(), "no such history entry: 9")
+	assert.Contains(t, out.String(), "    2  domain check a.se\n")
+}
+
+func TestPrinter(t *testing.T) {
+	response := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <response>
+    <result code="1301"><msg>Command completed successfully; ack to dequeue</msg></result>
+    <msgQ count="5" id="12345"><qDate>2000-06-08T22:00:00.0Z</qDate><msg>Transfer requested &amp; pending.</msg></msgQ>
+    <resData>
+      <domain:chkData xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:cd><domain:name avail="1">example.se</domain:name></domain:cd>
+        <domain:cd><domain:name avail="0">example.com</domain:name><domain:reason>In use</domain:reason></domain:cd>
+      </domain:chkData>
+    </resData>
+    <trID><clTRID>ABC-12345</clTRID><svTRID>54322-XYZ</svTRID></trID>
+  </response>
+</epp>`)
+
+	assert.Equal(t, 1301, resultCode(response))
+
+	var buf bytes.Buffer
+
+	p, err := newPrinter(&buf, outputPretty)
+	require.Nil(t, err)
+	require.Nil(t, p.print(response))
+
+	out := buf.String()
+	assert.Contains(t, out, "1301 Command completed successfully; ack to dequeue\n")
+	assert.Contains(t, out, "queue: 5 message(s), id 12345, queued 2000-06-08T22:00:00.0Z: Transfer requested & pending.\n")
+	assert.Contains(t, out, `<domain:chkData xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">`+"\n  <domain:cd>\n")
+	assert.Contains(t, out, `    <domain:name avail="0">example.com</domain:name>`+"\n")
+	assert.Contains(t, out, "clTRID: ABC-12345 svTRID: 54322-XYZ\n")
+
+	buf.Reset()
+
+	p, err = newPrinter(&buf, outputJSON)
+	require.Nil(t, err)
+	require.Nil(t, p.print(response))
+
+	var decoded struct {
+		EPP struct {
+			Response struct {
+				Result struct {
+					Code string `json:"@code"`
+				} `json:"result"`
+				MsgQ struct {
+					ID  string `json:"@id"`
+					Msg string `json:"msg"`
+				} `json:"msgQ"`
+				ResData struct {
+					CheckData struct {
+						CD []struct {
+							Name struct {
+								Available string `json:"@avail"`
+								Text      string `json:"#text"`
+							} `json:"name"`
+						} `json:"cd"`
+					} `json:"chkData"`
+				} `json:"resData"`
+			} `json:"response"`
+		} `json:"epp"`
+	}
+
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &decoded), buf.String())
+
+	r := decoded.EPP.Response
+	assert.Equal(t, "1301", r.Result.Code)
+	assert.Equal(t, "12345", r.MsgQ.ID)
+	assert.Equal(t, "Transfer requested & pending.", r.MsgQ.Msg)
+	require.Len(t, r.ResData.CheckData.CD, 2)
+	assert.Equal(t, "0", r.ResData.CheckData.CD[1].Name.Available)
+	assert.Equal(t, "example.com", r.ResData.CheckData.CD[1].Name.Text)
+
+	buf.Reset()
+
+	p, err = newPrinter(&buf, outputXML)
+	require.Nil(t, err)
+	require.Nil(t, p.print(response))
+	assert.Contains(t, buf.String(), "\n    <msgQ count=\"5\" id=\"12345\">\n      <qDate>")
+	assert.Contains(t, buf.String(), "Transfer requested &amp; pending.")
+
+	_, err = newPrinter(&buf, "yaml")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// passwordEnv is the environment variable overriding the password in the
+// configuration file.
+const passwordEnv = "EPPCTL_PASSWORD"
+
+// config holds the configuration read from the configuration file.
+type config struct {
+	// Server is the address of the EPP server, e.g. epp.example.com:700.
+	Server string `json:"server"`
+
+	// Certificate and Key are the files with the client certificate and key
+	// in PEM format.
+	Certificate string `json:"certificate"`
+	Key         string `json:"key"`
+
+	// CA is a file with the certificates used to verify the server. If empty
+	// the system roots are used.
+	CA string `json:"ca"`
+
+	// Insecure skips the verification of the server certificate.
+	Insecure bool `json:"insecure"`
+
+	// Username and Password are the credentials used to login.
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// History is the file where the REPL history is saved. If empty
+	// ~/.eppctl_history is used.
+	History string `json:"history"`
+}
+
+// defaultConfigPath returns the path to the default configuration file,
+// ~/.eppctl.json.
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".eppctl.json"
+	}
+
+	return filepath.Join(home, ".eppctl.json")
+}
+
+// loadConfig reads the configuration file at path. A missing file at the
+// default path isn't an error since all settings may be given as flags.
+func loadConfig(path string, required bool) (*config, error) {
+	c := &config{}
+
+	data, err := os.ReadFile(path)
+
+	switch {
+	case errors.Is(err, os.ErrNotExist) && !required:
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, c); err != nil {
+			return nil, fmt.Errorf("invalid configuration file %s: %w", path, err)
+		}
+	}
+
+	if password := os.Getenv(passwordEnv); password != "" {
+		c.Password = password
+	}
+
+	if c.History == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			c.History = filepath.Join(home, ".eppctl_history")
+		}
+	}
+
+	return c, nil
+}
+
+// tlsConfig creates the TLS configuration for the connection to the server.
+func (c *config) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.Insecure,
+	}
+
+	if c.Certificate != "" {
+		cert, err := tls.LoadX509KeyPair(c.Certificate, c.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if c.CA != "" {
+		pem, err := os.ReadFile(c.CA)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CA)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,271 @@
+// Command eppctl is a command line client for EPP servers. It connects and
+// logs in with the settings in a configuration file, runs a command and logs
+// out. Without a command an interactive shell with line editing is started
+// where commands can be run in the same session, with the history saved
+// between sessions.
+//
+//	eppctl domain check example.se example.com
+//	eppctl -o json domain info example.se
+//	eppctl contact create -file contact.json
+//	eppctl host update ns1.example.se -add 192.0.2.1 -rem 192.0.2.2
+//	eppctl poll req
+//	eppctl poll ack 12345
+//	eppctl raw xml/commands/info-domain.xml
+//
+// The configuration file is JSON and is read from ~/.eppctl.json unless
+// -config is given. The password may be set with the environment variable
+// EPPCTL_PASSWORD instead.
+//
+//	{
+//	  "server": "epp.example.se:700",
+//	  "certificate": "client.pem",
+//	  "key": "client.key",
+//	  "ca": "ca.pem",
+//	  "username": "registrar",
+//	  "password": "secret"
+//	}
+//
+// The exit status is 1 if any command fails with a result code of 2000 or
+// higher.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/chzyer/readline"
+)
+
+func main() {
+	var (
+		configPath = flag.String("config", "", "configuration file (default ~/.eppctl.json)")
+		server     = flag.String("server", "", "address of the EPP server, overrides the configuration file")
+		output     = flag.String("o", outputPretty, "output format, pretty, json or xml")
+		verbose    = flag.Bool("v", false, "print each request before sending it")
+	)
+
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+
+		fmt.Fprintf(out, "usage: %s [flags] [command [arguments]]\n\nflags:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintf(out, "\ncommands:\n")
+		printUsage(out)
+	}
+
+	flag.Parse()
+
+	path := *configPath
+	if path == "" {
+		path = defaultConfigPath()
+	}
+
+	cfg, err := loadConfig(path, *configPath != "")
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if *server != "" {
+		cfg.Server = *server
+	}
+
+	if cfg.Server == "" {
+		log.Fatal("no server configured, set server in the configuration file or use -server")
+	}
+
+	p, err := newPrinter(os.Stdout, *output)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	s := &session{
+		config:  cfg,
+		printer: p,
+		verbose: *verbose,
+	}
+
+	// A single command is checked before connecting to the server, which
+	// is done by run.
+	if flag.NArg() > 0 {
+		err = s.run(flag.Args())
+	} else {
+		if err := s.open(); err != nil {
+			log.Fatal(err.Error())
+		}
+
+		err = s.repl()
+	}
+
+	s.close()
+
+	if errors.Is(err, errUsage) {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if s.failed {
+		os.Exit(1)
+	}
+}
+
+// session is a connection to an EPP server.
+type session struct {
+	config  *config
+	client  *epp.Client
+	printer *printer
+	verbose bool
+
+	// failed is set if any command got a result code of 2000 or higher.
+	failed bool
+}
+
+// open connects to the server and logs in if a username is configured.
+func (s *session) open() error {
+	cfg := s.config
+
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	client := &epp.Client{
+		TLSConfig: tlsConfig,
+		Logger:    epp.NopLogger{},
+	}
+
+	greeting, err := client.Connect(cfg.Server)
+	if err != nil {
+		return err
+	}
+
+	if s.verbose {
+		if err := s.printer.print(greeting); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Username == "" {
+		s.client = client
+
+		return nil
+	}
+
+	response, err := client.Login(cfg.Username, cfg.Password)
+	if err != nil {
+		return err
+	}
+
+	if code := resultCode(response); code != int(epp.EppOk) {
+		_ = s.printer.print(response)
+
+		return fmt.Errorf("login failed with result code %d", code)
+	}
+
+	s.client = client
+
+	return nil
+}
+
+// close logs out from the server.
+func (s *session) close() {
+	if s.client != nil {
+		_, _ = s.client.Send([]byte(logoutRequest))
+	}
+}
+
+// run runs the command in args and prints the responses.
+func (s *session) run(args []string) error {
+	c, rest, ok := lookupCommand(args)
+	if !ok {
+		return fmt.Errorf("%w: unknown command %q, see -help for a list of commands", errUsage, args[0])
+	}
+
+	requests, err := c.build(rest)
+	if errors.Is(err, errUsage) {
+		return fmt.Errorf("%w\nusage: %s %s", err, c.name, c.usage)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// The connection is closed by the client when a command fails and may be
+	// closed by the server after a response, reconnect when needed.
+	if s.client == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+
+	for _, request := range requests {
+		if s.verbose {
+			if err := s.printer.print(request); err != nil {
+				return err
+			}
+		}
+
+		response, err := s.client.Send(request)
+		if err != nil {
+			s.client = nil
+
+			return err
+		}
+
+		code := resultCode(response)
+		if code >= 2000 {
+			s.failed = true
+		}
+
+		if epp.ResultCode(code).IsBye() {
+			s.client = nil
+		}
+
+		if err := s.printer.print(response); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// repl runs commands read from stdin until EOF or exit. Lines are edited in
+// the terminal if stdin and stdout are terminals.
+func (s *session) repl() error {
+	h, err := loadHistory(s.config.History)
+	if err != nil {
+		return err
+	}
+
+	var in lineReader = &lineScanner{in: bufio.NewScanner(os.Stdin), out: os.Stdout}
+
+	if readline.DefaultIsTerminal() {
+		if in, err = newLineEditor(h); err != nil {
+			return err
+		}
+	}
+
+	defer in.close()
+
+	r := &repl{
+		in:      in,
+		out:     os.Stdout,
+		history: h,
+		run:     s.run,
+	}
+
+	err = r.loop()
+
+	// Failed commands in the REPL doesn't affect the exit status.
+	s.failed = false
+
+	return err
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+
+	"aqwari.net/xml/xmltree"
+)
+
+// Output formats.
+const (
+	outputPretty = "pretty"
+	outputJSON   = "json"
+	outputXML    = "xml"
+)
+
+// printer prints responses in the configured format.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case outputPretty, outputJSON, outputXML:
+	default:
+		return nil, fmt.Errorf("unknown output format %q, use %s, %s or %s", format, outputPretty, outputJSON, outputXML)
+	}
+
+	return &printer{w: w, format: format}, nil
+}
+
+// print prints the message. Messages that can't be parsed are printed as is.
+func (p *printer) print(message []byte) error {
+	root, err := xmltree.Parse(message)
+	if err != nil {
+		_, err = fmt.Fprintf(p.w, "%s\n", message)
+
+		return err
+	}
+
+	switch p.format {
+	case outputJSON:
+		data, err := json.MarshalIndent(map[string]interface{}{
+			root.Name.Local: elementValue(root),
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(p.w, "%s\n", data)
+
+		return err
+	case outputXML:
+		return indentXML(p.w, message, nil)
+	}
+
+	return p.pretty(root, message)
+}
+
+// pretty prints a summary of the response with the result, the message queue
+// and the transaction IDs followed by the indented result data and
+// extensions. Messages other than responses, e.g. the greeting, are printed as
+// indented XML.
+func (p *printer) pretty(root *xmltree.Element, message []byte) error {
+	response := child(root, "response")
+	if response == nil {
+		return indentXML(p.w, message, nil)
+	}
+
+	var buf bytes.Buffer
+
+	for _, result := range children(response, "result") {
+		fmt.Fprintf(&buf, "%s %s\n", result.Attr("", "code"), text(child(result, "msg")))
+
+		for _, reason := range children(result, "extValue") {
+			fmt.Fprintf(&buf, "  reason: %s\n", text(child(reason, "reason")))
+		}
+	}
+
+	if msgQ := child(response, "msgQ"); msgQ != nil {
+		fmt.Fprintf(&buf, "queue: %s message(s), id %s", msgQ.Attr("", "count"), msgQ.Attr("", "id"))
+
+		if date := text(child(msgQ, "qDate")); date != "" {
+			fmt.Fprintf(&buf, ", queued %s", date)
+		}
+
+		if msg := text(child(msgQ, "msg")); msg != "" {
+			fmt.Fprintf(&buf, ": %s", msg)
+		}
+
+		buf.WriteString("\n")
+	}
+
+	for _, name := range []string{"resData", "extension"} {
+		if err := indentXML(&buf, message, []string{"epp", "response", name}); err != nil {
+			return err
+		}
+	}
+
+	if trID := child(response, "trID"); trID != nil {
+		fmt.Fprintf(&buf, "clTRID: %s svTRID: %s\n", orDash(text(child(trID, "clTRID"))), orDash(text(child(trID, "svTRID"))))
+	}
+
+	_, err := p.w.Write(buf.Bytes())
+
+	return err
+}
+
+// indentXML writes the XML in data indented with each element on its own line.
+// Elements only containing text are written on a single line. If parent is
+// set only the descendants of the element with the path of local names are
+// written. Prefixes are kept as in data.
+func indentXML(w io.Writer, data []byte, parent []string) error {
+	var (
+		d       = xml.NewDecoder(bytes.NewReader(data))
+		buf     bytes.Buffer
+		path    []string
+		depth   int
+		open    bool
+		hasText bool
+	)
+
+	inside := func() bool {
+		if len(path) <= len(parent) {
+			return false
+		}
+
+		for i, name := range parent {
+			if path[i] != name {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for {
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.ProcInst:
+			if parent == nil {
+				fmt.Fprintf(&buf, "<?%s %s?>\n", t.Target, t.Inst)
+			}
+		case xml.StartElement:
+			path = append(path, t.Name.Local)
+
+			if !inside() {
+				continue
+			}
+
+			if open {
+				buf.WriteString(">\n")
+			}
+
+			buf.WriteString(strings.Repeat("  ", depth) + "<" + qualifiedName(t.Name))
+
+			for _, attr := range t.Attr {
+				buf.WriteString(" " + qualifiedName(attr.Name) + `="`)
+				_ = xml.EscapeText(&buf, []byte(attr.Value))
+				buf.WriteString(`"`)
+			}
+
+			depth++
+			open, hasText = true, false
+		case xml.CharData:
+			content := bytes.TrimSpace(t)
+			if !inside() || len(content) == 0 {
+				continue
+			}
+
+			if open {
+				buf.WriteString(">")
+			}
+
+			_ = xml.EscapeText(&buf, content)
+			open, hasText = false, true
+		case xml.EndElement:
+			if inside() {
+				depth--
+
+				switch {
+				case open:
+					buf.WriteString(" />\n")
+				case hasText:
+					buf.WriteString("</" + qualifiedName(t.Name) + ">\n")
+				default:
+					buf.WriteString(strings.Repeat("  ", depth) + "</" + qualifiedName(t.Name) + ">\n")
+				}
+
+				open, hasText = false, false
+			}
+
+			path = path[:len(path)-1]
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+// qualifiedName returns the name with its prefix as written in the document.
+// Names from RawToken holds the prefix in Space.
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+
+	return name.Space + ":" + name.Local
+}
+
+// resultCode returns the first result code in the response or 0 if the
+// message isn't a response.
+func resultCode(message []byte) int {
+	root, err := xmltree.Parse(message)
+	if err != nil {
+		return 0
+	}
+
+	result := child(child(root, "response"), "result")
+	if result == nil {
+		return 0
+	}
+
+	code, _ := strconv.Atoi(result.Attr("", "code"))
+
+	return code
+}
+
+// elementValue converts an element to a value suitable for JSON. Elements
+// without attributes and children are represented by their text. Otherwise a
+// map is returned with attributes prefixed with @, the text as #text and the
+// children by their local name. Children occurring more than once are
+// represented as a list.
+func elementValue(el *xmltree.Element) interface{} {
+	attributes := make([]struct{ name, value string }, 0, len(el.StartElement.Attr))
+
+	for _, attr := range el.StartElement.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" || attr.Name.Local == "schemaLocation" {
+			continue
+		}
+
+		attributes = append(attributes, struct{ name, value string }{attr.Name.Local, attr.Value})
+	}
+
+	content := text(el)
+
+	if len(attributes) == 0 && len(el.Children) == 0 {
+		return content
+	}
+
+	m := map[string]interface{}{}
+
+	for _, attr := range attributes {
+		m["@"+attr.name] = attr.value
+	}
+
+	if len(el.Children) == 0 && content != "" {
+		m["#text"] = content
+	}
+
+	for i := range el.Children {
+		key := el.Children[i].Name.Local
+		value := elementValue(&el.Children[i])
+
+		switch existing := m[key].(type) {
+		case nil:
+			m[key] = value
+		case []interface{}:
+			m[key] = append(existing, value)
+		default:
+			m[key] = []interface{}{existing, value}
+		}
+	}
+
+	return m
+}
+
+// child returns the first child with the local name or nil.
+func child(el *xmltree.Element, local string) *xmltree.Element {
+	if el == nil {
+		return nil
+	}
+
+	for i := range el.Children {
+		if el.Children[i].Name.Local == local {
+			return &el.Children[i]
+		}
+	}
+
+	return nil
+}
+
+// children returns all children with the local name.
+func children(el *xmltree.Element, local string) []*xmltree.Element {
+	var found []*xmltree.Element
+
+	for i := range el.Children {
+		if el.Children[i].Name.Local == local {
+			found = append(found, &el.Children[i])
+		}
+	}
+
+	return found
+}
+
+// text returns the trimmed text content of the element or an empty string if
+// el is nil.
+func text(el *xmltree.Element) string {
+	if el == nil || len(el.Children) > 0 {
+		return ""
+	}
+
+	// The content is the raw XML so entities and character references must
+	// be unescaped. The predefined XML entities are a subset of the HTML
+	// entities.
+	return html.UnescapeString(strings.TrimSpace(string(el.Content)))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+// maxHistory is the maximum number of lines kept in the history.
+const maxHistory = 1000
+
+// prompt is the prompt shown before each line in the REPL.
+const prompt = "eppctl> "
+
+// history holds the lines entered in the REPL so they can be listed and run
+// again by number. Each line is appended to the history file when added so
+// it's kept between sessions.
+type history struct {
+	path  string
+	lines []string
+}
+
+// loadHistory reads the history from the file at path. If path is empty the
+// history is only kept in memory.
+func loadHistory(path string) (*history, error) {
+	h := &history{path: path}
+
+	if path == "" {
+		return h, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.lines = append(h.lines, line)
+		}
+	}
+
+	if len(h.lines) > maxHistory {
+		h.lines = h.lines[len(h.lines)-maxHistory:]
+	}
+
+	return h, nil
+}
+
+// add adds the line to the history and appends it to the file.
+func (h *history) add(line string) error {
+	if len(h.lines) > 0 && h.lines[len(h.lines)-1] == line {
+		return nil
+	}
+
+	h.lines = append(h.lines, line)
+
+	if h.path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
+
+// expand replaces a reference to the history with the referenced line. !!
+// refers to the previous line and !n to line n as listed by the history
+// command.
+func (h *history) expand(line string) (string, error) {
+	if !strings.HasPrefix(line, "!") {
+		return line, nil
+	}
+
+	if line == "!!" {
+		if len(h.lines) == 0 {
+			return "", errors.New("no commands in history")
+		}
+
+		return h.lines[len(h.lines)-1], nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 || n > len(h.lines) {
+		return "", fmt.Errorf("no such history entry: %s", line[1:])
+	}
+
+	return h.lines[n-1], nil
+}
+
+// lineReader reads the lines entered in the REPL.
+type lineReader interface {
+	// readLine returns the next line or io.EOF at the end of the input.
+	// errInterrupted is returned if the line is discarded with Ctrl-C.
+	readLine() (string, error)
+
+	// addHistory adds the line to the history navigated while editing.
+	addHistory(line string)
+
+	// close releases the terminal.
+	close() error
+}
+
+// errInterrupted is returned by a lineReader when the line being edited is
+// discarded.
+var errInterrupted = errors.New("interrupted")
+
+// lineEditor is a lineReader for terminals with line editing. The history is
+// navigated with the up and down arrows and searched with Ctrl-R.
+type lineEditor struct {
+	rl *readline.Instance
+}
+
+// newLineEditor creates a lineEditor for the terminal with the lines in the
+// history.
+func newLineEditor(h *history) (*lineEditor, error) {
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:                 prompt,
+		HistoryLimit:           maxHistory,
+		DisableAutoSaveHistory: true,
+		HistorySearchFold:      true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	e := &lineEditor{rl: rl}
+
+	for _, line := range h.lines {
+		e.addHistory(line)
+	}
+
+	return e, nil
+}
+
+func (e *lineEditor) readLine() (string, error) {
+	line, err := e.rl.Readline()
+	if errors.Is(err, readline.ErrInterrupt) {
+		return "", errInterrupted
+	}
+
+	return line, err
+}
+
+func (e *lineEditor) addHistory(line string) {
+	_ = e.rl.SaveHistory(line)
+}
+
+func (e *lineEditor) close() error {
+	return e.rl.Close()
+}
+
+// lineScanner is a lineReader without line editing used when the input isn't
+// a terminal, e.g. when commands are piped to eppctl.
+type lineScanner struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+func (s *lineScanner) readLine() (string, error) {
+	fmt.Fprint(s.out, prompt)
+
+	if !s.in.Scan() {
+		fmt.Fprintln(s.out)
+
+		if err := s.in.Err(); err != nil {
+			return "", err
+		}
+
+		return "", io.EOF
+	}
+
+	return s.in.Text(), nil
+}
+
+func (s *lineScanner) addHistory(string) {}
+
+func (s *lineScanner) close() error {
+	return nil
+}
+
+// repl reads commands from in and runs them until EOF or exit.
+type repl struct {
+	in      lineReader
+	out     io.Writer
+	history *history
+	run     func(args []string) error
+}
+
+func (r *repl) loop() error {
+	fmt.Fprintln(r.out, `Type "help" for a list of commands and "exit" to quit.`)
+
+	for {
+		line, err := r.in.readLine()
+
+		switch {
+		case errors.Is(err, errInterrupted):
+			continue
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		line, err = r.history.expand(strings.TrimSpace(line))
+		if err != nil {
+			fmt.Fprintln(r.out, err.Error())
+
+			continue
+		}
+
+		if line == "" {
+			continue
+		}
+
+		r.in.addHistory(line)
+
+		if err := r.history.add(line); err != nil {
+			fmt.Fprintf(r.out, "could not save history: %s\n", err.Error())
+		}
+
+		args, err := splitLine(line)
+		if err != nil {
+			fmt.Fprintln(r.out, err.Error())
+
+			continue
+		}
+
+		switch args[0] {
+		case "exit", "quit":
+			return nil
+		case "help":
+			printUsage(r.out)
+			fmt.Fprintln(r.out, "  history\n        list the history, run an entry again with !n or the last with !!")
+			fmt.Fprintln(r.out, "  exit\n        logout and exit")
+
+			continue
+		case "history":
+			for i, l := range r.history.lines {
+				fmt.Fprintf(r.out, "%5d  %s\n", i+1, l)
+			}
+
+			continue
+		}
+
+		if err := r.run(args); err != nil {
+			fmt.Fprintln(r.out, err.Error())
+		}
+	}
+}
+
+// splitLine splits a line into arguments separated by whitespace. Arguments
+// may be quoted with single or double quotes to include whitespace.
+func splitLine(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		quote   rune
+		inArg   bool
+	)
+
+	for _, c := range line {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(c)
+		case c == '"' || c == '\'':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote %c", quote)
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,109 @@
+// Command eppreplay replays a transcript recorded with a TranscriptWriter
+// against an EPP server and prints the differences between the responses in
+// the transcript and the responses from the server. Server transaction IDs,
+// dates and ROIDs are normalized before comparing. The exit status is 1 if any
+// response differs.
+//
+// Passwords and authorization information are redacted in transcripts by
+// default. Use -password, -new-password and -auth to restore them before the
+// requests are sent and -clid to login as another client.
+//
+//	eppreplay -addr epp.example.com:700 -cert client.pem -key client.key -password secret session.transcript
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	epp "github.com/bombsimon/epp-go"
+)
+
+func main() {
+	var (
+		addr        = flag.String("addr", "localhost:700", "address of the EPP server")
+		certFile    = flag.String("cert", "", "client certificate file")
+		keyFile     = flag.String("key", "", "client key file")
+		insecure    = flag.Bool("insecure", false, "skip verification of the server certificate")
+		keepTimings = flag.Bool("timings", false, "wait between requests as in the transcript")
+		clientID    = flag.String("clid", "", "client ID to login with instead of the one in the transcript")
+		password    = flag.String("password", "", "password replacing the redacted password in login commands")
+		newPassword = flag.String("new-password", "", "password replacing the redacted new password in login commands")
+		authInfo    = flag.String("auth", "", "password replacing redacted authorization information")
+	)
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] transcript\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	entries, err := epp.ReadTranscript(f)
+	_ = f.Close()
+
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: *insecure,
+	}
+
+	if *certFile != "" {
+		cert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	conn, err := tls.Dial("tcp", *addr, tlsConfig)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	defer conn.Close()
+
+	credentials := epp.ReplayCredentials{
+		ClientID:    *clientID,
+		Password:    *password,
+		NewPassword: *newPassword,
+		AuthInfo:    *authInfo,
+	}
+
+	replayer := &epp.Replayer{
+		KeepTimings: *keepTimings,
+		Rewrite:     credentials.Rewrite,
+	}
+
+	differences, err := replayer.Replay(conn, entries)
+
+	for _, d := range differences {
+		fmt.Printf("--- response %d differs\n", d.Index)
+		fmt.Print(d.Diff)
+	}
+
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if len(differences) > 0 {
+		os.Exit(1)
+	}
+
+	fmt.Printf("replayed %d messages without differences\n", len(entries))
+}
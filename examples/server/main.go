@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -24,13 +25,15 @@ import (
 func main() {
 	mux := epp.NewMux()
 
-	validator, err := epp.NewValidator("./xml/index.xsd")
+	validator, err := epp.NewDefaultValidator()
 	if err != nil {
 		panic(err)
 	}
 
 	server := epp.Server{
-		Addr: ":4701",
+		Addr:                 ":4701",
+		MaxSessions:          100,
+		MaxSessionsPerClient: 5,
 		TLSConfig: &tls.Config{
 			Certificates: []tls.Certificate{generateCertificate()},
 			ClientAuth:   tls.RequireAnyClientCert,
@@ -59,7 +62,13 @@ func main() {
 		sigs := make(chan os.Signal, 1)
 		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 		<-sigs
-		server.Stop()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := server.Shutdown(ctx); err != nil {
+			log.Println(err.Error())
+		}
 	}()
 
 	log.Println("Running server...")
@@ -91,6 +100,12 @@ func greeting(s *epp.Session) ([]byte, error) {
 					types.NameSpaceContact,
 					types.NameSpaceHost,
 				},
+				ServiceExtension: &types.ServiceExtension{
+					ExtensionURI: []string{
+						types.NameSpaceDNSSEC11,
+						types.NameSpaceIIS12,
+					},
+				},
 			},
 			DCP: types.DCP{
 				Access: types.DCPAccess{
@@ -124,11 +139,18 @@ func login(s *epp.Session, data []byte) ([]byte, error) {
 
 	// Authenticate the user found in login type.
 
+	code := epp.EppOk
+
+	// Enforce the maximum number of sessions per client.
+	if err := s.Login(login.ClientID); err != nil {
+		code = epp.EppSessionLimitExceededBye
+	}
+
 	response := types.Response{
 		Result: []types.Result{
 			{
-				Code:    epp.EppOk.Code(),
-				Message: epp.EppOk.Message(),
+				Code:    code.Code(),
+				Message: code.Message(),
 			},
 		},
 		TransactionID: types.TransactionID{
@@ -143,9 +165,9 @@ func login(s *epp.Session, data []byte) ([]byte, error) {
 }
 
 func infoDomainWithExtension(s *epp.Session, data []byte) ([]byte, error) {
-	di := types.DomainInfoTypeIn{}
+	di := types.DomainInfoType{}
 
-	if err := xml.Unmarshal(data, &di); err != nil {
+	if err := epp.Decode(data, &di); err != nil {
 		return nil, err
 	}
 
@@ -222,9 +244,9 @@ func infoDomainWithExtension(s *epp.Session, data []byte) ([]byte, error) {
 }
 
 func createDomain(s *epp.Session, data []byte) ([]byte, error) {
-	dc := types.DomainCreateTypeIn{}
+	dc := types.DomainCreateType{}
 
-	if err := xml.Unmarshal(data, &dc); err != nil {
+	if err := epp.Decode(data, &dc); err != nil {
 		return nil, err
 	}
 
@@ -238,11 +260,11 @@ func createDomain(s *epp.Session, data []byte) ([]byte, error) {
 
 func createContactWithExtension(s *epp.Session, data []byte) ([]byte, error) {
 	cc := struct {
-		types.ContactCreate
-		types.IISExtensionCreate
+		types.ContactCreateType
+		types.IISExtensionCreateType
 	}{}
 
-	if err := xml.Unmarshal(data, &cc); err != nil {
+	if err := epp.Decode(data, &cc); err != nil {
 		return nil, err
 	}
 
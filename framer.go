@@ -0,0 +1,279 @@
+package epp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// DefaultMaxFrameSize is the maximum size of a frame, including the four
+// byte length header, used when no maximum frame size is configured.
+const DefaultMaxFrameSize = 1 << 20
+
+// DefaultFrameTimeout is the time allowed to read the content of a frame once
+// the header is read and to write a frame, used by ReadMessage, WriteMessage
+// and frames created with NewFramer.
+const DefaultFrameTimeout = 10 * time.Second
+
+// frameHeaderSize is the size of the length header preceding each message.
+const frameHeaderSize = 4
+
+// ErrInvalidFrameHeader is returned when the length in a frame header is
+// smaller than the header itself.
+var ErrInvalidFrameHeader = errors.New("frame length is smaller than the frame header")
+
+// FrameSizeError is returned when the length in a frame header is larger than
+// the maximum frame size. Only the header is read so the content must be
+// discarded with DiscardFrame to continue reading messages.
+type FrameSizeError struct {
+	// Size is the size of the frame, including the header.
+	Size uint32
+
+	// MaxSize is the maximum size allowed.
+	MaxSize int
+}
+
+// Error implements the error interface.
+func (e *FrameSizeError) Error() string {
+	return fmt.Sprintf("frame size %d exceeds max frame size %d", e.Size, e.MaxSize)
+}
+
+// FrameTimeoutError is returned when the content of a frame isn't read within
+// the read timeout. The header and possibly parts of the content are already
+// read so the stream is out of sync and no more messages can be read. A
+// timeout while reading the header is returned as is since the part of the
+// header read is kept and reading may be retried.
+type FrameTimeoutError struct {
+	// Size is the size of the frame, including the header.
+	Size uint32
+
+	// Err is the timeout error from the reader.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *FrameTimeoutError) Error() string {
+	return fmt.Sprintf("timeout reading content of frame with size %d: %s", e.Size, e.Err)
+}
+
+// Unwrap returns the timeout error from the reader.
+func (e *FrameTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// timeout is implemented by errors which may be timeouts, such as net.Error.
+type timeout interface {
+	Timeout() bool
+}
+
+// readDeadliner is implemented by connections supporting read deadlines, such
+// as net.Conn.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// writeDeadliner is implemented by connections supporting write deadlines,
+// such as net.Conn.
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// Framer reads and writes EPP frames as described in RFC 5734 section 4, that
+// is each message preceded by a four byte header holding the total length of
+// the frame. A Framer may be used over any io.ReadWriter such as a net.Conn, a
+// pipe or an in-memory buffer. Reads are buffered and the buffers are reused
+// between frames. A Framer isn't safe to use from multiple goroutines at the
+// same time, although one goroutine may read while another one writes.
+type Framer struct {
+	// MaxFrameSize is the maximum size of a frame to read, including the
+	// header. If zero DefaultMaxFrameSize is used.
+	MaxFrameSize int
+
+	// ReadTimeout is the time allowed to read the content of a frame once the
+	// header is read. The deadline is only set if the underlying reader
+	// implements SetReadDeadline. If zero no deadline is set.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the time allowed to write a frame. The deadline is only
+	// set if the underlying writer implements SetWriteDeadline. If zero no
+	// deadline is set.
+	WriteTimeout time.Duration
+
+	// r and w is the reader and writer to use while conn is the original
+	// value to set deadlines on since the reader may be buffered.
+	r    io.Reader
+	w    io.Writer
+	conn interface{}
+
+	header     [frameHeaderSize]byte
+	headerRead int
+	readBuf    []byte
+	writeBuf   []byte
+}
+
+// NewFramer creates a new Framer reading from and writing to rw. The read and
+// write timeouts are set to DefaultFrameTimeout.
+func NewFramer(rw io.ReadWriter) *Framer {
+	return &Framer{
+		ReadTimeout:  DefaultFrameTimeout,
+		WriteTimeout: DefaultFrameTimeout,
+		r:            bufio.NewReader(rw),
+		w:            rw,
+		conn:         rw,
+	}
+}
+
+// ReadMessage reads one full message. The returned slice is owned by the
+// caller. If the frame is larger than MaxFrameSize a *FrameSizeError is
+// returned and if the length in the header is smaller than the header
+// ErrInvalidFrameHeader is returned. A timeout while reading the content is
+// returned as a *FrameTimeoutError.
+func (f *Framer) ReadMessage() ([]byte, error) {
+	return f.readFrame(false)
+}
+
+// ReadFrame works like ReadMessage but returns a slice of a buffer reused
+// between frames. The returned slice is only valid until the next call to
+// ReadFrame.
+func (f *Framer) ReadFrame() ([]byte, error) {
+	return f.readFrame(true)
+}
+
+func (f *Framer) readFrame(reuse bool) ([]byte, error) {
+	// https://tools.ietf.org/html/rfc5734#section-4
+	// A partially read header is kept so reading may be retried after an
+	// error such as a timeout without losing sync with the stream.
+	for f.headerRead < frameHeaderSize {
+		n, err := f.r.Read(f.header[f.headerRead:])
+		f.headerRead += n
+
+		if err != nil {
+			if err == io.EOF && f.headerRead > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+
+			return nil, err
+		}
+	}
+
+	f.headerRead = 0
+	totalSize := binary.BigEndian.Uint32(f.header[:])
+
+	// Ensure a reasonable time for reading the message.
+	if err := f.setReadDeadline(); err != nil {
+		return nil, err
+	}
+
+	if totalSize < frameHeaderSize {
+		return nil, ErrInvalidFrameHeader
+	}
+
+	maxSize := f.MaxFrameSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFrameSize
+	}
+
+	if int64(totalSize) > int64(maxSize) {
+		return nil, &FrameSizeError{
+			Size:    totalSize,
+			MaxSize: maxSize,
+		}
+	}
+
+	contentSize := int(totalSize) - frameHeaderSize
+
+	var buf []byte
+
+	if reuse {
+		if cap(f.readBuf) < contentSize {
+			f.readBuf = make([]byte, contentSize)
+		}
+
+		buf = f.readBuf[:contentSize]
+	} else {
+		buf = make([]byte, contentSize)
+	}
+
+	if _, err := io.ReadFull(f.r, buf); err != nil {
+		var t timeout
+		if errors.As(err, &t) && t.Timeout() {
+			return nil, &FrameTimeoutError{Size: totalSize, Err: err}
+		}
+
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// DiscardFrame reads and discards the content of a frame where only the header
+// has been read, e.g. after a *FrameSizeError.
+func (f *Framer) DiscardFrame(size uint32) error {
+	if size < frameHeaderSize {
+		return ErrInvalidFrameHeader
+	}
+
+	_, err := io.CopyN(io.Discard, f.r, int64(size)-frameHeaderSize)
+
+	return err
+}
+
+// WriteMessage writes data with the correct header. The header and the data
+// are written with a single write.
+func (f *Framer) WriteMessage(data []byte) error {
+	// Begin by writing the len(b) as Big Endian uint32, including the
+	// size of the content length header.
+	// https://tools.ietf.org/html/rfc5734#section-4
+	totalSize := len(data) + frameHeaderSize
+
+	// Bounds check.
+	if totalSize > math.MaxUint32 {
+		return errors.New("content is too large")
+	}
+
+	if err := f.setWriteDeadline(); err != nil {
+		return err
+	}
+
+	if cap(f.writeBuf) < totalSize {
+		f.writeBuf = make([]byte, totalSize)
+	}
+
+	buf := f.writeBuf[:totalSize]
+	binary.BigEndian.PutUint32(buf, uint32(totalSize))
+	copy(buf[frameHeaderSize:], data)
+
+	_, err := f.w.Write(buf)
+
+	return err
+}
+
+func (f *Framer) setReadDeadline() error {
+	if f.ReadTimeout <= 0 {
+		return nil
+	}
+
+	conn, ok := f.conn.(readDeadliner)
+	if !ok {
+		return nil
+	}
+
+	return conn.SetReadDeadline(time.Now().Add(f.ReadTimeout))
+}
+
+func (f *Framer) setWriteDeadline() error {
+	if f.WriteTimeout <= 0 {
+		return nil
+	}
+
+	conn, ok := f.conn.(writeDeadliner)
+	if !ok {
+		return nil
+	}
+
+	return conn.SetWriteDeadline(time.Now().Add(f.WriteTimeout))
+}
@@ -0,0 +1,164 @@
+package epp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFramer(t *testing.T) {
+	conn1, conn2 := net.Pipe()
+
+	client, server := NewFramer(conn1), NewFramer(conn2)
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			err := client.WriteMessage([]byte(fmt.Sprintf("ping %d", i)))
+			require.Nil(t, err)
+
+			message, err := client.ReadMessage()
+			require.Nil(t, err)
+			assert.Equal(t, fmt.Sprintf("pong %d", i), string(message))
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		message, err := server.ReadFrame()
+		require.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("ping %d", i), string(message))
+
+		err = server.WriteMessage([]byte(fmt.Sprintf("pong %d", i)))
+		require.Nil(t, err)
+	}
+}
+
+func TestFramer_buffer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	f := NewFramer(buf)
+
+	for _, message := range []string{"first", "second message", ""} {
+		require.Nil(t, f.WriteMessage([]byte(message)))
+	}
+
+	assert.Equal(t, []byte{0, 0, 0, 9, 'f', 'i', 'r', 's', 't'}, buf.Bytes()[:9])
+
+	first, err := f.ReadMessage()
+	require.Nil(t, err)
+
+	second, err := f.ReadFrame()
+	require.Nil(t, err)
+	assert.Equal(t, "second message", string(second))
+
+	third, err := f.ReadFrame()
+	require.Nil(t, err)
+	assert.Equal(t, "", string(third))
+
+	// The buffer from ReadFrame is reused but the message from ReadMessage
+	// is owned by the caller.
+	assert.True(t, &second[0] == &third[:1][0])
+	assert.Equal(t, "first", string(first))
+}
+
+func TestFramer_partialHeader(t *testing.T) {
+	frames := &bytes.Buffer{}
+	require.Nil(t, WriteMessage(frames, []byte("ping")))
+
+	// Return a timeout after the first two bytes of the header.
+	r := &timeoutReader{
+		data:      frames.Bytes(),
+		timeoutAt: 2,
+	}
+
+	f := &Framer{r: iotest.OneByteReader(r)}
+
+	_, err := f.ReadMessage()
+	require.NotNil(t, err)
+	assert.True(t, err.(net.Error).Timeout())
+
+	message, err := f.ReadMessage()
+	require.Nil(t, err)
+	assert.Equal(t, "ping", string(message))
+}
+
+func TestFramer_contentTimeout(t *testing.T) {
+	frames := &bytes.Buffer{}
+	require.Nil(t, WriteMessage(frames, []byte("ping")))
+
+	// Return a timeout after the header and two bytes of the content.
+	r := &timeoutReader{
+		data:      frames.Bytes(),
+		timeoutAt: 6,
+	}
+
+	f := &Framer{r: iotest.OneByteReader(r)}
+
+	_, err := f.ReadMessage()
+
+	timeoutErr, ok := err.(*FrameTimeoutError)
+	require.True(t, ok)
+	assert.Equal(t, uint32(8), timeoutErr.Size)
+	assert.Equal(t, timeoutError{}, errors.Unwrap(err))
+
+	// A timeout reading the content isn't a net.Error since reading can't be
+	// retried.
+	_, ok = err.(net.Error)
+	assert.False(t, ok)
+}
+
+func TestFramer_DiscardFrame(t *testing.T) {
+	frames := &bytes.Buffer{}
+	require.Nil(t, WriteMessage(frames, []byte("too large")))
+	require.Nil(t, WriteMessage(frames, []byte("ping")))
+
+	f := NewFramer(frames)
+	f.MaxFrameSize = 8
+
+	_, err := f.ReadMessage()
+
+	sizeErr, ok := err.(*FrameSizeError)
+	require.True(t, ok)
+	assert.Equal(t, uint32(13), sizeErr.Size)
+
+	require.Nil(t, f.DiscardFrame(sizeErr.Size))
+
+	message, err := f.ReadMessage()
+	require.Nil(t, err)
+	assert.Equal(t, "ping", string(message))
+}
+
+// timeoutReader returns a timeout error once when timeoutAt bytes are read.
+type timeoutReader struct {
+	data      []byte
+	read      int
+	timeoutAt int
+}
+
+func (r *timeoutReader) Read(b []byte) (int, error) {
+	if r.read == r.timeoutAt {
+		r.timeoutAt = -1
+
+		return 0, timeoutError{}
+	}
+
+	if r.read == len(r.data) {
+		return 0, io.EOF
+	}
+
+	n := copy(b, r.data[r.read:])
+	r.read += n
+
+	return n, nil
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
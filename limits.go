@@ -0,0 +1,149 @@
+package epp
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"sync"
+)
+
+// ErrSessionLimitExceeded is returned when a new session would exceed any of
+// the configured session limits.
+var ErrSessionLimitExceeded = errors.New("session limit exceeded")
+
+// SessionCounts holds the number of active sessions on a server.
+type SessionCounts struct {
+	// Total is the total number of sessions.
+	Total int
+
+	// Clients holds the number of sessions for each client ID that has logged
+	// in.
+	Clients map[string]int
+
+	// Certificates holds the number of sessions for each client certificate,
+	// identified by CertificateFingerprint.
+	Certificates map[string]int
+}
+
+// CertificateFingerprint returns the hex encoded SHA-256 fingerprint of the
+// certificate, used to identify client certificates in session limits.
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionCounter keeps track of the number of sessions in total, per client ID
+// and per client certificate and enforces the limits for each. A limit of zero
+// means no limit.
+type sessionCounter struct {
+	mu sync.Mutex
+
+	maxSessions       int
+	maxPerClient      int
+	maxPerCertificate int
+
+	total        int
+	clients      map[string]int
+	certificates map[string]int
+}
+
+func newSessionCounter(maxSessions, maxPerClient, maxPerCertificate int) *sessionCounter {
+	return &sessionCounter{
+		maxSessions:       maxSessions,
+		maxPerClient:      maxPerClient,
+		maxPerCertificate: maxPerCertificate,
+		clients:           map[string]int{},
+		certificates:      map[string]int{},
+	}
+}
+
+// acquire will count a new session for the certificate. The certificate may be
+// empty if the client didn't present any certificate.
+func (c *sessionCounter) acquire(certificate string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxSessions > 0 && c.total >= c.maxSessions {
+		return ErrSessionLimitExceeded
+	}
+
+	if certificate != "" && c.maxPerCertificate > 0 && c.certificates[certificate] >= c.maxPerCertificate {
+		return ErrSessionLimitExceeded
+	}
+
+	c.total++
+
+	if certificate != "" {
+		c.certificates[certificate]++
+	}
+
+	return nil
+}
+
+// acquireClient will count a session for the client ID after a login.
+func (c *sessionCounter) acquireClient(clientID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxPerClient > 0 && c.clients[clientID] >= c.maxPerClient {
+		return ErrSessionLimitExceeded
+	}
+
+	c.clients[clientID]++
+
+	return nil
+}
+
+// releaseClient will remove a session for the client ID.
+func (c *sessionCounter) releaseClient(clientID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	decrement(c.clients, clientID)
+}
+
+// release will remove a session for the certificate.
+func (c *sessionCounter) release(certificate string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.total--
+
+	if certificate != "" {
+		decrement(c.certificates, certificate)
+	}
+}
+
+// counts returns a copy of the current counts.
+func (c *sessionCounter) counts() SessionCounts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := SessionCounts{
+		Total:        c.total,
+		Clients:      make(map[string]int, len(c.clients)),
+		Certificates: make(map[string]int, len(c.certificates)),
+	}
+
+	for k, v := range c.clients {
+		counts.Clients[k] = v
+	}
+
+	for k, v := range c.certificates {
+		counts.Certificates[k] = v
+	}
+
+	return counts
+}
+
+// decrement will decrement the count for key and remove it when reaching
+// zero.
+func decrement(m map[string]int, key string) {
+	m[key]--
+
+	if m[key] <= 0 {
+		delete(m, key)
+	}
+}
@@ -0,0 +1,114 @@
+package epp
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// LogLevel represents the severity of a log message.
+type LogLevel int
+
+// Constants representing the available log levels.
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+// String returns the name of the log level.
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "DEBUG"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
+	case LogLevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
+// Keys for the fields added to log messages.
+const (
+	FieldSessionID           = "session_id"
+	FieldRemoteAddr          = "remote_addr"
+	FieldClientID            = "client_id"
+	FieldClientTransactionID = "cltrid"
+	FieldServerTransactionID = "svtrid"
+	FieldCommand             = "command"
+	FieldResultCode          = "result_code"
+	FieldLatency             = "latency"
+	FieldError               = "error"
+)
+
+// Field represents a key and value added to a log message.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger is the interface used by Server, Session and Client to log messages.
+// Each message holds fields such as the session ID, the client ID and the
+// result code where available.
+type Logger interface {
+	Log(level LogLevel, msg string, fields ...Field)
+}
+
+// NopLogger is a Logger discarding all messages.
+type NopLogger struct{}
+
+// Log implements Logger.
+func (NopLogger) Log(LogLevel, string, ...Field) {}
+
+// StdLogger is a Logger writing messages with the fields as key=value pairs
+// to a logger from the log package. Messages below Level are discarded.
+type StdLogger struct {
+	Logger *log.Logger
+	Level  LogLevel
+}
+
+// NewStdLogger creates a new StdLogger writing to l. If l is nil the standard
+// logger from the log package is used.
+func NewStdLogger(l *log.Logger) *StdLogger {
+	if l == nil {
+		l = log.Default()
+	}
+
+	return &StdLogger{
+		Logger: l,
+		Level:  LogLevelInfo,
+	}
+}
+
+// Log implements Logger.
+func (l *StdLogger) Log(level LogLevel, msg string, fields ...Field) {
+	if level < l.Level {
+		return
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(level.String())
+	sb.WriteString(" ")
+	sb.WriteString(msg)
+
+	for _, f := range fields {
+		fmt.Fprintf(&sb, " %s=%v", f.Key, f.Value)
+	}
+
+	l.Logger.Print(sb.String())
+}
+
+// defaultLogger returns l or a StdLogger using the standard logger if l is nil.
+func defaultLogger(l Logger) Logger {
+	if l == nil {
+		return NewStdLogger(nil)
+	}
+
+	return l
+}
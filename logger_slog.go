@@ -0,0 +1,52 @@
+//go:build go1.21
+// +build go1.21
+
+package epp
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger is a Logger writing messages to a logger from the log/slog
+// package with each field as an attribute.
+type SlogLogger struct {
+	Logger *slog.Logger
+}
+
+// NewSlogLogger creates a new SlogLogger writing to l. If l is nil the default
+// logger from the log/slog package is used.
+func NewSlogLogger(l *slog.Logger) *SlogLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+
+	return &SlogLogger{
+		Logger: l,
+	}
+}
+
+// Log implements Logger.
+func (l *SlogLogger) Log(level LogLevel, msg string, fields ...Field) {
+	attrs := make([]slog.Attr, 0, len(fields))
+
+	for _, f := range fields {
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+
+	l.Logger.LogAttrs(context.Background(), slogLevel(level), msg, attrs...)
+}
+
+// slogLevel returns the slog level for level.
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LogLevelDebug:
+		return slog.LevelDebug
+	case LogLevelInfo:
+		return slog.LevelInfo
+	case LogLevelWarn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
@@ -0,0 +1,34 @@
+//go:build go1.21
+// +build go1.21
+
+package epp
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	})
+
+	logger := NewSlogLogger(slog.New(handler))
+
+	logger.Log(LogLevelDebug, "discarded")
+	logger.Log(LogLevelWarn, "session completed", Field{Key: FieldSessionID, Value: "abc"}, Field{Key: FieldResultCode, Value: 1000})
+
+	assert.Equal(t, "level=WARN msg=\"session completed\" session_id=abc result_code=1000\n", buf.String())
+}
@@ -0,0 +1,178 @@
+package epp
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type logEntry struct {
+	level  LogLevel
+	msg    string
+	fields map[string]interface{}
+}
+
+type memoryLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (l *memoryLogger) Log(level LogLevel, msg string, fields ...Field) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := logEntry{
+		level:  level,
+		msg:    msg,
+		fields: map[string]interface{}{},
+	}
+
+	for _, f := range fields {
+		entry.fields[f.Key] = f.Value
+	}
+
+	l.entries = append(l.entries, entry)
+}
+
+func (l *memoryLogger) find(msg string) (logEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, e := range l.entries {
+		if e.msg == msg {
+			return e, true
+		}
+	}
+
+	return logEntry{}, false
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := NewStdLogger(log.New(&buf, "", 0))
+
+	logger.Log(LogLevelDebug, "discarded")
+	logger.Log(LogLevelWarn, "session completed", Field{Key: FieldSessionID, Value: "abc"}, Field{Key: FieldResultCode, Value: 1000})
+
+	assert.Equal(t, "WARN session completed session_id=abc result_code=1000\n", buf.String())
+}
+
+func TestCommandFields(t *testing.T) {
+	request, err := os.ReadFile("xml/commands/info-domain.xml")
+	require.Nil(t, err)
+
+	response, err := Encode(types.Response{
+		Result: []types.Result{
+			{
+				Code:    EppOk.Code(),
+				Message: EppOk.Message(),
+			},
+		},
+		TransactionID: types.TransactionID{
+			ClientTransactionID: "ABC-12345",
+			ServerTransactionID: "SRV-1",
+		},
+	}, ServerXMLAttributes())
+	require.Nil(t, err)
+
+	command := summarizeCommand(request)
+	assert.Equal(t, CommandClassQuery, command.class)
+
+	assert.Equal(t, []Field{
+		{Key: FieldCommand, Value: "command/info/domain"},
+		{Key: FieldClientTransactionID, Value: "ABC-12345"},
+		{Key: FieldServerTransactionID, Value: "SRV-1"},
+		{Key: FieldResultCode, Value: "1000"},
+	}, commandFields(command, summarizeResponse(response)))
+
+	assert.Equal(t, []Field{
+		{Key: FieldCommand, Value: "command/info/domain"},
+		{Key: FieldClientTransactionID, Value: "ABC-12345"},
+	}, commandFields(command, summarizeResponse(nil)))
+
+	assert.Nil(t, commandFields(summarizeCommand([]byte("not xml")), messageSummary{}))
+}
+
+func TestServerLogger(t *testing.T) {
+	l := newPipeListener()
+	logger := &memoryLogger{}
+
+	srv := Server{
+		AllowPlaintext: true,
+		Logger:         logger,
+		SessionConfig: SessionConfig{
+			IdleTimeout:    10 * time.Minute,
+			SessionTimeout: 10 * time.Minute,
+			Handler: func(s *Session, in []byte) ([]byte, error) {
+				require.Nil(t, s.Login("client-1"))
+
+				return Encode(types.Response{
+					Result: []types.Result{
+						{
+							Code:    EppOk.Code(),
+							Message: EppOk.Message(),
+						},
+					},
+					TransactionID: types.TransactionID{
+						ClientTransactionID: ClientTransactionID(in),
+						ServerTransactionID: "SRV-1",
+					},
+				}, ServerXMLAttributes())
+			},
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+	}
+
+	served := make(chan error)
+
+	go func() {
+		served <- srv.Serve(l)
+	}()
+
+	conn, err := l.Dial()
+	require.Nil(t, err)
+
+	framer := NewFramer(conn)
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	request, err := os.ReadFile("xml/commands/info-domain.xml")
+	require.Nil(t, err)
+
+	require.Nil(t, framer.WriteMessage(request))
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	srv.Stop()
+	require.Nil(t, conn.Close())
+	require.Nil(t, <-served)
+
+	started, ok := logger.find("starting session")
+	require.True(t, ok)
+	assert.Equal(t, LogLevelInfo, started.level)
+	assert.NotEmpty(t, started.fields[FieldSessionID])
+	assert.Equal(t, "pipe", started.fields[FieldRemoteAddr])
+
+	completed, ok := logger.find("command completed")
+	require.True(t, ok)
+	assert.Equal(t, LogLevelDebug, completed.level)
+	assert.Equal(t, started.fields[FieldSessionID], completed.fields[FieldSessionID])
+	assert.Equal(t, "client-1", completed.fields[FieldClientID])
+	assert.Equal(t, "command/info/domain", completed.fields[FieldCommand])
+	assert.Equal(t, "ABC-12345", completed.fields[FieldClientTransactionID])
+	assert.Equal(t, "SRV-1", completed.fields[FieldServerTransactionID])
+	assert.Equal(t, "1000", completed.fields[FieldResultCode])
+	assert.IsType(t, time.Duration(0), completed.fields[FieldLatency])
+}
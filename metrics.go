@@ -0,0 +1,99 @@
+package epp
+
+import "time"
+
+// SessionCloseReason represents the reason a session was closed.
+type SessionCloseReason string
+
+// Constants representing the reasons a session is closed.
+const (
+	// SessionCloseIdle is used when the session reached the idle timeout.
+	SessionCloseIdle SessionCloseReason = "idle"
+
+	// SessionCloseTimeout is used when the session reached the session
+	// timeout.
+	SessionCloseTimeout SessionCloseReason = "timeout"
+
+	// SessionCloseStop is used when the server was stopped or shut down.
+	SessionCloseStop SessionCloseReason = "stop"
+
+	// SessionCloseLimit is used when the session was rejected or closed
+	// because of the session limits.
+	SessionCloseLimit SessionCloseReason = "limit"
+
+	// SessionCloseClient is used when the client closed the connection.
+	SessionCloseClient SessionCloseReason = "client"
+
+	// SessionCloseError is used when the session ended with any other error.
+	SessionCloseError SessionCloseReason = "error"
+)
+
+// Direction represents the direction of a message.
+type Direction string
+
+// Constants representing the direction of a message as seen from the side
+// recording the metrics.
+const (
+	DirectionInbound  Direction = "in"
+	DirectionOutbound Direction = "out"
+)
+
+// Metrics is the interface used by Server, Session, Mux and Client to record
+// metrics. Implementations must be safe to use from multiple goroutines. Use
+// PrometheusMetrics to expose the metrics in the Prometheus text format.
+type Metrics interface {
+	// SessionOpened is called when a client connects to the server.
+	SessionOpened()
+
+	// SessionClosed is called when a session is closed with the reason.
+	SessionClosed(reason SessionCloseReason)
+
+	// CommandHandled is called by Session for each command passed to the
+	// handler with the command path as used by Mux, the result code from the
+	// response and the duration of the handler. The result code is empty if
+	// the handler returned an error.
+	CommandHandled(route, code string, duration time.Duration)
+
+	// ValidationFailed is called when a message fails validation by the
+	// session validator.
+	ValidationFailed(direction Direction)
+
+	// FrameSize is called with the size of each frame, including the four
+	// byte header, read or written by a session.
+	FrameSize(direction Direction, size int)
+
+	// ClientRoundTrip is called by Client.Send for each command with the
+	// route, the result code from the response and the duration until the
+	// response was read. The result code is empty if the command failed.
+	ClientRoundTrip(route, code string, duration time.Duration)
+}
+
+// NopMetrics is a Metrics discarding all metrics.
+type NopMetrics struct{}
+
+// SessionOpened implements Metrics.
+func (NopMetrics) SessionOpened() {}
+
+// SessionClosed implements Metrics.
+func (NopMetrics) SessionClosed(SessionCloseReason) {}
+
+// CommandHandled implements Metrics.
+func (NopMetrics) CommandHandled(string, string, time.Duration) {}
+
+// ValidationFailed implements Metrics.
+func (NopMetrics) ValidationFailed(Direction) {}
+
+// FrameSize implements Metrics.
+func (NopMetrics) FrameSize(Direction, int) {}
+
+// ClientRoundTrip implements Metrics.
+func (NopMetrics) ClientRoundTrip(string, string, time.Duration) {}
+
+// defaultMetrics returns m or NopMetrics if m is nil.
+func defaultMetrics(m Metrics) Metrics {
+	if m == nil {
+		return NopMetrics{}
+	}
+
+	return m
+}
@@ -26,15 +26,12 @@ type Mux struct {
 	namespaceAliases map[string]string
 }
 
-// NewMux will create and return a new Mux.
+// NewMux will create and return a new Mux. All namespaces registered in
+// types.DefaultNamespaceRegistry may be routed by their alias.
 func NewMux() *Mux {
 	m := &Mux{
-		namespaceAliases: map[string]string{
-			types.NameSpaceDomain:  "domain",
-			types.NameSpaceHost:    "host",
-			types.NameSpaceContact: "contact",
-		},
-		handlers: make(map[string]HandlerFunc),
+		namespaceAliases: map[string]string{},
+		handlers:         make(map[string]HandlerFunc),
 	}
 
 	return m
@@ -42,7 +39,8 @@ func NewMux() *Mux {
 
 // AddNamespaceAlias will add an alias for the specified namespace. After the
 // alias is added it can be used in routing. Multiple namespaces can be added
-// to the same alias.
+// to the same alias. Aliases added to the mux takes precedence over the ones in
+// types.DefaultNamespaceRegistry and are only used for routing.
 //  m.AddNamespaceAlias("urn:ietf:params:xml:ns:contact-1.0", "host-and-contact")
 //  m.AddNamespaceAlias("urn:ietf:params:xml:ns:host-1.0", "host-and-contact")
 func (m *Mux) AddNamespaceAlias(ns, alias string) {
@@ -117,6 +115,8 @@ func (m *Mux) buildPath(root *xmltree.Element) (string, error) {
 
 			if alias, ok := m.namespaceAliases[ns]; ok {
 				ns = alias
+			} else if alias, ok := types.DefaultNamespaceRegistry.Alias(ns); ok {
+				ns = alias
 			}
 
 			pathParts = append(pathParts, name, ns)
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"aqwari.net/xml/xmltree"
+	"github.com/bombsimon/epp-go/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -66,3 +67,46 @@ func Test_buildPath(t *testing.T) {
 		})
 	}
 }
+
+// useNamespaceRegistry replaces types.DefaultNamespaceRegistry with a new
+// registry which is used until the test ends so namespaces registered by the
+// test don't affect other tests.
+func useNamespaceRegistry(t *testing.T) *types.NamespaceRegistry {
+	original := types.DefaultNamespaceRegistry
+	t.Cleanup(func() {
+		types.DefaultNamespaceRegistry = original
+	})
+
+	types.DefaultNamespaceRegistry = types.NewNamespaceRegistry()
+
+	return types.DefaultNamespaceRegistry
+}
+
+func Test_buildPathRegisteredNamespace(t *testing.T) {
+	registry := useNamespaceRegistry(t)
+	require.Nil(t, registry.Register("urn:example:object-1.0", "object"))
+
+	root, err := xmltree.Parse([]byte(`
+		<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+		  <command>
+		    <info>
+		      <object:info xmlns:object="urn:example:object-1.0">
+		        <object:id>object-1</object:id>
+		      </object:info>
+		    </info>
+		  </command>
+		</epp>`))
+	require.Nil(t, err)
+
+	m := NewMux()
+
+	path, err := m.buildPath(root)
+	require.Nil(t, err)
+	assert.Equal(t, "command/info/object", path)
+
+	m.AddNamespaceAlias("urn:example:object-1.0", "custom")
+
+	path, err = m.buildPath(root)
+	require.Nil(t, err)
+	assert.Equal(t, "command/info/custom", path)
+}
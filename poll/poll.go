@@ -0,0 +1,325 @@
+// Package poll implements message queues for the poll command in RFC 5730.
+// Each client has its own queue kept in a store.Store. Messages are added
+// with Enqueue, optionally with result data such as a transfer notification,
+// and retrieved by the client with poll req and removed with poll ack.
+//
+//	q := poll.New(s)
+//	q.Register(mux)
+//
+//	id, err := q.Enqueue("registrar-1", "Transfer requested.", types.DomainTransferDataType{
+//	    TransferData: types.DomainTransferData{
+//	        Name:           "example.se",
+//	        TransferStatus: types.DomainTransferPending,
+//	        ...
+//	    },
+//	})
+package poll
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"time"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/store"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/google/uuid"
+)
+
+// ErrUnknownMessage is returned when acknowledging a message which isn't in
+// the queue of the client.
+var ErrUnknownMessage = errors.New("poll: unknown message")
+
+// maxAttempts is the number of times an operation is run if the transaction
+// conflicts with another transaction.
+const maxAttempts = 3
+
+// Queue holds the message queues for all clients in a store.
+type Queue struct {
+	// Store holds the messages.
+	Store store.Store
+
+	// Now returns the current time used as the queue date for messages
+	// added with Enqueue. If nil time.Now is used.
+	Now func() time.Time
+
+	// Before is called first in each transaction run by the queue if set.
+	// It can be used to enqueue messages for events which are due before
+	// the queue is read.
+	Before func(tx store.Tx) error
+}
+
+// New creates a Queue keeping the messages in the store.
+func New(s store.Store) *Queue {
+	return &Queue{Store: s}
+}
+
+// Register adds Handle as the handler for the poll command to the mux.
+func (q *Queue) Register(m *epp.Mux) {
+	m.AddHandler("command/poll", q.Handle)
+}
+
+// Enqueue adds a message with the text to the queue of the client and
+// returns the ID of the message. The result data is optional and if not nil
+// it's encoded as the content of the resData element when the message is
+// retrieved.
+func (q *Queue) Enqueue(clientID, text string, resData interface{}) (string, error) {
+	m := &store.Message{
+		ClientID:  clientID,
+		QueueDate: q.now(),
+		Text:      text,
+	}
+
+	err := q.run(func(tx store.Tx) error {
+		return Enqueue(tx, m, resData)
+	})
+
+	return m.ID, err
+}
+
+// Request returns the oldest message in the queue of the client and the
+// number of messages in the queue. The message is nil if the queue is empty.
+func (q *Queue) Request(clientID string) (*store.Message, int, error) {
+	var (
+		m     *store.Message
+		count int
+	)
+
+	err := q.run(func(tx store.Tx) (err error) {
+		m, count, err = Request(tx, clientID)
+		return err
+	})
+
+	return m, count, err
+}
+
+// Acknowledge removes the message with the ID from the queue of the client
+// and returns the oldest remaining message and the number of messages left
+// in the queue. ErrUnknownMessage is returned if the message isn't in the
+// queue.
+func (q *Queue) Acknowledge(clientID, id string) (*store.Message, int, error) {
+	var (
+		m     *store.Message
+		count int
+	)
+
+	err := q.run(func(tx store.Tx) (err error) {
+		m, count, err = Acknowledge(tx, clientID, id)
+		return err
+	})
+
+	return m, count, err
+}
+
+// Handle handles the poll command for the logged in client. The req
+// operation responds with the oldest message with the result code 1301, or
+// 1300 if the queue is empty. The ack operation removes the message with
+// the msgID and responds with the number of remaining messages and the ID of
+// the oldest of them.
+func (q *Queue) Handle(s *epp.Session, data []byte) ([]byte, error) {
+	if s.ClientID() == "" {
+		return respond(data, epp.EppUseError, nil)
+	}
+
+	request := types.Poll{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return respond(data, epp.EppSyntaxError, nil)
+	}
+
+	var (
+		m     *store.Message
+		count int
+		err   error
+	)
+
+	switch request.Poll.Operation {
+	case types.PollOperationRequest:
+		if m, count, err = q.Request(s.ClientID()); err != nil {
+			return respond(data, epp.EppCommandFailed, nil)
+		}
+
+		if m == nil {
+			return respond(data, epp.EppOkNoMessages, nil)
+		}
+
+		return respond(data, epp.EppOkMessages, &message{Message: m, Count: count})
+
+	case types.PollOperationAcknowledge:
+		if request.Poll.MessageID == "" {
+			return respond(data, epp.EppMissingParam, nil)
+		}
+
+		m, count, err = q.Acknowledge(s.ClientID(), request.Poll.MessageID)
+
+		switch {
+		case errors.Is(err, ErrUnknownMessage):
+			return respond(data, epp.EppObjectDoesNotExist, nil)
+		case err != nil:
+			return respond(data, epp.EppCommandFailed, nil)
+		case m == nil:
+			return respond(data, epp.EppOk, nil)
+		}
+
+		// Only the count and the ID of the next message is included when
+		// acknowledging a message.
+		return respond(data, epp.EppOk, &message{Message: &store.Message{ID: m.ID}, Count: count})
+	}
+
+	return respond(data, epp.EppParamSyntaxError, nil)
+}
+
+// run runs Before and the function in a transaction. They are run again if
+// the transaction conflicts with another transaction.
+func (q *Queue) run(f func(tx store.Tx) error) error {
+	var err error
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var tx store.Tx
+
+		if tx, err = q.Store.Begin(); err != nil {
+			return err
+		}
+
+		if q.Before != nil {
+			err = q.Before(tx)
+		}
+
+		if err == nil {
+			err = f(tx)
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err = tx.Commit(); !errors.Is(err, store.ErrConflict) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// now returns the current time in UTC with the precision used for dates in
+// responses.
+func (q *Queue) now() time.Time {
+	now := time.Now
+	if q.Now != nil {
+		now = q.Now
+	}
+
+	return now().UTC().Truncate(time.Second)
+}
+
+// Enqueue adds the message to the queue of the client with the client ID in
+// the message in the transaction and sets the ID of the message. The result
+// data is optional and if not nil it's encoded to the data of the message.
+func Enqueue(tx store.Tx, m *store.Message, resData interface{}) error {
+	if resData != nil {
+		data, err := encodeResultData(resData)
+		if err != nil {
+			return err
+		}
+
+		m.Data = data
+	}
+
+	return tx.AddMessage(m)
+}
+
+// encodeResultData encodes the result data as the content of a resData
+// element in the same way as the result data of a types.Response.
+func encodeResultData(resData interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := xml.NewEncoder(&buf).EncodeElement(resData, xml.StartElement{Name: xml.Name{Local: "resData"}}); err != nil {
+		return nil, err
+	}
+
+	element := resultData{}
+
+	if err := xml.Unmarshal(buf.Bytes(), &element); err != nil {
+		return nil, err
+	}
+
+	return element.Content, nil
+}
+
+// Request returns the oldest message in the queue of the client and the
+// number of messages in the queue in the transaction. The message is nil if
+// the queue is empty.
+func Request(tx store.Tx, clientID string) (*store.Message, int, error) {
+	messages, err := tx.Messages(clientID)
+	if err != nil || len(messages) == 0 {
+		return nil, 0, err
+	}
+
+	return messages[0], len(messages), nil
+}
+
+// Acknowledge removes the message with the ID from the queue of the client in
+// the transaction and returns the oldest remaining message and the number of
+// messages left in the queue. ErrUnknownMessage is returned if the message
+// isn't in the queue.
+func Acknowledge(tx store.Tx, clientID, id string) (*store.Message, int, error) {
+	err := tx.DeleteMessage(clientID, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, 0, ErrUnknownMessage
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return Request(tx, clientID)
+}
+
+// message is a message and the number of messages in the queue to respond
+// with.
+type message struct {
+	*store.Message
+	Count int
+}
+
+// resultData holds the encoded result data of a message.
+type resultData struct {
+	Content []byte `xml:",innerxml"`
+}
+
+// respond creates a response to the request with the result code and the
+// message, if any.
+func respond(request []byte, code epp.ResultCode, m *message) ([]byte, error) {
+	response := types.Response{
+		Result: []types.Result{
+			{
+				Code:    code.Code(),
+				Message: code.Message(),
+			},
+		},
+		TransactionID: types.TransactionID{
+			ClientTransactionID: epp.ClientTransactionID(request),
+			ServerTransactionID: uuid.New().String(),
+		},
+	}
+
+	if m != nil {
+		response.MessageQ = &types.MessageQueue{
+			Message: m.Text,
+			Count:   m.Count,
+			ID:      m.ID,
+		}
+
+		if !m.QueueDate.IsZero() {
+			response.MessageQ.QueueDate = &m.QueueDate
+		}
+
+		if len(m.Data) > 0 {
+			response.ResultData = resultData{Content: m.Data}
+		}
+	}
+
+	return epp.Encode(response, epp.ServerXMLAttributes())
+}
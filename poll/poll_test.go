@@ -0,0 +1,203 @@
+package poll
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/store"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestQueue() *Queue {
+	q := New(store.NewMemory())
+	q.Now = func() time.Time { return testTime }
+
+	return q
+}
+
+func TestQueue(t *testing.T) {
+	q := newTestQueue()
+
+	m, count, err := q.Request("registrar-1")
+	require.Nil(t, err)
+	assert.Nil(t, m)
+	assert.Equal(t, 0, count)
+
+	first, err := q.Enqueue("registrar-1", "first", nil)
+	require.Nil(t, err)
+
+	second, err := q.Enqueue("registrar-1", "second", types.DomainTransferDataType{
+		TransferData: types.DomainTransferData{Name: "example.se"},
+	})
+	require.Nil(t, err)
+
+	other, err := q.Enqueue("registrar-2", "other", nil)
+	require.Nil(t, err)
+
+	// The oldest message is returned until it's acknowledged.
+	for i := 0; i < 2; i++ {
+		m, count, err = q.Request("registrar-1")
+		require.Nil(t, err)
+		require.NotNil(t, m)
+		assert.Equal(t, first, m.ID)
+		assert.Equal(t, "first", m.Text)
+		assert.Equal(t, testTime, m.QueueDate)
+		assert.Nil(t, m.Data)
+		assert.Equal(t, 2, count)
+	}
+
+	// Messages can only be acknowledged by the client they're queued for.
+	_, _, err = q.Acknowledge("registrar-1", other)
+	assert.True(t, errors.Is(err, ErrUnknownMessage))
+
+	_, _, err = q.Acknowledge("registrar-1", "unknown")
+	assert.True(t, errors.Is(err, ErrUnknownMessage))
+
+	m, count, err = q.Acknowledge("registrar-1", first)
+	require.Nil(t, err)
+	require.NotNil(t, m)
+	assert.Equal(t, second, m.ID)
+	assert.Contains(t, string(m.Data), "example.se")
+	assert.Equal(t, 1, count)
+
+	_, _, err = q.Acknowledge("registrar-1", first)
+	assert.True(t, errors.Is(err, ErrUnknownMessage))
+
+	m, count, err = q.Acknowledge("registrar-1", second)
+	require.Nil(t, err)
+	assert.Nil(t, m)
+	assert.Equal(t, 0, count)
+
+	m, count, err = q.Request("registrar-2")
+	require.Nil(t, err)
+	require.NotNil(t, m)
+	assert.Equal(t, other, m.ID)
+	assert.Equal(t, 1, count)
+}
+
+func TestQueueBefore(t *testing.T) {
+	q := newTestQueue()
+	q.Before = func(tx store.Tx) error {
+		messages, err := tx.Messages("registrar-1")
+		if err != nil || len(messages) > 0 {
+			return err
+		}
+
+		return Enqueue(tx, &store.Message{ClientID: "registrar-1", Text: "due"}, nil)
+	}
+
+	m, count, err := q.Request("registrar-1")
+	require.Nil(t, err)
+	require.NotNil(t, m)
+	assert.Equal(t, "due", m.Text)
+	assert.Equal(t, 1, count)
+
+	q.Before = func(tx store.Tx) error {
+		return errors.New("failed")
+	}
+
+	_, _, err = q.Request("registrar-1")
+	assert.EqualError(t, err, "failed")
+}
+
+func TestHandle(t *testing.T) {
+	validator, err := epp.NewDefaultValidator()
+	require.Nil(t, err)
+
+	defer validator.Free()
+
+	q := newTestQueue()
+	mux := epp.NewMux()
+	q.Register(mux)
+
+	conn, other := net.Pipe()
+	defer conn.Close()
+	defer other.Close()
+
+	session := epp.NewSession(conn, epp.SessionConfig{})
+
+	poll := func(code epp.ResultCode, attributes string) *epp.DecodedResponse {
+		t.Helper()
+
+		request := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><command><poll ` + attributes + `/><clTRID>TEST-1</clTRID></command></epp>`)
+		require.Nil(t, validator.Validate(request))
+
+		data, err := mux.Handle(session, request)
+		require.Nil(t, err)
+		require.Nil(t, validator.Validate(data), string(data))
+
+		response, err := epp.DecodeResponse(data)
+		require.Nil(t, err)
+		require.Equal(t, code, response.Code())
+		assert.Equal(t, "TEST-1", response.TransactionID.ClientTransactionID)
+
+		return response
+	}
+
+	poll(epp.EppUseError, `op="req"`)
+	require.Nil(t, session.Login("registrar-1"))
+
+	response := poll(epp.EppOkNoMessages, `op="req"`)
+	assert.Nil(t, response.MessageQ)
+
+	first, err := q.Enqueue("registrar-1", "Transfer requested.", types.DomainTransferDataType{
+		TransferData: types.DomainTransferData{
+			Name:           "example.se",
+			TransferStatus: types.DomainTransferPending,
+			RequestingID:   "registrar-2",
+			RequestingDate: "2026-03-01T12:00:00Z",
+			ActingID:       "registrar-1",
+			ActingDate:     "2026-03-06T12:00:00Z",
+		},
+	})
+	require.Nil(t, err)
+
+	second, err := q.Enqueue("registrar-1", "Second message.", nil)
+	require.Nil(t, err)
+
+	response = poll(epp.EppOkMessages, `op="req"`)
+	require.NotNil(t, response.MessageQ)
+	assert.Equal(t, first, response.MessageQ.ID)
+	assert.Equal(t, 2, response.MessageQ.Count)
+	assert.Equal(t, "Transfer requested.", response.MessageQ.Message)
+	require.NotNil(t, response.MessageQ.QueueDate)
+	assert.True(t, testTime.Equal(*response.MessageQ.QueueDate))
+
+	transfer := response.DomainTransferData()
+	require.NotNil(t, transfer)
+	assert.Equal(t, "example.se", transfer.Name)
+	assert.Equal(t, types.DomainTransferPending, transfer.TransferStatus)
+	assert.Equal(t, "registrar-2", transfer.RequestingID)
+
+	poll(epp.EppMissingParam, `op="ack"`)
+	poll(epp.EppObjectDoesNotExist, `op="ack" msgID="unknown"`)
+
+	// The count and the ID of the next message is returned when a message is
+	// acknowledged.
+	response = poll(epp.EppOk, `op="ack" msgID="`+first+`"`)
+	require.NotNil(t, response.MessageQ)
+	assert.Equal(t, second, response.MessageQ.ID)
+	assert.Equal(t, 1, response.MessageQ.Count)
+	assert.Nil(t, response.MessageQ.QueueDate)
+
+	poll(epp.EppObjectDoesNotExist, `op="ack" msgID="`+first+`"`)
+
+	response = poll(epp.EppOkMessages, `op="req"`)
+	require.NotNil(t, response.MessageQ)
+	assert.Equal(t, second, response.MessageQ.ID)
+	assert.Equal(t, "Second message.", response.MessageQ.Message)
+	assert.Empty(t, response.ResultData)
+
+	response = poll(epp.EppOk, `op="ack" msgID="`+second+`"`)
+	assert.Nil(t, response.MessageQ)
+
+	poll(epp.EppOkNoMessages, `op="req"`)
+}
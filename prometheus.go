@@ -0,0 +1,354 @@
+package epp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PrometheusContentType is the content type of the Prometheus text exposition
+// format written by PrometheusMetrics.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Default buckets used by PrometheusMetrics.
+var (
+	// DefaultLatencyBuckets holds the upper bounds in seconds for the latency
+	// histograms.
+	DefaultLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+	// DefaultFrameSizeBuckets holds the upper bounds in bytes for the frame
+	// size histogram.
+	DefaultFrameSizeBuckets = []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576}
+)
+
+// PrometheusMetrics is a Metrics keeping the metrics in memory and writing
+// them in the Prometheus text exposition format. It implements http.Handler
+// so it can be served directly on a metrics endpoint.
+//
+//	metrics := epp.NewPrometheusMetrics("epp")
+//	http.Handle("/metrics", metrics)
+//
+//	srv := epp.Server{Metrics: metrics}
+type PrometheusMetrics struct {
+	mu sync.Mutex
+
+	sessionsOpened     *metricVec
+	sessionsClosed     *metricVec
+	commands           *metricVec
+	handlerDuration    *metricVec
+	validationFailures *metricVec
+	frameSize          *metricVec
+	clientCommands     *metricVec
+	clientDuration     *metricVec
+}
+
+// NewPrometheusMetrics creates a new PrometheusMetrics where each metric name
+// is prefixed with namespace, e.g. epp_sessions_opened_total for the
+// namespace epp. The namespace may be empty.
+func NewPrometheusMetrics(namespace string) *PrometheusMetrics {
+	name := func(n string) string {
+		if namespace == "" {
+			return n
+		}
+
+		return namespace + "_" + n
+	}
+
+	return &PrometheusMetrics{
+		sessionsOpened: newCounterVec(
+			name("sessions_opened_total"),
+			"Total number of sessions opened.",
+		),
+		sessionsClosed: newCounterVec(
+			name("sessions_closed_total"),
+			"Total number of sessions closed by reason.",
+			"reason",
+		),
+		commands: newCounterVec(
+			name("commands_total"),
+			"Total number of commands handled by route and result code.",
+			"route", "code",
+		),
+		handlerDuration: newHistogramVec(
+			name("handler_duration_seconds"),
+			"Duration of the command handlers by route.",
+			DefaultLatencyBuckets,
+			"route",
+		),
+		validationFailures: newCounterVec(
+			name("validation_failures_total"),
+			"Total number of messages failing validation by direction.",
+			"direction",
+		),
+		frameSize: newHistogramVec(
+			name("frame_size_bytes"),
+			"Size of the frames read and written by direction.",
+			DefaultFrameSizeBuckets,
+			"direction",
+		),
+		clientCommands: newCounterVec(
+			name("client_commands_total"),
+			"Total number of commands sent by clients by route and result code.",
+			"route", "code",
+		),
+		clientDuration: newHistogramVec(
+			name("client_round_trip_seconds"),
+			"Duration of client round trips by route.",
+			DefaultLatencyBuckets,
+			"route",
+		),
+	}
+}
+
+// SessionOpened implements Metrics.
+func (m *PrometheusMetrics) SessionOpened() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessionsOpened.add(1)
+}
+
+// SessionClosed implements Metrics.
+func (m *PrometheusMetrics) SessionClosed(reason SessionCloseReason) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessionsClosed.add(1, string(reason))
+}
+
+// CommandHandled implements Metrics.
+func (m *PrometheusMetrics) CommandHandled(route, code string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.commands.add(1, route, code)
+	m.handlerDuration.observe(duration.Seconds(), route)
+}
+
+// ValidationFailed implements Metrics.
+func (m *PrometheusMetrics) ValidationFailed(direction Direction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.validationFailures.add(1, string(direction))
+}
+
+// FrameSize implements Metrics.
+func (m *PrometheusMetrics) FrameSize(direction Direction, size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.frameSize.observe(float64(size), string(direction))
+}
+
+// ClientRoundTrip implements Metrics.
+func (m *PrometheusMetrics) ClientRoundTrip(route, code string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.clientCommands.add(1, route, code)
+	m.clientDuration.observe(duration.Seconds(), route)
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format to w.
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	for _, v := range []*metricVec{
+		m.sessionsOpened,
+		m.sessionsClosed,
+		m.commands,
+		m.handlerDuration,
+		m.validationFailures,
+		m.frameSize,
+		m.clientCommands,
+		m.clientDuration,
+	} {
+		v.write(bw)
+	}
+
+	err := bw.Flush()
+
+	return cw.n, err
+}
+
+// ServeHTTP implements http.Handler by writing all metrics in the Prometheus
+// text exposition format.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", PrometheusContentType)
+
+	_, _ = m.WriteTo(w)
+}
+
+// metricVec is a counter or histogram with a value for each combination of
+// label values.
+type metricVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	values  map[string]*metricValue
+}
+
+// metricValue holds the value for a combination of label values. Counters only
+// use sum while histograms use all fields.
+type metricValue struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+func newCounterVec(name, help string, labels ...string) *metricVec {
+	return &metricVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: map[string]*metricValue{},
+	}
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *metricVec {
+	v := newCounterVec(name, help, labels...)
+	v.buckets = append([]float64{}, buckets...)
+
+	sort.Float64s(v.buckets)
+
+	return v
+}
+
+func (v *metricVec) value(labelValues []string) *metricValue {
+	key := strings.Join(labelValues, "\xff")
+
+	value, ok := v.values[key]
+	if !ok {
+		value = &metricValue{
+			labelValues: labelValues,
+			counts:      make([]uint64, len(v.buckets)),
+		}
+
+		v.values[key] = value
+	}
+
+	return value
+}
+
+func (v *metricVec) add(delta float64, labelValues ...string) {
+	v.value(labelValues).sum += delta
+}
+
+func (v *metricVec) observe(observation float64, labelValues ...string) {
+	value := v.value(labelValues)
+
+	for i, upperBound := range v.buckets {
+		if observation <= upperBound {
+			value.counts[i]++
+		}
+	}
+
+	value.count++
+	value.sum += observation
+}
+
+// write writes the metric with the values sorted by label values.
+func (v *metricVec) write(w io.Writer) {
+	kind := "counter"
+	if v.buckets != nil {
+		kind = "histogram"
+	}
+
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, kind)
+
+	// Counters without labels are always written so the metric exists
+	// before the first event.
+	if len(v.labels) == 0 && len(v.values) == 0 {
+		v.value(nil)
+	}
+
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := v.values[key]
+
+		if v.buckets == nil {
+			fmt.Fprintf(w, "%s%s %s\n", v.name, v.labelPairs(value.labelValues, ""), formatFloat(value.sum))
+
+			continue
+		}
+
+		for i, upperBound := range v.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, v.labelPairs(value.labelValues, formatFloat(upperBound)), value.counts[i])
+		}
+
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, v.labelPairs(value.labelValues, "+Inf"), value.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, v.labelPairs(value.labelValues, ""), formatFloat(value.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, v.labelPairs(value.labelValues, ""), value.count)
+	}
+}
+
+// labelPairs returns the label pairs for the label values including the le
+// label for histogram buckets if le isn't empty.
+func (v *metricVec) labelPairs(labelValues []string, le string) string {
+	pairs := make([]string, 0, len(labelValues)+1)
+
+	for i, value := range labelValues {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, v.labels[i], escapeLabelValue(value)))
+	}
+
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf(`le="%s"`, le))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escapeLabelValue escapes backslashes, double quotes and newlines as required
+// by the text format.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+
+	return n, err
+}
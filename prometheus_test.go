@@ -0,0 +1,137 @@
+package epp
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	m := NewPrometheusMetrics("epp")
+
+	m.SessionOpened()
+	m.SessionOpened()
+	m.SessionClosed(SessionCloseIdle)
+	m.CommandHandled("command/info/domain", "1000", 20*time.Millisecond)
+	m.CommandHandled("command/info/domain", "2303", 2*time.Second)
+	m.ValidationFailed(DirectionInbound)
+	m.FrameSize(DirectionInbound, 512)
+	m.ClientRoundTrip("hello", `1"0\0`, time.Millisecond)
+
+	var buf bytes.Buffer
+
+	n, err := m.WriteTo(&buf)
+	require.Nil(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	for _, line := range []string{
+		"# TYPE epp_sessions_opened_total counter",
+		"epp_sessions_opened_total 2",
+		`epp_sessions_closed_total{reason="idle"} 1`,
+		`epp_commands_total{route="command/info/domain",code="1000"} 1`,
+		`epp_commands_total{route="command/info/domain",code="2303"} 1`,
+		"# TYPE epp_handler_duration_seconds histogram",
+		`epp_handler_duration_seconds_bucket{route="command/info/domain",le="0.01"} 0`,
+		`epp_handler_duration_seconds_bucket{route="command/info/domain",le="0.025"} 1`,
+		`epp_handler_duration_seconds_bucket{route="command/info/domain",le="2.5"} 2`,
+		`epp_handler_duration_seconds_bucket{route="command/info/domain",le="+Inf"} 2`,
+		`epp_handler_duration_seconds_sum{route="command/info/domain"} 2.02`,
+		`epp_handler_duration_seconds_count{route="command/info/domain"} 2`,
+		`epp_validation_failures_total{direction="in"} 1`,
+		`epp_frame_size_bytes_bucket{direction="in",le="256"} 0`,
+		`epp_frame_size_bytes_bucket{direction="in",le="1024"} 1`,
+		`epp_client_commands_total{route="hello",code="1\"0\\0"} 1`,
+	} {
+		assert.Contains(t, strings.Split(buf.String(), "\n"), line)
+	}
+
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, PrometheusContentType, recorder.Header().Get("Content-Type"))
+	assert.Equal(t, buf.String(), recorder.Body.String())
+}
+
+func TestServerMetrics(t *testing.T) {
+	l := newPipeListener()
+	metrics := NewPrometheusMetrics("")
+
+	mux := NewMux()
+	mux.AddHandler("command/info/domain", func(s *Session, in []byte) ([]byte, error) {
+		return Encode(types.Response{
+			Result: []types.Result{
+				{
+					Code:    EppOk.Code(),
+					Message: EppOk.Message(),
+				},
+			},
+		}, ServerXMLAttributes())
+	})
+
+	srv := Server{
+		AllowPlaintext: true,
+		Logger:         NopLogger{},
+		Metrics:        metrics,
+		SessionConfig: SessionConfig{
+			IdleTimeout:    10 * time.Minute,
+			SessionTimeout: 10 * time.Minute,
+			Handler:        mux.Handle,
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+	}
+
+	served := make(chan error)
+
+	go func() {
+		served <- srv.Serve(l)
+	}()
+
+	conn, err := l.Dial()
+	require.Nil(t, err)
+
+	framer := NewFramer(conn)
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	request, err := os.ReadFile("xml/commands/info-domain.xml")
+	require.Nil(t, err)
+
+	require.Nil(t, framer.WriteMessage(request))
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	// Closing the connection ends the session.
+	require.Nil(t, conn.Close())
+
+	for i := 0; srv.SessionCounts().Total > 0 && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	srv.Stop()
+	require.Nil(t, <-served)
+
+	var buf bytes.Buffer
+
+	_, err = metrics.WriteTo(&buf)
+	require.Nil(t, err)
+
+	lines := strings.Split(buf.String(), "\n")
+
+	assert.Contains(t, lines, "sessions_opened_total 1")
+	assert.Contains(t, lines, `sessions_closed_total{reason="client"} 1`)
+	assert.Contains(t, lines, `commands_total{route="command/info/domain",code="1000"} 1`)
+	assert.Contains(t, lines, `handler_duration_seconds_count{route="command/info/domain"} 1`)
+	assert.Contains(t, lines, `frame_size_bytes_count{direction="in"} 1`)
+	assert.Contains(t, lines, `frame_size_bytes_count{direction="out"} 2`)
+}
@@ -0,0 +1,312 @@
+package epp
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"aqwari.net/xml/xmltree"
+)
+
+// CommandClass represents a class of commands sharing the same rate limit.
+type CommandClass string
+
+// Constants representing the command classes used for rate limiting. Query
+// commands doesn't change any objects while transform commands does.
+const (
+	CommandClassQuery     CommandClass = "query"
+	CommandClassTransform CommandClass = "transform"
+)
+
+// clientBucketsSweepInterval is the minimum time between removing unused
+// client buckets.
+const clientBucketsSweepInterval = 1 * time.Minute
+
+// Rate represents the rate for a token bucket. Tokens are added with Limit
+// tokens per second up to Burst tokens and each command uses one token.
+type Rate struct {
+	Limit float64
+	Burst int
+}
+
+// RateLimiter limits the rate of commands with token buckets for each command
+// class. Each session has its own buckets and sessions for the same client ID
+// also shares buckets for the client. Session management commands such as
+// login, logout and hello are never limited. The same RateLimiter should be
+// used for all sessions, e.g. by setting it in SessionConfig.
+type RateLimiter struct {
+	// PerSession holds the rate for each command class for each session.
+	PerSession map[CommandClass]Rate
+
+	// PerClient holds the rate for each command class shared by all sessions
+	// for a client ID. Only sessions where Session.Login has been called are
+	// limited per client. Buckets for clients which haven't sent any command
+	// until their buckets are full again are removed.
+	PerClient map[CommandClass]Rate
+
+	// Code is the result code used when rejecting a command. If zero 2400
+	// (command failed) is used.
+	Code ResultCode
+
+	// Message is the message used when rejecting a command. If empty the
+	// default message for the result code is used.
+	Message string
+
+	// MaxDelay is the maximum time to delay a command waiting for a token
+	// before rejecting it. If zero throttled commands are rejected directly.
+	MaxDelay time.Duration
+
+	// MaxQueue is the maximum number of commands delayed at the same time for
+	// each bucket. Commands exceeding the queue are rejected. If zero there is
+	// no limit other than MaxDelay.
+	MaxQueue int
+
+	mu      sync.Mutex
+	clients map[string]*tokenBucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+// result returns the result code and message to use when rejecting commands.
+func (l *RateLimiter) result() (ResultCode, string) {
+	code := l.Code
+	if code == 0 {
+		code = EppCommandFailed
+	}
+
+	message := l.Message
+	if message == "" {
+		message = code.Message()
+	}
+
+	return code, message
+}
+
+func (l *RateLimiter) time() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+
+	return time.Now()
+}
+
+// newSessionBuckets returns the buckets to use for a new session.
+func (l *RateLimiter) newSessionBuckets() map[CommandClass]*tokenBucket {
+	buckets := map[CommandClass]*tokenBucket{}
+
+	for class, rate := range l.PerSession {
+		buckets[class] = newTokenBucket(rate, l.time())
+	}
+
+	return buckets
+}
+
+// clientBucket returns the bucket for the client ID and class, creating it if
+// it doesn't exist. l.mu must be held.
+func (l *RateLimiter) clientBucket(clientID string, class CommandClass, now time.Time) *tokenBucket {
+	rate, ok := l.PerClient[class]
+	if !ok || clientID == "" {
+		return nil
+	}
+
+	if l.clients == nil {
+		l.clients = map[string]*tokenBucket{}
+	}
+
+	l.sweepClientBuckets(now)
+
+	key := clientID + "/" + string(class)
+
+	bucket, ok := l.clients[key]
+	if !ok {
+		bucket = newTokenBucket(rate, now)
+		l.clients[key] = bucket
+	}
+
+	return bucket
+}
+
+// sweepClientBuckets removes the client buckets which are full without any
+// commands waiting. Such a bucket is the same as a new bucket so it's created
+// again when needed. l.mu must be held.
+func (l *RateLimiter) sweepClientBuckets(now time.Time) {
+	if now.Sub(l.swept) < clientBucketsSweepInterval {
+		return
+	}
+
+	l.swept = now
+
+	for key, bucket := range l.clients {
+		if bucket.unused(now) {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// reserve will reserve a token for a command of the class in each bucket for
+// the session and the client. Commands without a class aren't limited. The
+// returned delay is the time to wait before executing the command and done
+// must be called when the delay is over. If the command must be rejected false
+// is returned.
+func (l *RateLimiter) reserve(sessionBuckets map[CommandClass]*tokenBucket, clientID string, class CommandClass) (time.Duration, func(), bool) {
+	if class == "" {
+		return 0, func() {}, true
+	}
+
+	var (
+		now      = l.time()
+		delay    time.Duration
+		reserved []*tokenBucket
+	)
+
+	// The client bucket isn't removed as unused before the token is
+	// reserved since l.mu is held.
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, bucket := range []*tokenBucket{sessionBuckets[class], l.clientBucket(clientID, class, now)} {
+		if bucket == nil {
+			continue
+		}
+
+		d, ok := bucket.reserve(now, l.MaxDelay, l.MaxQueue)
+		if !ok {
+			for _, b := range reserved {
+				b.cancel()
+			}
+
+			return 0, nil, false
+		}
+
+		reserved = append(reserved, bucket)
+
+		if d > delay {
+			delay = d
+		}
+	}
+
+	done := func() {
+		for _, b := range reserved {
+			b.done()
+		}
+	}
+
+	return delay, done, true
+}
+
+// commandClassOf returns the class for the command in the parsed message or
+// an empty class for messages that shouldn't be limited.
+func commandClassOf(root *xmltree.Element) CommandClass {
+	if root.Name.Space != nsEPP || len(root.Children) != 1 {
+		return ""
+	}
+
+	command := root.Children[0]
+	if command.Name.Local != "command" {
+		return ""
+	}
+
+	for _, child := range command.Children {
+		switch child.Name.Local {
+		case "check", "info", "poll":
+			return CommandClassQuery
+		case "transfer":
+			if child.Attr("", "op") == "query" {
+				return CommandClassQuery
+			}
+
+			return CommandClassTransform
+		case "create", "delete", "renew", "update":
+			return CommandClassTransform
+		}
+	}
+
+	return ""
+}
+
+// tokenBucket is a token bucket where tokens may be reserved ahead of time,
+// making the number of tokens negative, to delay commands.
+type tokenBucket struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	waiting int
+}
+
+func newTokenBucket(rate Rate, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate.Limit,
+		burst:  float64(rate.Burst),
+		tokens: float64(rate.Burst),
+		last:   now,
+	}
+}
+
+// reserve will take a token and return the time to wait until the token is
+// available. If the wait is longer than maxDelay or maxQueue commands are
+// already waiting no token is taken and false is returned.
+func (b *tokenBucket) reserve(now time.Time, maxDelay time.Duration, maxQueue int) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.fill(now)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.waiting++
+
+		return 0, true
+	}
+
+	if b.rate <= 0 || (maxQueue > 0 && b.waiting >= maxQueue) {
+		return 0, false
+	}
+
+	delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if delay > maxDelay {
+		return 0, false
+	}
+
+	b.tokens--
+	b.waiting++
+
+	return delay, true
+}
+
+// unused returns true if the bucket is full and no commands are waiting.
+func (b *tokenBucket) unused(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.fill(now)
+
+	return b.waiting == 0 && b.tokens >= b.burst
+}
+
+// fill adds the tokens for the time elapsed since the last fill. b.mu must be
+// held.
+func (b *tokenBucket) fill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// cancel will return a reserved token.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens++
+	b.waiting--
+}
+
+// done will mark a reserved token as used.
+func (b *tokenBucket) done() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.waiting--
+}
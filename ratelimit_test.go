@@ -0,0 +1,186 @@
+package epp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rateLimitCommand(command string) []byte {
+	return []byte(fmt.Sprintf(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    %s
+    <clTRID>ABC-12345</clTRID>
+  </command>
+</epp>`, command))
+}
+
+var (
+	rateLimitCheck = rateLimitCommand(`<check>
+      <domain:check xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.se</domain:name>
+      </domain:check>
+    </check>`)
+	rateLimitCreate = rateLimitCommand(`<create>
+      <domain:create xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.se</domain:name>
+      </domain:create>
+    </create>`)
+	rateLimitTransferQuery = rateLimitCommand(`<transfer op="query">
+      <domain:transfer xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.se</domain:name>
+      </domain:transfer>
+    </transfer>`)
+	rateLimitLogout = rateLimitCommand(`<logout/>`)
+)
+
+func TestCommandClass(t *testing.T) {
+	cases := []struct {
+		description string
+		data        []byte
+		expected    CommandClass
+	}{
+		{"check", rateLimitCheck, CommandClassQuery},
+		{"create", rateLimitCreate, CommandClassTransform},
+		{"transfer query", rateLimitTransferQuery, CommandClassQuery},
+		{"logout", rateLimitLogout, ""},
+		{"hello", []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><hello/></epp>`), ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expected, summarizeCommand(tc.data).class)
+		})
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+
+	l := &RateLimiter{
+		PerSession: map[CommandClass]Rate{
+			CommandClassQuery:     {Limit: 1, Burst: 2},
+			CommandClassTransform: {Limit: 1, Burst: 1},
+		},
+		PerClient: map[CommandClass]Rate{
+			CommandClassTransform: {Limit: 1, Burst: 2},
+		},
+		now: func() time.Time { return now },
+	}
+
+	reserve := func(buckets map[CommandClass]*tokenBucket, clientID string, data []byte) bool {
+		delay, done, ok := l.reserve(buckets, clientID, summarizeCommand(data).class)
+		if ok {
+			assert.Equal(t, time.Duration(0), delay)
+			done()
+		}
+
+		return ok
+	}
+
+	first, second, third := l.newSessionBuckets(), l.newSessionBuckets(), l.newSessionBuckets()
+
+	// Query and transform commands uses separate buckets.
+	assert.True(t, reserve(first, "", rateLimitCheck))
+	assert.True(t, reserve(first, "", rateLimitCheck))
+	assert.False(t, reserve(first, "", rateLimitCheck))
+	assert.True(t, reserve(first, "", rateLimitCreate))
+	assert.False(t, reserve(first, "", rateLimitCreate))
+
+	// Session management commands are never limited.
+	assert.True(t, reserve(first, "", rateLimitLogout))
+
+	// Tokens are added over time.
+	now = now.Add(1 * time.Second)
+	assert.True(t, reserve(first, "", rateLimitCheck))
+	assert.False(t, reserve(first, "", rateLimitCheck))
+
+	// Sessions for the same client shares the client buckets.
+	assert.True(t, reserve(second, "client-1", rateLimitCreate))
+	assert.True(t, reserve(third, "client-1", rateLimitCreate))
+	assert.False(t, reserve(l.newSessionBuckets(), "client-1", rateLimitCreate))
+	assert.True(t, reserve(l.newSessionBuckets(), "client-2", rateLimitCreate))
+
+	// A rejected command doesn't use any token from the other buckets.
+	now = now.Add(1 * time.Second)
+	assert.True(t, reserve(second, "client-1", rateLimitCreate))
+	assert.False(t, reserve(third, "client-1", rateLimitCreate))
+	assert.True(t, reserve(third, "client-3", rateLimitCreate))
+}
+
+func TestRateLimiter_delay(t *testing.T) {
+	now := time.Now()
+
+	l := &RateLimiter{
+		PerSession: map[CommandClass]Rate{
+			CommandClassQuery: {Limit: 2, Burst: 1},
+		},
+		MaxDelay: 1 * time.Second,
+		MaxQueue: 1,
+		now:      func() time.Time { return now },
+	}
+
+	buckets := l.newSessionBuckets()
+
+	delay, done, ok := l.reserve(buckets, "", CommandClassQuery)
+	require.True(t, ok)
+	assert.Equal(t, time.Duration(0), delay)
+	done()
+
+	delay, done, ok = l.reserve(buckets, "", CommandClassQuery)
+	require.True(t, ok)
+	assert.Equal(t, 500*time.Millisecond, delay)
+
+	// The queue is full while the command is delayed.
+	_, _, ok = l.reserve(buckets, "", CommandClassQuery)
+	assert.False(t, ok)
+
+	done()
+
+	delay, done, ok = l.reserve(buckets, "", CommandClassQuery)
+	require.True(t, ok)
+	assert.Equal(t, 1*time.Second, delay)
+	done()
+
+	// The delay would exceed MaxDelay.
+	_, _, ok = l.reserve(buckets, "", CommandClassQuery)
+	assert.False(t, ok)
+}
+
+func TestRateLimiter_clientBuckets(t *testing.T) {
+	now := time.Now()
+
+	l := &RateLimiter{
+		PerClient: map[CommandClass]Rate{
+			CommandClassQuery: {Limit: 1, Burst: 2},
+		},
+		MaxDelay: 1 * time.Second,
+		now:      func() time.Time { return now },
+	}
+
+	for _, clientID := range []string{"client-1", "client-2", "client-3"} {
+		_, done, ok := l.reserve(l.newSessionBuckets(), clientID, CommandClassQuery)
+		require.True(t, ok)
+
+		if clientID != "client-3" {
+			done()
+		}
+	}
+
+	require.Len(t, l.clients, 3)
+
+	// Buckets are full again when the sweep interval has passed and the
+	// buckets without waiting commands are removed.
+	now = now.Add(clientBucketsSweepInterval)
+
+	_, done, ok := l.reserve(l.newSessionBuckets(), "client-4", CommandClassQuery)
+	require.True(t, ok)
+	done()
+
+	assert.Len(t, l.clients, 2)
+	assert.Contains(t, l.clients, "client-3/query")
+	assert.Contains(t, l.clients, "client-4/query")
+}
@@ -1,14 +1,11 @@
 package epp
 
 import (
-	"encoding/binary"
 	"encoding/xml"
-	"errors"
 	"fmt"
 	"io"
-	"math"
-	"net"
-	"time"
+	"reflect"
+	"strings"
 
 	"aqwari.net/xml/xmltree"
 	"github.com/bombsimon/epp-go/types"
@@ -18,64 +15,38 @@ const (
 	rootLocalName = "epp"
 )
 
-// ReadMessage reads one full message from r.
-func ReadMessage(conn net.Conn) ([]byte, error) {
-	// https://tools.ietf.org/html/rfc5734#section-4
-	var totalSize uint32
-
-	err := binary.Read(conn, binary.BigEndian, &totalSize)
-	if err != nil {
-		return nil, err
-	}
-
-	headerSize := binary.Size(totalSize)
-	contentSize := int(totalSize) - headerSize
-
-	// Ensure a reasonable time for reading the message.
-	err = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
-	if err != nil {
-		return nil, err
-	}
-
-	buf := make([]byte, contentSize)
-	_, err = io.ReadFull(conn, buf)
-	if err != nil {
-		return nil, err
-	}
-
-	return buf, nil
+// ReadMessage reads one full message from r. Frames larger than
+// DefaultMaxFrameSize are rejected. If r implements SetReadDeadline the
+// content must be read within DefaultFrameTimeout once the header is read.
+func ReadMessage(r io.Reader) ([]byte, error) {
+	return ReadMessageLimit(r, DefaultMaxFrameSize)
 }
 
-// WriteMessage writes data to w with the correct header.
-func WriteMessage(conn net.Conn, data []byte) error {
-	// Begin by writing the len(b) as Big Endian uint32, including the
-	// size of the content length header.
-	// https://tools.ietf.org/html/rfc5734#section-4
-	contentSize := len(data)
-	headerSize := binary.Size(uint32(contentSize))
-	totalSize := contentSize + headerSize
-
-	// Bounds check.
-	if totalSize > math.MaxUint32 {
-		return errors.New("content is too large")
+// ReadMessageLimit reads one full message from r. If the frame is larger than
+// maxSize a *FrameSizeError is returned and if the length in the header is
+// smaller than the header ErrInvalidFrameHeader is returned. A maxSize of 0
+// or less means DefaultMaxFrameSize.
+func ReadMessageLimit(r io.Reader, maxSize int) ([]byte, error) {
+	f := &Framer{
+		MaxFrameSize: maxSize,
+		ReadTimeout:  DefaultFrameTimeout,
+		r:            r,
+		conn:         r,
 	}
 
-	err := conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-	if err != nil {
-		return err
-	}
-
-	err = binary.Write(conn, binary.BigEndian, uint32(totalSize))
-	if err != nil {
-		return err
-	}
+	return f.ReadMessage()
+}
 
-	_, err = conn.Write(data)
-	if err != nil {
-		return err
+// WriteMessage writes data to w with the correct header. If w implements
+// SetWriteDeadline the frame must be written within DefaultFrameTimeout.
+func WriteMessage(w io.Writer, data []byte) error {
+	f := &Framer{
+		WriteTimeout: DefaultFrameTimeout,
+		w:            w,
+		conn:         w,
 	}
 
-	return nil
+	return f.WriteMessage(data)
 }
 
 // ServerXMLAttributes defines the default attributes from the server response.
@@ -133,17 +104,19 @@ func Encode(data interface{}, xmlAttributes []xml.Attr) ([]byte, error) {
 		return nil, err
 	}
 
-	addNameSpaceAlias(document, false)
-
-	// Replace the document root element with a proper EPP tag.
+	// Replace the document root element with a proper EPP tag. This is done
+	// before adding aliases since namespaces used in multiple branches will
+	// be declared on the root element.
 	document.StartElement = xml.StartElement{
 		Name: xml.Name{
 			Space: "",
 			Local: rootLocalName,
 		},
-		Attr: xmlAttributes,
+		Attr: append([]xml.Attr{}, xmlAttributes...),
 	}
 
+	addNameSpaceAliases(document, newDocumentAliases(types.DefaultNamespaceRegistry))
+
 	// Marshal the xmltree after fixing name spaces and attributes.
 	xmlBytes := xmltree.MarshalIndent(document, "", "  ")
 
@@ -153,40 +126,227 @@ func Encode(data interface{}, xmlAttributes []xml.Attr) ([]byte, error) {
 	return xmlBytes, nil
 }
 
-// addNameSpaceAlias will check each node/element in the XML tree and if the
-// node has an xml.Name.Space value set an alias will be created and then added
-// to all child nodes. The alias will only be setup for the root element.
-func addNameSpaceAlias(document *xmltree.Element, nsAdded bool) *xmltree.Element {
-	namespaceAliases := map[string]string{
-		types.NameSpaceDomain:   "domain",
-		types.NameSpaceHost:     "host",
-		types.NameSpaceContact:  "contact",
-		types.NameSpaceDNSSEC10: "sed",
-		types.NameSpaceDNSSEC11: "sec",
-		types.NameSpaceIIS12:    "iis",
+// Decode will unmarshal the XML in data to v. Before matching any elements all
+// namespace prefixes are resolved to their real namespace which means that
+// types with a namespace in their XML tag, such as types.DomainInfoType, can be
+// decoded no matter which alias (or default namespace) the peer used.
+func Decode(data []byte, v interface{}) error {
+	// Start by letting encoding/xml unmarshal everything it can match. This
+	// covers all fields without a namespace and namespaced fields without a
+	// path.
+	if err := xml.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	document, err := xmltree.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	return decodeNamespacedFields(document, reflect.ValueOf(v).Elem())
+}
+
+// decodeNamespacedFields will decode each field in v having both a namespace
+// and a path in the XML tag. encoding/xml requires every element in the path to
+// be in the namespace so these fields are decoded by walking the resolved
+// document tree instead, only comparing the namespace for the last element.
+// Embedded structs without a tag are handled like encoding/xml does, as if
+// their fields were a part of the outer struct.
+func decodeNamespacedFields(document *xmltree.Element, v reflect.Value) error {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("xml")
+
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			if err := decodeNamespacedFields(document, v.Field(i)); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		nameAndPath := strings.Split(strings.Split(tag, ",")[0], " ")
+		if len(nameAndPath) != 2 || !strings.Contains(nameAndPath[1], ">") {
+			continue
+		}
+
+		elements := findElements(document, nameAndPath[0], strings.Split(nameAndPath[1], ">"))
+
+		if err := decodeElements(elements, v.Field(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findElements will return all elements below root matching the path. The
+// namespace is only compared for the last element in the path.
+func findElements(root *xmltree.Element, ns string, path []string) []*xmltree.Element {
+	elements := []*xmltree.Element{root}
+
+	for i, local := range path {
+		matches := []*xmltree.Element{}
+		isLast := i == len(path)-1
+
+		for _, element := range elements {
+			for j := range element.Children {
+				child := &element.Children[j]
+
+				if child.Name.Local != local {
+					continue
+				}
+
+				if isLast && child.Name.Space != ns {
+					continue
+				}
+
+				matches = append(matches, child)
+			}
+		}
+
+		elements = matches
+	}
+
+	return elements
+}
+
+// decodeElements will unmarshal the elements to v. If v is a slice each
+// element will be appended, otherwise only the first element is used.
+func decodeElements(elements []*xmltree.Element, v reflect.Value) error {
+	if len(elements) == 0 {
+		return nil
+	}
+
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return xmltree.Unmarshal(elements[0], v.Addr().Interface())
 	}
 
-	if document.Name.Space != "" {
-		alias, ok := namespaceAliases[document.Name.Space]
-		if !ok {
-			return nil
+	for _, element := range elements {
+		item := reflect.New(v.Type().Elem())
+
+		if err := xmltree.Unmarshal(element, item.Interface()); err != nil {
+			return err
 		}
 
-		if !nsAdded {
-			xmlns := fmt.Sprintf("xmlns:%s", alias)
-			document.SetAttr("", xmlns, document.Name.Space)
+		v.Set(reflect.Append(v, item.Elem()))
+	}
 
-			// Namespace alias is now added so flip to true to skip child
-			// elements.
-			nsAdded = true
+	return nil
+}
+
+// documentAliases holds the namespace aliases used in a single document.
+// Aliases are fetched from types.DefaultNamespaceRegistry and namespaces not
+// found in the registry gets a generated alias.
+type documentAliases struct {
+	registry *types.NamespaceRegistry
+	aliases  map[string]string
+	used     map[string]struct{}
+}
+
+func newDocumentAliases(registry *types.NamespaceRegistry) *documentAliases {
+	return &documentAliases{
+		registry: registry,
+		aliases:  map[string]string{},
+		used:     map[string]struct{}{},
+	}
+}
+
+// alias returns the alias to use for the namespace in the document.
+func (d *documentAliases) alias(ns string) string {
+	if alias, ok := d.aliases[ns]; ok {
+		return alias
+	}
+
+	alias, ok := d.registry.Alias(ns)
+	if !ok {
+		alias = d.generateAlias()
+	}
+
+	d.aliases[ns] = alias
+	d.used[alias] = struct{}{}
+
+	return alias
+}
+
+// generateAlias will generate an alias not used in the document nor registered
+// for another namespace in the registry.
+func (d *documentAliases) generateAlias() string {
+	for i := 1; ; i++ {
+		alias := fmt.Sprintf("ns%d", i)
+
+		if _, ok := d.used[alias]; ok {
+			continue
+		}
+
+		if _, ok := d.registry.Namespace(alias); ok {
+			continue
 		}
 
-		document.Name.Local = fmt.Sprintf("%s:%s", alias, document.Name.Local)
+		return alias
 	}
+}
+
+// addNameSpaceAliases will check each node/element in the XML tree and if the
+// node has an xml.Name.Space value set an alias will be added to the node name.
+// The xmlns attribute for each alias is added to the nearest common ancestor of
+// all nodes using the namespace so the alias is declared for all of them, no
+// matter how many namespaces are mixed in the document.
+func addNameSpaceAliases(document *xmltree.Element, aliases *documentAliases) {
+	var (
+		namespaces = []string{}
+		ancestors  = map[string][]*xmltree.Element{}
+		walk       func(*xmltree.Element, []*xmltree.Element)
+	)
+
+	walk = func(element *xmltree.Element, path []*xmltree.Element) {
+		path = append(path[:len(path):len(path)], element)
+
+		if ns := element.Name.Space; ns != "" {
+			if common, ok := ancestors[ns]; ok {
+				ancestors[ns] = commonPath(common, path)
+			} else {
+				namespaces = append(namespaces, ns)
+				ancestors[ns] = path
+			}
+
+			element.Name.Local = fmt.Sprintf("%s:%s", aliases.alias(ns), element.Name.Local)
+		}
+
+		for i := range element.Children {
+			walk(&element.Children[i], path)
+		}
+	}
+
+	walk(document, nil)
+
+	// Add the declarations in the order the namespaces first occurred to get
+	// the same output each time.
+	for _, ns := range namespaces {
+		path := ancestors[ns]
+		xmlns := fmt.Sprintf("xmlns:%s", aliases.alias(ns))
+
+		path[len(path)-1].SetAttr("", xmlns, ns)
+	}
+}
 
-	for i, child := range document.Children {
-		document.Children[i] = *addNameSpaceAlias(&child, nsAdded)
+// commonPath returns the longest common prefix of two paths from the document
+// root. The last element in the returned path is the nearest common ancestor.
+func commonPath(a, b []*xmltree.Element) []*xmltree.Element {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
 	}
 
-	return document
+	return a[:i]
 }
@@ -0,0 +1,56 @@
+//go:build go1.18
+// +build go1.18
+
+package epp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func FuzzReadMessage(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 8, 'p', 'i', 'n', 'g'})
+	f.Add([]byte{0, 0, 0, 4})
+	f.Add([]byte{0, 0, 0, 3})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff})
+	f.Add([]byte{0, 0, 1})
+
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		message, err := ReadMessageLimit(bytes.NewBuffer(frame), 1024)
+		if err != nil {
+			return
+		}
+
+		size := binary.BigEndian.Uint32(frame)
+		if int(size) != len(message)+frameHeaderSize {
+			t.Fatalf("read %d bytes for frame of size %d", len(message), size)
+		}
+
+		if size > 1024 {
+			t.Fatalf("read frame of size %d larger than max size", size)
+		}
+	})
+}
+
+func FuzzWriteMessage(f *testing.F) {
+	f.Add([]byte("ping"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		buf := &bytes.Buffer{}
+
+		if err := WriteMessage(buf, data); err != nil {
+			t.Fatal(err)
+		}
+
+		message, err := ReadMessageLimit(buf, len(data)+frameHeaderSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(data, message) {
+			t.Fatalf("read %q, wrote %q", message, data)
+		}
+	})
+}
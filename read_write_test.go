@@ -1,7 +1,7 @@
 package epp
 
 import (
-	"encoding/xml"
+	"bytes"
 	"fmt"
 	"net"
 	"testing"
@@ -95,8 +95,187 @@ func TestEncode(t *testing.T) {
 	assert.Equal(t, string(expectedXMLWithNS), string(encoded))
 }
 
+func TestEncodeNestedNamespaces(t *testing.T) {
+	type dnssecData struct {
+		KeyTag uint `xml:"keyTag"`
+	}
+
+	type domainData struct {
+		Name   string     `xml:"name"`
+		DNSSEC dnssecData `xml:"urn:ietf:params:xml:ns:secDNS-1.1 dsData"`
+	}
+
+	type domainDataType struct {
+		Data domainData `xml:"urn:ietf:params:xml:ns:domain-1.0 response>resData>infData"`
+		Name string     `xml:"urn:ietf:params:xml:ns:domain-1.0 response>extension>name"`
+	}
+
+	data := domainDataType{
+		Data: domainData{
+			Name: "example.se",
+			DNSSEC: dnssecData{
+				KeyTag: 12345,
+			},
+		},
+		Name: "example.se",
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <response xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+    <resData>
+      <domain:infData xmlns="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.se</domain:name>
+        <sec:dsData xmlns:sec="urn:ietf:params:xml:ns:secDNS-1.1" xmlns="urn:ietf:params:xml:ns:secDNS-1.1">
+          <sec:keyTag>12345</sec:keyTag>
+        </sec:dsData>
+      </domain:infData>
+    </resData>
+    <extension>
+      <domain:name xmlns="urn:ietf:params:xml:ns:domain-1.0">example.se</domain:name>
+    </extension>
+  </response>
+</epp>
+`
+
+	encoded, err := Encode(data, ClientXMLAttributes())
+	require.Nil(t, err)
+
+	assert.Equal(t, expected, string(encoded))
+
+	decoded := domainDataType{}
+
+	require.Nil(t, Decode(encoded, &decoded))
+	assert.Equal(t, data, decoded)
+}
+
+func TestEncodeMultipleExtensionsValidates(t *testing.T) {
+	validator, err := NewValidator("xml/index.xsd")
+	require.Nil(t, err)
+
+	defer validator.Free()
+
+	response := types.Response{
+		Result: []types.Result{
+			{
+				Code:    EppOk.Code(),
+				Message: EppOk.Message(),
+			},
+		},
+		ResultData: types.DomainInfoDataType{
+			InfoData: types.DomainInfoData{
+				Name: "example.se",
+				ROID: "DOMAIN_0000000000-SE",
+				Status: []types.DomainStatus{
+					{
+						DomainStatusType: types.DomainStatusPendingDelete,
+					},
+				},
+				ClientID: "Some Client",
+			},
+		},
+		Extension: struct {
+			types.RGPExtensionInfoDataType
+			types.DNSSECExtensionInfoDataType
+			types.IISExtensionInfoDataType
+		}{
+			types.RGPExtensionInfoDataType{
+				InfoData: types.RGPExtensionData{
+					Status: []types.RGPStatus{
+						{
+							RGPStatusType: types.RGPStatusRedemptionPeriod,
+						},
+					},
+				},
+			},
+			types.DNSSECExtensionInfoDataType{
+				InfoData: types.DNSSECOrKeyData{
+					DNSSECData: []types.DNSSEC{
+						{
+							KeyTag:     12345,
+							Algorithm:  8,
+							DigestType: 2,
+							Digest:     "49FD46E6C4B45C55D4AC49FD46E6C4B45C55D4AC49FD46E6C4B45C55D4AC1234",
+						},
+					},
+				},
+			},
+			types.IISExtensionInfoDataType{
+				InfoData: types.IISExtensionInfoData{
+					State: "active",
+				},
+			},
+		},
+		TransactionID: types.TransactionID{
+			ServerTransactionID: "ABC-123",
+		},
+	}
+
+	encoded, err := Encode(response, ServerXMLAttributes())
+	require.Nil(t, err)
+
+	assert.Contains(t, string(encoded), `<domain:infData xmlns:domain="urn:ietf:params:xml:ns:domain-1.0"`)
+	assert.Contains(t, string(encoded), `<rgp:infData xmlns:rgp="urn:ietf:params:xml:ns:rgp-1.0"`)
+	assert.Contains(t, string(encoded), `<sec:infData xmlns:sec="urn:ietf:params:xml:ns:secDNS-1.1"`)
+	assert.Contains(t, string(encoded), `<iis:infData xmlns:iis="urn:se:iis:xml:epp:iis-1.2"`)
+
+	assert.Nil(t, validator.Validate(encoded))
+}
+
+func TestEncodeNamespaceRegistry(t *testing.T) {
+	type extensionData struct {
+		Value string `xml:"value"`
+	}
+
+	type unknownExtensionType struct {
+		Data extensionData `xml:"urn:example:unknown-1.0 command>extension>data"`
+	}
+
+	type registeredExtensionType struct {
+		Data extensionData `xml:"urn:example:registered-1.0 command>extension>data"`
+	}
+
+	registry := useNamespaceRegistry(t)
+	require.Nil(t, registry.Register("urn:example:registered-1.0", "reg"))
+	require.NotNil(t, registry.Register("urn:example:other-1.0", "reg"))
+
+	assert.Equal(t, "urn:example:registered-1.0", types.AliasToNameSpace("reg"))
+
+	cases := []struct {
+		description string
+		data        interface{}
+		expected    string
+	}{
+		{
+			description: "unknown namespace gets generated alias",
+			data:        unknownExtensionType{Data: extensionData{Value: "some-value"}},
+			expected:    `<ns1:data xmlns:ns1="urn:example:unknown-1.0" xmlns="urn:example:unknown-1.0">`,
+		},
+		{
+			description: "registered namespace uses preferred alias",
+			data:        registeredExtensionType{Data: extensionData{Value: "some-value"}},
+			expected:    `<reg:data xmlns:reg="urn:example:registered-1.0" xmlns="urn:example:registered-1.0">`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			encoded, err := Encode(tc.data, ClientXMLAttributes())
+			require.Nil(t, err)
+
+			assert.Contains(t, string(encoded), tc.expected)
+		})
+	}
+}
+
 func TestDecode(t *testing.T) {
-	x := []byte(`<?xml version="1.0"?>
+	cases := []struct {
+		description string
+		xml         []byte
+	}{
+		{
+			description: "namespace alias",
+			xml: []byte(`<?xml version="1.0"?>
 <epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
   <command>
     <create>
@@ -117,30 +296,145 @@ func TestDecode(t *testing.T) {
     </create>
     <clTRID>ABC-12345</clTRID>
   </command>
-</epp>`)
+</epp>`),
+		},
+		{
+			description: "custom namespace alias",
+			xml: []byte(`<?xml version="1.0"?>
+<e:epp xmlns:e="urn:ietf:params:xml:ns:epp-1.0" xmlns:d="urn:ietf:params:xml:ns:domain-1.0">
+  <e:command>
+    <e:create>
+      <d:create>
+        <d:name>example.net</d:name>
+        <d:period unit="m">12</d:period>
+        <d:ns>
+          <d:hostObj>ns1.example.net</d:hostObj>
+          <d:hostObj>ns2.example.net</d:hostObj>
+        </d:ns>
+        <d:registrant>registrant-00001</d:registrant>
+        <d:contact type="tech">contact-00001</d:contact>
+        <d:contact type="admin">contact-00002</d:contact>
+        <d:authInfo>
+          <d:pw>some-password</d:pw>
+        </d:authInfo>
+      </d:create>
+    </e:create>
+    <e:clTRID>ABC-12345</e:clTRID>
+  </e:command>
+</e:epp>`),
+		},
+		{
+			description: "default namespace",
+			xml: []byte(`<?xml version="1.0"?>
+<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <create>
+      <create xmlns="urn:ietf:params:xml:ns:domain-1.0">
+        <name>example.net</name>
+        <period unit="m">12</period>
+        <ns>
+          <hostObj>ns1.example.net</hostObj>
+          <hostObj>ns2.example.net</hostObj>
+        </ns>
+        <registrant>registrant-00001</registrant>
+        <contact type="tech">contact-00001</contact>
+        <contact type="admin">contact-00002</contact>
+        <authInfo>
+          <pw>some-password</pw>
+        </authInfo>
+      </create>
+    </create>
+    <clTRID>ABC-12345</clTRID>
+  </command>
+</epp>`),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			dct := types.DomainCreateType{}
 
-	dci := types.DomainCreateTypeIn{}
+			require.Nil(t, Decode(tc.xml, &dct))
 
-	if err := xml.Unmarshal(x, &dci); err != nil {
-		assert.Fail(t, "could not unmarshal")
+			dc := dct.Create
+
+			assert.Equal(t, "example.net", dc.Name, "domain name found")
+			assert.Equal(t, 12, dc.Period.Value, "period found")
+			assert.Equal(t, "m", dc.Period.Unit, "period unit found")
+			assert.Equal(t, []string{"ns1.example.net", "ns2.example.net"}, dc.NameServer.HostObject, "host objects found")
+			assert.Equal(t, "registrant-00001", dc.Registrant, "registrant found")
+			require.Len(t, dc.Contacts, 2, "contacts found")
+			assert.Equal(t, "contact-00001", dc.Contacts[0].Name, "contact found")
+			assert.Equal(t, "tech", dc.Contacts[0].Type, "contact type found")
+			assert.Equal(t, "contact-00002", dc.Contacts[1].Name, "contact found")
+			assert.Equal(t, "admin", dc.Contacts[1].Type, "contact type found")
+			require.NotNil(t, dc.AuthInfo, "auth info found")
+			assert.Equal(t, "some-password", dc.AuthInfo.Password, "auth info found")
+		})
+	}
+}
+
+func TestDecodeEncoded(t *testing.T) {
+	di := types.DomainInfoType{
+		Info: types.DomainInfo{
+			Name: types.DomainInfoName{
+				Name:  "example.se",
+				Hosts: types.DomainHostsAll,
+			},
+		},
 	}
 
-	dc := dci.Create
+	encoded, err := Encode(di, ClientXMLAttributes())
+	require.Nil(t, err)
+
+	decoded := types.DomainInfoType{}
 
-	assert.Equal(t, "example.net", dc.Name, "domain name found")
-	assert.Equal(t, 12, dc.Period.Value, "period found")
-	assert.Equal(t, "m", dc.Period.Unit, "period unit found")
-	assert.Equal(t, "ns1.example.net", dc.NameServer.HostObject[0], "host object found")
-	assert.Equal(t, "ns2.example.net", dc.NameServer.HostObject[1], "host object found")
-	assert.Equal(t, "registrant-00001", dc.Registrant, "registrant found")
-	assert.Equal(t, "contact-00001", dc.Contacts[0].Name, "contact found")
-	assert.Equal(t, "tech", dc.Contacts[0].Type, "contact type found")
-	assert.Equal(t, "contact-00002", dc.Contacts[1].Name, "contact found")
-	assert.Equal(t, "admin", dc.Contacts[1].Type, "contact type found")
-	assert.Equal(t, "some-password", dc.AuthInfo.Password, "auth info found")
+	require.Nil(t, Decode(encoded, &decoded))
+	assert.Equal(t, di, decoded)
 }
 
-func ExampleAddNamespace() {
+func TestDecodeWithExtension(t *testing.T) {
+	x := []byte(`<?xml version="1.0"?>
+<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <create>
+      <contact:create xmlns:contact="urn:ietf:params:xml:ns:contact-1.0">
+        <contact:id>contact-00001</contact:id>
+        <contact:email>info@example.se</contact:email>
+      </contact:create>
+    </create>
+    <extension>
+      <iis:create xmlns:iis="urn:se:iis:xml:epp:iis-1.2">
+        <iis:orgno>[SE]556677-8899</iis:orgno>
+      </iis:create>
+      <sec:create xmlns:sec="urn:ietf:params:xml:ns:secDNS-1.1">
+        <sec:dsData>
+          <sec:keyTag>12345</sec:keyTag>
+        </sec:dsData>
+      </sec:create>
+    </extension>
+    <clTRID>ABC-12345</clTRID>
+  </command>
+</epp>`)
+
+	cc := struct {
+		types.ContactCreateType
+		types.IISExtensionCreateType
+		types.DomainCreateType
+	}{}
+
+	require.Nil(t, Decode(x, &cc))
+
+	assert.Equal(t, "contact-00001", cc.ContactCreateType.Create.ID)
+	assert.Equal(t, "info@example.se", cc.ContactCreateType.Create.Email)
+	assert.Equal(t, "[SE]556677-8899", cc.IISExtensionCreateType.Create.OrganizationNumber)
+
+	// The domain create shares the same path as the contact create but is
+	// in another namespace and should not be decoded.
+	assert.Equal(t, "", cc.DomainCreateType.Create.Name)
+}
+
+func ExampleEncode() {
 	// Construct the response with basic data.
 	diResponse := types.DomainInfoDataType{
 		InfoData: types.DomainInfoData{
@@ -242,3 +536,55 @@ func ExampleAddNamespace() {
 	// </epp>
 
 }
+
+func TestReadMessageLimit(t *testing.T) {
+	cases := []struct {
+		description string
+		frame       []byte
+		maxSize     int
+		expected    []byte
+		expectedErr error
+	}{
+		{
+			description: "valid frame",
+			frame:       []byte{0, 0, 0, 8, 'p', 'i', 'n', 'g'},
+			maxSize:     8,
+			expected:    []byte("ping"),
+		},
+		{
+			description: "empty frame",
+			frame:       []byte{0, 0, 0, 4},
+			expected:    []byte{},
+		},
+		{
+			description: "frame length smaller than header",
+			frame:       []byte{0, 0, 0, 3},
+			expectedErr: ErrInvalidFrameHeader,
+		},
+		{
+			description: "frame length zero",
+			frame:       []byte{0, 0, 0, 0},
+			expectedErr: ErrInvalidFrameHeader,
+		},
+		{
+			description: "frame larger than max size",
+			frame:       []byte{0, 0, 0, 9, 'p', 'i', 'n', 'g', 's'},
+			maxSize:     8,
+			expectedErr: &FrameSizeError{Size: 9, MaxSize: 8},
+		},
+		{
+			description: "max length header with default max size",
+			frame:       []byte{0xff, 0xff, 0xff, 0xff},
+			expectedErr: &FrameSizeError{Size: 0xffffffff, MaxSize: DefaultMaxFrameSize},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			message, err := ReadMessageLimit(bytes.NewBuffer(tc.frame), tc.maxSize)
+
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expected, message)
+		})
+	}
+}
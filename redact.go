@@ -0,0 +1,249 @@
+package epp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bombsimon/epp-go/types"
+)
+
+// DefaultRedactionRules holds the rules used by DefaultRedactor, redacting
+// passwords and authorization information.
+var DefaultRedactionRules = []string{"//pw", "//newPW", "//authInfo"}
+
+// ContactPostalInfoRedactionRules holds rules redacting the postal info for
+// contacts, e.g. to add to DefaultRedactionRules to keep personal data out of
+// audit logs.
+var ContactPostalInfoRedactionRules = []string{"//contact:postalInfo"}
+
+// DefaultRedactor is a Redactor using DefaultRedactionRules.
+var DefaultRedactor = MustRedactor(DefaultRedactionRules...)
+
+// RedactedContent is the content replacing the content of redacted elements.
+const RedactedContent = "REDACTED"
+
+// Redactor replaces the content of elements matching any of its rules with
+// RedactedContent. Rules are written as a subset of XPath with steps separated
+// by / for children and // for descendants. Each step is a local name, a local
+// name prefixed with an alias from types.DefaultNamespaceRegistry, a local name
+// prefixed with a namespace in braces or *.
+//
+//	//pw                                matches pw in any namespace
+//	//contact:postalInfo                matches postalInfo for contacts
+//	//{urn:ietf:params:xml:ns:contact-1.0}voice
+//	/epp/command/login/pw
+type Redactor struct {
+	rules [][]redactionStep
+}
+
+// redactionStep is a single step in a rule. An empty space matches any
+// namespace and the local name * matches any name.
+type redactionStep struct {
+	descendant bool
+	space      string
+	local      string
+}
+
+// NewRedactor creates a new Redactor with the rules. An error is returned if
+// any rule is invalid.
+func NewRedactor(rules ...string) (*Redactor, error) {
+	r := &Redactor{}
+
+	for _, rule := range rules {
+		steps, err := parseRedactionRule(rule)
+		if err != nil {
+			return nil, err
+		}
+
+		r.rules = append(r.rules, steps)
+	}
+
+	return r, nil
+}
+
+// MustRedactor is like NewRedactor but panics if any rule is invalid.
+func MustRedactor(rules ...string) *Redactor {
+	r, err := NewRedactor(rules...)
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+func parseRedactionRule(rule string) ([]redactionStep, error) {
+	if !strings.HasPrefix(rule, "/") {
+		return nil, fmt.Errorf("invalid redaction rule %q: must start with /", rule)
+	}
+
+	var steps []redactionStep
+
+	for rest := rule; rest != ""; {
+		step := redactionStep{}
+
+		switch {
+		case strings.HasPrefix(rest, "//"):
+			step.descendant = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "/"):
+			rest = rest[1:]
+		default:
+			return nil, fmt.Errorf("invalid redaction rule %q", rule)
+		}
+
+		name := rest
+		if strings.HasPrefix(rest, "{") {
+			end := strings.Index(rest, "}")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid redaction rule %q: unterminated namespace", rule)
+			}
+
+			step.space = rest[1:end]
+			rest = rest[end+1:]
+			name = rest
+		}
+
+		if i := strings.Index(rest, "/"); i >= 0 {
+			name, rest = rest[:i], rest[i:]
+		} else {
+			rest = ""
+		}
+
+		if i := strings.Index(name, ":"); i >= 0 && step.space == "" {
+			ns, ok := types.DefaultNamespaceRegistry.Namespace(name[:i])
+			if !ok {
+				return nil, fmt.Errorf("invalid redaction rule %q: unknown alias %s", rule, name[:i])
+			}
+
+			step.space = ns
+			name = name[i+1:]
+		}
+
+		if name == "" {
+			return nil, fmt.Errorf("invalid redaction rule %q: empty step", rule)
+		}
+
+		step.local = name
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// Redact returns a copy of data with the content of all elements matching any
+// rule replaced. Elements inside a redacted element are not matched. An error
+// is returned if data isn't valid XML since it can't be redacted safely. A
+// Redactor without rules returns data as is without parsing it.
+func (r *Redactor) Redact(data []byte) ([]byte, error) {
+	if len(r.rules) == 0 {
+		return data, nil
+	}
+	type span struct{ start, end int }
+
+	var (
+		d       = xml.NewDecoder(bytes.NewReader(data))
+		path    []xml.Name
+		starts  []int
+		matched = -1
+		spans   []span
+	)
+
+	for {
+		offset := int(d.InputOffset())
+
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name)
+			starts = append(starts, int(d.InputOffset()))
+
+			if matched < 0 && r.matches(path) {
+				matched = len(path)
+			}
+		case xml.EndElement:
+			if matched == len(path) {
+				if start := starts[len(starts)-1]; start < offset {
+					spans = append(spans, span{start, offset})
+				}
+
+				matched = -1
+			}
+
+			path = path[:len(path)-1]
+			starts = starts[:len(starts)-1]
+		}
+	}
+
+	if len(spans) == 0 {
+		return data, nil
+	}
+
+	var (
+		buf  bytes.Buffer
+		last int
+	)
+
+	for _, s := range spans {
+		buf.Write(data[last:s.start])
+		buf.WriteString(RedactedContent)
+
+		last = s.end
+	}
+
+	buf.Write(data[last:])
+
+	return buf.Bytes(), nil
+}
+
+// matches returns true if the element at the end of path matches any rule.
+func (r *Redactor) matches(path []xml.Name) bool {
+	for _, rule := range r.rules {
+		if matchSteps(rule, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchSteps returns true if the steps matches the complete path with the last
+// step matching the last element.
+func matchSteps(steps []redactionStep, path []xml.Name) bool {
+	if len(steps) == 0 {
+		return len(path) == 0
+	}
+
+	step := steps[0]
+
+	for i := range path {
+		if i > 0 && !step.descendant {
+			break
+		}
+
+		if step.matches(path[i]) && matchSteps(steps[1:], path[i+1:]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s redactionStep) matches(name xml.Name) bool {
+	if s.space != "" && s.space != name.Space {
+		return false
+	}
+
+	return s.local == "*" || s.local == name.Local
+}
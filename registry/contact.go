@@ -0,0 +1,626 @@
+package registry
+
+import (
+	"encoding/xml"
+	"errors"
+	"time"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/store"
+	"github.com/bombsimon/epp-go/types"
+)
+
+// contactInfoDataType is the result data for a contact info command. It's
+// used instead of types.ContactInfoDataType to omit the elements that aren't
+// set.
+type contactInfoDataType struct {
+	InfoData contactInfoData `xml:"urn:ietf:params:xml:ns:contact-1.0 infData"`
+}
+
+// contactInfoData represents the response for a contact info command.
+type contactInfoData struct {
+	Name         string                `xml:"id"`
+	ROID         string                `xml:"roid"`
+	Status       []types.ContactStatus `xml:"status"`
+	PostalInfo   []types.PostalInfo    `xml:"postalInfo"`
+	Voice        *types.E164Type       `xml:"voice,omitempty"`
+	Fax          *types.E164Type       `xml:"fax,omitempty"`
+	Email        string                `xml:"email"`
+	ClientID     string                `xml:"clID"`
+	CreateID     string                `xml:"crID"`
+	CreateDate   time.Time             `xml:"crDate"`
+	UpdateID     string                `xml:"upID,omitempty"`
+	UpdateDate   *time.Time            `xml:"upDate,omitempty"`
+	TransferDate *time.Time            `xml:"trDate,omitempty"`
+	AuthInfo     *types.AuthInfo       `xml:"authInfo,omitempty"`
+	Disclose     *disclose             `xml:"disclose,omitempty"`
+}
+
+// disclose represents the fields to disclose for a contact. It's used
+// instead of types.Disclose to omit the fields that aren't set, both when
+// decoding and encoding.
+type disclose struct {
+	Name         *types.InternationalOrLocalType `xml:"name,omitempty"`
+	Organization *types.InternationalOrLocalType `xml:"org,omitempty"`
+	Address      *types.InternationalOrLocalType `xml:"addr,omitempty"`
+	Voice        *types.EmptyTag                 `xml:"voice,omitempty"`
+	Fax          *types.EmptyTag                 `xml:"fax,omitempty"`
+	Email        *types.EmptyTag                 `xml:"email,omitempty"`
+	Flag         bool                            `xml:"flag,attr"`
+}
+
+// contactElement returns the name of the element in the contact namespace.
+func contactElement(local string) xml.Name {
+	return xml.Name{Space: types.NameSpaceContact, Local: local}
+}
+
+// checkContact checks if contacts are available.
+func (r *Registry) checkContact(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.ContactCheckType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	response := types.ContactCheckDataType{}
+
+	for _, id := range request.Check.Names {
+		check := types.CheckContact{
+			Name: types.CheckName{Value: id},
+		}
+
+		_, err := tx.Contact(id)
+
+		exists, err := found(err)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if exists {
+			check.Reason = "In use"
+		} else {
+			check.Name.Available = true
+		}
+
+		response.CheckData.Name = append(response.CheckData.Name, check)
+	}
+
+	return epp.EppOk, response, nil
+}
+
+// infoContact returns information about a contact. The authorization
+// information is only returned to the sponsoring client.
+func (r *Registry) infoContact(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.ContactInfoType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	c, err := contact(tx, request.Info.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if hasAuthInfo(&request.Info.AuthInfo) && !authorized(&request.Info.AuthInfo, c.AuthInfo.Password) {
+		return 0, nil, &resultError{code: epp.EppInvalidAuthInfo, element: contactElement("id"), value: c.Name}
+	}
+
+	statuses, err := contactStatuses(tx, c)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	info := contactInfoData{
+		Name:       c.Name,
+		ROID:       c.ROID,
+		Status:     statuses,
+		PostalInfo: copyPostalInfo(c.PostalInfo),
+		Email:      c.Email,
+		ClientID:   c.ClientID,
+		CreateID:   c.CreateID,
+		CreateDate: c.CreateDate,
+		UpdateID:   c.UpdateID,
+		Disclose:   discloseElement(c.Disclose),
+	}
+
+	if c.Voice.Value != "" {
+		voice := c.Voice
+		info.Voice = &voice
+	}
+
+	if c.Fax.Value != "" {
+		fax := c.Fax
+		info.Fax = &fax
+	}
+
+	if !c.UpdateDate.IsZero() {
+		info.UpdateDate = timePtr(c.UpdateDate)
+	}
+
+	if !c.TransferDate.IsZero() {
+		info.TransferDate = timePtr(c.TransferDate)
+	}
+
+	if clientID == c.ClientID {
+		authInfo := c.AuthInfo
+		info.AuthInfo = &authInfo
+	}
+
+	return epp.EppOk, contactInfoDataType{InfoData: info}, nil
+}
+
+// createContact creates a contact sponsored by the client.
+func (r *Registry) createContact(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.ContactCreateType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	var discloseRequest struct {
+		Disclose *disclose `xml:"urn:ietf:params:xml:ns:contact-1.0 command>create>create>disclose"`
+	}
+
+	if err := epp.Decode(data, &discloseRequest); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	create := request.Create
+
+	_, err := tx.Contact(create.ID)
+
+	exists, err := found(err)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	switch {
+	case exists:
+		return 0, nil, &resultError{code: epp.EppObjectExists, element: contactElement("id"), value: create.ID}
+	case !hasAuthInfo(&create.AuthInfo):
+		return 0, nil, &resultError{code: epp.EppMissingParam, element: contactElement("authInfo"), reason: "authorization information is required"}
+	}
+
+	postalInfo, err := updatePostalInfo(nil, create.PostalInfo)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	roid, err := r.newROID(tx, "C")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	now := r.now()
+
+	err = tx.PutContact(&types.ContactInfoData{
+		Name:       create.ID,
+		ROID:       roid,
+		PostalInfo: postalInfo,
+		Voice:      create.Voice,
+		Fax:        create.Fax,
+		Email:      create.Email,
+		ClientID:   clientID,
+		CreateID:   clientID,
+		CreateDate: now,
+		AuthInfo:   create.AuthInfo,
+		Disclose:   storedDisclose(discloseRequest.Disclose),
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return epp.EppOk, types.ContactCreateDataType{
+		CreateData: types.ContactCreateData{
+			Name:       create.ID,
+			CreateDate: now,
+		},
+	}, nil
+}
+
+// updateContact updates a contact sponsored by the client. The update is
+// only applied if all changes are valid.
+func (r *Registry) updateContact(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.ContactUpdateType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	var discloseRequest struct {
+		Disclose *disclose `xml:"urn:ietf:params:xml:ns:contact-1.0 command>update>update>chg>disclose"`
+	}
+
+	if err := epp.Decode(data, &discloseRequest); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	update := request.Update
+
+	c, err := sponsoredContact(tx, clientID, update.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	add, remove := update.Add, update.Remove
+	if add == nil {
+		add = &types.ContactAddRemove{}
+	}
+
+	if remove == nil {
+		remove = &types.ContactAddRemove{}
+	}
+
+	current, err := contactStatuses(tx, c)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := checkProhibited(contactStatusList(current), opUpdate, contactElement("id"), c.Name, contactStatusList(remove.Status)...); err != nil {
+		return 0, nil, err
+	}
+
+	updated := copyContact(c)
+
+	statuses, err := updateStatuses(contactStatusList(c.Status), contactStatusList(add.Status), contactStatusList(remove.Status), contactElement("status"))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	updated.Status = contactStatusTypes(statuses)
+
+	if change := update.Change; change != nil {
+		if updated.PostalInfo, err = updatePostalInfo(updated.PostalInfo, change.PostalInfo); err != nil {
+			return 0, nil, err
+		}
+
+		if change.Voice.Value != "" {
+			updated.Voice = change.Voice
+		}
+
+		if change.Fax.Value != "" {
+			updated.Fax = change.Fax
+		}
+
+		if change.Email != "" {
+			updated.Email = change.Email
+		}
+
+		if hasAuthInfo(&change.AuthInfo) {
+			updated.AuthInfo = change.AuthInfo
+		}
+
+		if discloseRequest.Disclose != nil {
+			updated.Disclose = storedDisclose(discloseRequest.Disclose)
+		}
+	}
+
+	updated.UpdateID, updated.UpdateDate = clientID, r.now()
+
+	return epp.EppOk, nil, tx.PutContact(updated)
+}
+
+// deleteContact deletes a contact sponsored by the client if it's not used
+// by any domain.
+func (r *Registry) deleteContact(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.ContactDeleteType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	c, err := sponsoredContact(tx, clientID, request.Delete.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	statuses, err := contactStatuses(tx, c)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := checkProhibited(contactStatusList(statuses), opDelete, contactElement("id"), c.Name); err != nil {
+		return 0, nil, err
+	}
+
+	domains, err := contactDomains(tx, c.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if len(domains) > 0 {
+		return 0, nil, &resultError{
+			code:    epp.EppAssocProhibitsOp,
+			element: contactElement("id"),
+			value:   c.Name,
+			reason:  "contact is used by " + domains[0].Name,
+		}
+	}
+
+	if err := tx.DeleteContact(c.Name); err != nil {
+		return 0, nil, err
+	}
+
+	return epp.EppOk, nil, deleteTransfer(tx, c.ROID)
+}
+
+// transferContact handles all transfer operations for a contact.
+func (r *Registry) transferContact(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.ContactTransferType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	op, err := transferOperation(data)
+	if err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	c, err := contact(tx, request.Transfer.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	code, t, err := r.transferCommand(tx, clientID, op, transferObject{
+		kind:     store.KindContact,
+		name:     c.Name,
+		roid:     c.ROID,
+		clientID: c.ClientID,
+		password: c.AuthInfo.Password,
+		statuses: contactStatusList(c.Status),
+		element:  contactElement("id"),
+	}, &request.Transfer.AuthInfo, types.Period{})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return code, transferData(t), nil
+}
+
+// contact returns the contact with the ID.
+func contact(tx store.Tx, id string) (*types.ContactInfoData, error) {
+	c, err := tx.Contact(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, &resultError{code: epp.EppObjectDoesNotExist, element: contactElement("id"), value: id}
+	}
+
+	return c, err
+}
+
+// sponsoredContact returns the contact with the ID if it's sponsored by the
+// client.
+func sponsoredContact(tx store.Tx, clientID, id string) (*types.ContactInfoData, error) {
+	c, err := contact(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ClientID != clientID {
+		return nil, &resultError{code: epp.EppAuthorisationError, element: contactElement("id"), value: id}
+	}
+
+	return c, nil
+}
+
+// contactDomains returns the domains using the contact as registrant or
+// contact.
+func contactDomains(tx store.Tx, id string) ([]*types.DomainInfoData, error) {
+	all, err := tx.Domains()
+	if err != nil {
+		return nil, err
+	}
+
+	domains := []*types.DomainInfoData{}
+
+	for _, d := range all {
+		used := d.Registrant == id
+
+		for _, c := range d.Contact {
+			used = used || c.Name == id
+		}
+
+		if used {
+			domains = append(domains, d)
+		}
+	}
+
+	return domains, nil
+}
+
+// contactStatuses returns the statuses for the contact, including statuses
+// derived from the state of the registry.
+func contactStatuses(tx store.Tx, c *types.ContactInfoData) ([]types.ContactStatus, error) {
+	statuses := append([]types.ContactStatus{}, c.Status...)
+
+	pending, err := pendingTransfer(tx, c.ROID)
+	if err != nil {
+		return nil, err
+	}
+
+	if pending {
+		statuses = append(statuses, types.ContactStatus{ContactStatusType: types.ContactStatusPendingTransfer})
+	}
+
+	if len(statuses) == 0 {
+		statuses = append(statuses, types.ContactStatus{ContactStatusType: types.ContactStatusOk})
+	}
+
+	domains, err := contactDomains(tx, c.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(domains) > 0 {
+		statuses = append(statuses, types.ContactStatus{ContactStatusType: types.ContactStatusLinked})
+	}
+
+	return statuses, nil
+}
+
+// updatePostalInfo returns the postal information with the postal
+// information in change applied. Postal information of a type not already
+// added must be complete, for existing types only the fields set are changed.
+func updatePostalInfo(postalInfo, change []types.PostalInfo) ([]types.PostalInfo, error) {
+	result := copyPostalInfo(postalInfo)
+
+	for _, p := range change {
+		if p.Type != types.PostalInfoLocal && p.Type != types.PostalInfoInternational {
+			return nil, &resultError{code: epp.EppParamSyntaxError, element: contactElement("postalInfo"), value: string(p.Type), reason: "unknown postal info type"}
+		}
+
+		found := false
+
+		for i := range result {
+			if result[i].Type != p.Type {
+				continue
+			}
+
+			if p.Name != "" {
+				result[i].Name = p.Name
+			}
+
+			if p.Organization != "" {
+				result[i].Organization = p.Organization
+			}
+
+			if p.Address.City != "" {
+				result[i].Address = p.Address
+			}
+
+			found = true
+		}
+
+		if found {
+			continue
+		}
+
+		if p.Name == "" || p.Address.City == "" || p.Address.CountryCode == "" {
+			return nil, &resultError{code: epp.EppMissingParam, element: contactElement("postalInfo"), value: string(p.Type), reason: "name and address are required"}
+		}
+
+		result = append(result, p)
+	}
+
+	return result, nil
+}
+
+// copyPostalInfo returns a deep copy of the postal information.
+func copyPostalInfo(postalInfo []types.PostalInfo) []types.PostalInfo {
+	if postalInfo == nil {
+		return nil
+	}
+
+	result := make([]types.PostalInfo, 0, len(postalInfo))
+
+	for _, p := range postalInfo {
+		p.Address.Street = append([]string(nil), p.Address.Street...)
+		result = append(result, p)
+	}
+
+	return result
+}
+
+// copyContact returns a deep copy of the contact.
+func copyContact(c *types.ContactInfoData) *types.ContactInfoData {
+	cp := *c
+	cp.Status = append([]types.ContactStatus(nil), c.Status...)
+	cp.PostalInfo = copyPostalInfo(c.PostalInfo)
+
+	return &cp
+}
+
+// storedDisclose converts the disclose element from a request to the type
+// stored for a contact.
+func storedDisclose(d *disclose) types.Disclose {
+	if d == nil {
+		return types.Disclose{}
+	}
+
+	result := types.Disclose{
+		Voice: d.Voice != nil,
+		Fax:   d.Fax != nil,
+		Email: d.Email != nil,
+		Flag:  d.Flag,
+	}
+
+	if d.Name != nil {
+		result.Name = *d.Name
+	}
+
+	if d.Organization != nil {
+		result.Organization = *d.Organization
+	}
+
+	if d.Address != nil {
+		result.Address = *d.Address
+	}
+
+	return result
+}
+
+// discloseElement converts the disclose stored for a contact to the element
+// for a response or nil if no fields are disclosed or hidden.
+func discloseElement(d types.Disclose) *disclose {
+	result := &disclose{Flag: d.Flag}
+
+	for _, f := range []struct {
+		value types.InternationalOrLocalType
+		field **types.InternationalOrLocalType
+	}{
+		{d.Name, &result.Name},
+		{d.Organization, &result.Organization},
+		{d.Address, &result.Address},
+	} {
+		if f.value.Type != "" {
+			value := f.value
+			*f.field = &value
+		}
+	}
+
+	for _, f := range []struct {
+		value bool
+		field **types.EmptyTag
+	}{
+		{d.Voice, &result.Voice},
+		{d.Fax, &result.Fax},
+		{d.Email, &result.Email},
+	} {
+		if f.value {
+			*f.field = &types.EmptyTag{}
+		}
+	}
+
+	if *result == (disclose{Flag: d.Flag}) {
+		return nil
+	}
+
+	return result
+}
+
+// contactStatusList converts contact statuses to statuses.
+func contactStatusList(statuses []types.ContactStatus) []status {
+	result := make([]status, 0, len(statuses))
+
+	for _, s := range statuses {
+		result = append(result, status{value: string(s.ContactStatusType), message: s.Status, language: s.Language})
+	}
+
+	return result
+}
+
+// contactStatusTypes converts statuses to contact statuses.
+func contactStatusTypes(statuses []status) []types.ContactStatus {
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	result := make([]types.ContactStatus, 0, len(statuses))
+
+	for _, s := range statuses {
+		result = append(result, types.ContactStatus{ContactStatusType: types.ContactStatusType(s.value), Status: s.message, Language: s.language})
+	}
+
+	return result
+}
@@ -0,0 +1,184 @@
+package registry
+
+import (
+	"testing"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createContact creates the contact with the password secret.
+func createContact(c *testClient, id string) {
+	c.t.Helper()
+
+	c.expect(epp.EppOk, object("create", "contact", `<contact:id>`+id+`</contact:id>
+<contact:postalInfo type="int">
+  <contact:name>John Doe</contact:name>
+  <contact:addr><contact:city>Stockholm</contact:city><contact:cc>SE</contact:cc></contact:addr>
+</contact:postalInfo>
+<contact:email>jdoe@example.se</contact:email>
+<contact:authInfo><contact:pw>secret</contact:pw></contact:authInfo>`))
+}
+
+func infoContact(c *testClient, body string) *types.ContactInfoData {
+	c.t.Helper()
+
+	info := c.expect(epp.EppOk, object("info", "contact", body)).ContactInfoData()
+	require.NotNil(c.t, info)
+
+	return info
+}
+
+func contactStatusValues(info *types.ContactInfoData) []types.ContactStatusType {
+	values := []types.ContactStatusType{}
+
+	for _, s := range info.Status {
+		values = append(values, s.ContactStatusType)
+	}
+
+	return values
+}
+
+func TestContactCheck(t *testing.T) {
+	tr := newTestRegistry(t)
+	c := tr.login("registrar-1")
+
+	createContact(c, "contact-1")
+
+	check := c.expect(epp.EppOk, object("check", "contact", "<contact:id>contact-1</contact:id><contact:id>contact-2</contact:id>")).ContactCheckData()
+	require.NotNil(t, check)
+	require.Len(t, check.Name, 2)
+	assert.False(t, check.Name[0].Name.Available)
+	assert.True(t, check.Name[1].Name.Available)
+}
+
+func TestContactCreateAndInfo(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	c1.expect(epp.EppOk, object("create", "contact", `<contact:id>contact-1</contact:id>
+<contact:postalInfo type="loc">
+  <contact:name>Jöns Doe</contact:name>
+  <contact:org>Example AB</contact:org>
+  <contact:addr><contact:street>Storgatan 1</contact:street><contact:city>Stockholm</contact:city><contact:pc>111 22</contact:pc><contact:cc>SE</contact:cc></contact:addr>
+</contact:postalInfo>
+<contact:voice x="1234">+46.812345678</contact:voice>
+<contact:email>jdoe@example.se</contact:email>
+<contact:authInfo><contact:pw>secret</contact:pw></contact:authInfo>
+<contact:disclose flag="0"><contact:name type="loc"/><contact:voice/></contact:disclose>`))
+
+	createContact(c1, "contact-2")
+	c1.expect(epp.EppObjectExists, object("create", "contact", `<contact:id>contact-2</contact:id>
+<contact:postalInfo type="int"><contact:name>Jane Doe</contact:name><contact:addr><contact:city>Stockholm</contact:city><contact:cc>SE</contact:cc></contact:addr></contact:postalInfo>
+<contact:email>jane@example.se</contact:email>
+<contact:authInfo><contact:pw>secret</contact:pw></contact:authInfo>`))
+
+	// The empty voice, fax and email elements in the response can't be
+	// decoded to the bool fields in types.Disclose.
+	stored, err := tr.tx().Contact("contact-1")
+	require.Nil(t, err)
+	assert.True(t, stored.Disclose.Voice)
+
+	info := infoContact(c1, "<contact:id>contact-1</contact:id>")
+	assert.Equal(t, "C1-EPP", info.ROID)
+	assert.Equal(t, []types.ContactStatusType{types.ContactStatusOk}, contactStatusValues(info))
+	require.Len(t, info.PostalInfo, 1)
+	assert.Equal(t, "Jöns Doe", info.PostalInfo[0].Name)
+	assert.Equal(t, []string{"Storgatan 1"}, info.PostalInfo[0].Address.Street)
+	assert.Equal(t, types.E164Type{Value: "+46.812345678", X: "1234"}, info.Voice)
+	assert.Equal(t, "secret", info.AuthInfo.Password)
+	assert.Equal(t, types.PostalInfoLocal, info.Disclose.Name.Type)
+	assert.False(t, info.Disclose.Flag)
+
+	info = infoContact(c2, "<contact:id>contact-1</contact:id>")
+	assert.Empty(t, info.AuthInfo.Password)
+
+	c2.expect(epp.EppInvalidAuthInfo, object("info", "contact", "<contact:id>contact-1</contact:id><contact:authInfo><contact:pw>wrong</contact:pw></contact:authInfo>"))
+	c2.expect(epp.EppObjectDoesNotExist, object("info", "contact", "<contact:id>contact-3</contact:id>"))
+}
+
+func TestContactUpdate(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	createContact(c1, "contact-1")
+
+	c2.expect(epp.EppAuthorisationError, object("update", "contact", `<contact:id>contact-1</contact:id>
+<contact:chg><contact:email>new@example.se</contact:email></contact:chg>`))
+
+	c1.expect(epp.EppOk, object("update", "contact", `<contact:id>contact-1</contact:id>
+<contact:add><contact:status s="clientDeleteProhibited"/></contact:add>
+<contact:chg>
+  <contact:postalInfo type="int"><contact:org>Example AB</contact:org></contact:postalInfo>
+  <contact:postalInfo type="loc"><contact:name>Jöns Doe</contact:name><contact:addr><contact:city>Göteborg</contact:city><contact:cc>SE</contact:cc></contact:addr></contact:postalInfo>
+  <contact:email>new@example.se</contact:email>
+  <contact:authInfo><contact:pw>new-secret</contact:pw></contact:authInfo>
+</contact:chg>`))
+
+	info := infoContact(c1, "<contact:id>contact-1</contact:id>")
+	assert.Equal(t, []types.ContactStatusType{types.ContactStatusClientDeleteProhibited}, contactStatusValues(info))
+	require.Len(t, info.PostalInfo, 2)
+	assert.Equal(t, "John Doe", info.PostalInfo[0].Name)
+	assert.Equal(t, "Example AB", info.PostalInfo[0].Organization)
+	assert.Equal(t, "Göteborg", info.PostalInfo[1].Address.City)
+	assert.Equal(t, "new@example.se", info.Email)
+	assert.Equal(t, "new-secret", info.AuthInfo.Password)
+	assert.Equal(t, "registrar-1", info.UpdateID)
+
+	c1.expect(epp.EppStatusProhibitsOp, object("delete", "contact", "<contact:id>contact-1</contact:id>"))
+	c1.expect(epp.EppParamPolicyError, object("update", "contact", `<contact:id>contact-1</contact:id>
+<contact:add><contact:status s="clientDeleteProhibited"/></contact:add>`))
+}
+
+func TestContactDelete(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	createContact(c1, "contact-1")
+	createDomain(c2, "example.se", `<domain:contact type="tech">contact-1</domain:contact>`)
+
+	info := infoContact(c1, "<contact:id>contact-1</contact:id>")
+	assert.Equal(t, []types.ContactStatusType{types.ContactStatusOk, types.ContactStatusLinked}, contactStatusValues(info))
+
+	c2.expect(epp.EppAuthorisationError, object("delete", "contact", "<contact:id>contact-1</contact:id>"))
+	c1.expect(epp.EppAssocProhibitsOp, object("delete", "contact", "<contact:id>contact-1</contact:id>"))
+
+	c2.expect(epp.EppOk, object("delete", "domain", "<domain:name>example.se</domain:name>"))
+	c1.expect(epp.EppOk, object("delete", "contact", "<contact:id>contact-1</contact:id>"))
+	c1.expect(epp.EppObjectDoesNotExist, object("info", "contact", "<contact:id>contact-1</contact:id>"))
+}
+
+func TestContactTransfer(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	createContact(c1, "contact-1")
+
+	transfer := func(c *testClient, code epp.ResultCode, op string) *types.ContactTransferData {
+		return c.expect(code, transferCommand(op, "contact", `<contact:id>contact-1</contact:id>
+<contact:authInfo><contact:pw>secret</contact:pw></contact:authInfo>`)).ContactTransferData()
+	}
+
+	transfer(c1, epp.EppNotTransferrable, "request")
+
+	requested := transfer(c2, epp.EppOkPending, "request")
+	require.NotNil(t, requested)
+	assert.Equal(t, types.ContactTransferPending, requested.TransferStatus)
+	assert.Equal(t, tr.clock.Add(DefaultTransferPeriod), requested.ActingDate)
+
+	info := infoContact(c1, "<contact:id>contact-1</contact:id>")
+	assert.Equal(t, []types.ContactStatusType{types.ContactStatusPendingTransfer}, contactStatusValues(info))
+
+	approved := transfer(c1, epp.EppOk, "approve")
+	assert.Equal(t, types.ContactTransferClientApproved, approved.TransferStatus)
+
+	info = infoContact(c2, "<contact:id>contact-1</contact:id>")
+	assert.Equal(t, "registrar-2", info.ClientID)
+	assert.Equal(t, tr.clock, info.TransferDate)
+}
@@ -0,0 +1,833 @@
+package registry
+
+import (
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/poll"
+	"github.com/bombsimon/epp-go/store"
+	"github.com/bombsimon/epp-go/types"
+)
+
+// maxNameServers is the maximum number of name servers for a domain.
+const maxNameServers = 13
+
+// domainElement returns the name of the element in the domain namespace.
+func domainElement(local string) xml.Name {
+	return xml.Name{Space: types.NameSpaceDomain, Local: local}
+}
+
+// checkDomain checks if domains are available.
+func (r *Registry) checkDomain(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.DomainCheckType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	response := types.DomainChekDataType{}
+
+	for _, name := range request.Check.Names {
+		check := types.CheckType{
+			Name: types.CheckName{Value: name},
+		}
+
+		n := normalizeName(name)
+
+		_, err := tx.Domain(n)
+
+		exists, err := found(err)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch {
+		case !validName(n):
+			check.Reason = "Invalid domain name"
+		case !r.allowedDomain(n):
+			check.Reason = "Not in a served zone"
+		case exists:
+			check.Reason = "In use"
+		default:
+			check.Name.Available = true
+		}
+
+		response.CheckData.CheckDomain = append(response.CheckData.CheckDomain, check)
+	}
+
+	return epp.EppOk, response, nil
+}
+
+// infoDomain returns information about a domain. Clients other than the
+// sponsoring client only get the public information unless they provide the
+// authorization information for the domain. The authorization information is
+// only returned to the sponsoring client.
+func (r *Registry) infoDomain(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.DomainInfoType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	d, err := domain(tx, request.Info.Name.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	full := clientID == d.ClientID
+
+	if hasAuthInfo(request.Info.AuthInfo) {
+		if !authorized(request.Info.AuthInfo, password(d.AuthInfo)) {
+			return 0, nil, &resultError{code: epp.EppInvalidAuthInfo, element: domainElement("name"), value: d.Name}
+		}
+
+		full = true
+	}
+
+	info := copyDomain(d)
+
+	if info.Status, err = domainStatuses(tx, d); err != nil {
+		return 0, nil, err
+	}
+
+	if clientID != d.ClientID {
+		info.AuthInfo = nil
+	}
+
+	if !full {
+		info.Registrant, info.Contact, info.NameServer = "", nil, nil
+		info.CreateID, info.UpdateID, info.UpdateDate, info.TransferDate = "", "", nil, nil
+
+		return epp.EppOk, types.DomainInfoDataType{InfoData: *info}, nil
+	}
+
+	hosts := request.Info.Name.Hosts
+	if hosts == "" {
+		hosts = types.DomainHostsAll
+	}
+
+	if hosts != types.DomainHostsAll && hosts != types.DomainHostsDel {
+		info.NameServer = nil
+	}
+
+	if hosts == types.DomainHostsAll || hosts == types.DomainHostsSub {
+		subordinates, err := subordinateHosts(tx, d.Name)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		for _, h := range subordinates {
+			info.Host = append(info.Host, h.Name)
+		}
+	}
+
+	return epp.EppOk, types.DomainInfoDataType{InfoData: *info}, nil
+}
+
+// createDomain creates a domain sponsored by the client. The registrant,
+// contacts and hosts must exist.
+func (r *Registry) createDomain(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.DomainCreateType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	create := request.Create
+	name := normalizeName(create.Name)
+
+	_, err := tx.Domain(name)
+
+	exists, err := found(err)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	switch {
+	case !validName(name):
+		return 0, nil, &resultError{code: epp.EppParamSyntaxError, element: domainElement("name"), value: create.Name, reason: "invalid domain name"}
+	case !r.allowedDomain(name):
+		return 0, nil, &resultError{code: epp.EppParamPolicyError, element: domainElement("name"), value: create.Name, reason: "not in a zone served by the registry"}
+	case exists:
+		return 0, nil, &resultError{code: epp.EppObjectExists, element: domainElement("name"), value: create.Name}
+	case !hasAuthInfo(create.AuthInfo):
+		return 0, nil, &resultError{code: epp.EppMissingParam, element: domainElement("authInfo"), reason: "authorization information is required"}
+	}
+
+	now := r.now()
+
+	period := create.Period
+	if period.Value == 0 {
+		period = types.Period{Value: 1, Unit: "y"}
+	}
+
+	expireDate, err := r.extend(now, period)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	roid, err := r.newROID(tx, "D")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	d := &types.DomainInfoData{
+		Name:       name,
+		ROID:       roid,
+		ClientID:   clientID,
+		CreateID:   clientID,
+		CreateDate: timePtr(now),
+		ExpireDate: timePtr(expireDate),
+		AuthInfo:   &types.AuthInfo{Password: create.AuthInfo.Password, Extension: create.AuthInfo.Extension},
+	}
+
+	if d.NameServer, err = addNameServers(tx, nil, create.NameServer); err != nil {
+		return 0, nil, err
+	}
+
+	if d.Registrant, err = registrant(tx, create.Registrant); err != nil {
+		return 0, nil, err
+	}
+
+	if d.Contact, err = addDomainContacts(tx, nil, create.Contacts); err != nil {
+		return 0, nil, err
+	}
+
+	if err := tx.PutDomain(d); err != nil {
+		return 0, nil, err
+	}
+
+	return epp.EppOk, types.DomainCreateDataType{
+		CreateData: types.DomainCreateData{
+			Name:       name,
+			CreateDate: now,
+			ExpireDate: expireDate,
+		},
+	}, nil
+}
+
+// updateDomain updates a domain sponsored by the client. The update is only
+// applied if all changes are valid.
+func (r *Registry) updateDomain(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.DomainUpdateType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	update := request.Update
+
+	d, err := sponsoredDomain(tx, clientID, update.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	add, remove := update.Add, update.Remove
+	if add == nil {
+		add = &types.DomainAddRemove{}
+	}
+
+	if remove == nil {
+		remove = &types.DomainAddRemove{}
+	}
+
+	if err := checkDomainProhibited(tx, d, opUpdate, domainStatusList(remove.Status)...); err != nil {
+		return 0, nil, err
+	}
+
+	updated := copyDomain(d)
+
+	statuses, err := updateStatuses(domainStatusList(d.Status), domainStatusList(add.Status), domainStatusList(remove.Status), domainElement("status"))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	updated.Status = domainStatusTypes(statuses)
+
+	if updated.NameServer, err = removeNameServers(updated.NameServer, remove.NameServer); err != nil {
+		return 0, nil, err
+	}
+
+	if updated.NameServer, err = addNameServers(tx, updated.NameServer, add.NameServer); err != nil {
+		return 0, nil, err
+	}
+
+	if updated.Contact, err = removeDomainContacts(updated.Contact, remove.Contact); err != nil {
+		return 0, nil, err
+	}
+
+	if updated.Contact, err = addDomainContacts(tx, updated.Contact, add.Contact); err != nil {
+		return 0, nil, err
+	}
+
+	if change := update.Change; change != nil {
+		if change.Registrant != "" {
+			if updated.Registrant, err = registrant(tx, change.Registrant); err != nil {
+				return 0, nil, err
+			}
+		}
+
+		if hasAuthInfo(change.AuthInfo) {
+			updated.AuthInfo = &types.AuthInfo{Password: change.AuthInfo.Password, Extension: change.AuthInfo.Extension}
+		}
+	}
+
+	updated.UpdateID, updated.UpdateDate = clientID, timePtr(r.now())
+
+	return epp.EppOk, nil, tx.PutDomain(updated)
+}
+
+// deleteDomain deletes a domain sponsored by the client together with its
+// subordinate hosts. The domain can't be deleted if any of the subordinate
+// hosts are used by other domains.
+func (r *Registry) deleteDomain(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.DomainDeleteType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	d, err := sponsoredDomain(tx, clientID, request.Delete.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := checkDomainProhibited(tx, d, opDelete); err != nil {
+		return 0, nil, err
+	}
+
+	subordinates, err := subordinateHosts(tx, d.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, h := range subordinates {
+		linking, err := linkingDomains(tx, h.Name)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		for _, linked := range linking {
+			if linked.Name != d.Name {
+				return 0, nil, &resultError{
+					code:    epp.EppAssocProhibitsOp,
+					element: domainElement("name"),
+					value:   d.Name,
+					reason:  "subordinate host " + h.Name + " is used by " + linked.Name,
+				}
+			}
+		}
+	}
+
+	for _, h := range subordinates {
+		if err := tx.DeleteHost(h.Name); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if err := tx.DeleteDomain(d.Name); err != nil {
+		return 0, nil, err
+	}
+
+	return epp.EppOk, nil, deleteTransfer(tx, d.ROID)
+}
+
+// renewDomain extends the registration period of a domain sponsored by the
+// client.
+func (r *Registry) renewDomain(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	// The current expiry date is a date without time which can't be decoded
+	// with types.DomainRenewType.
+	var request struct {
+		Renew struct {
+			Name       string       `xml:"name"`
+			ExpireDate string       `xml:"curExpDate"`
+			Period     types.Period `xml:"period"`
+		} `xml:"urn:ietf:params:xml:ns:domain-1.0 command>renew>renew"`
+	}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	renew := request.Renew
+
+	d, err := sponsoredDomain(tx, clientID, renew.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := checkDomainProhibited(tx, d, opRenew); err != nil {
+		return 0, nil, err
+	}
+
+	if renew.ExpireDate != d.ExpireDate.Format("2006-01-02") {
+		return 0, nil, &resultError{
+			code:    epp.EppParamPolicyError,
+			element: domainElement("curExpDate"),
+			value:   renew.ExpireDate,
+			reason:  "not the current expiry date",
+		}
+	}
+
+	period := renew.Period
+	if period.Value == 0 {
+		period = types.Period{Value: 1, Unit: "y"}
+	}
+
+	expireDate, err := r.extend(*d.ExpireDate, period)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	d.ExpireDate = timePtr(expireDate)
+
+	if err := tx.PutDomain(d); err != nil {
+		return 0, nil, err
+	}
+
+	return epp.EppOk, types.DomainRenewDataType{
+		RenewData: types.DomainRenewData{
+			Name:       d.Name,
+			ExpireDate: expireDate,
+		},
+	}, nil
+}
+
+// autoRenewPeriod is the period expired domains are renewed with.
+var autoRenewPeriod = types.Period{Value: 1, Unit: "y"}
+
+// expireDomains renews all domains where the expiry date has passed and
+// notifies the sponsoring client with a poll message holding the new expiry
+// date. Domains are renewed until the expiry date is in the future so each
+// expiry is only notified once.
+func (r *Registry) expireDomains(tx store.Tx) error {
+	domains, err := tx.Domains()
+	if err != nil {
+		return err
+	}
+
+	now := r.now()
+
+	for _, d := range domains {
+		if d.ExpireDate == nil || now.Before(*d.ExpireDate) {
+			continue
+		}
+
+		expireDate := *d.ExpireDate
+		for !now.Before(expireDate) {
+			expireDate = expireDate.AddDate(autoRenewPeriod.Value, 0, 0)
+		}
+
+		d.ExpireDate = timePtr(expireDate)
+
+		if err := tx.PutDomain(d); err != nil {
+			return err
+		}
+
+		m := &store.Message{
+			ClientID:  d.ClientID,
+			QueueDate: now,
+			Text:      "Domain expired and renewed.",
+		}
+
+		renewData := types.DomainRenewDataType{
+			RenewData: types.DomainRenewData{
+				Name:       d.Name,
+				ExpireDate: expireDate,
+			},
+		}
+
+		if err := poll.Enqueue(tx, m, renewData); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transferDomain handles all transfer operations for a domain.
+func (r *Registry) transferDomain(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.DomainTransferType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	op, err := transferOperation(data)
+	if err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	d, err := domain(tx, request.Transfer.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	code, t, err := r.transferCommand(tx, clientID, op, transferObject{
+		kind:       store.KindDomain,
+		name:       d.Name,
+		roid:       d.ROID,
+		clientID:   d.ClientID,
+		password:   password(d.AuthInfo),
+		statuses:   domainStatusList(d.Status),
+		element:    domainElement("name"),
+		expireDate: d.ExpireDate,
+	}, request.Transfer.Authinfo, request.Transfer.Period)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return code, transferData(t), nil
+}
+
+// domain returns the domain with the name.
+func domain(tx store.Tx, name string) (*types.DomainInfoData, error) {
+	d, err := tx.Domain(normalizeName(name))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, &resultError{code: epp.EppObjectDoesNotExist, element: domainElement("name"), value: name}
+	}
+
+	return d, err
+}
+
+// sponsoredDomain returns the domain with the name if it's sponsored by the
+// client.
+func sponsoredDomain(tx store.Tx, clientID, name string) (*types.DomainInfoData, error) {
+	d, err := domain(tx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.ClientID != clientID {
+		return nil, &resultError{code: epp.EppAuthorisationError, element: domainElement("name"), value: name}
+	}
+
+	return d, nil
+}
+
+// checkDomainProhibited returns an error if the statuses of the domain
+// prohibits the operation.
+func checkDomainProhibited(tx store.Tx, d *types.DomainInfoData, op string, removed ...status) error {
+	statuses, err := domainStatuses(tx, d)
+	if err != nil {
+		return err
+	}
+
+	return checkProhibited(domainStatusList(statuses), op, domainElement("name"), d.Name, removed...)
+}
+
+// domainStatuses returns the statuses for the domain, including statuses
+// derived from the state of the registry.
+func domainStatuses(tx store.Tx, d *types.DomainInfoData) ([]types.DomainStatus, error) {
+	statuses := append([]types.DomainStatus{}, d.Status...)
+
+	if d.NameServer == nil || len(d.NameServer.HostObject) == 0 {
+		statuses = append(statuses, types.DomainStatus{DomainStatusType: types.DomainStatusInactive})
+	}
+
+	pending, err := pendingTransfer(tx, d.ROID)
+	if err != nil {
+		return nil, err
+	}
+
+	if pending {
+		statuses = append(statuses, types.DomainStatus{DomainStatusType: types.DomainStatusPendingTransfer})
+	}
+
+	if len(statuses) == 0 {
+		statuses = append(statuses, types.DomainStatus{DomainStatusType: types.DomainStatusOk})
+	}
+
+	return statuses, nil
+}
+
+// subordinateHosts returns the hosts subordinate to the domain, sorted by
+// name.
+func subordinateHosts(tx store.Tx, name string) ([]*types.HostInfoData, error) {
+	all, err := tx.Hosts()
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := []*types.HostInfoData{}
+
+	for _, h := range all {
+		if !strings.HasSuffix(h.Name, "."+name) {
+			continue
+		}
+
+		d, err := superordinate(tx, h.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if d != nil && d.Name == name {
+			hosts = append(hosts, h)
+		}
+	}
+
+	return hosts, nil
+}
+
+// linkingDomains returns the domains using the host as a name server.
+func linkingDomains(tx store.Tx, host string) ([]*types.DomainInfoData, error) {
+	all, err := tx.Domains()
+	if err != nil {
+		return nil, err
+	}
+
+	domains := []*types.DomainInfoData{}
+
+	for _, d := range all {
+		if d.NameServer == nil {
+			continue
+		}
+
+		for _, h := range d.NameServer.HostObject {
+			if h == host {
+				domains = append(domains, d)
+				break
+			}
+		}
+	}
+
+	return domains, nil
+}
+
+// registrant returns the ID of the registrant if the contact exists.
+func registrant(tx store.Tx, id string) (string, error) {
+	if id == "" {
+		return "", nil
+	}
+
+	_, err := tx.Contact(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return "", &resultError{code: epp.EppObjectDoesNotExist, element: domainElement("registrant"), value: id}
+	}
+
+	return id, err
+}
+
+// addNameServers returns the name servers with the hosts in add added. The
+// hosts must exist and must not already be name servers. Host attributes
+// aren't supported.
+func addNameServers(tx store.Tx, nameServers *types.NameServer, add types.NameServer) (*types.NameServer, error) {
+	if len(add.HostAttribute) > 0 {
+		return nil, &resultError{
+			code:    epp.EppUnimplementedOption,
+			element: domainElement("hostAttr"),
+			reason:  "host attributes are not supported, use host objects",
+		}
+	}
+
+	hosts := []string{}
+	if nameServers != nil {
+		hosts = append(hosts, nameServers.HostObject...)
+	}
+
+	for _, host := range add.HostObject {
+		name := normalizeName(host)
+
+		_, err := tx.Host(name)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, &resultError{code: epp.EppObjectDoesNotExist, element: domainElement("hostObj"), value: host}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, h := range hosts {
+			if h == name {
+				return nil, &resultError{code: epp.EppParamPolicyError, element: domainElement("hostObj"), value: host, reason: "host is already a name server"}
+			}
+		}
+
+		hosts = append(hosts, name)
+	}
+
+	if len(hosts) > maxNameServers {
+		return nil, &resultError{
+			code:    epp.EppParamPolicyError,
+			element: domainElement("ns"),
+			reason:  "at most " + strconv.Itoa(maxNameServers) + " name servers are allowed",
+		}
+	}
+
+	if len(hosts) == 0 {
+		return nil, nil
+	}
+
+	return &types.NameServer{HostObject: hosts}, nil
+}
+
+// removeNameServers returns the name servers with the hosts in remove
+// removed. The hosts must be name servers.
+func removeNameServers(nameServers *types.NameServer, remove types.NameServer) (*types.NameServer, error) {
+	if len(remove.HostAttribute) > 0 {
+		return nil, &resultError{
+			code:    epp.EppUnimplementedOption,
+			element: domainElement("hostAttr"),
+			reason:  "host attributes are not supported, use host objects",
+		}
+	}
+
+	hosts := []string{}
+	if nameServers != nil {
+		hosts = append(hosts, nameServers.HostObject...)
+	}
+
+	for _, host := range remove.HostObject {
+		name, found := normalizeName(host), false
+
+		for i, h := range hosts {
+			if h == name {
+				hosts, found = append(hosts[:i], hosts[i+1:]...), true
+				break
+			}
+		}
+
+		if !found {
+			return nil, &resultError{code: epp.EppParamPolicyError, element: domainElement("hostObj"), value: host, reason: "host is not a name server"}
+		}
+	}
+
+	if len(hosts) == 0 {
+		return nil, nil
+	}
+
+	return &types.NameServer{HostObject: hosts}, nil
+}
+
+// addDomainContacts returns the contacts with the contacts in add added. The
+// contacts must exist and must not already be added with the same type.
+func addDomainContacts(tx store.Tx, contacts, add []types.Contact) ([]types.Contact, error) {
+	result := append([]types.Contact{}, contacts...)
+
+	for _, contact := range add {
+		_, err := tx.Contact(contact.Name)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, &resultError{code: epp.EppObjectDoesNotExist, element: domainElement("contact"), value: contact.Name}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range result {
+			if c == contact {
+				return nil, &resultError{code: epp.EppParamPolicyError, element: domainElement("contact"), value: contact.Name, reason: "contact is already added as " + contact.Type}
+			}
+		}
+
+		result = append(result, contact)
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+// removeDomainContacts returns the contacts with the contacts in remove
+// removed. The contacts must be added with the same type.
+func removeDomainContacts(contacts, remove []types.Contact) ([]types.Contact, error) {
+	result := append([]types.Contact{}, contacts...)
+
+	for _, contact := range remove {
+		found := false
+
+		for i, c := range result {
+			if c == contact {
+				result, found = append(result[:i], result[i+1:]...), true
+				break
+			}
+		}
+
+		if !found {
+			return nil, &resultError{code: epp.EppParamPolicyError, element: domainElement("contact"), value: contact.Name, reason: "contact is not added as " + contact.Type}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+// extend returns the date extended with the period. The result may not be
+// more than ten years from now.
+func (r *Registry) extend(date time.Time, period types.Period) (time.Time, error) {
+	var extended time.Time
+
+	switch period.Unit {
+	case "y":
+		extended = date.AddDate(period.Value, 0, 0)
+	case "m":
+		extended = date.AddDate(0, period.Value, 0)
+	}
+
+	if extended.IsZero() || period.Value < 1 || extended.After(r.now().AddDate(maxRegistrationYears, 0, 0)) {
+		return time.Time{}, &resultError{
+			code:    epp.EppParamRangeError,
+			element: domainElement("period"),
+			value:   strconv.Itoa(period.Value),
+			reason:  "the registration period may be at most " + strconv.Itoa(maxRegistrationYears) + " years",
+		}
+	}
+
+	return extended, nil
+}
+
+// copyDomain returns a deep copy of the domain.
+func copyDomain(d *types.DomainInfoData) *types.DomainInfoData {
+	c := *d
+	c.Status = append([]types.DomainStatus(nil), d.Status...)
+	c.Contact = append([]types.Contact(nil), d.Contact...)
+	c.Host = append([]string(nil), d.Host...)
+
+	if d.NameServer != nil {
+		c.NameServer = &types.NameServer{
+			HostObject: append([]string(nil), d.NameServer.HostObject...),
+		}
+	}
+
+	if d.AuthInfo != nil {
+		authInfo := *d.AuthInfo
+		c.AuthInfo = &authInfo
+	}
+
+	return &c
+}
+
+// domainStatusList converts domain statuses to statuses.
+func domainStatusList(statuses []types.DomainStatus) []status {
+	result := make([]status, 0, len(statuses))
+
+	for _, s := range statuses {
+		result = append(result, status{value: string(s.DomainStatusType), message: s.Status, language: s.Language})
+	}
+
+	return result
+}
+
+// domainStatusTypes converts statuses to domain statuses.
+func domainStatusTypes(statuses []status) []types.DomainStatus {
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	result := make([]types.DomainStatus, 0, len(statuses))
+
+	for _, s := range statuses {
+		result = append(result, types.DomainStatus{DomainStatusType: types.DomainStatusType(s.value), Status: s.message, Language: s.language})
+	}
+
+	return result
+}
@@ -0,0 +1,427 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createDomain creates the domain with the password secret. The extra
+// elements are added after the name.
+func createDomain(c *testClient, name, extra string) {
+	c.t.Helper()
+
+	c.expect(epp.EppOk, object("create", "domain", "<domain:name>"+name+"</domain:name>"+extra+
+		"<domain:authInfo><domain:pw>secret</domain:pw></domain:authInfo>"))
+}
+
+func infoDomain(c *testClient, body string) *types.DomainInfoData {
+	c.t.Helper()
+
+	info := c.expect(epp.EppOk, object("info", "domain", body)).DomainInfoData()
+	require.NotNil(c.t, info)
+
+	return info
+}
+
+func domainStatusValues(info *types.DomainInfoData) []types.DomainStatusType {
+	values := []types.DomainStatusType{}
+
+	for _, s := range info.Status {
+		values = append(values, s.DomainStatusType)
+	}
+
+	return values
+}
+
+func TestDomainCheck(t *testing.T) {
+	tr := newTestRegistry(t)
+	c := tr.login("registrar-1")
+
+	createDomain(c, "taken.se", "")
+
+	check := c.expect(epp.EppOk, object("check", "domain", `<domain:name>free.se</domain:name><domain:name>TAKEN.se</domain:name>
+<domain:name>example.com</domain:name><domain:name>sub.free.se</domain:name>`)).DomainCheckData()
+	require.NotNil(t, check)
+	require.Len(t, check.CheckDomain, 4)
+
+	assert.True(t, check.CheckDomain[0].Name.Available)
+	assert.False(t, check.CheckDomain[1].Name.Available)
+	assert.Equal(t, "In use", check.CheckDomain[1].Reason)
+	assert.False(t, check.CheckDomain[2].Name.Available)
+	assert.False(t, check.CheckDomain[3].Name.Available)
+}
+
+func TestDomainCreate(t *testing.T) {
+	tr := newTestRegistry(t)
+	c := tr.login("registrar-1")
+
+	createContact(c, "contact-1")
+	createDomain(c, "example.se", "")
+	c.expect(epp.EppOk, object("create", "host", "<host:name>ns1.example.net</host:name>"))
+
+	created := c.expect(epp.EppOk, object("create", "domain", `<domain:name>Example2.SE</domain:name>
+<domain:period unit="y">2</domain:period>
+<domain:ns><domain:hostObj>ns1.example.net</domain:hostObj></domain:ns>
+<domain:registrant>contact-1</domain:registrant>
+<domain:contact type="admin">contact-1</domain:contact>
+<domain:authInfo><domain:pw>secret</domain:pw></domain:authInfo>`)).DomainCreateData()
+	require.NotNil(t, created)
+	assert.Equal(t, "example2.se", created.Name)
+	assert.Equal(t, tr.clock, created.CreateDate)
+	assert.Equal(t, tr.clock.AddDate(2, 0, 0), created.ExpireDate)
+
+	for code, body := range map[epp.ResultCode]string{
+		epp.EppObjectExists:        "<domain:name>example.se</domain:name>",
+		epp.EppParamSyntaxError:    "<domain:name>exa_mple.se</domain:name>",
+		epp.EppParamPolicyError:    "<domain:name>sub.example.se</domain:name>",
+		epp.EppParamRangeError:     `<domain:name>example3.se</domain:name><domain:period unit="y">11</domain:period>`,
+		epp.EppObjectDoesNotExist:  "<domain:name>example3.se</domain:name><domain:registrant>missing</domain:registrant>",
+		epp.EppUnimplementedOption: "<domain:name>example3.se</domain:name><domain:ns><domain:hostAttr><domain:hostName>ns1.example.net</domain:hostName></domain:hostAttr></domain:ns>",
+	} {
+		c.expect(code, object("create", "domain", body+"<domain:authInfo><domain:pw>secret</domain:pw></domain:authInfo>"))
+	}
+
+	c.expect(epp.EppObjectDoesNotExist, object("create", "domain", `<domain:name>example3.se</domain:name>
+<domain:ns><domain:hostObj>ns2.example.net</domain:hostObj></domain:ns>
+<domain:authInfo><domain:pw>secret</domain:pw></domain:authInfo>`))
+	c.expect(epp.EppObjectDoesNotExist, object("create", "domain", `<domain:name>example3.se</domain:name>
+<domain:contact type="tech">missing</domain:contact>
+<domain:authInfo><domain:pw>secret</domain:pw></domain:authInfo>`))
+
+	domains, err := tr.tx().Domains()
+	require.Nil(t, err)
+	assert.Len(t, domains, 2)
+}
+
+func TestDomainInfo(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	createContact(c1, "contact-1")
+	createDomain(c1, "example.se", "<domain:registrant>contact-1</domain:registrant>")
+	c1.expect(epp.EppOk, object("create", "host", "<host:name>ns1.example.se</host:name><host:addr>192.0.2.1</host:addr>"))
+
+	info := infoDomain(c1, "<domain:name>example.se</domain:name>")
+	assert.Equal(t, "example.se", info.Name)
+	assert.Equal(t, "D2-EPP", info.ROID)
+	assert.Equal(t, []types.DomainStatusType{types.DomainStatusInactive}, domainStatusValues(info))
+	assert.Equal(t, "contact-1", info.Registrant)
+	assert.Equal(t, []string{"ns1.example.se"}, info.Host)
+	assert.Equal(t, "registrar-1", info.ClientID)
+	assert.Equal(t, "registrar-1", info.CreateID)
+	require.NotNil(t, info.AuthInfo)
+	assert.Equal(t, "secret", info.AuthInfo.Password)
+
+	c1.expect(epp.EppOk, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:add><domain:ns><domain:hostObj>ns1.example.se</domain:hostObj></domain:ns></domain:add>`))
+
+	info = infoDomain(c1, `<domain:name hosts="del">example.se</domain:name>`)
+	assert.Equal(t, []types.DomainStatusType{types.DomainStatusOk}, domainStatusValues(info))
+	require.NotNil(t, info.NameServer)
+	assert.Equal(t, []string{"ns1.example.se"}, info.NameServer.HostObject)
+	assert.Empty(t, info.Host)
+
+	info = infoDomain(c1, `<domain:name hosts="none">example.se</domain:name>`)
+	assert.Nil(t, info.NameServer)
+	assert.Empty(t, info.Host)
+
+	// Other clients only get the public information unless they know the
+	// password and never get the password.
+	info = infoDomain(c2, "<domain:name>example.se</domain:name>")
+	assert.Equal(t, "registrar-1", info.ClientID)
+	assert.Empty(t, info.Registrant)
+	assert.Nil(t, info.NameServer)
+	assert.Nil(t, info.AuthInfo)
+
+	info = infoDomain(c2, "<domain:name>example.se</domain:name><domain:authInfo><domain:pw>secret</domain:pw></domain:authInfo>")
+	assert.Equal(t, "contact-1", info.Registrant)
+	assert.NotNil(t, info.NameServer)
+	assert.Nil(t, info.AuthInfo)
+
+	c2.expect(epp.EppInvalidAuthInfo, object("info", "domain", "<domain:name>example.se</domain:name><domain:authInfo><domain:pw>wrong</domain:pw></domain:authInfo>"))
+}
+
+func TestDomainUpdate(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	createContact(c1, "contact-1")
+	createContact(c1, "contact-2")
+	createDomain(c1, "example.se", `<domain:contact type="admin">contact-1</domain:contact>`)
+
+	for _, name := range []string{"ns1.example.net", "ns2.example.net"} {
+		c1.expect(epp.EppOk, object("create", "host", "<host:name>"+name+"</host:name>"))
+	}
+
+	c2.expect(epp.EppAuthorisationError, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:chg><domain:registrant>contact-1</domain:registrant></domain:chg>`))
+
+	c1.expect(epp.EppOk, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:add>
+  <domain:ns><domain:hostObj>ns1.example.net</domain:hostObj><domain:hostObj>ns2.example.net</domain:hostObj></domain:ns>
+  <domain:contact type="tech">contact-2</domain:contact>
+  <domain:status s="clientHold" lang="en">Payment overdue.</domain:status>
+</domain:add>
+<domain:rem><domain:contact type="admin">contact-1</domain:contact></domain:rem>
+<domain:chg>
+  <domain:registrant>contact-2</domain:registrant>
+  <domain:authInfo><domain:pw>new-secret</domain:pw></domain:authInfo>
+</domain:chg>`))
+
+	info := infoDomain(c1, "<domain:name>example.se</domain:name>")
+	assert.Equal(t, []types.DomainStatus{{DomainStatusType: types.DomainStatusClientHold, Status: "Payment overdue.", Language: "en"}}, info.Status)
+	assert.Equal(t, []string{"ns1.example.net", "ns2.example.net"}, info.NameServer.HostObject)
+	assert.Equal(t, []types.Contact{{Name: "contact-2", Type: "tech"}}, info.Contact)
+	assert.Equal(t, "contact-2", info.Registrant)
+	assert.Equal(t, "new-secret", info.AuthInfo.Password)
+	assert.Equal(t, "registrar-1", info.UpdateID)
+	require.NotNil(t, info.UpdateDate)
+
+	// Failing updates doesn't change anything.
+	for code, body := range map[epp.ResultCode]string{
+		epp.EppParamPolicyError:   `<domain:add><domain:ns><domain:hostObj>ns1.example.net</domain:hostObj></domain:ns></domain:add>`,
+		epp.EppObjectDoesNotExist: `<domain:add><domain:ns><domain:hostObj>ns3.example.net</domain:hostObj></domain:ns></domain:add><domain:rem><domain:status s="clientHold"/></domain:rem>`,
+	} {
+		c1.expect(code, object("update", "domain", "<domain:name>example.se</domain:name>"+body))
+	}
+
+	c1.expect(epp.EppParamPolicyError, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:add><domain:status s="serverHold"/></domain:add>`))
+	c1.expect(epp.EppParamPolicyError, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:rem><domain:contact type="admin">contact-1</domain:contact></domain:rem>`))
+
+	info = infoDomain(c1, "<domain:name>example.se</domain:name>")
+	assert.Equal(t, []types.DomainStatusType{types.DomainStatusClientHold}, domainStatusValues(info))
+
+	// When updates are prohibited only the status can be removed.
+	c1.expect(epp.EppOk, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:add><domain:status s="clientUpdateProhibited"/></domain:add>`))
+	c1.expect(epp.EppStatusProhibitsOp, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:rem><domain:status s="clientHold"/></domain:rem>`))
+	c1.expect(epp.EppOk, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:rem><domain:status s="clientUpdateProhibited"/></domain:rem>`))
+
+	tx := tr.tx()
+
+	d, err := tx.Domain("example.se")
+	require.Nil(t, err)
+
+	d.Status = append(d.Status, types.DomainStatus{DomainStatusType: types.DomainStatusServerUpdateProhibited})
+	require.Nil(t, tx.PutDomain(d))
+	require.Nil(t, tx.Commit())
+
+	c1.expect(epp.EppStatusProhibitsOp, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:rem><domain:status s="clientHold"/></domain:rem>`))
+}
+
+func TestDomainDelete(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	createDomain(c1, "example.se", "")
+	createDomain(c1, "example2.se", "")
+	c1.expect(epp.EppOk, object("create", "host", "<host:name>ns1.example.se</host:name><host:addr>192.0.2.1</host:addr>"))
+	c1.expect(epp.EppOk, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:add><domain:ns><domain:hostObj>ns1.example.se</domain:hostObj></domain:ns></domain:add>`))
+
+	c2.expect(epp.EppAuthorisationError, object("delete", "domain", "<domain:name>example.se</domain:name>"))
+
+	// Subordinate hosts used by other domains prohibits delete.
+	c1.expect(epp.EppOk, object("update", "domain", `<domain:name>example2.se</domain:name>
+<domain:add><domain:ns><domain:hostObj>ns1.example.se</domain:hostObj></domain:ns></domain:add>`))
+	c1.expect(epp.EppAssocProhibitsOp, object("delete", "domain", "<domain:name>example.se</domain:name>"))
+	c1.expect(epp.EppOk, object("update", "domain", `<domain:name>example2.se</domain:name>
+<domain:rem><domain:ns><domain:hostObj>ns1.example.se</domain:hostObj></domain:ns></domain:rem>`))
+
+	c1.expect(epp.EppOk, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:add><domain:status s="clientDeleteProhibited"/></domain:add>`))
+	c1.expect(epp.EppStatusProhibitsOp, object("delete", "domain", "<domain:name>example.se</domain:name>"))
+	c1.expect(epp.EppOk, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:rem><domain:status s="clientDeleteProhibited"/></domain:rem>`))
+
+	c1.expect(epp.EppOk, object("delete", "domain", "<domain:name>example.se</domain:name>"))
+	c1.expect(epp.EppObjectDoesNotExist, object("info", "domain", "<domain:name>example.se</domain:name>"))
+	c1.expect(epp.EppObjectDoesNotExist, object("info", "host", "<host:name>ns1.example.se</host:name>"))
+}
+
+func TestDomainRenew(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	createDomain(c1, "example.se", "")
+
+	renew := func(c *testClient, code epp.ResultCode, curExpDate, period string) *epp.DecodedResponse {
+		return c.expect(code, object("renew", "domain", "<domain:name>example.se</domain:name><domain:curExpDate>"+curExpDate+"</domain:curExpDate>"+period))
+	}
+
+	renew(c2, epp.EppAuthorisationError, "2027-03-01", "")
+	renew(c1, epp.EppParamPolicyError, "2027-03-02", "")
+	renew(c1, epp.EppParamRangeError, "2027-03-01", `<domain:period unit="y">10</domain:period>`)
+
+	renewed := renew(c1, epp.EppOk, "2027-03-01", `<domain:period unit="m">6</domain:period>`).DomainRenewData()
+	require.NotNil(t, renewed)
+	assert.Equal(t, time.Date(2027, 9, 1, 12, 0, 0, 0, time.UTC), renewed.ExpireDate)
+
+	renew(c1, epp.EppOk, "2027-09-01", "")
+
+	info := infoDomain(c1, "<domain:name>example.se</domain:name>")
+	require.NotNil(t, info.ExpireDate)
+	assert.Equal(t, time.Date(2028, 9, 1, 12, 0, 0, 0, time.UTC), *info.ExpireDate)
+
+	c1.expect(epp.EppOk, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:add><domain:status s="clientRenewProhibited"/></domain:add>`))
+	renew(c1, epp.EppStatusProhibitsOp, "2028-09-01", "")
+}
+
+func TestDomainExpiry(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	createDomain(c1, "example.se", "")
+
+	tr.clock = time.Date(2027, 3, 1, 11, 0, 0, 0, time.UTC)
+	assert.Empty(t, pollMessages(c1))
+
+	// The domain is renewed when it expires and the sponsoring client is
+	// notified once.
+	tr.clock = time.Date(2027, 3, 2, 12, 0, 0, 0, time.UTC)
+
+	messages := pollMessages(c1)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Domain expired and renewed.", messages[0].MessageQ.Message)
+	require.NotNil(t, messages[0].MessageQ.QueueDate)
+	assert.Equal(t, tr.clock, *messages[0].MessageQ.QueueDate)
+
+	renewed := messages[0].DomainRenewData()
+	require.NotNil(t, renewed)
+	assert.Equal(t, "example.se", renewed.Name)
+	assert.Equal(t, time.Date(2028, 3, 1, 12, 0, 0, 0, time.UTC), renewed.ExpireDate)
+
+	info := infoDomain(c1, "<domain:name>example.se</domain:name>")
+	require.NotNil(t, info.ExpireDate)
+	assert.Equal(t, renewed.ExpireDate, *info.ExpireDate)
+
+	assert.Empty(t, pollMessages(c1))
+	assert.Empty(t, pollMessages(c2))
+
+	// A domain expired for several years is renewed until the expiry date
+	// is in the future with one message.
+	tr.clock = time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	messages = pollMessages(c1)
+	require.Len(t, messages, 1)
+
+	renewed = messages[0].DomainRenewData()
+	require.NotNil(t, renewed)
+	assert.Equal(t, time.Date(2031, 3, 1, 12, 0, 0, 0, time.UTC), renewed.ExpireDate)
+}
+
+func TestDomainTransfer(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	createDomain(c1, "example.se", "")
+	c1.expect(epp.EppOk, object("create", "host", "<host:name>ns1.example.se</host:name><host:addr>192.0.2.1</host:addr>"))
+
+	transfer := func(c *testClient, code epp.ResultCode, op, password string) *types.DomainTransferData {
+		body := "<domain:name>example.se</domain:name>"
+		if password != "" {
+			body += "<domain:authInfo><domain:pw>" + password + "</domain:pw></domain:authInfo>"
+		}
+
+		return c.expect(code, transferCommand(op, "domain", body)).DomainTransferData()
+	}
+
+	transfer(c2, epp.EppObjectNotPendingTransfer, "query", "secret")
+	transfer(c2, epp.EppAuthorisationError, "query", "")
+	transfer(c1, epp.EppNotTransferrable, "request", "secret")
+	transfer(c2, epp.EppInvalidAuthInfo, "request", "wrong")
+	transfer(c2, epp.EppInvalidAuthInfo, "request", "")
+
+	requested := transfer(c2, epp.EppOkPending, "request", "secret")
+	require.NotNil(t, requested)
+	assert.Equal(t, types.DomainTransferPending, requested.TransferStatus)
+	assert.Equal(t, "registrar-2", requested.RequestingID)
+	assert.Equal(t, "registrar-1", requested.ActingID)
+	assert.Equal(t, "2026-03-06T12:00:00Z", requested.ActingDate)
+	assert.Equal(t, "2028-03-01T12:00:00Z", requested.ExpireDate)
+
+	transfer(c2, epp.EppObjectPendingTransfer, "request", "secret")
+	transfer(c2, epp.EppAuthorisationError, "approve", "")
+	transfer(c1, epp.EppAuthorisationError, "cancel", "")
+
+	info := infoDomain(c1, "<domain:name>example.se</domain:name>")
+	assert.Contains(t, domainStatusValues(info), types.DomainStatusPendingTransfer)
+
+	c1.expect(epp.EppStatusProhibitsOp, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:add><domain:status s="clientHold"/></domain:add>`))
+
+	// The requesting client can query the transfer without the password.
+	queried := transfer(c2, epp.EppOk, "query", "")
+	assert.Equal(t, types.DomainTransferPending, queried.TransferStatus)
+
+	rejected := transfer(c1, epp.EppOk, "reject", "")
+	assert.Equal(t, types.DomainTransferClientRejected, rejected.TransferStatus)
+	assert.Empty(t, rejected.ExpireDate)
+
+	transfer(c1, epp.EppObjectNotPendingTransfer, "approve", "")
+	transfer(c2, epp.EppOkPending, "request", "secret")
+
+	cancelled := transfer(c2, epp.EppOk, "cancel", "")
+	assert.Equal(t, types.DomainTransferClientCancelled, cancelled.TransferStatus)
+
+	transfer(c2, epp.EppOkPending, "request", "secret")
+
+	approved := transfer(c1, epp.EppOk, "approve", "")
+	assert.Equal(t, types.DomainTransferClientApproved, approved.TransferStatus)
+
+	info = infoDomain(c2, "<domain:name>example.se</domain:name>")
+	assert.Equal(t, "registrar-2", info.ClientID)
+	assert.Equal(t, []types.DomainStatusType{types.DomainStatusInactive}, domainStatusValues(info))
+	require.NotNil(t, info.TransferDate)
+	assert.Equal(t, tr.clock, *info.TransferDate)
+	assert.Equal(t, time.Date(2028, 3, 1, 12, 0, 0, 0, time.UTC), *info.ExpireDate)
+
+	// Subordinate hosts are transferred with the domain.
+	host := c2.expect(epp.EppOk, object("info", "host", "<host:name>ns1.example.se</host:name>")).HostInfoData()
+	assert.Equal(t, "registrar-2", host.ClientID)
+
+	c1.expect(epp.EppAuthorisationError, object("delete", "domain", "<domain:name>example.se</domain:name>"))
+
+	// Transfers are approved by the server when the transfer period has
+	// passed.
+	c1.expect(epp.EppOkPending, transferCommand("request", "domain", `<domain:name>example.se</domain:name>
+<domain:period unit="y">2</domain:period><domain:authInfo><domain:pw>secret</domain:pw></domain:authInfo>`))
+	tr.clock = tr.clock.Add(DefaultTransferPeriod)
+
+	queried = transfer(c1, epp.EppOk, "query", "")
+	assert.Equal(t, types.DomainTransferServerApproved, queried.TransferStatus)
+	assert.Equal(t, "2030-03-01T12:00:00Z", queried.ExpireDate)
+
+	info = infoDomain(c1, "<domain:name>example.se</domain:name>")
+	assert.Equal(t, "registrar-1", info.ClientID)
+}
+
+func TestDomainTransferProhibited(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	createDomain(c1, "example.se", "")
+	c1.expect(epp.EppOk, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:add><domain:status s="clientTransferProhibited"/></domain:add>`))
+
+	c2.expect(epp.EppStatusProhibitsOp, transferCommand("request", "domain", `<domain:name>example.se</domain:name>
+<domain:authInfo><domain:pw>secret</domain:pw></domain:authInfo>`))
+}
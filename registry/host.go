@@ -0,0 +1,573 @@
+package registry
+
+import (
+	"encoding/xml"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/store"
+	"github.com/bombsimon/epp-go/types"
+)
+
+// hostCreateType is a host create command. It's used instead of
+// types.HostCreateType which only holds one address.
+type hostCreateType struct {
+	Create struct {
+		Name    string              `xml:"name"`
+		Address []types.HostAddress `xml:"addr"`
+	} `xml:"urn:ietf:params:xml:ns:host-1.0 command>create>create"`
+}
+
+// hostInfoDataType is the result data for a host info command. It's used
+// instead of types.HostInfoDataType to omit the dates that aren't set.
+type hostInfoDataType struct {
+	InfoData hostInfoData `xml:"urn:ietf:params:xml:ns:host-1.0 infData"`
+}
+
+// hostInfoData represents the response for a host info command.
+type hostInfoData struct {
+	Name         string              `xml:"name"`
+	ROID         string              `xml:"roid"`
+	Status       []types.HostStatus  `xml:"status"`
+	Address      []types.HostAddress `xml:"addr,omitempty"`
+	ClientID     string              `xml:"clID"`
+	CreateID     string              `xml:"crID"`
+	CreateDate   time.Time           `xml:"crDate"`
+	UpdateID     string              `xml:"upID,omitempty"`
+	UpdateDate   *time.Time          `xml:"upDate,omitempty"`
+	TransferDate *time.Time          `xml:"trDate,omitempty"`
+}
+
+// hostElement returns the name of the element in the host namespace.
+func hostElement(local string) xml.Name {
+	return xml.Name{Space: types.NameSpaceHost, Local: local}
+}
+
+// checkHost checks if hosts are available.
+func (r *Registry) checkHost(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.HostCheckType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	response := types.HostCheckDataType{}
+
+	for _, name := range request.Check.Names {
+		check := types.CheckType{
+			Name: types.CheckName{Value: name},
+		}
+
+		n := normalizeName(name)
+
+		_, err := tx.Host(n)
+
+		exists, err := found(err)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch {
+		case !validName(n):
+			check.Reason = "Invalid host name"
+		case exists:
+			check.Reason = "In use"
+		default:
+			check.Name.Available = true
+		}
+
+		response.CheckData.Name = append(response.CheckData.Name, check)
+	}
+
+	return epp.EppOk, response, nil
+}
+
+// infoHost returns information about a host.
+func (r *Registry) infoHost(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.HostInfoType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	h, err := host(tx, request.Info.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	statuses, err := hostStatuses(tx, h)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	info := hostInfoData{
+		Name:       h.Name,
+		ROID:       h.ROID,
+		Status:     statuses,
+		Address:    append([]types.HostAddress(nil), h.Address...),
+		ClientID:   h.ClientID,
+		CreateID:   h.CreateID,
+		CreateDate: h.CreateDate,
+		UpdateID:   h.UpdateID,
+	}
+
+	if !h.UpdateDate.IsZero() {
+		info.UpdateDate = timePtr(h.UpdateDate)
+	}
+
+	if !h.TransferDate.IsZero() {
+		info.TransferDate = timePtr(h.TransferDate)
+	}
+
+	return epp.EppOk, hostInfoDataType{InfoData: info}, nil
+}
+
+// createHost creates a host sponsored by the client. Hosts subordinate to a
+// domain must be created by the sponsoring client of the domain and only
+// subordinate hosts may have addresses.
+func (r *Registry) createHost(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := hostCreateType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	create := request.Create
+	name := normalizeName(create.Name)
+
+	_, err := tx.Host(name)
+
+	exists, err := found(err)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	switch {
+	case !validName(name):
+		return 0, nil, &resultError{code: epp.EppParamSyntaxError, element: hostElement("name"), value: create.Name, reason: "invalid host name"}
+	case exists:
+		return 0, nil, &resultError{code: epp.EppObjectExists, element: hostElement("name"), value: create.Name}
+	}
+
+	addresses, err := addHostAddresses(nil, create.Address)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := r.checkHostPlacement(tx, clientID, name, len(addresses) > 0); err != nil {
+		return 0, nil, err
+	}
+
+	roid, err := r.newROID(tx, "H")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	now := r.now()
+
+	err = tx.PutHost(&types.HostInfoData{
+		Name:       name,
+		ROID:       roid,
+		Address:    addresses,
+		ClientID:   clientID,
+		CreateID:   clientID,
+		CreateDate: now,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return epp.EppOk, types.HostCreateDataType{
+		CreateData: types.HostCreateData{
+			Name:       name,
+			CreateDate: now,
+		},
+	}, nil
+}
+
+// updateHost updates a host sponsored by the client. When a host is renamed
+// the name servers of all domains using it are renamed as well.
+func (r *Registry) updateHost(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.HostUpdateType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	update := request.Update
+
+	h, err := sponsoredHost(tx, clientID, update.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	add, remove := update.Add, update.Remove
+	if add == nil {
+		add = &types.HostAddRemove{}
+	}
+
+	if remove == nil {
+		remove = &types.HostAddRemove{}
+	}
+
+	// The status types aren't part of types.HostAddRemove.
+	var statuses struct {
+		Add    []types.HostStatus `xml:"urn:ietf:params:xml:ns:host-1.0 command>update>update>add>status"`
+		Remove []types.HostStatus `xml:"urn:ietf:params:xml:ns:host-1.0 command>update>update>rem>status"`
+	}
+
+	if err := epp.Decode(data, &statuses); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	current, err := hostStatuses(tx, h)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := checkProhibited(hostStatusList(current), opUpdate, hostElement("name"), h.Name, hostStatusList(statuses.Remove)...); err != nil {
+		return 0, nil, err
+	}
+
+	updated := copyHost(h)
+
+	newStatuses, err := updateStatuses(hostStatusList(h.Status), hostStatusList(statuses.Add), hostStatusList(statuses.Remove), hostElement("status"))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	updated.Status = hostStatusTypes(newStatuses)
+
+	if updated.Address, err = removeHostAddresses(updated.Address, remove.Address); err != nil {
+		return 0, nil, err
+	}
+
+	if updated.Address, err = addHostAddresses(updated.Address, add.Address); err != nil {
+		return 0, nil, err
+	}
+
+	if update.Change != "" {
+		updated.Name = normalizeName(update.Change)
+
+		_, err := tx.Host(updated.Name)
+
+		exists, err := found(err)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch {
+		case !validName(updated.Name):
+			return 0, nil, &resultError{code: epp.EppParamSyntaxError, element: hostElement("name"), value: update.Change, reason: "invalid host name"}
+		case updated.Name != h.Name && exists:
+			return 0, nil, &resultError{code: epp.EppObjectExists, element: hostElement("name"), value: update.Change}
+		}
+	}
+
+	if err := r.checkHostPlacement(tx, clientID, updated.Name, len(updated.Address) > 0); err != nil {
+		return 0, nil, err
+	}
+
+	if updated.Name != h.Name {
+		if err := renameNameServers(tx, h.Name, updated.Name); err != nil {
+			return 0, nil, err
+		}
+
+		if err := tx.DeleteHost(h.Name); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	updated.UpdateID, updated.UpdateDate = clientID, r.now()
+
+	return epp.EppOk, nil, tx.PutHost(updated)
+}
+
+// deleteHost deletes a host sponsored by the client if it's not used by any
+// domain.
+func (r *Registry) deleteHost(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	request := types.HostDeleteType{}
+
+	if err := epp.Decode(data, &request); err != nil {
+		return 0, nil, syntaxError(err)
+	}
+
+	h, err := sponsoredHost(tx, clientID, request.Delete.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	statuses, err := hostStatuses(tx, h)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := checkProhibited(hostStatusList(statuses), opDelete, hostElement("name"), h.Name); err != nil {
+		return 0, nil, err
+	}
+
+	domains, err := linkingDomains(tx, h.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if len(domains) > 0 {
+		return 0, nil, &resultError{
+			code:    epp.EppAssocProhibitsOp,
+			element: hostElement("name"),
+			value:   h.Name,
+			reason:  "host is used by " + domains[0].Name,
+		}
+	}
+
+	return epp.EppOk, nil, tx.DeleteHost(h.Name)
+}
+
+// host returns the host with the name.
+func host(tx store.Tx, name string) (*types.HostInfoData, error) {
+	h, err := tx.Host(normalizeName(name))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, &resultError{code: epp.EppObjectDoesNotExist, element: hostElement("name"), value: name}
+	}
+
+	return h, err
+}
+
+// sponsoredHost returns the host with the name if it's sponsored by the
+// client.
+func sponsoredHost(tx store.Tx, clientID, name string) (*types.HostInfoData, error) {
+	h, err := host(tx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.ClientID != clientID {
+		return nil, &resultError{code: epp.EppAuthorisationError, element: hostElement("name"), value: name}
+	}
+
+	return h, nil
+}
+
+// checkHostPlacement returns an error if the client may not have a host with
+// the name. Hosts subordinate to a domain must be sponsored by the sponsoring
+// client of the domain, hosts in the zones served by the registry must be
+// subordinate to a domain and only subordinate hosts may have addresses.
+func (r *Registry) checkHostPlacement(tx store.Tx, clientID, name string, hasAddresses bool) error {
+	d, err := superordinate(tx, name)
+	if err != nil {
+		return err
+	}
+
+	if d != nil {
+		if d.ClientID != clientID {
+			return &resultError{
+				code:    epp.EppAuthorisationError,
+				element: hostElement("name"),
+				value:   name,
+				reason:  "superordinate domain " + d.Name + " is sponsored by another client",
+			}
+		}
+
+		return nil
+	}
+
+	if r.inZone(name) {
+		return &resultError{
+			code:    epp.EppAssocProhibitsOp,
+			element: hostElement("name"),
+			value:   name,
+			reason:  "superordinate domain does not exist",
+		}
+	}
+
+	if hasAddresses {
+		return &resultError{
+			code:    epp.EppParamPolicyError,
+			element: hostElement("addr"),
+			reason:  "addresses are only allowed for subordinate hosts",
+		}
+	}
+
+	return nil
+}
+
+// hostStatuses returns the statuses for the host, including statuses derived
+// from the state of the registry.
+func hostStatuses(tx store.Tx, h *types.HostInfoData) ([]types.HostStatus, error) {
+	statuses := append([]types.HostStatus{}, h.Status...)
+
+	d, err := superordinate(tx, h.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if d != nil {
+		pending, err := pendingTransfer(tx, d.ROID)
+		if err != nil {
+			return nil, err
+		}
+
+		if pending {
+			statuses = append(statuses, types.HostStatus{HostStatusType: types.HostStatusPendingTransfer})
+		}
+	}
+
+	if len(statuses) == 0 {
+		statuses = append(statuses, types.HostStatus{HostStatusType: types.HostStatusOk})
+	}
+
+	domains, err := linkingDomains(tx, h.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(domains) > 0 {
+		statuses = append(statuses, types.HostStatus{HostStatusType: types.HostStatusLinked})
+	}
+
+	return statuses, nil
+}
+
+// renameNameServers renames the host from name to newName for all domains
+// using it as a name server.
+func renameNameServers(tx store.Tx, name, newName string) error {
+	domains, err := linkingDomains(tx, name)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range domains {
+		for i, h := range d.NameServer.HostObject {
+			if h == name {
+				d.NameServer.HostObject[i] = newName
+			}
+		}
+
+		if err := tx.PutDomain(d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addHostAddresses returns the addresses with the addresses in add added. The
+// addresses must be valid for the IP version and not already added.
+func addHostAddresses(addresses, add []types.HostAddress) ([]types.HostAddress, error) {
+	result := append([]types.HostAddress{}, addresses...)
+
+	for _, address := range add {
+		normalized, err := normalizeAddress(address)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range result {
+			if a == normalized {
+				return nil, &resultError{code: epp.EppParamPolicyError, element: hostElement("addr"), value: address.Address, reason: "address is already added"}
+			}
+		}
+
+		result = append(result, normalized)
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+// removeHostAddresses returns the addresses with the addresses in remove
+// removed. The addresses must be added.
+func removeHostAddresses(addresses, remove []types.HostAddress) ([]types.HostAddress, error) {
+	result := append([]types.HostAddress{}, addresses...)
+
+	for _, address := range remove {
+		normalized, err := normalizeAddress(address)
+		if err != nil {
+			return nil, err
+		}
+
+		found := false
+
+		for i, a := range result {
+			if a == normalized {
+				result, found = append(result[:i], result[i+1:]...), true
+				break
+			}
+		}
+
+		if !found {
+			return nil, &resultError{code: epp.EppParamPolicyError, element: hostElement("addr"), value: address.Address, reason: "address is not added"}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+// normalizeAddress returns the address in its canonical form with the IP
+// version set. An error is returned if the address isn't valid for the IP
+// version.
+func normalizeAddress(address types.HostAddress) (types.HostAddress, error) {
+	version := address.IP
+	if version == "" {
+		version = types.HostIPv4
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(address.Address))
+	isV4 := ip != nil && !strings.Contains(address.Address, ":")
+
+	if ip == nil || (version == types.HostIPv4) != isV4 {
+		return types.HostAddress{}, &resultError{
+			code:    epp.EppParamSyntaxError,
+			element: hostElement("addr"),
+			value:   address.Address,
+			reason:  "invalid IP" + string(version) + " address",
+		}
+	}
+
+	return types.HostAddress{Address: ip.String(), IP: version}, nil
+}
+
+// copyHost returns a deep copy of the host.
+func copyHost(h *types.HostInfoData) *types.HostInfoData {
+	c := *h
+	c.Status = append([]types.HostStatus(nil), h.Status...)
+	c.Address = append([]types.HostAddress(nil), h.Address...)
+
+	return &c
+}
+
+// hostStatusList converts host statuses to statuses.
+func hostStatusList(statuses []types.HostStatus) []status {
+	result := make([]status, 0, len(statuses))
+
+	for _, s := range statuses {
+		result = append(result, status{value: string(s.HostStatusType), message: s.Status, language: s.Language})
+	}
+
+	return result
+}
+
+// hostStatusTypes converts statuses to host statuses.
+func hostStatusTypes(statuses []status) []types.HostStatus {
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	result := make([]types.HostStatus, 0, len(statuses))
+
+	for _, s := range statuses {
+		result = append(result, types.HostStatus{HostStatusType: types.HostStatusType(s.value), Status: s.message, Language: s.language})
+	}
+
+	return result
+}
@@ -0,0 +1,136 @@
+package registry
+
+import (
+	"testing"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func infoHost(c *testClient, name string) *types.HostInfoData {
+	c.t.Helper()
+
+	info := c.expect(epp.EppOk, object("info", "host", "<host:name>"+name+"</host:name>")).HostInfoData()
+	require.NotNil(c.t, info)
+
+	return info
+}
+
+func hostStatusValues(info *types.HostInfoData) []types.HostStatusType {
+	values := []types.HostStatusType{}
+
+	for _, s := range info.Status {
+		values = append(values, s.HostStatusType)
+	}
+
+	return values
+}
+
+func TestHostCheck(t *testing.T) {
+	tr := newTestRegistry(t)
+	c := tr.login("registrar-1")
+
+	c.expect(epp.EppOk, object("create", "host", "<host:name>ns1.example.net</host:name>"))
+
+	check := c.expect(epp.EppOk, object("check", "host", "<host:name>NS1.example.net</host:name><host:name>ns2.example.net</host:name>")).HostCheckData()
+	require.NotNil(t, check)
+	require.Len(t, check.Name, 2)
+	assert.False(t, check.Name[0].Name.Available)
+	assert.True(t, check.Name[1].Name.Available)
+}
+
+func TestHostCreate(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	createDomain(c1, "example.se", "")
+
+	created := c1.expect(epp.EppOk, object("create", "host", `<host:name>ns1.example.se</host:name>
+<host:addr ip="v4">192.0.2.1</host:addr><host:addr ip="v6">2001:DB8::1</host:addr>`)).HostCreateData()
+	require.NotNil(t, created)
+	assert.Equal(t, "ns1.example.se", created.Name)
+	assert.Equal(t, tr.clock, created.CreateDate)
+
+	info := infoHost(c1, "ns1.example.se")
+	assert.Equal(t, "H2-EPP", info.ROID)
+	assert.Equal(t, []types.HostStatusType{types.HostStatusOk}, hostStatusValues(info))
+	assert.Equal(t, []types.HostAddress{{Address: "192.0.2.1", IP: types.HostIPv4}, {Address: "2001:db8::1", IP: types.HostIPv6}}, info.Address)
+
+	for code, body := range map[epp.ResultCode]string{
+		epp.EppObjectExists:       "<host:name>ns1.example.se</host:name>",
+		epp.EppAssocProhibitsOp:   "<host:name>ns1.missing.se</host:name>",
+		epp.EppParamPolicyError:   "<host:name>ns1.example.net</host:name><host:addr>192.0.2.1</host:addr>",
+		epp.EppParamSyntaxError:   `<host:name>ns2.example.se</host:name><host:addr ip="v6">192.0.2.1</host:addr>`,
+		epp.EppAuthorisationError: "<host:name>ns2.example.se</host:name>",
+	} {
+		c := c1
+		if code == epp.EppAuthorisationError {
+			c = c2
+		}
+
+		c.expect(code, object("create", "host", body))
+	}
+}
+
+func TestHostUpdate(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	createDomain(c1, "example.se", "")
+	c1.expect(epp.EppOk, object("create", "host", "<host:name>ns1.example.se</host:name><host:addr>192.0.2.1</host:addr>"))
+	createDomain(c2, "example2.se", "<domain:ns><domain:hostObj>ns1.example.se</domain:hostObj></domain:ns>")
+
+	c2.expect(epp.EppAuthorisationError, object("update", "host", `<host:name>ns1.example.se</host:name>
+<host:add><host:addr>192.0.2.2</host:addr></host:add>`))
+
+	c1.expect(epp.EppOk, object("update", "host", `<host:name>ns1.example.se</host:name>
+<host:add><host:addr>192.0.2.2</host:addr><host:status s="clientDeleteProhibited"/></host:add>
+<host:rem><host:addr>192.0.2.1</host:addr></host:rem>`))
+
+	info := infoHost(c1, "ns1.example.se")
+	assert.Equal(t, []types.HostStatusType{types.HostStatusClientDeleteProhibited, types.HostStatusLinked}, hostStatusValues(info))
+	assert.Equal(t, []types.HostAddress{{Address: "192.0.2.2", IP: types.HostIPv4}}, info.Address)
+	assert.Equal(t, "registrar-1", info.UpdateID)
+
+	c1.expect(epp.EppParamPolicyError, object("update", "host", `<host:name>ns1.example.se</host:name>
+<host:rem><host:addr>192.0.2.1</host:addr></host:rem>`))
+
+	// Renaming to an external name requires the addresses to be removed.
+	c1.expect(epp.EppParamPolicyError, object("update", "host", `<host:name>ns1.example.se</host:name>
+<host:chg><host:name>ns1.example.net</host:name></host:chg>`))
+
+	// Renaming updates the domains using the host.
+	c1.expect(epp.EppOk, object("update", "host", `<host:name>ns1.example.se</host:name>
+<host:chg><host:name>ns2.example.se</host:name></host:chg>`))
+	c1.expect(epp.EppObjectDoesNotExist, object("info", "host", "<host:name>ns1.example.se</host:name>"))
+
+	domain := infoDomain(c2, "<domain:name>example2.se</domain:name>")
+	require.NotNil(t, domain.NameServer)
+	assert.Equal(t, []string{"ns2.example.se"}, domain.NameServer.HostObject)
+
+	c1.expect(epp.EppOk, object("update", "host", `<host:name>ns2.example.se</host:name>
+<host:add><host:status s="clientUpdateProhibited"/></host:add>`))
+	c1.expect(epp.EppStatusProhibitsOp, object("update", "host", `<host:name>ns2.example.se</host:name>
+<host:add><host:addr>192.0.2.3</host:addr></host:add>`))
+}
+
+func TestHostDelete(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	c1.expect(epp.EppOk, object("create", "host", "<host:name>ns1.example.net</host:name>"))
+	createDomain(c2, "example.se", "<domain:ns><domain:hostObj>ns1.example.net</domain:hostObj></domain:ns>")
+
+	c2.expect(epp.EppAuthorisationError, object("delete", "host", "<host:name>ns1.example.net</host:name>"))
+	c1.expect(epp.EppAssocProhibitsOp, object("delete", "host", "<host:name>ns1.example.net</host:name>"))
+
+	c2.expect(epp.EppOk, object("update", "domain", `<domain:name>example.se</domain:name>
+<domain:rem><domain:ns><domain:hostObj>ns1.example.net</domain:hostObj></domain:ns></domain:rem>`))
+	c1.expect(epp.EppOk, object("delete", "host", "<host:name>ns1.example.net</host:name>"))
+	c1.expect(epp.EppObjectDoesNotExist, object("info", "host", "<host:name>ns1.example.net</host:name>"))
+}
@@ -0,0 +1,454 @@
+// Package registry implements an EPP registry with the domain, contact and
+// host commands from RFC 5731, RFC 5732 and RFC 5733. The registry is added to
+// a Mux with Register and enforces the semantics of the RFCs such as
+// sponsorship, statuses, linked objects, subordinate hosts, authorization
+// information and transfers. Expired domains are renewed automatically.
+// Clients are notified of transfers and expired domains with poll messages. Objects are kept in a store.Store, in memory by default. It's
+// useful as a test double for registrar software and as a starting point for
+// a registry backend.
+//
+//	r := registry.New()
+//	r.AddRegistrar("registrar-1", "secret-password")
+//
+//	mux := epp.NewMux()
+//	r.Register(mux)
+//
+//	server := epp.Server{
+//	    TLSConfig: tlsConfig,
+//	    SessionConfig: epp.SessionConfig{
+//	        Handler: mux.Handle,
+//	    },
+//	}
+package registry
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/poll"
+	"github.com/bombsimon/epp-go/store"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/google/uuid"
+)
+
+// DefaultTransferPeriod is the time a transfer is pending before it's
+// approved by the server if the sponsoring client doesn't act.
+const DefaultTransferPeriod = 5 * 24 * time.Hour
+
+// DefaultROIDSuffix is the suffix used for ROIDs if none is configured.
+const DefaultROIDSuffix = "EPP"
+
+// maxRegistrationYears is the maximum number of years a domain may be
+// registered for, counted from the current time.
+const maxRegistrationYears = 10
+
+// Registry is a registry keeping its objects in a store. Settings must be
+// configured before the registry is registered on a Mux.
+type Registry struct {
+	// ROIDSuffix is the repository identifier added to each ROID, e.g. a
+	// ROID for a domain is D1-EPP. If empty DefaultROIDSuffix is used.
+	ROIDSuffix string
+
+	// Zones holds the zones served by the registry, e.g. "se". Domains can
+	// only be created in these zones and hosts in the zones are internal and
+	// must have a superordinate domain. If empty domains can be created with
+	// any name and hosts are internal if their superordinate domain exists.
+	Zones []string
+
+	// TransferPeriod is the time a transfer is pending before it's approved
+	// by the server. If zero DefaultTransferPeriod is used.
+	TransferPeriod time.Duration
+
+	// Now returns the current time. If nil time.Now is used.
+	Now func() time.Time
+
+	// Store holds the domains, contacts, hosts, transfers and poll
+	// messages. New sets it to a store keeping everything in memory.
+	Store store.Store
+
+	mu         sync.Mutex
+	registrars map[string]string
+}
+
+// New creates a new empty Registry.
+func New() *Registry {
+	return &Registry{
+		Store:      store.NewMemory(),
+		registrars: map[string]string{},
+	}
+}
+
+// AddRegistrar adds a registrar that can login with the client ID and the
+// password. Adding an existing registrar changes the password.
+func (r *Registry) AddRegistrar(clientID, password string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.registrars[clientID] = password
+}
+
+// Register adds handlers for login, logout, poll and all domain, contact and
+// host commands to the mux. Messages are added to the poll queues when
+// transfers are requested, approved, rejected and cancelled, when the
+// transfer period expires and when domains expire and are renewed.
+func (r *Registry) Register(m *epp.Mux) {
+	m.AddHandler("command/login", r.login)
+	m.AddHandler("command/logout", r.logout)
+
+	q := poll.New(r.Store)
+	q.Now = r.now
+	q.Before = r.expire
+	q.Register(m)
+
+	for path, cmd := range map[string]command{
+		"command/check/domain":     r.checkDomain,
+		"command/info/domain":      r.infoDomain,
+		"command/create/domain":    r.createDomain,
+		"command/update/domain":    r.updateDomain,
+		"command/delete/domain":    r.deleteDomain,
+		"command/renew/domain":     r.renewDomain,
+		"command/transfer/domain":  r.transferDomain,
+		"command/check/contact":    r.checkContact,
+		"command/info/contact":     r.infoContact,
+		"command/create/contact":   r.createContact,
+		"command/update/contact":   r.updateContact,
+		"command/delete/contact":   r.deleteContact,
+		"command/transfer/contact": r.transferContact,
+		"command/check/host":       r.checkHost,
+		"command/info/host":        r.infoHost,
+		"command/create/host":      r.createHost,
+		"command/update/host":      r.updateHost,
+		"command/delete/host":      r.deleteHost,
+	} {
+		m.AddHandler(path, r.handler(cmd))
+	}
+}
+
+// maxAttempts is the number of times a command is run if the transaction
+// conflicts with another transaction.
+const maxAttempts = 3
+
+// command is a command implemented by the registry. It's called with a
+// transaction and the client ID of the logged in client and returns the
+// result code and the result data for the response. An error of the type
+// *resultError is used as the result, other errors are responded to with
+// the result code 2400. The transaction is only committed if no error is
+// returned.
+type command func(tx store.Tx, clientID string, data []byte) (epp.ResultCode, interface{}, error)
+
+// handler returns a handler calling cmd if the client is logged in.
+func (r *Registry) handler(cmd command) epp.HandlerFunc {
+	return func(s *epp.Session, data []byte) ([]byte, error) {
+		if s.ClientID() == "" {
+			return r.respond(data, &resultError{code: epp.EppUseError, reason: "not logged in"}, nil)
+		}
+
+		r.mu.Lock()
+		code, resData, err := r.run(cmd, s.ClientID(), data)
+		r.mu.Unlock()
+
+		if err != nil {
+			return r.respond(data, err, nil)
+		}
+
+		return r.respond(data, code, resData)
+	}
+}
+
+// run runs the command in a transaction. Pending transfers and domains which
+// have expired are handled first with expire. Commands
+// are run again if the transaction conflicts with a transaction from another
+// registry using the same store.
+func (r *Registry) run(cmd command, clientID string, data []byte) (epp.ResultCode, interface{}, error) {
+	var err error
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var (
+			tx      store.Tx
+			code    epp.ResultCode
+			resData interface{}
+		)
+
+		if tx, err = r.Store.Begin(); err != nil {
+			return 0, nil, err
+		}
+
+		if err = r.expire(tx); err == nil {
+			code, resData, err = cmd(tx, clientID, data)
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return 0, nil, err
+		}
+
+		if err = tx.Commit(); !errors.Is(err, store.ErrConflict) {
+			return code, resData, err
+		}
+	}
+
+	return 0, nil, err
+}
+
+// expire approves pending transfers where the time for the sponsoring client
+// to act has passed and then renews expired domains.
+func (r *Registry) expire(tx store.Tx) error {
+	if err := r.expireTransfers(tx); err != nil {
+		return err
+	}
+
+	return r.expireDomains(tx)
+}
+
+// respond creates a response to the request with the result and the result
+// data. The result is either an epp.ResultCode or an error. Errors not of
+// the type *resultError are responded to with the result code 2400.
+func (r *Registry) respond(request []byte, result interface{}, resData interface{}) ([]byte, error) {
+	res := types.Result{}
+
+	switch v := result.(type) {
+	case epp.ResultCode:
+		res.Code, res.Message = v.Code(), v.Message()
+	case error:
+		var resErr *resultError
+		if !errors.As(v, &resErr) {
+			resErr = &resultError{code: epp.EppCommandFailed}
+		}
+
+		res.Code, res.Message = resErr.code.Code(), resErr.code.Message()
+
+		if resErr.element.Local != "" {
+			res.ExternalValue = epp.NewExternalErrorValue(resErr.element, resErr.value, resErr.reason)
+		}
+	}
+
+	response := types.Response{
+		Result:     []types.Result{res},
+		ResultData: resData,
+		TransactionID: types.TransactionID{
+			ClientTransactionID: epp.ClientTransactionID(request),
+			ServerTransactionID: uuid.New().String(),
+		},
+	}
+
+	return epp.Encode(response, epp.ServerXMLAttributes())
+}
+
+// login authenticates the client with the registrars added with
+// AddRegistrar. The password is changed if a new password is given.
+func (r *Registry) login(s *epp.Session, data []byte) ([]byte, error) {
+	login := types.Login{}
+
+	if err := epp.Decode(data, &login); err != nil {
+		return r.respond(data, syntaxError(err), nil)
+	}
+
+	if s.ClientID() != "" {
+		return r.respond(data, &resultError{code: epp.EppUseError, reason: "already logged in"}, nil)
+	}
+
+	r.mu.Lock()
+
+	password, ok := r.registrars[login.ClientID]
+	if ok && password == login.Password && login.NewPassword != "" {
+		r.registrars[login.ClientID] = login.NewPassword
+	}
+
+	r.mu.Unlock()
+
+	if !ok || password != login.Password {
+		return r.respond(data, epp.EppAuthenticationError, nil)
+	}
+
+	if err := s.Login(login.ClientID); err != nil {
+		return r.respond(data, epp.EppSessionLimitExceededBye, nil)
+	}
+
+	return r.respond(data, epp.EppOk, nil)
+}
+
+// logout ends the session.
+func (r *Registry) logout(s *epp.Session, data []byte) ([]byte, error) {
+	s.Logout()
+
+	return r.respond(data, epp.EppOkBye, nil)
+}
+
+// now returns the current time in UTC with the precision used for dates in
+// responses.
+func (r *Registry) now() time.Time {
+	now := time.Now
+	if r.Now != nil {
+		now = r.Now
+	}
+
+	return now().UTC().Truncate(time.Second)
+}
+
+// newROID returns a new unique ROID with the prefix.
+func (r *Registry) newROID(tx store.Tx, prefix string) (string, error) {
+	suffix := r.ROIDSuffix
+	if suffix == "" {
+		suffix = DefaultROIDSuffix
+	}
+
+	return tx.NewROID(prefix, suffix)
+}
+
+// transferPeriod returns the time a transfer is pending.
+func (r *Registry) transferPeriod() time.Duration {
+	if r.TransferPeriod == 0 {
+		return DefaultTransferPeriod
+	}
+
+	return r.TransferPeriod
+}
+
+// inZone returns true if the name is in any of the zones served by the
+// registry.
+func (r *Registry) inZone(name string) bool {
+	for _, zone := range r.Zones {
+		if strings.HasSuffix(name, "."+normalizeName(zone)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowedDomain returns true if a domain with the name may be created, that
+// is if it's directly below one of the zones served by the registry.
+func (r *Registry) allowedDomain(name string) bool {
+	if len(r.Zones) == 0 {
+		return true
+	}
+
+	for _, zone := range r.Zones {
+		label := strings.TrimSuffix(name, "."+normalizeName(zone))
+		if label != name && !strings.Contains(label, ".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// superordinate returns the domain a host name is subordinate to, that is
+// the domain with the longest name the host name is below, or nil if there's
+// no such domain.
+func superordinate(tx store.Tx, host string) (*types.DomainInfoData, error) {
+	labels := strings.Split(host, ".")
+
+	for i := 1; i < len(labels); i++ {
+		d, err := tx.Domain(strings.Join(labels[i:], "."))
+		if ok, err := found(err); err != nil || ok {
+			return d, err
+		}
+	}
+
+	return nil, nil
+}
+
+// found returns true if err is nil and false if it's store.ErrNotFound.
+// Other errors are returned.
+func found(err error) (bool, error) {
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, store.ErrNotFound):
+		return false, nil
+	}
+
+	return false, err
+}
+
+// authorized returns true if the authorization information matches the
+// password for an object.
+func authorized(authInfo *types.AuthInfo, password string) bool {
+	return authInfo != nil && authInfo.Password != "" && authInfo.Password == password
+}
+
+// hasAuthInfo returns true if authorization information is given.
+func hasAuthInfo(authInfo *types.AuthInfo) bool {
+	return authInfo != nil && (authInfo.Password != "" || authInfo.Extension != "")
+}
+
+// password returns the password in the authorization information.
+func password(authInfo *types.AuthInfo) string {
+	if authInfo == nil {
+		return ""
+	}
+
+	return authInfo.Password
+}
+
+// normalizeName returns the domain or host name in lower case without a
+// trailing dot.
+func normalizeName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+// validName returns true if the name is a valid domain or host name with at
+// least two labels.
+func validName(name string) bool {
+	labels := strings.Split(name, ".")
+	if len(labels) < 2 || len(name) > 253 {
+		return false
+	}
+
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// timePtr returns a pointer to the time.
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// formatTime formats the time as used for dates in responses.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// resultError is an error with the result code to respond with and
+// optionally the element and value causing the error.
+type resultError struct {
+	code    epp.ResultCode
+	element xml.Name
+	value   string
+	reason  string
+}
+
+// Error implements the error interface.
+func (e *resultError) Error() string {
+	if e.reason == "" {
+		return e.code.Message()
+	}
+
+	return fmt.Sprintf("%s: %s", e.code.Message(), e.reason)
+}
+
+// syntaxError returns an error for a request that couldn't be decoded.
+func syntaxError(err error) error {
+	return &resultError{
+		code:    epp.EppSyntaxError,
+		element: xml.Name{Local: "command"},
+		reason:  err.Error(),
+	}
+}
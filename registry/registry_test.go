@@ -0,0 +1,336 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/store"
+	"github.com/bombsimon/epp-go/store/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRegistry is a registry for the zone se with two registrars and a
+// clock that only moves when told to.
+type testRegistry struct {
+	*Registry
+	t         *testing.T
+	mux       *epp.Mux
+	validator *epp.XMLValidator
+	clock     time.Time
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	validator, err := epp.NewDefaultValidator()
+	require.Nil(t, err)
+
+	t.Cleanup(validator.Free)
+
+	tr := &testRegistry{
+		Registry:  New(),
+		t:         t,
+		mux:       epp.NewMux(),
+		validator: validator,
+		clock:     time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	tr.Zones = []string{"se"}
+	tr.Now = func() time.Time { return tr.clock }
+	tr.AddRegistrar("registrar-1", "password-1")
+	tr.AddRegistrar("registrar-2", "password-2")
+	tr.Register(tr.mux)
+
+	return tr
+}
+
+// tx returns a transaction on the store of the registry which is rolled back
+// after the test.
+func (tr *testRegistry) tx() store.Tx {
+	tx, err := tr.Store.Begin()
+	require.Nil(tr.t, err)
+
+	tr.t.Cleanup(func() {
+		tx.Rollback()
+	})
+
+	return tx
+}
+
+// testClient is a session for a client.
+type testClient struct {
+	*testRegistry
+	session *epp.Session
+}
+
+// client returns a session for the client which isn't logged in.
+func (tr *testRegistry) client() *testClient {
+	conn, other := net.Pipe()
+
+	tr.t.Cleanup(func() {
+		conn.Close()
+		other.Close()
+	})
+
+	return &testClient{
+		testRegistry: tr,
+		session:      epp.NewSession(conn, epp.SessionConfig{}),
+	}
+}
+
+// login returns a session for the client logged in.
+func (tr *testRegistry) login(clientID string) *testClient {
+	c := tr.client()
+	c.expect(epp.EppOk, loginCommand(clientID, "password-"+clientID[len(clientID)-1:], ""))
+
+	return c
+}
+
+// send sends the command to the registry and returns the response. Both the
+// request and the response must be valid.
+func (c *testClient) send(body string) *epp.DecodedResponse {
+	c.t.Helper()
+
+	request := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><command>` + body + `<clTRID>TEST-1</clTRID></command></epp>`)
+	requireValid(c.t, c.validator, request)
+
+	response, err := c.mux.Handle(c.session, request)
+	require.Nil(c.t, err)
+	requireValid(c.t, c.validator, response)
+
+	decoded, err := epp.DecodeResponse(response)
+	require.Nil(c.t, err)
+	require.Equal(c.t, "TEST-1", decoded.TransactionID.ClientTransactionID)
+
+	return decoded
+}
+
+// requireValid requires the document to be valid and reports each error.
+func requireValid(t *testing.T, validator epp.Validator, document []byte) {
+	t.Helper()
+
+	var errs epp.ValidationErrors
+
+	if err := validator.Validate(document); errors.As(err, &errs) {
+		for _, e := range errs {
+			t.Error(e.Error())
+		}
+
+		require.FailNow(t, "invalid document", string(document))
+	}
+}
+
+// expect sends the command and requires the result code.
+func (c *testClient) expect(code epp.ResultCode, body string) *epp.DecodedResponse {
+	c.t.Helper()
+
+	response := c.send(body)
+
+	reason := ""
+	if v := response.Result[0].ExternalValue; v != nil {
+		reason = v.Reason
+	}
+
+	require.Equal(c.t, code, response.Code(), reason)
+
+	return response
+}
+
+func loginCommand(clientID, password, newPassword string) string {
+	if newPassword != "" {
+		newPassword = "<newPW>" + newPassword + "</newPW>"
+	}
+
+	return fmt.Sprintf(`<login><clID>%s</clID><pw>%s</pw>%s<options><version>1.0</version><lang>en</lang></options>
+<svcs><objURI>urn:ietf:params:xml:ns:domain-1.0</objURI></svcs></login>`, clientID, password, newPassword)
+}
+
+// object returns a command for the object with the namespace prefix ns.
+func object(command, ns, body string) string {
+	return fmt.Sprintf(`<%[1]s><%[2]s:%[1]s xmlns:%[2]s="urn:ietf:params:xml:ns:%[2]s-1.0">%[3]s</%[2]s:%[1]s></%[1]s>`, command, ns, body)
+}
+
+// transferCommand returns a transfer command with the op for the object with
+// the namespace prefix ns.
+func transferCommand(op, ns, body string) string {
+	return fmt.Sprintf(`<transfer op="%[1]s"><%[2]s:transfer xmlns:%[2]s="urn:ietf:params:xml:ns:%[2]s-1.0">%[3]s</%[2]s:transfer></transfer>`, op, ns, body)
+}
+
+func TestLogin(t *testing.T) {
+	tr := newTestRegistry(t)
+
+	c := tr.client()
+	c.expect(epp.EppUseError, object("info", "domain", "<domain:name>example.se</domain:name>"))
+	c.expect(epp.EppAuthenticationError, loginCommand("registrar-1", "wrong-password", ""))
+	c.expect(epp.EppAuthenticationError, loginCommand("unknown", "password-1", ""))
+	c.expect(epp.EppOk, loginCommand("registrar-1", "password-1", "new-password"))
+	assert.Equal(t, "registrar-1", c.session.ClientID())
+
+	c.expect(epp.EppUseError, loginCommand("registrar-1", "new-password", ""))
+
+	c = tr.client()
+	c.expect(epp.EppAuthenticationError, loginCommand("registrar-1", "password-1", ""))
+	c.expect(epp.EppOk, loginCommand("registrar-1", "new-password", ""))
+	c.expect(epp.EppOkBye, "<logout/>")
+}
+
+func TestErrorValue(t *testing.T) {
+	tr := newTestRegistry(t)
+	c := tr.login("registrar-1")
+
+	response := c.expect(epp.EppObjectDoesNotExist, object("info", "domain", "<domain:name>missing.se</domain:name>"))
+	require.NotNil(t, response.Result[0].ExternalValue)
+
+	response = c.expect(epp.EppParamPolicyError, object("create", "domain", `<domain:name>example.com</domain:name>
+<domain:authInfo><domain:pw>secret</domain:pw></domain:authInfo>`))
+	require.NotNil(t, response.Result[0].ExternalValue)
+	assert.Equal(t, "not in a zone served by the registry", response.Result[0].ExternalValue.Reason)
+}
+
+func TestROID(t *testing.T) {
+	tr := newTestRegistry(t)
+	tr.ROIDSuffix = "SE"
+
+	c := tr.login("registrar-1")
+	createContact(c, "contact-1")
+	createDomain(c, "example.se", "")
+	c.expect(epp.EppOk, object("create", "host", "<host:name>ns1.example.se</host:name><host:addr>192.0.2.1</host:addr>"))
+
+	tx := tr.tx()
+
+	contact, err := tx.Contact("contact-1")
+	require.Nil(t, err)
+	assert.Equal(t, "C1-SE", contact.ROID)
+
+	domain, err := tx.Domain("example.se")
+	require.Nil(t, err)
+	assert.Equal(t, "D2-SE", domain.ROID)
+
+	host, err := tx.Host("ns1.example.se")
+	require.Nil(t, err)
+	assert.Equal(t, "H3-SE", host.ROID)
+}
+
+// conflictStore is a store where the given number of commits conflict.
+type conflictStore struct {
+	store.Store
+	conflicts int
+}
+
+func (s *conflictStore) Begin() (store.Tx, error) {
+	tx, err := s.Store.Begin()
+
+	return &conflictTx{Tx: tx, store: s}, err
+}
+
+type conflictTx struct {
+	store.Tx
+	store *conflictStore
+}
+
+func (tx *conflictTx) Commit() error {
+	if tx.store.conflicts > 0 {
+		tx.store.conflicts--
+		tx.Tx.Rollback()
+
+		return store.ErrConflict
+	}
+
+	return tx.Tx.Commit()
+}
+
+func TestConflict(t *testing.T) {
+	tr := newTestRegistry(t)
+	conflicts := &conflictStore{Store: tr.Store}
+	tr.Store = conflicts
+
+	c := tr.login("registrar-1")
+
+	// Commands are retried when the transaction conflicts.
+	conflicts.conflicts = maxAttempts - 1
+	createContact(c, "contact-1")
+
+	conflicts.conflicts = maxAttempts
+	c.expect(epp.EppCommandFailed, object("delete", "contact", "<contact:id>contact-1</contact:id>"))
+	infoContact(c, "<contact:id>contact-1</contact:id>")
+}
+
+func TestSharedStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.db")
+
+	// Two registries, e.g. in different processes, using the same database.
+	registries := []*testRegistry{newTestRegistry(t), newTestRegistry(t)}
+
+	for _, tr := range registries {
+		s, err := sqlite.Open(path)
+		require.Nil(t, err)
+
+		t.Cleanup(func() {
+			s.Close()
+		})
+
+		tr.Store = s
+	}
+
+	c1 := registries[0].login("registrar-1")
+	c2 := registries[1].login("registrar-2")
+
+	createContact(c1, "contact-1")
+	createDomain(c2, "example.se", `<domain:registrant>contact-1</domain:registrant>`)
+
+	c1.expect(epp.EppAssocProhibitsOp, object("delete", "contact", "<contact:id>contact-1</contact:id>"))
+	assert.Equal(t, "D2-EPP", infoDomain(c1, "<domain:name>example.se</domain:name>").ROID)
+}
+
+func TestCheckProhibited(t *testing.T) {
+	element := domainElement("name")
+	statuses := []status{{value: "clientUpdateProhibited"}, {value: "clientDeleteProhibited"}}
+
+	assert.NotNil(t, checkProhibited(statuses, opUpdate, element, "example.se"))
+	assert.Nil(t, checkProhibited(statuses, opUpdate, element, "example.se", status{value: "clientUpdateProhibited"}))
+	assert.NotNil(t, checkProhibited(statuses, opDelete, element, "example.se", status{value: "clientUpdateProhibited"}))
+	assert.Nil(t, checkProhibited(statuses, opRenew, element, "example.se"))
+	assert.NotNil(t, checkProhibited([]status{{value: "serverUpdateProhibited"}}, opUpdate, element, "example.se", status{value: "serverUpdateProhibited"}))
+	assert.NotNil(t, checkProhibited([]status{{value: statusPendingTransfer}}, opRenew, element, "example.se"))
+}
+
+func TestUpdateStatuses(t *testing.T) {
+	element := domainElement("status")
+	statuses := []status{{value: "clientHold"}}
+
+	result, err := updateStatuses(statuses, []status{{value: "clientUpdateProhibited", message: "locked"}}, []status{{value: "clientHold"}}, element)
+	require.Nil(t, err)
+	assert.Equal(t, []status{{value: "clientUpdateProhibited", message: "locked"}}, result)
+
+	for _, tc := range []struct {
+		add, remove []status
+	}{
+		{add: []status{{value: "serverHold"}}},
+		{remove: []status{{value: "serverHold"}}},
+		{add: []status{{value: "clientHold"}}},
+		{remove: []status{{value: "clientUpdateProhibited"}}},
+	} {
+		_, err := updateStatuses(statuses, tc.add, tc.remove, element)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestValidName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"example.se":            true,
+		"ns1.sub.example.se":    true,
+		"xn--rksmrgs-5wao1o.se": true,
+		"se":                    false,
+		"-example.se":           false,
+		"example-.se":           false,
+		"exa_mple.se":           false,
+		"example..se":           false,
+	} {
+		assert.Equal(t, valid, validName(name), name)
+	}
+}
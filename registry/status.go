@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"encoding/xml"
+	"strings"
+
+	epp "github.com/bombsimon/epp-go"
+)
+
+// Status values shared by domains, contacts and hosts. Only the statuses
+// assigned by clients or the server are stored on the objects, statuses
+// derived from the state of the registry such as ok, linked, inactive and
+// pendingTransfer are added when the object is returned.
+const (
+	statusPendingDelete   = "pendingDelete"
+	statusPendingTransfer = "pendingTransfer"
+)
+
+// Operations that may be prohibited by the client and server statuses, e.g.
+// clientUpdateProhibited and serverUpdateProhibited.
+const (
+	opDelete   = "Delete"
+	opRenew    = "Renew"
+	opTransfer = "Transfer"
+	opUpdate   = "Update"
+)
+
+// status is a status value with an optional message, used to apply updates
+// to the status types of domains, contacts and hosts in the same way.
+type status struct {
+	value    string
+	message  string
+	language string
+}
+
+// isClientStatus returns true if the status may be set by clients.
+func isClientStatus(value string) bool {
+	return strings.HasPrefix(value, "client")
+}
+
+// hasStatus returns true if any of the values is among the statuses.
+func hasStatus(statuses []status, values ...string) bool {
+	for _, s := range statuses {
+		for _, v := range values {
+			if s.value == v {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// checkProhibited returns an error with the result code 2304 if the statuses
+// prohibits the operation. No operations are allowed for objects pending
+// delete or transfer. Updates are allowed if the update removes the
+// clientUpdateProhibited status.
+func checkProhibited(statuses []status, op string, element xml.Name, value string, removed ...status) error {
+	prohibited := []string{statusPendingDelete, statusPendingTransfer, "server" + op + "Prohibited"}
+
+	if op != opUpdate || !hasStatus(removed, "clientUpdateProhibited") {
+		prohibited = append(prohibited, "client"+op+"Prohibited")
+	}
+
+	for _, s := range statuses {
+		for _, p := range prohibited {
+			if s.value == p {
+				return &resultError{
+					code:    epp.EppStatusProhibitsOp,
+					element: element,
+					value:   value,
+					reason:  "object status " + s.value + " prohibits operation",
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateStatuses returns the statuses with the statuses in add added and the
+// statuses in remove removed. Only client statuses may be added or removed
+// and only statuses not already set may be added and only statuses set may be
+// removed.
+func updateStatuses(statuses, add, remove []status, element xml.Name) ([]status, error) {
+	for _, s := range append(append([]status{}, add...), remove...) {
+		if !isClientStatus(s.value) {
+			return nil, &resultError{
+				code:    epp.EppParamPolicyError,
+				element: element,
+				value:   s.value,
+				reason:  "only client statuses may be added or removed",
+			}
+		}
+	}
+
+	result := []status{}
+
+	for _, s := range statuses {
+		if hasStatus(remove, s.value) {
+			continue
+		}
+
+		result = append(result, s)
+	}
+
+	for _, s := range remove {
+		if !hasStatus(statuses, s.value) {
+			return nil, &resultError{
+				code:    epp.EppParamPolicyError,
+				element: element,
+				value:   s.value,
+				reason:  "status is not set",
+			}
+		}
+	}
+
+	for _, s := range add {
+		if hasStatus(result, s.value) {
+			return nil, &resultError{
+				code:    epp.EppParamPolicyError,
+				element: element,
+				value:   s.value,
+				reason:  "status is already set",
+			}
+		}
+
+		result = append(result, s)
+	}
+
+	return result, nil
+}
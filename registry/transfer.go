@@ -0,0 +1,375 @@
+package registry
+
+import (
+	"encoding/xml"
+	"errors"
+	"time"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/poll"
+	"github.com/bombsimon/epp-go/store"
+	"github.com/bombsimon/epp-go/types"
+)
+
+// Transfer operations from the op attribute of the transfer command.
+const (
+	transferApprove = "approve"
+	transferCancel  = "cancel"
+	transferQuery   = "query"
+	transferReject  = "reject"
+	transferRequest = "request"
+)
+
+// Transfer statuses, the same for domains and contacts.
+const (
+	transferClientApproved  = "clientApproved"
+	transferClientCancelled = "clientCancelled"
+	transferClientRejected  = "clientRejected"
+	transferPending         = "pending"
+	transferServerApproved  = "serverApproved"
+)
+
+// transferObject holds what's needed to process a transfer command for a
+// domain or a contact. The kind is store.KindDomain or store.KindContact.
+type transferObject struct {
+	kind     string
+	name     string
+	roid     string
+	clientID string
+	password string
+	statuses []status
+	element  xml.Name
+
+	// expireDate is the current expiry date for domains.
+	expireDate *time.Time
+}
+
+// transferOperation returns the op attribute from a transfer command.
+func transferOperation(data []byte) (string, error) {
+	var request struct {
+		Command struct {
+			Transfer struct {
+				Op string `xml:"op,attr"`
+			} `xml:"transfer"`
+		} `xml:"command"`
+	}
+
+	if err := xml.Unmarshal(data, &request); err != nil {
+		return "", err
+	}
+
+	return request.Command.Transfer.Op, nil
+}
+
+// pendingTransfer returns true if the object with the ROID has a pending
+// transfer.
+func pendingTransfer(tx store.Tx, roid string) (bool, error) {
+	t, err := tx.Transfer(roid)
+	if ok, err := found(err); !ok {
+		return false, err
+	}
+
+	return t.Status == transferPending, nil
+}
+
+// transferCommand processes a transfer command with the operation op for the
+// object and returns the result code and the resulting transfer.
+func (r *Registry) transferCommand(tx store.Tx, clientID, op string, obj transferObject, authInfo *types.AuthInfo, period types.Period) (epp.ResultCode, *store.Transfer, error) {
+	t, err := tx.Transfer(obj.roid)
+
+	ok, err := found(err)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	notPending := &resultError{
+		code:    epp.EppObjectNotPendingTransfer,
+		element: obj.element,
+		value:   obj.name,
+	}
+
+	switch op {
+	case transferQuery:
+		if hasAuthInfo(authInfo) && !authorized(authInfo, obj.password) {
+			return 0, nil, &resultError{code: epp.EppInvalidAuthInfo, element: obj.element, value: obj.name}
+		}
+
+		if !hasAuthInfo(authInfo) && clientID != obj.clientID && (!ok || clientID != t.RequestingID) {
+			return 0, nil, &resultError{code: epp.EppAuthorisationError, element: obj.element, value: obj.name}
+		}
+
+		if !ok {
+			return 0, nil, notPending
+		}
+
+		return epp.EppOk, t, nil
+
+	case transferRequest:
+		return r.requestTransfer(tx, clientID, obj, authInfo, period)
+
+	case transferApprove, transferReject:
+		if clientID != obj.clientID {
+			return 0, nil, &resultError{code: epp.EppAuthorisationError, element: obj.element, value: obj.name}
+		}
+
+		if !ok || t.Status != transferPending {
+			return 0, nil, notPending
+		}
+
+		if op == transferReject {
+			return epp.EppOk, t, r.endTransfer(tx, t, transferClientRejected, r.now())
+		}
+
+		return epp.EppOk, t, r.completeTransfer(tx, t, transferClientApproved, r.now())
+
+	case transferCancel:
+		if !ok || t.Status != transferPending {
+			return 0, nil, notPending
+		}
+
+		if clientID != t.RequestingID {
+			return 0, nil, &resultError{code: epp.EppAuthorisationError, element: obj.element, value: obj.name}
+		}
+
+		return epp.EppOk, t, r.endTransfer(tx, t, transferClientCancelled, r.now())
+	}
+
+	return 0, nil, &resultError{
+		code:    epp.EppParamSyntaxError,
+		element: xml.Name{Local: "transfer"},
+		value:   op,
+		reason:  "unknown transfer operation",
+	}
+}
+
+// requestTransfer creates a pending transfer of the object to the client.
+func (r *Registry) requestTransfer(tx store.Tx, clientID string, obj transferObject, authInfo *types.AuthInfo, period types.Period) (epp.ResultCode, *store.Transfer, error) {
+	if clientID == obj.clientID {
+		return 0, nil, &resultError{
+			code:    epp.EppNotTransferrable,
+			element: obj.element,
+			value:   obj.name,
+			reason:  "object is already sponsored by the client",
+		}
+	}
+
+	if !authorized(authInfo, obj.password) {
+		return 0, nil, &resultError{code: epp.EppInvalidAuthInfo, element: obj.element, value: obj.name}
+	}
+
+	pending, err := pendingTransfer(tx, obj.roid)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if pending {
+		return 0, nil, &resultError{code: epp.EppObjectPendingTransfer, element: obj.element, value: obj.name}
+	}
+
+	if err := checkProhibited(obj.statuses, opTransfer, obj.element, obj.name); err != nil {
+		return 0, nil, err
+	}
+
+	now := r.now()
+
+	t := &store.Transfer{
+		ROID:           obj.roid,
+		Kind:           obj.kind,
+		Name:           obj.name,
+		Status:         transferPending,
+		RequestingID:   clientID,
+		RequestingDate: now,
+		ActingID:       obj.clientID,
+		ActingDate:     now.Add(r.transferPeriod()),
+	}
+
+	if obj.expireDate != nil {
+		if period.Value == 0 {
+			period = types.Period{Value: 1, Unit: "y"}
+		}
+
+		expireDate, err := r.extend(*obj.expireDate, period)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		t.Period, t.ExpireDate = period, &expireDate
+	}
+
+	if err := tx.PutTransfer(t); err != nil {
+		return 0, nil, err
+	}
+
+	if err := r.notifyTransfer(tx, t); err != nil {
+		return 0, nil, err
+	}
+
+	return epp.EppOkPending, t, nil
+}
+
+// expireTransfers approves all pending transfers where the time for the
+// sponsoring client to act has passed.
+func (r *Registry) expireTransfers(tx store.Tx) error {
+	transfers, err := tx.Transfers()
+	if err != nil {
+		return err
+	}
+
+	now := r.now()
+
+	for _, t := range transfers {
+		if t.Status == transferPending && !now.Before(t.ActingDate) {
+			if err := r.completeTransfer(tx, t, transferServerApproved, t.ActingDate); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// completeTransfer moves the object to the requesting client.
+func (r *Registry) completeTransfer(tx store.Tx, t *store.Transfer, status string, date time.Time) error {
+	if err := r.endTransfer(tx, t, status, date); err != nil {
+		return err
+	}
+
+	switch t.Kind {
+	case store.KindDomain:
+		d, err := tx.Domain(t.Name)
+		if ok, err := found(err); !ok {
+			return err
+		}
+
+		// Subordinate hosts are transferred with the domain.
+		hosts, err := subordinateHosts(tx, t.Name)
+		if err != nil {
+			return err
+		}
+
+		for _, h := range hosts {
+			h.ClientID, h.TransferDate = t.RequestingID, date
+
+			if err := tx.PutHost(h); err != nil {
+				return err
+			}
+		}
+
+		d.ClientID, d.TransferDate, d.ExpireDate = t.RequestingID, timePtr(date), t.ExpireDate
+
+		return tx.PutDomain(d)
+
+	case store.KindContact:
+		c, err := tx.Contact(t.Name)
+		if ok, err := found(err); !ok {
+			return err
+		}
+
+		c.ClientID, c.TransferDate = t.RequestingID, date
+
+		return tx.PutContact(c)
+	}
+
+	return nil
+}
+
+// endTransfer sets the final status of the transfer.
+func (r *Registry) endTransfer(tx store.Tx, t *store.Transfer, status string, date time.Time) error {
+	t.Status, t.ActingDate = status, date
+
+	if status != transferClientApproved && status != transferServerApproved {
+		t.ExpireDate = nil
+	}
+
+	if err := tx.PutTransfer(t); err != nil {
+		return err
+	}
+
+	return r.notifyTransfer(tx, t)
+}
+
+// transferMessages holds the text of the poll message for each transfer
+// status.
+var transferMessages = map[string]string{
+	transferClientApproved:  "Transfer approved.",
+	transferClientCancelled: "Transfer cancelled.",
+	transferClientRejected:  "Transfer rejected.",
+	transferPending:         "Transfer requested.",
+	transferServerApproved:  "Transfer approved by the server.",
+}
+
+// notifyTransfer adds a poll message with the transfer data to the clients
+// concerned by the current status of the transfer. The sponsoring client is
+// notified of requested and cancelled transfers and the requesting client of
+// approved and rejected transfers. Both clients are notified of transfers
+// approved by the server.
+func (r *Registry) notifyTransfer(tx store.Tx, t *store.Transfer) error {
+	var clientIDs []string
+
+	switch t.Status {
+	case transferPending, transferClientCancelled:
+		clientIDs = []string{t.ActingID}
+	case transferClientApproved, transferClientRejected:
+		clientIDs = []string{t.RequestingID}
+	case transferServerApproved:
+		clientIDs = []string{t.RequestingID, t.ActingID}
+	}
+
+	for _, clientID := range clientIDs {
+		m := &store.Message{
+			ClientID:  clientID,
+			QueueDate: r.now(),
+			Text:      transferMessages[t.Status],
+		}
+
+		if err := poll.Enqueue(tx, m, transferData(t)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transferData returns the result data for the transfer of a domain or a
+// contact.
+func transferData(t *store.Transfer) interface{} {
+	if t.Kind == store.KindContact {
+		return types.ContactTransferDataType{
+			TransferData: types.ContactTransferData{
+				Name:           t.Name,
+				TransferStatus: types.ContactTransferStatusType(t.Status),
+				RequestingID:   t.RequestingID,
+				RequestingDate: t.RequestingDate,
+				ActingID:       t.ActingID,
+				ActingDate:     t.ActingDate,
+			},
+		}
+	}
+
+	data := types.DomainTransferDataType{
+		TransferData: types.DomainTransferData{
+			Name:           t.Name,
+			TransferStatus: types.DomainTransferStatusType(t.Status),
+			RequestingID:   t.RequestingID,
+			RequestingDate: formatTime(t.RequestingDate),
+			ActingID:       t.ActingID,
+			ActingDate:     formatTime(t.ActingDate),
+		},
+	}
+
+	if t.ExpireDate != nil {
+		data.TransferData.ExpireDate = formatTime(*t.ExpireDate)
+	}
+
+	return data
+}
+
+// deleteTransfer deletes the transfer for the object with the ROID if
+// there's one.
+func deleteTransfer(tx store.Tx, roid string) error {
+	if err := tx.DeleteTransfer(roid); !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
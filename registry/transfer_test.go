@@ -0,0 +1,147 @@
+package registry
+
+import (
+	"testing"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pollMessages retrieves and acknowledges all messages in the queue of the
+// client and returns the responses to poll req.
+func pollMessages(c *testClient) []*epp.DecodedResponse {
+	c.t.Helper()
+
+	messages := []*epp.DecodedResponse{}
+
+	for {
+		response := c.send(`<poll op="req"/>`)
+		if response.Code() == epp.EppOkNoMessages {
+			return messages
+		}
+
+		require.Equal(c.t, epp.EppOkMessages, response.Code())
+		require.NotNil(c.t, response.MessageQ)
+
+		messages = append(messages, response)
+		c.expect(epp.EppOk, `<poll op="ack" msgID="`+response.MessageQ.ID+`"/>`)
+	}
+}
+
+// messageTexts returns the text of each message.
+func messageTexts(messages []*epp.DecodedResponse) []string {
+	texts := []string{}
+
+	for _, m := range messages {
+		texts = append(texts, m.MessageQ.Message)
+	}
+
+	return texts
+}
+
+func TestTransferMessages(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	c1.expect(epp.EppMissingParam, `<poll op="ack"/>`)
+	c1.expect(epp.EppOkNoMessages, `<poll op="req"/>`)
+
+	createDomain(c1, "example.se", "")
+	createContact(c1, "contact-1")
+
+	domainTransfer := func(c *testClient, code epp.ResultCode, op string) {
+		c.expect(code, transferCommand(op, "domain", `<domain:name>example.se</domain:name>
+<domain:authInfo><domain:pw>secret</domain:pw></domain:authInfo>`))
+	}
+
+	// The sponsoring client is notified of requested transfers.
+	domainTransfer(c2, epp.EppOkPending, "request")
+
+	messages := pollMessages(c1)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Transfer requested.", messages[0].MessageQ.Message)
+	require.NotNil(t, messages[0].MessageQ.QueueDate)
+	assert.Equal(t, tr.clock, *messages[0].MessageQ.QueueDate)
+
+	requested := messages[0].DomainTransferData()
+	require.NotNil(t, requested)
+	assert.Equal(t, "example.se", requested.Name)
+	assert.Equal(t, types.DomainTransferPending, requested.TransferStatus)
+	assert.Equal(t, "registrar-2", requested.RequestingID)
+	assert.Equal(t, "registrar-1", requested.ActingID)
+	assert.Equal(t, "2028-03-01T12:00:00Z", requested.ExpireDate)
+
+	assert.Empty(t, pollMessages(c2))
+
+	// The requesting client is notified of rejected transfers and the
+	// sponsoring client of cancelled transfers.
+	domainTransfer(c1, epp.EppOk, "reject")
+	domainTransfer(c2, epp.EppOkPending, "request")
+	domainTransfer(c2, epp.EppOk, "cancel")
+
+	messages = pollMessages(c2)
+	assert.Equal(t, []string{"Transfer rejected."}, messageTexts(messages))
+	assert.Equal(t, types.DomainTransferClientRejected, messages[0].DomainTransferData().TransferStatus)
+
+	messages = pollMessages(c1)
+	assert.Equal(t, []string{"Transfer requested.", "Transfer cancelled."}, messageTexts(messages))
+	assert.Equal(t, types.DomainTransferClientCancelled, messages[1].DomainTransferData().TransferStatus)
+
+	// The requesting client is notified of approved transfers.
+	c2.expect(epp.EppOkPending, transferCommand("request", "contact", `<contact:id>contact-1</contact:id>
+<contact:authInfo><contact:pw>secret</contact:pw></contact:authInfo>`))
+	c1.expect(epp.EppOk, transferCommand("approve", "contact", `<contact:id>contact-1</contact:id>
+<contact:authInfo><contact:pw>secret</contact:pw></contact:authInfo>`))
+
+	messages = pollMessages(c2)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Transfer approved.", messages[0].MessageQ.Message)
+
+	approved := messages[0].ContactTransferData()
+	require.NotNil(t, approved)
+	assert.Equal(t, "contact-1", approved.Name)
+	assert.Equal(t, types.ContactTransferClientApproved, approved.TransferStatus)
+
+	assert.Equal(t, []string{"Transfer requested."}, messageTexts(pollMessages(c1)))
+
+	// Both clients are notified when the transfer period expires, which
+	// happens before the queue is read.
+	domainTransfer(c2, epp.EppOkPending, "request")
+	assert.Equal(t, []string{"Transfer requested."}, messageTexts(pollMessages(c1)))
+
+	tr.clock = tr.clock.Add(DefaultTransferPeriod)
+
+	messages = pollMessages(c2)
+	assert.Equal(t, []string{"Transfer approved by the server."}, messageTexts(messages))
+	assert.Equal(t, types.DomainTransferServerApproved, messages[0].DomainTransferData().TransferStatus)
+
+	messages = pollMessages(c1)
+	assert.Equal(t, []string{"Transfer approved by the server."}, messageTexts(messages))
+
+	info := infoDomain(c2, "<domain:name>example.se</domain:name>")
+	assert.Equal(t, "registrar-2", info.ClientID)
+}
+
+func TestPollOtherClient(t *testing.T) {
+	tr := newTestRegistry(t)
+	c1 := tr.login("registrar-1")
+	c2 := tr.login("registrar-2")
+
+	tr.client().expect(epp.EppUseError, `<poll op="req"/>`)
+
+	createDomain(c1, "example.se", "")
+	c2.expect(epp.EppOkPending, transferCommand("request", "domain", `<domain:name>example.se</domain:name>
+<domain:authInfo><domain:pw>secret</domain:pw></domain:authInfo>`))
+
+	response := c1.expect(epp.EppOkMessages, `<poll op="req"/>`)
+	require.NotNil(t, response.MessageQ)
+	assert.Equal(t, 1, response.MessageQ.Count)
+
+	// Messages can only be acknowledged by the client they're queued for.
+	c2.expect(epp.EppObjectDoesNotExist, `<poll op="ack" msgID="`+response.MessageQ.ID+`"/>`)
+	c1.expect(epp.EppOk, `<poll op="ack" msgID="`+response.MessageQ.ID+`"/>`)
+	c1.expect(epp.EppOkNoMessages, `<poll op="req"/>`)
+}
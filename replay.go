@@ -0,0 +1,297 @@
+package epp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	serverTransactionIDRe = regexp.MustCompile(`(<(?:[\w.-]+:)?svTRID>)[^<]*(</)`)
+	roidElementRe         = regexp.MustCompile(`(<(?:[\w.-]+:)?roid>)[^<]*(</)`)
+	roidAttributeRe       = regexp.MustCompile(`(\sroid=")[^"]*(")`)
+	dateTimeRe            = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})?`)
+	whitespaceBetweenRe   = regexp.MustCompile(`>\s+<`)
+
+	clientIDRe         = regexp.MustCompile(`(<(?:[\w.-]+:)?clID>)[^<]*(</)`)
+	redactedPasswordRe = regexp.MustCompile(`(<(?:[\w.-]+:)?pw(?:\s[^>]*)?>)` + RedactedContent + `(</)`)
+	redactedNewPWRe    = regexp.MustCompile(`(<(?:[\w.-]+:)?newPW(?:\s[^>]*)?>)` + RedactedContent + `(</)`)
+	redactedAuthInfoRe = regexp.MustCompile(`(<([\w.-]+:)?authInfo(?:\s[^>]*)?>)` + RedactedContent + `(</)`)
+)
+
+// NormalizeMessage returns a copy of data where values expected to differ
+// each time a command is executed are replaced, making it possible to compare
+// responses. The server transaction ID, all dates and ROIDs are replaced with
+// placeholders and whitespace between elements is removed.
+func NormalizeMessage(data []byte) []byte {
+	data = serverTransactionIDRe.ReplaceAll(data, []byte("${1}SVTRID${2}"))
+	data = roidElementRe.ReplaceAll(data, []byte("${1}ROID${2}"))
+	data = roidAttributeRe.ReplaceAll(data, []byte("${1}ROID${2}"))
+	data = dateTimeRe.ReplaceAll(data, []byte("DATE"))
+	data = whitespaceBetweenRe.ReplaceAll(data, []byte("><"))
+
+	return bytes.TrimSpace(data)
+}
+
+// ReplayCredentials holds the credentials restored in requests redacted by
+// DefaultRedactor before they are replayed. Empty values are left as they are
+// in the transcript.
+type ReplayCredentials struct {
+	// ClientID replaces the client ID in login commands.
+	ClientID string
+
+	// Password and NewPassword replaces the redacted pw and newPW in login
+	// commands.
+	Password    string
+	NewPassword string
+
+	// AuthInfo replaces redacted authorization information, e.g. in transfer
+	// commands, with a password.
+	AuthInfo string
+}
+
+// Rewrite returns a copy of the request with the credentials restored. It's
+// meant to be used as Replayer.Rewrite.
+func (c ReplayCredentials) Rewrite(request []byte) []byte {
+	if c.ClientID != "" {
+		request = replaceContent(clientIDRe, request, c.ClientID)
+	}
+
+	if c.Password != "" {
+		request = replaceContent(redactedPasswordRe, request, c.Password)
+	}
+
+	if c.NewPassword != "" {
+		request = replaceContent(redactedNewPWRe, request, c.NewPassword)
+	}
+
+	if c.AuthInfo != "" {
+		// The whole content of authInfo is redacted so the pw element is
+		// added again with the same prefix as authInfo.
+		request = redactedAuthInfoRe.ReplaceAllFunc(request, func(m []byte) []byte {
+			groups := redactedAuthInfoRe.FindSubmatch(m)
+			prefix := string(groups[2])
+
+			return []byte(string(groups[1]) + "<" + prefix + "pw>" + escapeText(c.AuthInfo) + "</" + prefix + "pw>" + string(groups[3]))
+		})
+	}
+
+	return request
+}
+
+// replaceContent replaces the content between the first and second group of
+// each match of re with the escaped value.
+func replaceContent(re *regexp.Regexp, data []byte, value string) []byte {
+	return re.ReplaceAllFunc(data, func(m []byte) []byte {
+		groups := re.FindSubmatch(m)
+
+		return []byte(string(groups[1]) + escapeText(value) + string(groups[len(groups)-1]))
+	})
+}
+
+// escapeText returns s escaped to be used as the content of an element.
+func escapeText(s string) string {
+	var buf bytes.Buffer
+
+	_ = xml.EscapeText(&buf, []byte(s))
+
+	return buf.String()
+}
+
+// ReplayDifference describes a response from a replay not matching the
+// response in the transcript.
+type ReplayDifference struct {
+	// Index is the index of the entry in the transcript with the expected
+	// message.
+	Index int
+
+	Request  []byte
+	Expected []byte
+	Actual   []byte
+
+	// Diff is a line based diff between the normalized messages with each
+	// element on its own line. Removed lines are prefixed with - and added
+	// lines with +.
+	Diff string
+}
+
+// Replayer replays a transcript against a server and compares the responses
+// with the ones in the transcript.
+type Replayer struct {
+	// Normalize is used to normalize the messages before comparing them. If
+	// nil NormalizeMessage is used.
+	Normalize func([]byte) []byte
+
+	// KeepTimings will wait between each request as long as in the
+	// transcript.
+	KeepTimings bool
+
+	// MaxFrameSize is the maximum size of a frame accepted from the server.
+	// If zero DefaultMaxFrameSize is used.
+	MaxFrameSize int
+
+	// Rewrite is called with each request before it's sent if set. Since
+	// transcripts are redacted by default it can be used to restore the
+	// credentials, e.g. with ReplayCredentials.Rewrite.
+	Rewrite func([]byte) []byte
+}
+
+// Replay will read the greeting from rw and then send each request in the
+// transcript to rw and compare the responses with the following response in
+// the transcript. Responses in the transcript not following a request are
+// skipped. All differences are returned, including the greeting if it
+// differs. An error is returned if the communication with the server fails.
+func (r *Replayer) Replay(rw io.ReadWriter, entries []TranscriptEntry) ([]ReplayDifference, error) {
+	framer := NewFramer(rw)
+	framer.MaxFrameSize = r.MaxFrameSize
+
+	var (
+		differences []ReplayDifference
+		request     []byte
+		actual      []byte
+		started     = time.Now()
+		greeted     bool
+	)
+
+	for i, entry := range entries {
+		switch entry.Kind {
+		case TranscriptGreeting:
+			greeting, err := framer.ReadMessage()
+			if err != nil {
+				return differences, err
+			}
+
+			greeted = true
+
+			if diff, ok := r.compare(i, nil, entry.Data, greeting); !ok {
+				differences = append(differences, diff)
+			}
+		case TranscriptRequest:
+			if !greeted {
+				if _, err := framer.ReadMessage(); err != nil {
+					return differences, err
+				}
+
+				greeted = true
+			}
+
+			if r.KeepTimings {
+				time.Sleep(entry.Elapsed - time.Since(started))
+			}
+
+			request = entry.Data
+			if r.Rewrite != nil {
+				request = r.Rewrite(request)
+			}
+
+			if err := framer.WriteMessage(request); err != nil {
+				return differences, err
+			}
+
+			response, err := framer.ReadMessage()
+			if err != nil {
+				return differences, err
+			}
+
+			actual = response
+		case TranscriptResponse:
+			// A response without a request was sent by the server without
+			// being asked, e.g. the 2500 response when the server shuts
+			// down or the response to a frame that couldn't be read. What
+			// caused it isn't part of the transcript and can't be replayed
+			// so the response is skipped.
+			if actual == nil {
+				continue
+			}
+
+			if diff, ok := r.compare(i, request, entry.Data, actual); !ok {
+				differences = append(differences, diff)
+			}
+
+			request, actual = nil, nil
+		default:
+			return differences, fmt.Errorf("unknown transcript entry kind %q at index %d", entry.Kind, i)
+		}
+	}
+
+	return differences, nil
+}
+
+// compare returns the difference between the expected and actual message and
+// false if they differ after normalization.
+func (r *Replayer) compare(index int, request, expected, actual []byte) (ReplayDifference, bool) {
+	normalize := r.Normalize
+	if normalize == nil {
+		normalize = NormalizeMessage
+	}
+
+	normalizedExpected := normalize(expected)
+	normalizedActual := normalize(actual)
+
+	if bytes.Equal(normalizedExpected, normalizedActual) {
+		return ReplayDifference{}, true
+	}
+
+	return ReplayDifference{
+		Index:    index,
+		Request:  request,
+		Expected: expected,
+		Actual:   actual,
+		Diff:     diffLines(splitElements(normalizedExpected), splitElements(normalizedActual)),
+	}, false
+}
+
+// splitElements splits the message to lines with each tag on its own line.
+func splitElements(data []byte) []string {
+	s := strings.ReplaceAll(string(data), "><", ">\n<")
+
+	return strings.Split(s, "\n")
+}
+
+// diffLines returns a diff between a and b based on the longest common
+// subsequence. Unchanged lines are prefixed with two spaces, removed lines with
+// "- " and added lines with "+ ".
+func diffLines(a, b []string) string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var sb strings.Builder
+
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			sb.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case j >= len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+
+	return sb.String()
+}
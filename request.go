@@ -0,0 +1,156 @@
+package epp
+
+import (
+	"encoding/xml"
+	"reflect"
+	"sync"
+
+	"aqwari.net/xml/xmltree"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/google/uuid"
+)
+
+var (
+	requestTypesMu sync.RWMutex
+	requestTypes   = map[xml.Name]reflect.Type{}
+)
+
+func init() {
+	for name, v := range map[xml.Name]interface{}{
+		{Space: types.NameSpaceDomain, Local: "check"}:    types.DomainCheckType{},
+		{Space: types.NameSpaceDomain, Local: "create"}:   types.DomainCreateType{},
+		{Space: types.NameSpaceDomain, Local: "delete"}:   types.DomainDeleteType{},
+		{Space: types.NameSpaceDomain, Local: "info"}:     types.DomainInfoType{},
+		{Space: types.NameSpaceDomain, Local: "renew"}:    types.DomainRenewType{},
+		{Space: types.NameSpaceDomain, Local: "transfer"}: types.DomainTransferType{},
+		{Space: types.NameSpaceDomain, Local: "update"}:   types.DomainUpdateType{},
+		{Space: types.NameSpaceHost, Local: "check"}:      types.HostCheckType{},
+		{Space: types.NameSpaceHost, Local: "create"}:     types.HostCreateType{},
+		{Space: types.NameSpaceHost, Local: "delete"}:     types.HostDeleteType{},
+		{Space: types.NameSpaceHost, Local: "info"}:       types.HostInfoType{},
+		{Space: types.NameSpaceHost, Local: "update"}:     types.HostUpdateType{},
+		{Space: types.NameSpaceContact, Local: "create"}:  types.ContactCreateType{},
+		{Space: types.NameSpaceContact, Local: "update"}:  types.ContactUpdateType{},
+		{Space: types.NameSpaceDNSSEC11, Local: "create"}: types.DNSSECExtensionCreateType{},
+		{Space: types.NameSpaceDNSSEC11, Local: "update"}: types.DNSSECExtensionUpdateType{},
+	} {
+		RegisterRequestType(name.Space, name.Local, v)
+	}
+}
+
+// RegisterRequestType will register the type of v to be used when validating
+// values for an element with the namespace and local name in a command or in
+// the extension tag of a command. The whole request is decoded to the type so
+// the type must define the full path from the epp tag. A pointer to the type
+// must implement Validate() error to be used by ValidateValues. Registering a
+// type for an already registered element will replace the previous type.
+//
+//	RegisterRequestType("urn:ietf:params:xml:ns:domain-1.0", "create", types.DomainCreateType{})
+func RegisterRequestType(ns, local string, v interface{}) {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	requestTypesMu.Lock()
+	defer requestTypesMu.Unlock()
+
+	requestTypes[xml.Name{Space: ns, Local: local}] = t
+}
+
+// ValidateValues is a middleware validating the values in each command. The
+// command is decoded to the types registered with RegisterRequestType for the
+// command and each extension and their Validate method is called. If any
+// value isn't valid a response with the result code 2004 or 2005 for each
+// value is returned without calling next. Messages that can't be decoded are
+// passed to next as is.
+//
+//	config := epp.SessionConfig{
+//	    Handler: epp.ValidateValues(mux.Handle),
+//	}
+func ValidateValues(next HandlerFunc) HandlerFunc {
+	return func(s *Session, data []byte) ([]byte, error) {
+		valueErrors := validateValues(data)
+		if len(valueErrors) == 0 {
+			return next(s, data)
+		}
+
+		response := CreateValueErrorResponse(data, valueErrors)
+		response.TransactionID.ServerTransactionID = uuid.New().String()
+
+		return Encode(response, ServerXMLAttributes())
+	}
+}
+
+// validateValues returns all value errors for the registered types matching
+// the elements in the command.
+func validateValues(data []byte) types.ValueErrors {
+	root, err := xmltree.Parse(data)
+	if err != nil {
+		return nil
+	}
+
+	var valueErrors types.ValueErrors
+
+	for _, name := range commandElements(root) {
+		requestTypesMu.RLock()
+		t, ok := requestTypes[name]
+		requestTypesMu.RUnlock()
+
+		if !ok {
+			continue
+		}
+
+		v := reflect.New(t).Interface()
+
+		validator, ok := v.(interface{ Validate() error })
+		if !ok {
+			continue
+		}
+
+		if err := Decode(data, v); err != nil {
+			continue
+		}
+
+		if errs, ok := validator.Validate().(types.ValueErrors); ok {
+			valueErrors = append(valueErrors, errs...)
+		}
+	}
+
+	return valueErrors
+}
+
+// commandElements returns the names of the object elements in a command, that
+// is the child of the command tag such as domain:create and each child of the
+// extension tag.
+func commandElements(root *xmltree.Element) []xml.Name {
+	if root.Name.Space != nsEPP || root.Name.Local != "epp" || len(root.Children) != 1 {
+		return nil
+	}
+
+	command := root.Children[0]
+	if command.Name.Local != "command" {
+		return nil
+	}
+
+	var names []xml.Name
+
+	for _, child := range command.Children {
+		switch child.Name.Local {
+		case "clTRID":
+			continue
+		case "extension":
+			for _, extension := range child.Children {
+				names = append(names, extension.Name)
+			}
+
+			continue
+		}
+
+		if len(child.Children) > 0 {
+			names = append(names, child.Children[0].Name)
+		}
+	}
+
+	return names
+}
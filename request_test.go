@@ -0,0 +1,252 @@
+package epp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateValues_commands(t *testing.T) {
+	files, err := filepath.Glob("xml/commands/*.xml")
+	require.Nil(t, err)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			require.Nil(t, err)
+
+			assert.Empty(t, validateValues(data))
+		})
+	}
+}
+
+func TestValidateValues(t *testing.T) {
+	command := func(object string) []byte {
+		return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    %s
+    <clTRID>ABC-12345</clTRID>
+  </command>
+</epp>`, object))
+	}
+
+	domainCheck := func(name string) []byte {
+		return command(fmt.Sprintf(`<check>
+      <domain:check xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>%s</domain:name>
+      </domain:check>
+    </check>`, name))
+	}
+
+	longLabel := strings.Repeat("a", 64)
+	longName := strings.Repeat(strings.Repeat("a", 60)+".", 5) + "se"
+
+	cases := []struct {
+		description string
+		xml         []byte
+		expected    types.ValueErrors
+	}{
+		{
+			description: "valid names",
+			xml:         domainCheck("example.se"),
+		},
+		{
+			description: "valid A-label",
+			xml:         domainCheck("xn--rksmrgs-5wao1o.se"),
+		},
+		{
+			description: "label too long",
+			xml:         domainCheck(longLabel + ".se"),
+			expected: types.ValueErrors{
+				{
+					Element:    xml.Name{Space: types.NameSpaceDomain, Local: "name"},
+					Value:      longLabel + ".se",
+					Reason:     fmt.Sprintf("label %q is longer than 63 characters", longLabel),
+					OutOfRange: true,
+				},
+			},
+		},
+		{
+			description: "name too long",
+			xml:         domainCheck(longName),
+			expected: types.ValueErrors{
+				{
+					Element:    xml.Name{Space: types.NameSpaceDomain, Local: "name"},
+					Value:      longName,
+					Reason:     "name is longer than 253 characters",
+					OutOfRange: true,
+				},
+			},
+		},
+		{
+			description: "invalid A-label",
+			xml:         domainCheck("xn--zz.se"),
+			expected: types.ValueErrors{
+				{
+					Element: xml.Name{Space: types.NameSpaceDomain, Local: "name"},
+					Value:   "xn--zz.se",
+					Reason:  `label "xn--zz" isn't a valid IDNA A-label`,
+				},
+			},
+		},
+		{
+			description: "invalid characters",
+			xml:         domainCheck("exa_mple.se"),
+			expected: types.ValueErrors{
+				{
+					Element: xml.Name{Space: types.NameSpaceDomain, Local: "name"},
+					Value:   "exa_mple.se",
+					Reason:  `label "exa_mple" contains characters other than letters, digits and hyphen`,
+				},
+			},
+		},
+		{
+			description: "host address mismatch",
+			xml: command(`<create>
+      <host:create xmlns:host="urn:ietf:params:xml:ns:host-1.0">
+        <host:name>ns1.example.se</host:name>
+        <host:addr ip="v4">2001:db8::1</host:addr>
+      </host:create>
+    </create>`),
+			expected: types.ValueErrors{
+				{
+					Element: xml.Name{Space: types.NameSpaceHost, Local: "addr"},
+					Value:   "2001:db8::1",
+					Reason:  "IPv6 address with ip set to v4",
+				},
+			},
+		},
+		{
+			description: "contact with invalid country code and phone number",
+			xml: command(`<create>
+      <contact:create xmlns:contact="urn:ietf:params:xml:ns:contact-1.0">
+        <contact:id>sh8013</contact:id>
+        <contact:postalInfo type="int">
+          <contact:name>John Doe</contact:name>
+          <contact:addr>
+            <contact:city>Dulles</contact:city>
+            <contact:cc>XX</contact:cc>
+          </contact:addr>
+        </contact:postalInfo>
+        <contact:voice>+46.70355599991234</contact:voice>
+        <contact:fax>+0.7035555555</contact:fax>
+        <contact:email>jdoe@example.com</contact:email>
+        <contact:authInfo>
+          <contact:pw>2fooBAR</contact:pw>
+        </contact:authInfo>
+      </contact:create>
+    </create>`),
+			expected: types.ValueErrors{
+				{
+					Element: xml.Name{Space: types.NameSpaceContact, Local: "cc"},
+					Value:   "XX",
+					Reason:  "not an ISO 3166-1 alpha-2 country code",
+				},
+				{
+					Element:    xml.Name{Space: types.NameSpaceContact, Local: "voice"},
+					Value:      "+46.70355599991234",
+					Reason:     "number has more than 15 digits",
+					OutOfRange: true,
+				},
+				{
+					Element: xml.Name{Space: types.NameSpaceContact, Local: "fax"},
+					Value:   "+0.7035555555",
+					Reason:  "country code can't start with 0",
+				},
+			},
+		},
+		{
+			description: "DS digest length",
+			xml: command(`<create>
+      <domain:create xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.se</domain:name>
+        <domain:authInfo>
+          <domain:pw>2fooBAR</domain:pw>
+        </domain:authInfo>
+      </domain:create>
+    </create>
+    <extension>
+      <secDNS:create xmlns:secDNS="urn:ietf:params:xml:ns:secDNS-1.1">
+        <secDNS:dsData>
+          <secDNS:keyTag>12345</secDNS:keyTag>
+          <secDNS:alg>8</secDNS:alg>
+          <secDNS:digestType>2</secDNS:digestType>
+          <secDNS:digest>49FD46E6C4B45C55D4AC</secDNS:digest>
+        </secDNS:dsData>
+      </secDNS:create>
+    </extension>`),
+			expected: types.ValueErrors{
+				{
+					Element: xml.Name{Space: types.NameSpaceDNSSEC11, Local: "digest"},
+					Value:   "49FD46E6C4B45C55D4AC",
+					Reason:  "digest for digest type 2 must be 64 hex characters, got 20",
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expected, validateValues(tc.xml))
+		})
+	}
+}
+
+func TestValidateValues_middleware(t *testing.T) {
+	validator, err := NewDefaultValidator()
+	require.Nil(t, err)
+
+	defer validator.Free()
+
+	called := false
+	handler := ValidateValues(func(s *Session, data []byte) ([]byte, error) {
+		called = true
+
+		return nil, nil
+	})
+
+	request := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <create>
+      <host:create xmlns:host="urn:ietf:params:xml:ns:host-1.0">
+        <host:name>ns1.example-.se</host:name>
+        <host:addr ip="v6">192.0.2.1</host:addr>
+      </host:create>
+    </create>
+    <clTRID>ABC-12345</clTRID>
+  </command>
+</epp>`)
+
+	require.Nil(t, validator.Validate(request))
+
+	data, err := handler(nil, request)
+	require.Nil(t, err)
+	assert.False(t, called)
+	assert.Nil(t, validator.Validate(data))
+
+	response, err := DecodeResponse(data)
+	require.Nil(t, err)
+
+	require.Len(t, response.Result, 2)
+	assert.Equal(t, EppParamSyntaxError.Code(), response.Result[0].Code)
+	assert.Equal(t, `label "example-" starts or ends with a hyphen`, response.Result[0].ExternalValue.Reason)
+	assert.Equal(t, EppParamSyntaxError.Code(), response.Result[1].Code)
+	assert.Equal(t, "IPv4 address with ip set to v6", response.Result[1].ExternalValue.Reason)
+	assert.Equal(t, "ABC-12345", response.TransactionID.ClientTransactionID)
+
+	valid, err := os.ReadFile("xml/commands/create-host.xml")
+	require.Nil(t, err)
+
+	_, err = handler(nil, valid)
+	require.Nil(t, err)
+	assert.True(t, called)
+}
@@ -0,0 +1,285 @@
+package epp
+
+import (
+	"encoding/xml"
+	"reflect"
+	"sync"
+
+	"aqwari.net/xml/xmltree"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/pkg/errors"
+)
+
+var (
+	responseTypesMu sync.RWMutex
+	responseTypes   = map[xml.Name]reflect.Type{}
+)
+
+func init() {
+	for name, v := range map[xml.Name]interface{}{
+		{Space: types.NameSpaceDomain, Local: "chkData"}:   types.DomainCheckData{},
+		{Space: types.NameSpaceDomain, Local: "creData"}:   types.DomainCreateData{},
+		{Space: types.NameSpaceDomain, Local: "infData"}:   types.DomainInfoData{},
+		{Space: types.NameSpaceDomain, Local: "panData"}:   types.DomainPendingActivationNotificationData{},
+		{Space: types.NameSpaceDomain, Local: "renData"}:   types.DomainRenewData{},
+		{Space: types.NameSpaceDomain, Local: "trnData"}:   types.DomainTransferData{},
+		{Space: types.NameSpaceContact, Local: "chkData"}:  types.ContactCheckData{},
+		{Space: types.NameSpaceContact, Local: "creData"}:  types.ContactCreateData{},
+		{Space: types.NameSpaceContact, Local: "infData"}:  types.ContactInfoData{},
+		{Space: types.NameSpaceContact, Local: "panData"}:  types.ContactPendingActivationNotificationData{},
+		{Space: types.NameSpaceContact, Local: "trnData"}:  types.ContactTransferData{},
+		{Space: types.NameSpaceHost, Local: "chkData"}:     types.HostCheckData{},
+		{Space: types.NameSpaceHost, Local: "creData"}:     types.HostCreateData{},
+		{Space: types.NameSpaceHost, Local: "infData"}:     types.HostInfoData{},
+		{Space: types.NameSpaceDNSSEC11, Local: "infData"}: types.DNSSECOrKeyData{},
+		{Space: types.NameSpaceRGP10, Local: "infData"}:    types.RGPExtensionData{},
+		{Space: types.NameSpaceRGP10, Local: "upData"}:     types.RGPExtensionData{},
+		{Space: types.NameSpaceIIS12, Local: "infData"}:    types.IISExtensionInfoData{},
+	} {
+		RegisterResponseType(name.Space, name.Local, v)
+	}
+}
+
+// RegisterResponseType will register the type of v to be used when decoding an
+// element with the namespace and local name in the resData or extension tag of
+// a response. Registering a type for an already registered element will
+// replace the previous type.
+//
+//	RegisterResponseType("urn:ietf:params:xml:ns:domain-1.0", "infData", types.DomainInfoData{})
+func RegisterResponseType(ns, local string, v interface{}) {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	responseTypesMu.Lock()
+	defer responseTypesMu.Unlock()
+
+	responseTypes[xml.Name{Space: ns, Local: local}] = t
+}
+
+// ResponseElement represents a child element of the resData or extension tag
+// in a response.
+type ResponseElement struct {
+	// Name is the namespace and local name of the element.
+	Name xml.Name
+
+	// Value holds a pointer to the registered type for the element with the
+	// element decoded. If no type is registered for the element the value is
+	// nil.
+	Value interface{}
+
+	// Raw holds the raw XML for the element.
+	Raw []byte
+}
+
+// DecodedResponse represents an EPP response where the resData and extension
+// elements are decoded to their registered types.
+type DecodedResponse struct {
+	Result        []types.Result
+	MessageQ      *types.MessageQueue
+	TransactionID types.TransactionID
+	ResultData    []ResponseElement
+	Extension     []ResponseElement
+}
+
+// DecodeResponse will decode an EPP response. Each child to the resData and
+// extension tags are decoded to the type registered with RegisterResponseType.
+// Elements without a registered type are kept as raw XML.
+func DecodeResponse(data []byte) (*DecodedResponse, error) {
+	response := types.Response{}
+
+	if err := Decode(data, &response); err != nil {
+		return nil, err
+	}
+
+	document, err := xmltree.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if document.Name.Space != types.NameSpaceEPP10 || document.Name.Local != rootLocalName {
+		return nil, errors.New("missing <epp> tag")
+	}
+
+	decoded := &DecodedResponse{
+		Result:        response.Result,
+		MessageQ:      response.MessageQ,
+		TransactionID: response.TransactionID,
+	}
+
+	responseElements := findElements(document, types.NameSpaceEPP10, []string{"response"})
+	if len(responseElements) != 1 {
+		return nil, errors.New("<epp> should contain one <response> element")
+	}
+
+	for i := range responseElements[0].Children {
+		child := &responseElements[0].Children[i]
+
+		switch child.Name.Local {
+		case "resData":
+			decoded.ResultData, err = decodeResponseElements(child)
+		case "extension":
+			decoded.Extension, err = decodeResponseElements(child)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return decoded, nil
+}
+
+func decodeResponseElements(parent *xmltree.Element) ([]ResponseElement, error) {
+	elements := []ResponseElement{}
+
+	responseTypesMu.RLock()
+	defer responseTypesMu.RUnlock()
+
+	for i := range parent.Children {
+		child := &parent.Children[i]
+
+		element := ResponseElement{
+			Name: child.Name,
+			Raw:  xmltree.Marshal(child),
+		}
+
+		if t, ok := responseTypes[child.Name]; ok {
+			v := reflect.New(t).Interface()
+
+			if err := xml.Unmarshal(element.Raw, v); err != nil {
+				return nil, errors.Wrapf(err, "could not decode %s %s", child.Name.Space, child.Name.Local)
+			}
+
+			element.Value = v
+		}
+
+		elements = append(elements, element)
+	}
+
+	return elements, nil
+}
+
+// Code returns the result code for the first result in the response.
+func (r *DecodedResponse) Code() ResultCode {
+	if len(r.Result) == 0 {
+		return 0
+	}
+
+	return ResultCode(r.Result[0].Code)
+}
+
+// ResultDataFor returns the decoded value for the element with the namespace
+// and local name in resData. If no such element exist, nil is returned.
+func (r *DecodedResponse) ResultDataFor(ns, local string) interface{} {
+	return findResponseElement(r.ResultData, ns, local)
+}
+
+// ExtensionFor returns the decoded value for the element with the namespace
+// and local name in extension. If no such element exist, nil is returned.
+func (r *DecodedResponse) ExtensionFor(ns, local string) interface{} {
+	return findResponseElement(r.Extension, ns, local)
+}
+
+func findResponseElement(elements []ResponseElement, ns, local string) interface{} {
+	for _, element := range elements {
+		if element.Name.Space == ns && element.Name.Local == local {
+			return element.Value
+		}
+	}
+
+	return nil
+}
+
+// DomainCheckData returns the domain check data in the response or nil.
+func (r *DecodedResponse) DomainCheckData() *types.DomainCheckData {
+	v, _ := r.ResultDataFor(types.NameSpaceDomain, "chkData").(*types.DomainCheckData)
+	return v
+}
+
+// DomainCreateData returns the domain create data in the response or nil.
+func (r *DecodedResponse) DomainCreateData() *types.DomainCreateData {
+	v, _ := r.ResultDataFor(types.NameSpaceDomain, "creData").(*types.DomainCreateData)
+	return v
+}
+
+// DomainInfoData returns the domain info data in the response or nil.
+func (r *DecodedResponse) DomainInfoData() *types.DomainInfoData {
+	v, _ := r.ResultDataFor(types.NameSpaceDomain, "infData").(*types.DomainInfoData)
+	return v
+}
+
+// DomainRenewData returns the domain renew data in the response or nil.
+func (r *DecodedResponse) DomainRenewData() *types.DomainRenewData {
+	v, _ := r.ResultDataFor(types.NameSpaceDomain, "renData").(*types.DomainRenewData)
+	return v
+}
+
+// DomainTransferData returns the domain transfer data in the response or nil.
+func (r *DecodedResponse) DomainTransferData() *types.DomainTransferData {
+	v, _ := r.ResultDataFor(types.NameSpaceDomain, "trnData").(*types.DomainTransferData)
+	return v
+}
+
+// ContactCheckData returns the contact check data in the response or nil.
+func (r *DecodedResponse) ContactCheckData() *types.ContactCheckData {
+	v, _ := r.ResultDataFor(types.NameSpaceContact, "chkData").(*types.ContactCheckData)
+	return v
+}
+
+// ContactCreateData returns the contact create data in the response or nil.
+func (r *DecodedResponse) ContactCreateData() *types.ContactCreateData {
+	v, _ := r.ResultDataFor(types.NameSpaceContact, "creData").(*types.ContactCreateData)
+	return v
+}
+
+// ContactInfoData returns the contact info data in the response or nil.
+func (r *DecodedResponse) ContactInfoData() *types.ContactInfoData {
+	v, _ := r.ResultDataFor(types.NameSpaceContact, "infData").(*types.ContactInfoData)
+	return v
+}
+
+// ContactTransferData returns the contact transfer data in the response or
+// nil.
+func (r *DecodedResponse) ContactTransferData() *types.ContactTransferData {
+	v, _ := r.ResultDataFor(types.NameSpaceContact, "trnData").(*types.ContactTransferData)
+	return v
+}
+
+// HostCheckData returns the host check data in the response or nil.
+func (r *DecodedResponse) HostCheckData() *types.HostCheckData {
+	v, _ := r.ResultDataFor(types.NameSpaceHost, "chkData").(*types.HostCheckData)
+	return v
+}
+
+// HostCreateData returns the host create data in the response or nil.
+func (r *DecodedResponse) HostCreateData() *types.HostCreateData {
+	v, _ := r.ResultDataFor(types.NameSpaceHost, "creData").(*types.HostCreateData)
+	return v
+}
+
+// HostInfoData returns the host info data in the response or nil.
+func (r *DecodedResponse) HostInfoData() *types.HostInfoData {
+	v, _ := r.ResultDataFor(types.NameSpaceHost, "infData").(*types.HostInfoData)
+	return v
+}
+
+// DNSSECInfoData returns the secDNS-1.1 info data extension in the response or
+// nil.
+func (r *DecodedResponse) DNSSECInfoData() *types.DNSSECOrKeyData {
+	v, _ := r.ExtensionFor(types.NameSpaceDNSSEC11, "infData").(*types.DNSSECOrKeyData)
+	return v
+}
+
+// RGPInfoData returns the rgp-1.0 info data extension in the response or nil.
+func (r *DecodedResponse) RGPInfoData() *types.RGPExtensionData {
+	v, _ := r.ExtensionFor(types.NameSpaceRGP10, "infData").(*types.RGPExtensionData)
+	return v
+}
+
+// IISInfoData returns the iis-1.2 info data extension in the response or nil.
+func (r *DecodedResponse) IISInfoData() *types.IISExtensionInfoData {
+	v, _ := r.ExtensionFor(types.NameSpaceIIS12, "infData").(*types.IISExtensionInfoData)
+	return v
+}
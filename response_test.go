@@ -0,0 +1,117 @@
+package epp
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeResponse(t *testing.T) {
+	x := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <response>
+    <result code="1000">
+      <msg>Command completed successfully</msg>
+    </result>
+    <resData>
+      <domain:infData xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.se</domain:name>
+        <domain:roid>DOMAIN_0000000000-SE</domain:roid>
+        <domain:status s="ok"/>
+        <domain:clID>Some Client</domain:clID>
+      </domain:infData>
+    </resData>
+    <extension>
+      <secDNS:infData xmlns:secDNS="urn:ietf:params:xml:ns:secDNS-1.1">
+        <secDNS:dsData>
+          <secDNS:keyTag>12345</secDNS:keyTag>
+          <secDNS:alg>8</secDNS:alg>
+          <secDNS:digestType>2</secDNS:digestType>
+          <secDNS:digest>49FD46E6C4B45C55D4AC</secDNS:digest>
+        </secDNS:dsData>
+      </secDNS:infData>
+      <rgp:infData xmlns:rgp="urn:ietf:params:xml:ns:rgp-1.0">
+        <rgp:rgpStatus s="addPeriod"/>
+      </rgp:infData>
+      <unknown:infData xmlns:unknown="urn:example:unknown-1.0">
+        <unknown:value>some-value</unknown:value>
+      </unknown:infData>
+    </extension>
+    <trID>
+      <clTRID>ABC-12345</clTRID>
+      <svTRID>54321-XYZ</svTRID>
+    </trID>
+  </response>
+</epp>`)
+
+	response, err := DecodeResponse(x)
+	require.Nil(t, err)
+
+	assert.Equal(t, EppOk, response.Code())
+	assert.Equal(t, "ABC-12345", response.TransactionID.ClientTransactionID)
+	assert.Equal(t, "54321-XYZ", response.TransactionID.ServerTransactionID)
+
+	domainInfo := response.DomainInfoData()
+	require.NotNil(t, domainInfo)
+	assert.Equal(t, "example.se", domainInfo.Name)
+	assert.Equal(t, "DOMAIN_0000000000-SE", domainInfo.ROID)
+	assert.Equal(t, types.DomainStatusOk, domainInfo.Status[0].DomainStatusType)
+
+	assert.Nil(t, response.ContactInfoData())
+	assert.Nil(t, response.IISInfoData())
+
+	dnssec := response.DNSSECInfoData()
+	require.NotNil(t, dnssec)
+	require.Len(t, dnssec.DNSSECData, 1)
+	assert.Equal(t, uint(12345), dnssec.DNSSECData[0].KeyTag)
+
+	rgp := response.RGPInfoData()
+	require.NotNil(t, rgp)
+	assert.Equal(t, types.RGPStatusAddPeriod, rgp.Status[0].RGPStatusType)
+
+	require.Len(t, response.Extension, 3)
+
+	unknown := response.Extension[2]
+	assert.Equal(t, xml.Name{Space: "urn:example:unknown-1.0", Local: "infData"}, unknown.Name)
+	assert.Nil(t, unknown.Value)
+	assert.Contains(t, string(unknown.Raw), "some-value")
+}
+
+func TestDecodeResponseRegisteredType(t *testing.T) {
+	type customData struct {
+		Value string `xml:"value"`
+	}
+
+	RegisterResponseType("urn:example:custom-1.0", "infData", customData{})
+
+	x := []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <response>
+    <result code="1000">
+      <msg>Command completed successfully</msg>
+    </result>
+    <extension>
+      <custom:infData xmlns:custom="urn:example:custom-1.0">
+        <custom:value>some-value</custom:value>
+      </custom:infData>
+    </extension>
+    <trID>
+      <svTRID>54321-XYZ</svTRID>
+    </trID>
+  </response>
+</epp>`)
+
+	response, err := DecodeResponse(x)
+	require.Nil(t, err)
+
+	custom, ok := response.ExtensionFor("urn:example:custom-1.0", "infData").(*customData)
+	require.True(t, ok)
+	assert.Equal(t, "some-value", custom.Value)
+}
+
+func TestDecodeResponseNoResponse(t *testing.T) {
+	_, err := DecodeResponse([]byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><hello/></epp>`))
+	require.NotNil(t, err)
+}
@@ -1,8 +1,10 @@
 package epp
 
 import (
+	"encoding/xml"
 	"fmt"
 
+	"aqwari.net/xml/xmltree"
 	"github.com/bombsimon/epp-go/types"
 )
 
@@ -160,3 +162,143 @@ func CreateErrorResponse(code ResultCode, reason string) types.Response {
 		},
 	}
 }
+
+// CreateValidationErrorResponse will create a response with the result code
+// 2001 for a document that failed validation. Each ValidationError in err is
+// added as a result with a copy of the offending element from the document in
+// the value and the message from the validator as reason. If err isn't
+// ValidationErrors, e.g. if the document isn't well formed, a single result
+// without any value is returned.
+func CreateValidationErrorResponse(document []byte, err error) types.Response {
+	response := types.Response{}
+
+	xErrors, ok := err.(ValidationErrors)
+	if !ok || len(xErrors) == 0 {
+		response.Result = []types.Result{
+			{
+				Code:    EppSyntaxError.Code(),
+				Message: EppSyntaxError.Message(),
+			},
+		}
+
+		return response
+	}
+
+	root, parseErr := xmltree.Parse(document)
+
+	for _, e := range xErrors {
+		result := types.Result{
+			Code:    EppSyntaxError.Code(),
+			Message: EppSyntaxError.Message(),
+		}
+
+		var el *xmltree.Element
+		if parseErr == nil {
+			el = findByPath(root, e.Path)
+		}
+
+		if el != nil {
+			result.ExternalValue = &types.ExternalErrorValue{
+				Value:  offendingElement(el),
+				Reason: e.Message,
+			}
+		}
+
+		response.Result = append(response.Result, result)
+	}
+
+	if parseErr == nil {
+		if clTRID := root.Search(types.NameSpaceEPP10, "clTRID"); len(clTRID) > 0 {
+			response.TransactionID.ClientTransactionID = string(clTRID[0].Content)
+		}
+	}
+
+	return response
+}
+
+// CreateValueErrorResponse will create a response for a request holding values
+// that failed semantic validation. Each types.ValueError in err is added as a
+// result with the code 2004 if the value is out of range or 2005 if the value
+// has an invalid syntax. The client transaction ID is copied from the document.
+func CreateValueErrorResponse(document []byte, err types.ValueErrors) types.Response {
+	response := types.Response{}
+
+	for _, e := range err {
+		code := EppParamSyntaxError
+		if e.OutOfRange {
+			code = EppParamRangeError
+		}
+
+		response.Result = append(response.Result, types.Result{
+			Code:          code.Code(),
+			Message:       code.Message(),
+			ExternalValue: NewExternalErrorValue(e.Element, e.Value, e.Reason),
+		})
+	}
+
+	response.TransactionID.ClientTransactionID = ClientTransactionID(document)
+
+	return response
+}
+
+// NewExternalErrorValue creates the value of a result for the element with
+// the offending value and the reason the value caused the error.
+func NewExternalErrorValue(element xml.Name, value, reason string) *types.ExternalErrorValue {
+	return &types.ExternalErrorValue{
+		Value: errorValue{
+			Element: valueElement{
+				XMLName: element,
+				Content: value,
+			},
+		},
+		Reason: reason,
+	}
+}
+
+// ClientTransactionID returns the client transaction ID from the document or
+// an empty string if the document has no client transaction ID.
+func ClientTransactionID(document []byte) string {
+	return summarizeCommand(document).clientTransactionID
+}
+
+// errorValue holds the element added to a value element.
+type errorValue struct {
+	Element valueElement
+}
+
+// valueElement represents a copy of an element from a document.
+type valueElement struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",chardata"`
+}
+
+// offendingElement returns a copy of the element without any children to use
+// as value in a result. The content is kept for elements without children.
+func offendingElement(el *xmltree.Element) errorValue {
+	value := valueElement{
+		XMLName: el.Name,
+	}
+
+	// Elements in the EPP namespace uses the default namespace from the
+	// response.
+	if value.XMLName.Space == types.NameSpaceEPP10 {
+		value.XMLName.Space = ""
+	}
+
+	for _, attr := range el.StartElement.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+
+		value.Attrs = append(value.Attrs, attr)
+	}
+
+	if len(el.Children) == 0 {
+		value.Content = string(el.Content)
+	}
+
+	return errorValue{
+		Element: value,
+	}
+}
@@ -0,0 +1,147 @@
+package epp
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sync"
+
+	"aqwari.net/xml/xmltree"
+	"github.com/pkg/errors"
+)
+
+const (
+	schemaRootName = "index.xsd"
+	nsXSD          = "http://www.w3.org/2001/XMLSchema"
+)
+
+// bundledSchemas holds all the XSD schemas bundled with the library.
+//
+//go:embed xml/*.xsd
+var bundledSchemas embed.FS
+
+// schemaImport represents a schema imported by the generated root schema.
+type schemaImport struct {
+	namespace string
+	name      string
+}
+
+// SchemaSet holds a set of XSD schemas in memory. A validator created from the
+// set will resolve all includes and imports from the set so no schemas are
+// read from disk.
+type SchemaSet struct {
+	mu      sync.RWMutex
+	schemas map[string][]byte
+	imports []schemaImport
+}
+
+// NewSchemaSet will create a new schema set holding all the schemas bundled
+// with the library.
+func NewSchemaSet() (*SchemaSet, error) {
+	s := &SchemaSet{
+		schemas: map[string][]byte{},
+		imports: []schemaImport{},
+	}
+
+	entries, err := bundledSchemas.ReadDir("xml")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		data, err := bundledSchemas.ReadFile(path.Join("xml", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		s.schemas[entry.Name()] = data
+	}
+
+	// Keep the order from the bundled root schema since schemas importing a
+	// namespace without a schema location depends on it being imported
+	// before.
+	root, err := xmltree.Parse(s.schemas[schemaRootName])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, el := range root.Search(nsXSD, "import") {
+		s.imports = append(s.imports, schemaImport{
+			namespace: el.Attr("", "namespace"),
+			name:      el.Attr("", "schemaLocation"),
+		})
+	}
+
+	delete(s.schemas, schemaRootName)
+
+	return s, nil
+}
+
+// Add will add an XSD schema to the set. The name is the schema location used
+// when other schemas include or import it. If the schema has a target
+// namespace it will be imported by the root schema used for validation, after
+// all the schemas already in the set.
+func (s *SchemaSet) Add(name string, xsd []byte) error {
+	root, err := xmltree.Parse(xsd)
+	if err != nil {
+		return errors.Wrapf(err, "could not parse schema %s", name)
+	}
+
+	if root.Name.Space != nsXSD || root.Name.Local != "schema" {
+		return errors.Errorf("%s is not an XSD schema", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.schemas[name]; !ok {
+		if ns := root.Attr("", "targetNamespace"); ns != "" {
+			s.imports = append(s.imports, schemaImport{
+				namespace: ns,
+				name:      name,
+			})
+		}
+	}
+
+	s.schemas[name] = xsd
+
+	return nil
+}
+
+// Schema returns the schema with the given name and true if it's found in the
+// set.
+func (s *SchemaSet) Schema(name string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if name == schemaRootName {
+		return s.rootSchema(), true
+	}
+
+	xsd, ok := s.schemas[name]
+
+	return xsd, ok
+}
+
+// rootSchema returns a schema importing all schemas in the set that has a
+// target namespace.
+func (s *SchemaSet) rootSchema() []byte {
+	root := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<schema xmlns="%s" elementFormDefault="qualified">`+"\n", nsXSD)
+
+	for _, i := range s.imports {
+		root += fmt.Sprintf(`  <import namespace="%s" schemaLocation="%s"/>`+"\n", i.namespace, i.name)
+	}
+
+	return []byte(root + "</schema>\n")
+}
+
+// NewDefaultValidator creates a validator using all the schemas bundled with
+// the library. No files are read from disk.
+func NewDefaultValidator() (*XMLValidator, error) {
+	schemas, err := NewSchemaSet()
+	if err != nil {
+		return nil, err
+	}
+
+	return schemas.NewValidator()
+}
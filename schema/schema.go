@@ -0,0 +1,763 @@
+// Package schema implements validation of XML documents towards XSD schemas in
+// pure Go. Only the subset of XSD used by the EPP schemas is supported; element
+// declarations and references, sequences, choices, wildcards, occurrence
+// constraints, attributes, simple and complex content derivation and the
+// enumeration, pattern, length and inclusive range facets.
+package schema
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"aqwari.net/xml/xmltree"
+)
+
+const (
+	nsXSD = "http://www.w3.org/2001/XMLSchema"
+	nsXSI = "http://www.w3.org/2001/XMLSchema-instance"
+	nsXML = "http://www.w3.org/XML/1998/namespace"
+
+	unbounded = -1
+)
+
+// Loader returns the content of the schema found at location. The location is
+// the path to the root schema or the schema location of an import or include,
+// relative to the location of the schema importing it.
+type Loader func(location string) ([]byte, error)
+
+// Schema represents a set of parsed XSD schemas that documents can be validated
+// towards. A Schema is read only after being parsed and is safe to use from
+// multiple goroutines.
+type Schema struct {
+	elements     map[xml.Name]*element
+	complexTypes map[xml.Name]*complexType
+	simpleTypes  map[xml.Name]*simpleType
+	loaded       map[string]struct{}
+
+	// All declarations, including anonymous and local ones, that needs
+	// their references resolved after all schemas are loaded.
+	allElements     []*element
+	allComplexTypes []*complexType
+	allSimpleTypes  []*simpleType
+	allAttributes   []*attribute
+}
+
+// schemaDocument holds the properties for a single XSD document being parsed.
+type schemaDocument struct {
+	targetNamespace string
+	qualified       bool
+}
+
+// particle is a part of a content model; an element, a wildcard or a group.
+type particle interface {
+	occurs() (int, int)
+}
+
+type occurrence struct {
+	min int
+	max int
+}
+
+func (o occurrence) occurs() (int, int) {
+	return o.min, o.max
+}
+
+// element represents an element declaration or reference.
+type element struct {
+	occurrence
+
+	name     xml.Name
+	typeName xml.Name
+	ref      xml.Name
+
+	// Resolved references.
+	refElement  *element
+	complexType *complexType
+	simpleType  *simpleType
+}
+
+// declaration returns the declaration for the element, following references.
+func (e *element) declaration() *element {
+	if e.refElement != nil {
+		return e.refElement
+	}
+
+	return e
+}
+
+// group represents a sequence or a choice.
+type group struct {
+	occurrence
+
+	choice    bool
+	particles []particle
+}
+
+// wildcard represents an any element.
+type wildcard struct {
+	occurrence
+
+	namespace       string
+	targetNamespace string
+	processContents string
+}
+
+// attribute represents an attribute declaration.
+type attribute struct {
+	name       string
+	typeName   xml.Name
+	required   bool
+	simpleType *simpleType
+}
+
+// complexType represents a complex type definition.
+type complexType struct {
+	name          xml.Name
+	mixed         bool
+	content       *group
+	attributes    []*attribute
+	anyAttribute  bool
+	base          xml.Name
+	extension     bool
+	simpleContent bool
+
+	// simpleRestriction holds the facets when restricting a type with simple
+	// content.
+	simpleRestriction *simpleType
+
+	// Effective values after resolving the base type.
+	resolved           bool
+	resolving          bool
+	effectiveContent   *group
+	effectiveAttrs     []*attribute
+	effectiveAnyAttr   bool
+	effectiveMixed     bool
+	effectiveValueType *simpleType
+}
+
+// anyType is the ur-type allowing any attributes and any content.
+var anyType = &complexType{
+	name:  xml.Name{Space: nsXSD, Local: "anyType"},
+	mixed: true,
+	content: &group{
+		occurrence: occurrence{min: 1, max: 1},
+		particles: []particle{
+			&wildcard{
+				occurrence:      occurrence{min: 0, max: unbounded},
+				namespace:       "##any",
+				processContents: "lax",
+			},
+		},
+	},
+	anyAttribute:       true,
+	resolved:           true,
+	effectiveMixed:     true,
+	effectiveAnyAttr:   true,
+	effectiveAttrs:     []*attribute{},
+	effectiveValueType: nil,
+}
+
+func init() {
+	anyType.effectiveContent = anyType.content
+}
+
+// Parse will parse the schema at location and all schemas it imports or
+// includes, using load to read them.
+func Parse(location string, load Loader) (*Schema, error) {
+	s := &Schema{
+		elements:     map[xml.Name]*element{},
+		complexTypes: map[xml.Name]*complexType{},
+		simpleTypes:  map[xml.Name]*simpleType{},
+		loaded:       map[string]struct{}{},
+	}
+
+	if err := s.load(location, load); err != nil {
+		return nil, err
+	}
+
+	if err := s.resolve(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Schema) load(location string, load Loader) error {
+	if _, ok := s.loaded[location]; ok {
+		return nil
+	}
+
+	s.loaded[location] = struct{}{}
+
+	data, err := load(location)
+	if err != nil {
+		return err
+	}
+
+	root, err := xmltree.Parse(data)
+	if err != nil {
+		return fmt.Errorf("could not parse schema %s: %s", location, err.Error())
+	}
+
+	if root.Name.Space != nsXSD || root.Name.Local != "schema" {
+		return fmt.Errorf("%s is not an XSD schema", location)
+	}
+
+	doc := &schemaDocument{
+		targetNamespace: root.Attr("", "targetNamespace"),
+		qualified:       root.Attr("", "elementFormDefault") == "qualified",
+	}
+
+	for i := range root.Children {
+		child := &root.Children[i]
+
+		if child.Name.Space != nsXSD {
+			continue
+		}
+
+		switch child.Name.Local {
+		case "import", "include":
+			schemaLocation := child.Attr("", "schemaLocation")
+			if schemaLocation == "" {
+				// Imports without a location must be loaded by
+				// another schema.
+				continue
+			}
+
+			if err := s.load(resolveLocation(location, schemaLocation), load); err != nil {
+				return err
+			}
+		case "element":
+			e, err := s.parseElement(child, doc, true)
+			if err != nil {
+				return err
+			}
+
+			s.elements[e.name] = e
+		case "complexType":
+			ct, err := s.parseComplexType(child, doc)
+			if err != nil {
+				return err
+			}
+
+			s.complexTypes[ct.name] = ct
+		case "simpleType":
+			st, err := s.parseSimpleType(child, doc)
+			if err != nil {
+				return err
+			}
+
+			s.simpleTypes[st.name] = st
+		case "annotation":
+		default:
+			return fmt.Errorf("%s: unsupported schema component <%s>", location, child.Name.Local)
+		}
+	}
+
+	return nil
+}
+
+// resolveLocation returns the location relative to the base location unless
+// the location is absolute.
+func resolveLocation(base, location string) string {
+	if path.IsAbs(location) || strings.Contains(location, "://") {
+		return location
+	}
+
+	return path.Join(path.Dir(base), location)
+}
+
+func parseOccurrence(el *xmltree.Element) (occurrence, error) {
+	o := occurrence{min: 1, max: 1}
+
+	if v := el.Attr("", "minOccurs"); v != "" {
+		min, err := strconv.Atoi(v)
+		if err != nil {
+			return o, fmt.Errorf("invalid minOccurs '%s'", v)
+		}
+
+		o.min = min
+	}
+
+	if v := el.Attr("", "maxOccurs"); v != "" {
+		if v == "unbounded" {
+			o.max = unbounded
+			return o, nil
+		}
+
+		max, err := strconv.Atoi(v)
+		if err != nil {
+			return o, fmt.Errorf("invalid maxOccurs '%s'", v)
+		}
+
+		o.max = max
+	}
+
+	return o, nil
+}
+
+func (s *Schema) parseElement(el *xmltree.Element, doc *schemaDocument, global bool) (*element, error) {
+	o, err := parseOccurrence(el)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &element{
+		occurrence: o,
+	}
+
+	s.allElements = append(s.allElements, e)
+
+	if ref := el.Attr("", "ref"); ref != "" {
+		e.ref = el.Resolve(ref)
+
+		return e, nil
+	}
+
+	e.name = xml.Name{Local: el.Attr("", "name")}
+	if global || doc.qualified || el.Attr("", "form") == "qualified" {
+		e.name.Space = doc.targetNamespace
+	}
+
+	if t := el.Attr("", "type"); t != "" {
+		e.typeName = el.Resolve(t)
+
+		return e, nil
+	}
+
+	for i := range el.Children {
+		child := &el.Children[i]
+
+		switch child.Name.Local {
+		case "complexType":
+			e.complexType, err = s.parseComplexType(child, doc)
+		case "simpleType":
+			e.simpleType, err = s.parseSimpleType(child, doc)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// An element without any type is of the ur-type allowing anything.
+	if e.complexType == nil && e.simpleType == nil {
+		e.complexType = anyType
+	}
+
+	return e, nil
+}
+
+func (s *Schema) parseGroup(el *xmltree.Element, doc *schemaDocument) (*group, error) {
+	o, err := parseOccurrence(el)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &group{
+		occurrence: o,
+		choice:     el.Name.Local == "choice",
+	}
+
+	for i := range el.Children {
+		child := &el.Children[i]
+
+		var p particle
+
+		switch child.Name.Local {
+		case "element":
+			p, err = s.parseElement(child, doc, false)
+		case "sequence", "choice":
+			p, err = s.parseGroup(child, doc)
+		case "any":
+			p, err = parseWildcard(child, doc)
+		case "annotation":
+			continue
+		default:
+			return nil, fmt.Errorf("unsupported content model component <%s>", child.Name.Local)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		g.particles = append(g.particles, p)
+	}
+
+	return g, nil
+}
+
+func parseWildcard(el *xmltree.Element, doc *schemaDocument) (*wildcard, error) {
+	o, err := parseOccurrence(el)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &wildcard{
+		occurrence:      o,
+		namespace:       el.Attr("", "namespace"),
+		targetNamespace: doc.targetNamespace,
+		processContents: el.Attr("", "processContents"),
+	}
+
+	if w.namespace == "" {
+		w.namespace = "##any"
+	}
+
+	if w.processContents == "" {
+		w.processContents = "strict"
+	}
+
+	return w, nil
+}
+
+func (s *Schema) parseAttribute(el *xmltree.Element, doc *schemaDocument) (*attribute, error) {
+	if el.Attr("", "ref") != "" {
+		return nil, fmt.Errorf("attribute references are not supported")
+	}
+
+	a := &attribute{
+		name:     el.Attr("", "name"),
+		required: el.Attr("", "use") == "required",
+	}
+
+	s.allAttributes = append(s.allAttributes, a)
+
+	if t := el.Attr("", "type"); t != "" {
+		a.typeName = el.Resolve(t)
+
+		return a, nil
+	}
+
+	for i := range el.Children {
+		if el.Children[i].Name.Local != "simpleType" {
+			continue
+		}
+
+		st, err := s.parseSimpleType(&el.Children[i], doc)
+		if err != nil {
+			return nil, err
+		}
+
+		a.simpleType = st
+	}
+
+	if a.simpleType == nil {
+		a.simpleType = builtinTypes["anySimpleType"]
+	}
+
+	return a, nil
+}
+
+func (s *Schema) parseComplexType(el *xmltree.Element, doc *schemaDocument) (*complexType, error) {
+	ct := &complexType{
+		mixed: el.Attr("", "mixed") == "true",
+	}
+
+	if name := el.Attr("", "name"); name != "" {
+		ct.name = xml.Name{Space: doc.targetNamespace, Local: name}
+	}
+
+	s.allComplexTypes = append(s.allComplexTypes, ct)
+
+	if err := s.parseComplexContent(el, ct, doc); err != nil {
+		return nil, err
+	}
+
+	return ct, nil
+}
+
+// parseComplexContent parses the children of a complex type or a derivation.
+func (s *Schema) parseComplexContent(el *xmltree.Element, ct *complexType, doc *schemaDocument) error {
+	for i := range el.Children {
+		child := &el.Children[i]
+
+		switch child.Name.Local {
+		case "sequence", "choice":
+			g, err := s.parseGroup(child, doc)
+			if err != nil {
+				return err
+			}
+
+			ct.content = g
+		case "attribute":
+			a, err := s.parseAttribute(child, doc)
+			if err != nil {
+				return err
+			}
+
+			ct.attributes = append(ct.attributes, a)
+		case "anyAttribute":
+			ct.anyAttribute = true
+		case "simpleContent", "complexContent":
+			ct.simpleContent = child.Name.Local == "simpleContent"
+
+			if child.Attr("", "mixed") == "true" {
+				ct.mixed = true
+			}
+
+			for j := range child.Children {
+				derivation := &child.Children[j]
+
+				switch derivation.Name.Local {
+				case "extension", "restriction":
+				case "annotation":
+					continue
+				default:
+					return fmt.Errorf("unsupported derivation <%s>", derivation.Name.Local)
+				}
+
+				ct.base = derivation.Resolve(derivation.Attr("", "base"))
+				ct.extension = derivation.Name.Local == "extension"
+
+				if ct.simpleContent && !ct.extension {
+					st, err := s.parseFacets(derivation, xml.Name{})
+					if err != nil {
+						return err
+					}
+
+					// The base is the value type of the complex
+					// base type and is set when resolving.
+					st.baseName = xml.Name{}
+
+					ct.simpleRestriction = st
+				}
+
+				if err := s.parseComplexContent(derivation, ct, doc); err != nil {
+					return err
+				}
+			}
+		case "annotation", "enumeration", "pattern", "length", "minLength",
+			"maxLength", "minInclusive", "maxInclusive", "minExclusive",
+			"maxExclusive", "whiteSpace", "totalDigits", "fractionDigits":
+			// Facets are handled when parsing the restriction.
+		default:
+			return fmt.Errorf("unsupported complex type component <%s>", child.Name.Local)
+		}
+	}
+
+	return nil
+}
+
+// resolve will resolve all references to types and elements and calculate the
+// effective content for all complex types.
+func (s *Schema) resolve() error {
+	for _, st := range s.allSimpleTypes {
+		if st.builtin != nil || st.base != nil || st.baseName.Local == "" {
+			continue
+		}
+
+		base, ok := s.lookupSimpleType(st.baseName)
+		if !ok {
+			return fmt.Errorf("simple type '%s' not found", formatName(st.baseName))
+		}
+
+		st.base = base
+	}
+
+	for _, a := range s.allAttributes {
+		if a.simpleType != nil {
+			continue
+		}
+
+		st, ok := s.lookupSimpleType(a.typeName)
+		if !ok {
+			return fmt.Errorf("simple type '%s' not found for attribute '%s'", formatName(a.typeName), a.name)
+		}
+
+		a.simpleType = st
+	}
+
+	for _, e := range s.allElements {
+		if e.ref.Local != "" {
+			ref, ok := s.elements[e.ref]
+			if !ok {
+				return fmt.Errorf("element '%s' not found", formatName(e.ref))
+			}
+
+			e.refElement = ref
+
+			continue
+		}
+
+		if e.typeName.Local == "" {
+			continue
+		}
+
+		if ct, ok := s.lookupComplexType(e.typeName); ok {
+			e.complexType = ct
+			continue
+		}
+
+		if st, ok := s.lookupSimpleType(e.typeName); ok {
+			e.simpleType = st
+			continue
+		}
+
+		return fmt.Errorf("type '%s' not found for element '%s'", formatName(e.typeName), e.name.Local)
+	}
+
+	for _, ct := range s.allComplexTypes {
+		if err := s.resolveComplexType(ct); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) resolveComplexType(ct *complexType) error {
+	if ct.resolved {
+		return nil
+	}
+
+	if ct.resolving {
+		return fmt.Errorf("circular derivation for type '%s'", formatName(ct.name))
+	}
+
+	ct.resolving = true
+	defer func() {
+		ct.resolving = false
+	}()
+
+	ct.effectiveContent = ct.content
+	ct.effectiveAttrs = ct.attributes
+	ct.effectiveAnyAttr = ct.anyAttribute
+	ct.effectiveMixed = ct.mixed
+
+	if ct.base.Local == "" {
+		ct.resolved = true
+		return nil
+	}
+
+	if ct.simpleContent {
+		valueType, attributes, err := s.simpleContentBase(ct.base)
+		if err != nil {
+			return err
+		}
+
+		if ct.simpleRestriction != nil {
+			ct.simpleRestriction.base = valueType
+			valueType = ct.simpleRestriction
+		}
+
+		ct.effectiveValueType = valueType
+		ct.effectiveAttrs = mergeAttributes(attributes, ct.attributes)
+		ct.resolved = true
+
+		return nil
+	}
+
+	base, ok := s.lookupComplexType(ct.base)
+	if !ok {
+		return fmt.Errorf("complex type '%s' not found", formatName(ct.base))
+	}
+
+	if err := s.resolveComplexType(base); err != nil {
+		return err
+	}
+
+	if ct.extension {
+		ct.effectiveMixed = ct.mixed || base.effectiveMixed
+		ct.effectiveAnyAttr = ct.anyAttribute || base.effectiveAnyAttr
+		ct.effectiveValueType = base.effectiveValueType
+
+		switch {
+		case base.effectiveContent == nil:
+			ct.effectiveContent = ct.content
+		case ct.content == nil:
+			ct.effectiveContent = base.effectiveContent
+		default:
+			ct.effectiveContent = &group{
+				occurrence: occurrence{min: 1, max: 1},
+				particles:  []particle{base.effectiveContent, ct.content},
+			}
+		}
+	}
+
+	if base != anyType {
+		ct.effectiveAttrs = mergeAttributes(base.effectiveAttrs, ct.attributes)
+	}
+
+	ct.resolved = true
+
+	return nil
+}
+
+// simpleContentBase returns the value type and attributes for a base type used
+// in simple content.
+func (s *Schema) simpleContentBase(name xml.Name) (*simpleType, []*attribute, error) {
+	if st, ok := s.lookupSimpleType(name); ok {
+		return st, nil, nil
+	}
+
+	base, ok := s.lookupComplexType(name)
+	if !ok {
+		return nil, nil, fmt.Errorf("type '%s' not found", formatName(name))
+	}
+
+	if err := s.resolveComplexType(base); err != nil {
+		return nil, nil, err
+	}
+
+	if base.effectiveValueType == nil {
+		return nil, nil, fmt.Errorf("type '%s' does not have simple content", formatName(name))
+	}
+
+	return base.effectiveValueType, base.effectiveAttrs, nil
+}
+
+// mergeAttributes returns the base attributes with the derived attributes
+// added or replacing attributes with the same name.
+func mergeAttributes(base, derived []*attribute) []*attribute {
+	merged := []*attribute{}
+
+	for _, a := range base {
+		replaced := false
+
+		for _, d := range derived {
+			if d.name == a.name {
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			merged = append(merged, a)
+		}
+	}
+
+	return append(merged, derived...)
+}
+
+func (s *Schema) lookupComplexType(name xml.Name) (*complexType, bool) {
+	if name.Space == nsXSD && name.Local == "anyType" {
+		return anyType, true
+	}
+
+	ct, ok := s.complexTypes[name]
+
+	return ct, ok
+}
+
+func (s *Schema) lookupSimpleType(name xml.Name) (*simpleType, bool) {
+	if name.Space == nsXSD {
+		st, ok := builtinTypes[name.Local]
+		return st, ok
+	}
+
+	st, ok := s.simpleTypes[name]
+
+	return st, ok
+}
+
+func formatName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+
+	return fmt.Sprintf("{%s}%s", name.Space, name.Local)
+}
@@ -0,0 +1,291 @@
+package schema
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseEPPSchema(t *testing.T) *Schema {
+	t.Helper()
+
+	s, err := Parse("../xml/index.xsd", os.ReadFile)
+	require.Nil(t, err)
+
+	return s
+}
+
+func TestValidateCommands(t *testing.T) {
+	s := parseEPPSchema(t)
+
+	files, err := filepath.Glob("../xml/commands/*.xml")
+	require.Nil(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			document, err := os.ReadFile(file)
+			require.Nil(t, err)
+
+			err = s.Validate(document)
+			if errs, ok := err.(Errors); ok {
+				for _, e := range errs {
+					t.Log(e.Error())
+				}
+			}
+
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := parseEPPSchema(t)
+
+	cases := []struct {
+		description string
+		xml         string
+		notXML      bool
+		errors      []Error
+	}{
+		{
+			description: "empty document",
+			xml:         ``,
+			notXML:      true,
+		},
+		{
+			description: "not well formed",
+			xml:         `<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><command></epp>`,
+			notXML:      true,
+		},
+		{
+			description: "unknown root",
+			xml:         `<epp><command></command></epp>`,
+			errors: []Error{
+				{
+					Line:    1,
+					Path:    "/epp",
+					Element: xml.Name{Local: "epp"},
+					Message: "Element 'epp': No matching global declaration available for the validation root.",
+				},
+			},
+		},
+		{
+			description: "invalid attribute value",
+			xml: `<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <poll op="-INVALID-"/>
+  </command>
+</epp>`,
+			errors: []Error{
+				{
+					Line:    3,
+					Path:    "/epp/command/poll/@op",
+					Element: xml.Name{Space: "urn:ietf:params:xml:ns:epp-1.0", Local: "poll"},
+					Message: "Element 'poll': [attribute 'op'] [facet 'enumeration'] The value '-INVALID-' is not an element of the set {'ack', 'req'}.",
+				},
+			},
+		},
+		{
+			description: "missing required attribute",
+			xml: `<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <poll/>
+  </command>
+</epp>`,
+			errors: []Error{
+				{
+					Line:    3,
+					Path:    "/epp/command/poll/@op",
+					Element: xml.Name{Space: "urn:ietf:params:xml:ns:epp-1.0", Local: "poll"},
+					Message: "Element 'poll': The attribute 'op' is required but missing.",
+				},
+			},
+		},
+		{
+			description: "invalid value with position",
+			xml: `<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <check>
+      <domain:check xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.se</domain:name>
+        <domain:name></domain:name>
+      </domain:check>
+    </check>
+  </command>
+</epp>`,
+			errors: []Error{
+				{
+					Line:    6,
+					Path:    "/epp/command/check/domain:check/domain:name[2]",
+					Element: xml.Name{Space: "urn:ietf:params:xml:ns:domain-1.0", Local: "name"},
+					Message: "Element 'domain:name': [facet 'minLength'] The value '' has a length of '0'; this underruns the allowed minimum length of '1'.",
+				},
+			},
+		},
+		{
+			description: "unexpected element",
+			xml: `<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <info>
+      <domain:info xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.se</domain:name>
+        <domain:invalid/>
+      </domain:info>
+    </info>
+  </command>
+</epp>`,
+			errors: []Error{
+				{
+					Line:    6,
+					Path:    "/epp/command/info/domain:info/domain:invalid",
+					Element: xml.Name{Space: "urn:ietf:params:xml:ns:domain-1.0", Local: "invalid"},
+					Message: "Element 'domain:invalid': This element is not expected. Expected is one of ( {urn:ietf:params:xml:ns:domain-1.0}authInfo ).",
+				},
+			},
+		},
+		{
+			description: "missing element",
+			xml: `<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <info>
+      <domain:info xmlns:domain="urn:ietf:params:xml:ns:domain-1.0"/>
+    </info>
+  </command>
+</epp>`,
+			errors: []Error{
+				{
+					Line:    4,
+					Path:    "/epp/command/info/domain:info",
+					Element: xml.Name{Space: "urn:ietf:params:xml:ns:domain-1.0", Local: "info"},
+					Message: "Element 'domain:info': Missing child element(s). Expected is one of ( {urn:ietf:params:xml:ns:domain-1.0}name ).",
+				},
+			},
+		},
+		{
+			description: "strict wildcard without declaration",
+			xml: `<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <info>
+      <example:info xmlns:example="urn:example:unknown-1.0"/>
+    </info>
+  </command>
+</epp>`,
+			errors: []Error{
+				{
+					Line:    4,
+					Path:    "/epp/command/info/example:info",
+					Element: xml.Name{Space: "urn:example:unknown-1.0", Local: "info"},
+					Message: "Element 'example:info': No matching global element declaration available, but demanded by the strict wildcard.",
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := s.Validate([]byte(tc.xml))
+
+			if tc.notXML {
+				require.NotNil(t, err)
+
+				_, ok := err.(Errors)
+				assert.False(t, ok)
+
+				return
+			}
+
+			errs, ok := err.(Errors)
+			require.True(t, ok, "expected validation errors, got %v", err)
+			require.Len(t, errs, len(tc.errors))
+
+			for i := range tc.errors {
+				assert.Equal(t, tc.errors[i], *errs[i])
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	schemas := map[string]string{
+		"root.xsd": `<schema xmlns="http://www.w3.org/2001/XMLSchema" xmlns:t="urn:test" targetNamespace="urn:test" elementFormDefault="qualified">
+  <include schemaLocation="types.xsd"/>
+  <element name="root" type="t:rootType"/>
+  <complexType name="baseType">
+    <sequence>
+      <element name="a" type="t:codeType"/>
+    </sequence>
+    <attribute name="id" type="unsignedByte" use="required"/>
+  </complexType>
+  <complexType name="rootType">
+    <complexContent>
+      <extension base="t:baseType">
+        <choice minOccurs="0" maxOccurs="unbounded">
+          <element name="b" type="t:valueType"/>
+          <element name="c" type="dateTime"/>
+        </choice>
+      </extension>
+    </complexContent>
+  </complexType>
+</schema>`,
+		"types.xsd": `<schema xmlns="http://www.w3.org/2001/XMLSchema" xmlns:t="urn:test" targetNamespace="urn:test" elementFormDefault="qualified">
+  <simpleType name="codeType">
+    <restriction base="token">
+      <pattern value="\w{2}-\d+"/>
+    </restriction>
+  </simpleType>
+  <complexType name="valueType">
+    <simpleContent>
+      <extension base="t:codeType">
+        <attribute name="lang" type="language" default="en"/>
+      </extension>
+    </simpleContent>
+  </complexType>
+</schema>`,
+	}
+
+	load := func(location string) ([]byte, error) {
+		return []byte(schemas[location]), nil
+	}
+
+	s, err := Parse("root.xsd", load)
+	require.Nil(t, err)
+
+	cases := []struct {
+		xml   string
+		valid bool
+	}{
+		{`<root xmlns="urn:test" id="1"><a> se-1 </a></root>`, true},
+		{`<root xmlns="urn:test" id="1"><a>se-1</a><b lang="sv">ab-2</b><c>2019-01-01T00:00:00Z</c><b>ab-3</b></root>`, true},
+		{`<root xmlns="urn:test"><a>se-1</a></root>`, false},
+		{`<root xmlns="urn:test" id="256"><a>se-1</a></root>`, false},
+		{`<root xmlns="urn:test" id="1"><a>se</a></root>`, false},
+		{`<root xmlns="urn:test" id="1"><b>ab-2</b><a>se-1</a></root>`, false},
+		{`<root xmlns="urn:test" id="1"><a>se-1</a><c>yesterday</c></root>`, false},
+		{`<root xmlns="urn:test" id="1"><a>se-1</a><b unknown="1">ab-2</b></root>`, false},
+		{`<root xmlns="urn:test" id="1">text<a>se-1</a></root>`, false},
+	}
+
+	for _, tc := range cases {
+		err := s.Validate([]byte(tc.xml))
+
+		if tc.valid {
+			assert.Nil(t, err, tc.xml)
+		} else {
+			assert.NotNil(t, err, tc.xml)
+		}
+	}
+
+	_, err = Parse("root.xsd", func(location string) ([]byte, error) {
+		if location == "types.xsd" {
+			return []byte(`<schema xmlns="http://www.w3.org/2001/XMLSchema"/>`), nil
+		}
+
+		return load(location)
+	})
+	assert.NotNil(t, err, "missing types should not parse")
+}
@@ -0,0 +1,490 @@
+package schema
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"aqwari.net/xml/xmltree"
+)
+
+// White space handling for simple types.
+const (
+	whiteSpacePreserve = "preserve"
+	whiteSpaceReplace  = "replace"
+	whiteSpaceCollapse = "collapse"
+)
+
+// simpleType represents a simple type definition, either built in or derived
+// by restriction.
+type simpleType struct {
+	name     xml.Name
+	baseName xml.Name
+	base     *simpleType
+
+	// Set for built in types only.
+	builtin    func(string) error
+	whiteSpace string
+
+	enumeration  []string
+	patterns     []*regexp.Regexp
+	length       int
+	minLength    int
+	maxLength    int
+	minInclusive *big.Rat
+	maxInclusive *big.Rat
+	minExclusive *big.Rat
+	maxExclusive *big.Rat
+}
+
+func newSimpleType(name xml.Name) *simpleType {
+	return &simpleType{
+		name:      name,
+		length:    -1,
+		minLength: -1,
+		maxLength: -1,
+	}
+}
+
+func (s *Schema) parseSimpleType(el *xmltree.Element, doc *schemaDocument) (*simpleType, error) {
+	name := xml.Name{}
+	if n := el.Attr("", "name"); n != "" {
+		name = xml.Name{Space: doc.targetNamespace, Local: n}
+	}
+
+	for i := range el.Children {
+		child := &el.Children[i]
+
+		switch child.Name.Local {
+		case "restriction":
+			return s.parseFacets(child, name)
+		case "annotation":
+		default:
+			return nil, fmt.Errorf("unsupported simple type derivation <%s>", child.Name.Local)
+		}
+	}
+
+	return nil, fmt.Errorf("simple type '%s' has no restriction", name.Local)
+}
+
+// parseFacets parses the facets of a restriction to a new simple type. The base
+// type is resolved when all schemas are loaded.
+func (s *Schema) parseFacets(el *xmltree.Element, name xml.Name) (*simpleType, error) {
+	st := newSimpleType(name)
+
+	if base := el.Attr("", "base"); base != "" {
+		st.baseName = el.Resolve(base)
+	}
+
+	s.allSimpleTypes = append(s.allSimpleTypes, st)
+
+	// Patterns in the same restriction are combined and any of them may
+	// match.
+	patterns := []string{}
+
+	for i := range el.Children {
+		child := &el.Children[i]
+		value := child.Attr("", "value")
+
+		var err error
+
+		switch child.Name.Local {
+		case "enumeration":
+			st.enumeration = append(st.enumeration, value)
+		case "pattern":
+			patterns = append(patterns, "(?:"+translatePattern(value)+")")
+		case "length":
+			st.length, err = strconv.Atoi(value)
+		case "minLength":
+			st.minLength, err = strconv.Atoi(value)
+		case "maxLength":
+			st.maxLength, err = strconv.Atoi(value)
+		case "minInclusive":
+			st.minInclusive, err = parseNumber(value)
+		case "maxInclusive":
+			st.maxInclusive, err = parseNumber(value)
+		case "minExclusive":
+			st.minExclusive, err = parseNumber(value)
+		case "maxExclusive":
+			st.maxExclusive, err = parseNumber(value)
+		case "whiteSpace", "annotation", "attribute", "anyAttribute":
+		default:
+			return nil, fmt.Errorf("unsupported facet <%s>", child.Name.Local)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s' for facet <%s>", value, child.Name.Local)
+		}
+	}
+
+	if len(patterns) > 0 {
+		re, err := regexp.Compile("^(?:" + strings.Join(patterns, "|") + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("unsupported pattern: %s", err.Error())
+		}
+
+		st.patterns = append(st.patterns, re)
+	}
+
+	return st, nil
+}
+
+func parseNumber(value string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a number", value)
+	}
+
+	return r, nil
+}
+
+// translatePattern translates an XSD regular expression to the syntax used by
+// the regexp package. The multi-character escapes for XML names are
+// approximated.
+func translatePattern(pattern string) string {
+	var (
+		sb      strings.Builder
+		inClass bool
+	)
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+
+			sb.WriteString(translateEscape(pattern[i], inClass))
+		case c == '[':
+			inClass = true
+
+			sb.WriteByte(c)
+		case c == ']':
+			inClass = false
+
+			sb.WriteByte(c)
+		case c == '.' && !inClass:
+			sb.WriteString(`[^\n\r]`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return sb.String()
+}
+
+func translateEscape(c byte, inClass bool) string {
+	classes := map[byte]string{
+		'w': `\p{L}\p{M}\p{N}\p{S}`,
+		'd': `\p{Nd}`,
+		'i': `\p{L}_:`,
+		'c': `\p{L}\p{M}\p{N}.\-_:`,
+		's': `\x20\t\n\r`,
+	}
+
+	if class, ok := classes[c]; ok {
+		if inClass {
+			return class
+		}
+
+		return "[" + class + "]"
+	}
+
+	// The upper case versions are the negations.
+	if class, ok := classes[c+'a'-'A']; ok && c >= 'A' && c <= 'Z' {
+		return "[^" + class + "]"
+	}
+
+	return `\` + string(c)
+}
+
+// whiteSpaceMode returns how white space is handled for the type, which is
+// decided by the built in type it's derived from.
+func (t *simpleType) whiteSpaceMode() string {
+	for st := t; st != nil; st = st.base {
+		if st.whiteSpace != "" {
+			return st.whiteSpace
+		}
+	}
+
+	return whiteSpaceCollapse
+}
+
+// root returns the built in type this type is derived from.
+func (t *simpleType) root() *simpleType {
+	st := t
+	for st.base != nil {
+		st = st.base
+	}
+
+	return st
+}
+
+// validate normalizes white space in value and validates it towards the type
+// and all its base types.
+func (t *simpleType) validate(value string) error {
+	switch t.whiteSpaceMode() {
+	case whiteSpaceReplace:
+		value = strings.Map(replaceWhiteSpace, value)
+	case whiteSpaceCollapse:
+		value = strings.Join(strings.Fields(value), " ")
+	}
+
+	return t.check(value)
+}
+
+func replaceWhiteSpace(r rune) rune {
+	switch r {
+	case '\t', '\n', '\r':
+		return ' '
+	}
+
+	return r
+}
+
+func (t *simpleType) check(value string) error {
+	if t.base != nil {
+		if err := t.base.check(value); err != nil {
+			return err
+		}
+	}
+
+	if t.builtin != nil {
+		if err := t.builtin(value); err != nil {
+			return err
+		}
+	}
+
+	if len(t.enumeration) > 0 && !contains(t.enumeration, value) {
+		return fmt.Errorf(
+			"[facet 'enumeration'] The value '%s' is not an element of the set {'%s'}.",
+			value, strings.Join(t.enumeration, "', '"),
+		)
+	}
+
+	for _, re := range t.patterns {
+		if !re.MatchString(value) {
+			return fmt.Errorf("[facet 'pattern'] The value '%s' is not accepted by the pattern.", value)
+		}
+	}
+
+	if t.length >= 0 || t.minLength >= 0 || t.maxLength >= 0 {
+		length := t.root().valueLength(value)
+
+		if t.length >= 0 && length != t.length {
+			return fmt.Errorf("[facet 'length'] The value '%s' has a length of '%d'; this differs from the allowed length of '%d'.", value, length, t.length)
+		}
+
+		if t.minLength >= 0 && length < t.minLength {
+			return fmt.Errorf("[facet 'minLength'] The value '%s' has a length of '%d'; this underruns the allowed minimum length of '%d'.", value, length, t.minLength)
+		}
+
+		if t.maxLength >= 0 && length > t.maxLength {
+			return fmt.Errorf("[facet 'maxLength'] The value '%s' has a length of '%d'; this exceeds the allowed maximum length of '%d'.", value, length, t.maxLength)
+		}
+	}
+
+	return t.checkRange(value)
+}
+
+func (t *simpleType) checkRange(value string) error {
+	if t.minInclusive == nil && t.maxInclusive == nil && t.minExclusive == nil && t.maxExclusive == nil {
+		return nil
+	}
+
+	n, err := parseNumber(value)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case t.minInclusive != nil && n.Cmp(t.minInclusive) < 0:
+		return fmt.Errorf("[facet 'minInclusive'] The value '%s' is less than the minimum value allowed ('%s').", value, t.minInclusive.RatString())
+	case t.maxInclusive != nil && n.Cmp(t.maxInclusive) > 0:
+		return fmt.Errorf("[facet 'maxInclusive'] The value '%s' is greater than the maximum value allowed ('%s').", value, t.maxInclusive.RatString())
+	case t.minExclusive != nil && n.Cmp(t.minExclusive) <= 0:
+		return fmt.Errorf("[facet 'minExclusive'] The value '%s' must be greater than '%s'.", value, t.minExclusive.RatString())
+	case t.maxExclusive != nil && n.Cmp(t.maxExclusive) >= 0:
+		return fmt.Errorf("[facet 'maxExclusive'] The value '%s' must be less than '%s'.", value, t.maxExclusive.RatString())
+	}
+
+	return nil
+}
+
+// valueLength returns the length of the value as defined by the length facets;
+// octets for binary types and characters for everything else.
+func (t *simpleType) valueLength(value string) int {
+	switch t.name.Local {
+	case "hexBinary":
+		return len(value) / 2
+	case "base64Binary":
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+		if err != nil {
+			return 0
+		}
+
+		return len(decoded)
+	}
+
+	return utf8.RuneCountInString(value)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// builtinTypes holds the supported built in simple types by their local name.
+var builtinTypes = map[string]*simpleType{}
+
+func init() {
+	var (
+		reLanguage = regexp.MustCompile(`^[a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*$`)
+		reDateTime = regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`)
+		reDate     = regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}(Z|[+-]\d{2}:\d{2})?$`)
+		reTime     = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`)
+		reDuration = regexp.MustCompile(`^-?P(\d+Y)?(\d+M)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?$`)
+		reDecimal  = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+		reInteger  = regexp.MustCompile(`^[+-]?\d+$`)
+		reNCName   = regexp.MustCompile(`^[\p{L}_][\p{L}\p{M}\p{N}.\-_]*$`)
+		reQName    = regexp.MustCompile(`^([\p{L}_][\p{L}\p{M}\p{N}.\-_]*:)?[\p{L}_][\p{L}\p{M}\p{N}.\-_]*$`)
+	)
+
+	matches := func(re *regexp.Regexp, typeName string) func(string) error {
+		return func(value string) error {
+			if !re.MatchString(value) {
+				return fmt.Errorf("'%s' is not a valid value of the atomic type 'xs:%s'.", value, typeName)
+			}
+
+			return nil
+		}
+	}
+
+	integer := func(typeName, min, max string) func(string) error {
+		var minValue, maxValue *big.Int
+
+		if min != "" {
+			minValue, _ = new(big.Int).SetString(min, 10)
+		}
+
+		if max != "" {
+			maxValue, _ = new(big.Int).SetString(max, 10)
+		}
+
+		return func(value string) error {
+			n, ok := new(big.Int).SetString(strings.TrimPrefix(value, "+"), 10)
+			if !ok || !reInteger.MatchString(value) ||
+				(minValue != nil && n.Cmp(minValue) < 0) ||
+				(maxValue != nil && n.Cmp(maxValue) > 0) {
+				return fmt.Errorf("'%s' is not a valid value of the atomic type 'xs:%s'.", value, typeName)
+			}
+
+			return nil
+		}
+	}
+
+	any := func(string) error { return nil }
+
+	add := func(name, base, whiteSpace string, check func(string) error) {
+		st := newSimpleType(xml.Name{Space: nsXSD, Local: name})
+		st.builtin = check
+		st.whiteSpace = whiteSpace
+
+		if base != "" {
+			st.base = builtinTypes[base]
+		}
+
+		builtinTypes[name] = st
+	}
+
+	add("anySimpleType", "", whiteSpacePreserve, any)
+	add("string", "", whiteSpacePreserve, any)
+	add("normalizedString", "string", whiteSpaceReplace, any)
+	add("token", "normalizedString", whiteSpaceCollapse, any)
+	add("language", "token", "", matches(reLanguage, "language"))
+	add("Name", "token", "", matches(reQName, "Name"))
+	add("NCName", "Name", "", matches(reNCName, "NCName"))
+	add("ID", "NCName", "", any)
+	add("IDREF", "NCName", "", any)
+	add("NMTOKEN", "token", "", any)
+	add("QName", "", whiteSpaceCollapse, matches(reQName, "QName"))
+	add("anyURI", "", whiteSpaceCollapse, any)
+	add("boolean", "", whiteSpaceCollapse, func(value string) error {
+		switch value {
+		case "true", "false", "1", "0":
+			return nil
+		}
+
+		return fmt.Errorf("'%s' is not a valid value of the atomic type 'xs:boolean'.", value)
+	})
+	add("dateTime", "", whiteSpaceCollapse, matches(reDateTime, "dateTime"))
+	add("date", "", whiteSpaceCollapse, matches(reDate, "date"))
+	add("time", "", whiteSpaceCollapse, matches(reTime, "time"))
+	add("duration", "", whiteSpaceCollapse, func(value string) error {
+		if !reDuration.MatchString(value) || strings.HasSuffix(value, "P") || strings.HasSuffix(value, "T") {
+			return fmt.Errorf("'%s' is not a valid value of the atomic type 'xs:duration'.", value)
+		}
+
+		return nil
+	})
+	add("hexBinary", "", whiteSpaceCollapse, func(value string) error {
+		if _, err := hex.DecodeString(value); err != nil {
+			return fmt.Errorf("'%s' is not a valid value of the atomic type 'xs:hexBinary'.", value)
+		}
+
+		return nil
+	})
+	add("base64Binary", "", whiteSpaceCollapse, func(value string) error {
+		if _, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), "")); err != nil {
+			return fmt.Errorf("'%s' is not a valid value of the atomic type 'xs:base64Binary'.", value)
+		}
+
+		return nil
+	})
+	add("decimal", "", whiteSpaceCollapse, matches(reDecimal, "decimal"))
+	add("float", "", whiteSpaceCollapse, func(value string) error {
+		if _, err := strconv.ParseFloat(value, 32); err != nil && value != "INF" && value != "-INF" && value != "NaN" {
+			return fmt.Errorf("'%s' is not a valid value of the atomic type 'xs:float'.", value)
+		}
+
+		return nil
+	})
+	add("double", "", whiteSpaceCollapse, func(value string) error {
+		if _, err := strconv.ParseFloat(value, 64); err != nil && value != "INF" && value != "-INF" && value != "NaN" {
+			return fmt.Errorf("'%s' is not a valid value of the atomic type 'xs:double'.", value)
+		}
+
+		return nil
+	})
+
+	for _, t := range []struct {
+		name, base, min, max string
+	}{
+		{"integer", "decimal", "", ""},
+		{"nonNegativeInteger", "integer", "0", ""},
+		{"positiveInteger", "nonNegativeInteger", "1", ""},
+		{"nonPositiveInteger", "integer", "", "0"},
+		{"negativeInteger", "nonPositiveInteger", "", "-1"},
+		{"long", "integer", "-9223372036854775808", "9223372036854775807"},
+		{"int", "long", "-2147483648", "2147483647"},
+		{"short", "int", "-32768", "32767"},
+		{"byte", "short", "-128", "127"},
+		{"unsignedLong", "nonNegativeInteger", "0", "18446744073709551615"},
+		{"unsignedInt", "unsignedLong", "0", "4294967295"},
+		{"unsignedShort", "unsignedInt", "0", "65535"},
+		{"unsignedByte", "unsignedShort", "0", "255"},
+	} {
+		add(t.name, t.base, "", integer(t.name, t.min, t.max))
+	}
+}
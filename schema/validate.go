@@ -0,0 +1,555 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Error represents a single validation error in a document.
+type Error struct {
+	// Line is the line in the document where the element starts.
+	Line int
+
+	// Path is an XPath-like location to the element or attribute, using the
+	// qualified names from the document, e.g.
+	// /epp/command/create/domain:create/domain:name or /epp/command/poll/@op.
+	// A position is added to elements when they have siblings with the same
+	// name.
+	Path string
+
+	// Element is the namespace and local name of the element.
+	Element xml.Name
+
+	// Message describes the error.
+	Message string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Path, e.Message)
+}
+
+// Errors holds all errors found when validating a document.
+type Errors []*Error
+
+// Error implements the error interface.
+func (e Errors) Error() string {
+	return "schema validation failed"
+}
+
+// node represents an element in the document being validated.
+type node struct {
+	name     xml.Name
+	qname    string
+	attrs    []xml.Attr
+	qnames   []string
+	text     string
+	line     int
+	path     string
+	children []*node
+}
+
+// Validate validates the document towards the schema. If the document isn't
+// well formed the error from the parser is returned, otherwise all validation
+// errors are returned as Errors.
+func (s *Schema) Validate(document []byte) error {
+	root, err := parseDocument(document)
+	if err != nil {
+		return err
+	}
+
+	v := &validator{schema: s}
+
+	if decl, ok := s.elements[root.name]; ok {
+		v.validateElement(root, decl)
+	} else {
+		v.errorf(root, "", "No matching global declaration available for the validation root.")
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+
+	return nil
+}
+
+// parseDocument parses the document to a tree of nodes, keeping track of the
+// qualified names and line numbers.
+func parseDocument(document []byte) (*node, error) {
+	var (
+		decoder  = xml.NewDecoder(bytes.NewReader(document))
+		scopes   = []map[string]string{{"xml": nsXML}}
+		stack    = []*node{}
+		root     *node
+		line     = 1
+		lastSeen int64
+	)
+
+	resolve := func(prefix string) (string, bool) {
+		for i := len(scopes) - 1; i >= 0; i-- {
+			if ns, ok := scopes[i][prefix]; ok {
+				return ns, true
+			}
+		}
+
+		return "", prefix == ""
+	}
+
+	for {
+		offset := decoder.InputOffset()
+		line += bytes.Count(document[lastSeen:offset], []byte("\n"))
+		lastSeen = offset
+
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			scope := map[string]string{}
+
+			for _, attr := range t.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					scope[attr.Name.Local] = attr.Value
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					scope[""] = attr.Value
+				}
+			}
+
+			scopes = append(scopes, scope)
+
+			ns, ok := resolve(t.Name.Space)
+			if !ok {
+				return nil, fmt.Errorf("line %d: namespace prefix %s is not defined", line, t.Name.Space)
+			}
+
+			n := &node{
+				name:  xml.Name{Space: ns, Local: t.Name.Local},
+				qname: qualifiedName(t.Name),
+				line:  line,
+			}
+
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					continue
+				}
+
+				// Unprefixed attributes doesn't belong to any
+				// namespace.
+				attrNS := ""
+				if attr.Name.Space != "" {
+					attrNS, ok = resolve(attr.Name.Space)
+					if !ok {
+						return nil, fmt.Errorf("line %d: namespace prefix %s is not defined", line, attr.Name.Space)
+					}
+				}
+
+				n.attrs = append(n.attrs, xml.Attr{
+					Name:  xml.Name{Space: attrNS, Local: attr.Name.Local},
+					Value: attr.Value,
+				})
+				n.qnames = append(n.qnames, qualifiedName(attr.Name))
+			}
+
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: unexpected end element </%s>", line, qualifiedName(t.Name))
+			}
+
+			stack = stack[:len(stack)-1]
+			scopes = scopes[:len(scopes)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("document is empty")
+	}
+
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unexpected end of document")
+	}
+
+	setPaths(root, "/"+root.qname)
+
+	return root, nil
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+
+	return name.Space + ":" + name.Local
+}
+
+// setPaths sets the XPath-like path for all nodes in the tree.
+func setPaths(n *node, path string) {
+	n.path = path
+
+	count := map[string]int{}
+	for _, child := range n.children {
+		count[child.qname]++
+	}
+
+	seen := map[string]int{}
+
+	for _, child := range n.children {
+		childPath := path + "/" + child.qname
+
+		if count[child.qname] > 1 {
+			seen[child.qname]++
+			childPath = fmt.Sprintf("%s[%d]", childPath, seen[child.qname])
+		}
+
+		setPaths(child, childPath)
+	}
+}
+
+type validator struct {
+	schema *Schema
+	errors Errors
+}
+
+func (v *validator) errorf(n *node, attr string, format string, args ...interface{}) {
+	path := n.path
+	if attr != "" {
+		path += "/@" + attr
+	}
+
+	v.errors = append(v.errors, &Error{
+		Line:    n.line,
+		Path:    path,
+		Element: n.name,
+		Message: fmt.Sprintf("Element '%s': ", n.qname) + fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) validateElement(n *node, e *element) {
+	decl := e.declaration()
+
+	if decl.simpleType != nil {
+		v.validateAttributes(n, nil, false)
+
+		if len(n.children) > 0 {
+			v.errorf(n, "", "Element content is not allowed, because the type definition is simple.")
+			return
+		}
+
+		if err := decl.simpleType.validate(n.text); err != nil {
+			v.errorf(n, "", "%s", err.Error())
+		}
+
+		return
+	}
+
+	v.validateComplexType(n, decl.complexType)
+}
+
+func (v *validator) validateComplexType(n *node, ct *complexType) {
+	v.validateAttributes(n, ct.effectiveAttrs, ct.effectiveAnyAttr)
+
+	if ct.effectiveValueType != nil {
+		if len(n.children) > 0 {
+			v.errorf(n, "", "Element content is not allowed, because the content type is a simple type definition.")
+			return
+		}
+
+		if err := ct.effectiveValueType.validate(n.text); err != nil {
+			v.errorf(n, "", "%s", err.Error())
+		}
+
+		return
+	}
+
+	if !ct.effectiveMixed && strings.TrimSpace(n.text) != "" {
+		v.errorf(n, "", "Character content other than whitespace is not allowed because the content type is 'element-only'.")
+	}
+
+	if ct.effectiveContent == nil {
+		if len(n.children) > 0 {
+			v.errorf(n.children[0], "", "This element is not expected.")
+		}
+
+		return
+	}
+
+	m := &matcher{children: n.children}
+
+	var matched *state
+
+	for _, st := range m.matchOccurs(ct.effectiveContent, []state{{}}) {
+		if st.pos == len(n.children) {
+			matched = &st
+			break
+		}
+	}
+
+	if matched == nil {
+		expected := ""
+		if len(m.expected) > 0 {
+			expected = fmt.Sprintf(" Expected is one of ( %s ).", strings.Join(m.expected, ", "))
+		}
+
+		if m.furthest < len(n.children) {
+			v.errorf(n.children[m.furthest], "", "This element is not expected.%s", expected)
+		} else {
+			v.errorf(n, "", "Missing child element(s).%s", expected)
+		}
+
+		return
+	}
+
+	for i, child := range n.children {
+		switch p := matched.particles[i].(type) {
+		case *element:
+			v.validateElement(child, p)
+		case *wildcard:
+			v.validateWildcard(child, p)
+		}
+	}
+}
+
+func (v *validator) validateWildcard(n *node, w *wildcard) {
+	if w.processContents == "skip" {
+		return
+	}
+
+	decl, ok := v.schema.elements[n.name]
+	if !ok {
+		if w.processContents == "strict" {
+			v.errorf(n, "", "No matching global element declaration available, but demanded by the strict wildcard.")
+		}
+
+		return
+	}
+
+	v.validateElement(n, decl)
+}
+
+func (v *validator) validateAttributes(n *node, attributes []*attribute, anyAttribute bool) {
+	seen := map[string]struct{}{}
+
+	for i, attr := range n.attrs {
+		// Attributes from the schema instance and XML namespaces are
+		// always allowed.
+		if attr.Name.Space == nsXSI || attr.Name.Space == nsXML {
+			continue
+		}
+
+		var decl *attribute
+
+		if attr.Name.Space == "" {
+			for _, a := range attributes {
+				if a.name == attr.Name.Local {
+					decl = a
+					break
+				}
+			}
+		}
+
+		if decl == nil {
+			if !anyAttribute {
+				v.errorf(n, n.qnames[i], "The attribute '%s' is not allowed.", n.qnames[i])
+			}
+
+			continue
+		}
+
+		seen[decl.name] = struct{}{}
+
+		if err := decl.simpleType.validate(attr.Value); err != nil {
+			v.errorf(n, n.qnames[i], "[attribute '%s'] %s", n.qnames[i], err.Error())
+		}
+	}
+
+	for _, a := range attributes {
+		if _, ok := seen[a.name]; !ok && a.required {
+			v.errorf(n, a.name, "The attribute '%s' is required but missing.", a.name)
+		}
+	}
+}
+
+// state represents a position in the children when matching a content model
+// and the particles matched for each child before the position.
+type state struct {
+	pos       int
+	particles []particle
+}
+
+// matcher matches the children of an element towards a content model. All
+// possible ways to match are tried and the furthest position reached is kept
+// together with the elements expected there to report errors.
+type matcher struct {
+	children []*node
+	furthest int
+	expected []string
+}
+
+func (m *matcher) expect(pos int, name string) {
+	if pos > m.furthest {
+		m.furthest = pos
+		m.expected = nil
+	}
+
+	if pos == m.furthest && !contains(m.expected, name) {
+		m.expected = append(m.expected, name)
+	}
+}
+
+func (m *matcher) advance(st state, p particle) state {
+	particles := make([]particle, len(st.particles), len(st.particles)+1)
+	copy(particles, st.particles)
+
+	if st.pos+1 > m.furthest {
+		m.furthest = st.pos + 1
+		m.expected = nil
+	}
+
+	return state{
+		pos:       st.pos + 1,
+		particles: append(particles, p),
+	}
+}
+
+// matchOccurs matches the particle as many times as allowed from all the
+// states and returns all reachable states.
+func (m *matcher) matchOccurs(p particle, states []state) []state {
+	min, max := p.occurs()
+
+	var (
+		result  []state
+		current = states
+	)
+
+	for count := 0; ; count++ {
+		if count >= min {
+			// Only keep states that makes progress to not loop forever
+			// on particles matching nothing.
+			current = newStates(result, current)
+			result = append(result, current...)
+		}
+
+		if len(current) == 0 || (max != unbounded && count >= max) {
+			break
+		}
+
+		var next []state
+		for _, st := range current {
+			next = append(next, newStates(next, m.matchOnce(p, st))...)
+		}
+
+		current = next
+	}
+
+	return result
+}
+
+// newStates returns the states with a position not found in existing.
+func newStates(existing, states []state) []state {
+	var result []state
+
+	for _, st := range states {
+		found := false
+
+		for _, s := range append(existing[:len(existing):len(existing)], result...) {
+			if s.pos == st.pos {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			result = append(result, st)
+		}
+	}
+
+	return result
+}
+
+func (m *matcher) matchOnce(p particle, st state) []state {
+	switch p := p.(type) {
+	case *element:
+		decl := p.declaration()
+
+		if st.pos < len(m.children) && m.children[st.pos].name == decl.name {
+			return []state{m.advance(st, p)}
+		}
+
+		m.expect(st.pos, formatName(decl.name))
+	case *wildcard:
+		if st.pos < len(m.children) && p.allows(m.children[st.pos].name.Space) {
+			return []state{m.advance(st, p)}
+		}
+
+		m.expect(st.pos, "##"+strings.TrimPrefix(p.namespace, "##"))
+	case *group:
+		if p.choice {
+			var states []state
+			for _, child := range p.particles {
+				states = append(states, newStates(states, m.matchOccurs(child, []state{st}))...)
+			}
+
+			return states
+		}
+
+		states := []state{st}
+		for _, child := range p.particles {
+			states = m.matchOccurs(child, states)
+			if len(states) == 0 {
+				break
+			}
+		}
+
+		return states
+	}
+
+	return nil
+}
+
+// allows returns true if an element in the namespace is allowed by the
+// wildcard.
+func (w *wildcard) allows(ns string) bool {
+	for _, constraint := range strings.Fields(w.namespace) {
+		switch constraint {
+		case "##any":
+			return true
+		case "##other":
+			return ns != w.targetNamespace && ns != ""
+		case "##targetNamespace":
+			if ns == w.targetNamespace {
+				return true
+			}
+		case "##local":
+			if ns == "" {
+				return true
+			}
+		default:
+			if ns == constraint {
+				return true
+			}
+		}
+	}
+
+	return false
+}
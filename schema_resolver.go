@@ -0,0 +1,163 @@
+//go:build !purego
+// +build !purego
+
+package epp
+
+/*
+#cgo pkg-config: libxml-2.0
+#include <stdint.h>
+#include <libxml/xmlIO.h>
+
+extern int eppSchemaMatch(char *uri);
+extern uintptr_t eppSchemaOpen(char *uri);
+extern int eppSchemaRead(uintptr_t handle, char *buffer, int len);
+extern int eppSchemaClose(uintptr_t handle);
+
+static int eppSchemaMatchCallback(const char *uri) {
+	return eppSchemaMatch((char *)uri);
+}
+
+static void *eppSchemaOpenCallback(const char *uri) {
+	return (void *)eppSchemaOpen((char *)uri);
+}
+
+static int eppSchemaReadCallback(void *context, char *buffer, int len) {
+	return eppSchemaRead((uintptr_t)context, buffer, len);
+}
+
+static int eppSchemaCloseCallback(void *context) {
+	return eppSchemaClose((uintptr_t)context);
+}
+
+static int eppRegisterSchemaResolver() {
+	return xmlRegisterInputCallbacks(
+		eppSchemaMatchCallback,
+		eppSchemaOpenCallback,
+		eppSchemaReadCallback,
+		eppSchemaCloseCallback
+	);
+}
+*/
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// schemaScheme is the URI scheme used for schemas resolved from a SchemaSet.
+// A schema is referred to as epp-schema://<set id>/<schema name> which makes
+// libxml2 resolve all relative schema locations to the same set.
+const schemaScheme = "epp-schema://"
+
+var (
+	schemaResolverOnce sync.Once
+	schemaResolverErr  error
+
+	schemaResolverMu sync.Mutex
+	schemaSets       = map[uint64]*SchemaSet{}
+	schemaReaders    = map[uintptr]*bytes.Reader{}
+	nextSchemaSetID  uint64
+	nextSchemaHandle uintptr
+)
+
+// registerSchemaSet will make the schemas in the set resolvable by libxml2 and
+// return the URI to the root schema. The returned function must be called to
+// unregister the set when the schema is parsed.
+func registerSchemaSet(s *SchemaSet) (string, func(), error) {
+	schemaResolverOnce.Do(func() {
+		if C.eppRegisterSchemaResolver() < 0 {
+			schemaResolverErr = errors.New("could not register schema resolver")
+		}
+	})
+
+	if schemaResolverErr != nil {
+		return "", nil, schemaResolverErr
+	}
+
+	schemaResolverMu.Lock()
+	defer schemaResolverMu.Unlock()
+
+	nextSchemaSetID++
+	id := nextSchemaSetID
+	schemaSets[id] = s
+
+	unregister := func() {
+		schemaResolverMu.Lock()
+		defer schemaResolverMu.Unlock()
+
+		delete(schemaSets, id)
+	}
+
+	return fmt.Sprintf("%s%d/%s", schemaScheme, id, schemaRootName), unregister, nil
+}
+
+// resolveSchema returns the schema for an URI created by registerSchemaSet.
+func resolveSchema(uri string) ([]byte, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(uri, schemaScheme), "/", 2)
+	if len(parts) != 2 {
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+
+	schemaResolverMu.Lock()
+	s, ok := schemaSets[id]
+	schemaResolverMu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	return s.Schema(parts[1])
+}
+
+func openSchema(uri string) uintptr {
+	xsd, ok := resolveSchema(uri)
+	if !ok {
+		return 0
+	}
+
+	schemaResolverMu.Lock()
+	defer schemaResolverMu.Unlock()
+
+	nextSchemaHandle++
+	schemaReaders[nextSchemaHandle] = bytes.NewReader(xsd)
+
+	return nextSchemaHandle
+}
+
+func readSchema(handle uintptr, buf []byte) int {
+	schemaResolverMu.Lock()
+	r, ok := schemaReaders[handle]
+	schemaResolverMu.Unlock()
+
+	if !ok {
+		return -1
+	}
+
+	n, _ := r.Read(buf)
+
+	return n
+}
+
+func closeSchema(handle uintptr) int {
+	schemaResolverMu.Lock()
+	defer schemaResolverMu.Unlock()
+
+	if _, ok := schemaReaders[handle]; !ok {
+		return -1
+	}
+
+	delete(schemaReaders, handle)
+
+	return 0
+}
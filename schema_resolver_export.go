@@ -0,0 +1,50 @@
+//go:build !purego
+// +build !purego
+
+package epp
+
+/*
+#include <stdint.h>
+#include <libxml/xmlerror.h>
+*/
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+)
+
+// The functions in this file are called by libxml2 when loading schemas and
+// reporting validation errors. They are kept in a separate file since a file
+// with exported functions can't define any C functions.
+
+//export eppSchemaMatch
+func eppSchemaMatch(uri *C.char) C.int {
+	if strings.HasPrefix(C.GoString(uri), schemaScheme) {
+		return 1
+	}
+
+	return 0
+}
+
+//export eppSchemaOpen
+func eppSchemaOpen(uri *C.char) C.uintptr_t {
+	return C.uintptr_t(openSchema(C.GoString(uri)))
+}
+
+//export eppSchemaRead
+func eppSchemaRead(handle C.uintptr_t, buffer *C.char, length C.int) C.int {
+	buf := (*[1 << 30]byte)(unsafe.Pointer(buffer))[:int(length):int(length)]
+
+	return C.int(readSchema(uintptr(handle), buf))
+}
+
+//export eppSchemaClose
+func eppSchemaClose(handle C.uintptr_t) C.int {
+	return C.int(closeSchema(uintptr(handle)))
+}
+
+//export eppValidationError
+func eppValidationError(handle C.uintptr_t, err C.xmlErrorPtr) {
+	addValidationError(uintptr(handle), err)
+}
@@ -1,14 +1,18 @@
 package epp
 
 import (
+	"context"
 	"crypto/tls"
-	"fmt"
-	"log"
+	"errors"
 	"net"
 	"sync"
 	"time"
 )
 
+// ErrTLSConfigRequired is returned by ListenAndServe if TLSConfig isn't set
+// and plaintext connections aren't allowed with AllowPlaintext.
+var ErrTLSConfigRequired = errors.New("TLSConfig is required unless AllowPlaintext is set")
+
 // Server represents the server handling requests.
 type Server struct {
 	// Addr is the address to use when listening to incomming TCP connections.
@@ -23,10 +27,55 @@ type Server struct {
 	// SessionConfig holds the configuration to use for eachsession created.
 	SessionConfig SessionConfig
 
+	// KeepValidator tells Serve not to free SessionConfig.Validator when it
+	// returns. By default the validator is owned by the server and freed
+	// when Serve returns after all sessions have ended. Set KeepValidator to
+	// use the same validator for several servers or calls to Serve and free
+	// it when it's no longer used.
+	KeepValidator bool
+
 	// TLSConfig is the server TLS config with configuration such as
-	// certificates, client auth etcetera.
+	// certificates, client auth etcetera. If nil, connections from the
+	// listener must already use TLS, e.g. a listener created with
+	// tls.NewListener, unless AllowPlaintext is set.
 	TLSConfig *tls.Config
 
+	// AllowPlaintext allows connections not using TLS when TLSConfig is nil.
+	// RFC 5734 requires TLS so this should only be used when TLS is handled
+	// outside of the server or for tests. Connections not using TLS are
+	// rejected if AllowPlaintext isn't set.
+	AllowPlaintext bool
+
+	// ShutdownMessage is the message in the response with the result code
+	// 2500 sent to each client when the server is shut down with Shutdown. If
+	// empty the default message for the result code is used.
+	ShutdownMessage string
+
+	// MaxSessions is the maximum number of concurrent sessions. Clients
+	// connecting when the limit is reached gets a response with the result
+	// code 2502 instead of a greeting. If zero there is no limit.
+	MaxSessions int
+
+	// MaxSessionsPerClient is the maximum number of concurrent sessions for
+	// each client ID, enforced when the login handler calls Session.Login. If
+	// zero there is no limit.
+	MaxSessionsPerClient int
+
+	// MaxSessionsPerCertificate is the maximum number of concurrent sessions
+	// for each client certificate, enforced before login. Clients connecting
+	// when the limit is reached gets a response with the result code 2502
+	// instead of a greeting. If zero there is no limit.
+	MaxSessionsPerCertificate int
+
+	// Logger is used to log messages from the server. It's also used for the
+	// sessions unless SessionConfig.Logger is set. If nil, messages are
+	// written with the standard logger from the log package.
+	Logger Logger
+
+	// Metrics is used to record metrics for the sessions unless
+	// SessionConfig.Metrics is set. If nil no metrics are recorded.
+	Metrics Metrics
+
 	// Sessions will contain all the currently active sessions.
 	Sessions map[string]*Session
 
@@ -41,16 +90,23 @@ type Server struct {
 	// stopChan is the channel that will be closed to tell when the server
 	// should do a graceful shutdown.
 	stopChan chan struct{}
+
+	// listener is the listener passed to Serve, closed when the server is
+	// stopped.
+	listener net.Listener
+
+	// counter holds the number of sessions to enforce the session limits.
+	counter *sessionCounter
 }
 
-// ListenAndServe will start the epp server.
+// ListenAndServe will start the epp server. ErrTLSConfigRequired is returned
+// if TLSConfig is nil unless AllowPlaintext is set.
 func (s *Server) ListenAndServe() error {
-	addr, err := net.ResolveTCPAddr("tcp", s.Addr)
-	if err != nil {
-		return err
+	if s.TLSConfig == nil && !s.AllowPlaintext {
+		return ErrTLSConfigRequired
 	}
 
-	l, err := net.ListenTCP("tcp", addr)
+	l, err := net.Listen("tcp", s.Addr)
 	if err != nil {
 		return err
 	}
@@ -63,21 +119,37 @@ func (s *Server) ListenAndServe() error {
 	return nil
 }
 
-// Serve will serve connections by listening on l.
-func (s *Server) Serve(l *net.TCPListener) error {
+// Serve will serve connections by listening on l. Any listener may be used,
+// e.g. a TCP or Unix socket listener or a listener already wrapped in TLS. If
+// TLSConfig is set each connection not already using TLS is wrapped in TLS,
+// otherwise connections not using TLS are closed unless AllowPlaintext is
+// set. Serve returns nil when the server is
+// stopped, after all sessions have ended and SessionConfig.Validator is freed
+// unless KeepValidator is set.
+func (s *Server) Serve(l net.Listener) error {
+	s.sessionsMu.Lock()
 	s.sessionsWg = sync.WaitGroup{}
 	s.stopChan = make(chan struct{})
 	s.Sessions = map[string]*Session{}
+	s.listener = l
+	s.counter = newSessionCounter(s.MaxSessions, s.MaxSessionsPerClient, s.MaxSessionsPerCertificate)
+	s.sessionsMu.Unlock()
 
 	defer func() {
-		if closeErr := l.Close(); closeErr != nil {
-			fmt.Println(closeErr.Error())
+		if closeErr := l.Close(); closeErr != nil && !errors.Is(closeErr, net.ErrClosed) {
+			s.logger().Log(LogLevelError, "could not close listener", Field{Key: FieldError, Value: closeErr})
 		}
 
 		s.sessionsWg.Wait()
+
+		// The validator is shared by all sessions so it's not freed until
+		// all of them have ended.
+		if s.SessionConfig.Validator != nil && !s.KeepValidator {
+			s.SessionConfig.Validator.Free()
+		}
 	}()
 
-	tlsConfig := &tls.Config{}
+	var tlsConfig *tls.Config
 
 	// Use the same TLS config for the session if used on the server.
 	if s.TLSConfig != nil {
@@ -91,37 +163,35 @@ func (s *Server) Serve(l *net.TCPListener) error {
 	}
 
 	for {
-		// Reset deadline for the listener to stop blocking on accepting
-		// connections and allow shutdown.
-		if err := l.SetDeadline(time.Now().Add(1 * time.Second)); err != nil {
-			return err
-		}
-
-		conn, err := l.AcceptTCP()
+		conn, err := l.Accept()
 		if err != nil {
-			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-				select {
-				case <-s.stopChan:
-					return nil
-				default:
-					continue
-				}
+			// The listener is closed when the server is stopped.
+			select {
+			case <-s.stopChan:
+				return nil
+			default:
 			}
 
 			return err
 		}
 
 		// The connection must be allowed to be opened for up to 10 minutes
-		// without any manual activity so we enable keepalive on the TCP
-		// socket.
-		if err := conn.SetKeepAlive(true); err != nil {
-			log.Println(err.Error())
-			continue
-		}
+		// without any manual activity so we enable keepalive on TCP
+		// sockets.
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			if err := tcpConn.SetKeepAlive(true); err != nil {
+				s.logger().Log(LogLevelError, "could not enable keepalive", remoteAddrField(conn), Field{Key: FieldError, Value: err})
+				_ = conn.Close()
+
+				continue
+			}
 
-		if err := conn.SetKeepAlivePeriod(1 * time.Minute); err != nil {
-			log.Printf(err.Error())
-			continue
+			if err := tcpConn.SetKeepAlivePeriod(1 * time.Minute); err != nil {
+				s.logger().Log(LogLevelError, "could not set keepalive period", remoteAddrField(conn), Field{Key: FieldError, Value: err})
+				_ = conn.Close()
+
+				continue
+			}
 		}
 
 		go s.startSession(conn, tlsConfig)
@@ -129,21 +199,79 @@ func (s *Server) Serve(l *net.TCPListener) error {
 }
 
 func (s *Server) startSession(conn net.Conn, tlsConfig *tls.Config) {
-	// Initialize tls.
-	tlsConn := tls.Server(conn, tlsConfig)
+	// Initialize tls unless the listener already did.
+	if _, ok := conn.(*tls.Conn); !ok && tlsConfig != nil {
+		conn = tls.Server(conn, tlsConfig)
+	}
 
-	err := tlsConn.Handshake()
-	if err != nil {
-		log.Println(err.Error())
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if err := tlsConn.Handshake(); err != nil {
+			s.logger().Log(LogLevelWarn, "TLS handshake failed", remoteAddrField(conn), Field{Key: FieldError, Value: err})
+			_ = conn.Close()
+
+			return
+		}
+	} else if !s.AllowPlaintext {
+		s.logger().Log(LogLevelWarn, "connection not using TLS, closing", remoteAddrField(conn))
+		_ = conn.Close()
 
 		return
 	}
 
-	session := NewSession(tlsConn, s.SessionConfig)
+	config := s.SessionConfig
+	if config.Logger == nil {
+		config.Logger = s.Logger
+	}
 
-	// Ensure the session is added to our index.
-	s.sessionsWg.Add(1)
+	if config.Metrics == nil {
+		config.Metrics = s.Metrics
+	}
+
+	session := NewSession(conn, config)
+	session.metrics.SessionOpened()
+
+	var certificate string
+	if peerCertificates := session.ConnectionState().PeerCertificates; len(peerCertificates) > 0 {
+		certificate = CertificateFingerprint(peerCertificates[0])
+	}
+
+	if err := s.counter.acquire(certificate); err != nil {
+		session.log(LogLevelWarn, "session limit exceeded, rejecting session")
+
+		_ = session.writeResult(EppSessionLimitExceededBye, "")
+		_ = conn.Close()
+
+		session.metrics.SessionClosed(SessionCloseLimit)
+
+		return
+	}
+
+	session.counter = s.counter
+
+	defer func() {
+		if session.clientID != "" {
+			s.counter.releaseClient(session.clientID)
+		}
+
+		s.counter.release(certificate)
+	}()
+
+	// Ensure the session is added to our index unless the server was stopped
+	// during the handshake.
 	s.sessionsMu.Lock()
+
+	select {
+	case <-s.stopChan:
+		s.sessionsMu.Unlock()
+		_ = conn.Close()
+
+		session.metrics.SessionClosed(SessionCloseStop)
+
+		return
+	default:
+	}
+
+	s.sessionsWg.Add(1)
 	s.Sessions[session.SessionID] = session
 	s.sessionsMu.Unlock()
 
@@ -159,29 +287,136 @@ func (s *Server) startSession(conn net.Conn, tlsConfig *tls.Config) {
 		s.sessionsMu.Unlock()
 		s.sessionsWg.Done()
 
-		log.Println("session completed")
+		session.log(LogLevelInfo, "session completed")
 	}()
 
-	log.Println("starting session", session.SessionID)
+	session.log(LogLevelInfo, "starting session")
+
+	err := session.run()
+	if err != nil {
+		session.log(LogLevelError, "session ended with error", Field{Key: FieldError, Value: err})
+	}
+
+	session.metrics.SessionClosed(session.sessionCloseReason(err))
+}
+
+// logger returns the logger to use for the server.
+func (s *Server) logger() Logger {
+	return defaultLogger(s.Logger)
+}
+
+// remoteAddrField returns a field with the remote address for conn.
+func remoteAddrField(conn net.Conn) Field {
+	return Field{Key: FieldRemoteAddr, Value: conn.RemoteAddr().String()}
+}
+
+// SessionCounts returns the number of active sessions in total, per client ID
+// and per client certificate.
+func (s *Server) SessionCounts() SessionCounts {
+	s.sessionsMu.Lock()
+	counter := s.counter
+	s.sessionsMu.Unlock()
 
-	if err = session.run(); err != nil {
-		log.Println(err)
+	if counter == nil {
+		return SessionCounts{
+			Clients:      map[string]int{},
+			Certificates: map[string]int{},
+		}
 	}
+
+	return counter.counts()
 }
 
-// Stop will close the channel making no new regquests being processed and then
-// drain all ongoing requests til they're done.
+// Stop will close the listener making no new connections being accepted and
+// then tell all ongoing sessions to close without saying goodbye to the
+// clients. Use Shutdown to let the sessions finish the current command and
+// respond with a final result to the clients.
 func (s *Server) Stop() {
 	s.sessionsMu.Lock()
 	defer s.sessionsMu.Unlock()
 
-	log.Print("stopping listener channel")
-
-	close(s.stopChan)
+	if !s.stopListener() {
+		return
+	}
 
 	for _, session := range s.Sessions {
 		if err := session.Close(); err != nil {
-			log.Println("error closing session:", err.Error())
+			session.log(LogLevelError, "could not close session", Field{Key: FieldError, Value: err})
 		}
 	}
 }
+
+// Shutdown will close the listener making no new connections being accepted
+// and then tell all ongoing sessions to finish their current command, respond
+// with the result code 2500 and ShutdownMessage and close. Shutdown waits for
+// all sessions to close or until ctx is done. If ctx is done before all
+// sessions are closed the remaining connections are closed and the error from
+// ctx is returned directly without waiting for the sessions to end, e.g. if a
+// handler never returns. Serve returns when all sessions have ended.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.sessionsMu.Lock()
+
+	if !s.stopListener() {
+		s.sessionsMu.Unlock()
+
+		return nil
+	}
+
+	for _, session := range s.Sessions {
+		session.shutdown(s.ShutdownMessage)
+	}
+
+	s.sessionsMu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		s.sessionsWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.sessionsMu.Lock()
+
+	for _, session := range s.Sessions {
+		session.log(LogLevelWarn, "forcing session to close")
+
+		if err := session.conn.Close(); err != nil {
+			session.log(LogLevelError, "could not close connection", Field{Key: FieldError, Value: err})
+		}
+	}
+
+	s.sessionsMu.Unlock()
+
+	return ctx.Err()
+}
+
+// stopListener will mark the server as stopped and close the listener. False
+// is returned if the server isn't started or already stopped. The sessions
+// mutex must be held while calling stopListener.
+func (s *Server) stopListener() bool {
+	if s.stopChan == nil {
+		return false
+	}
+
+	select {
+	case <-s.stopChan:
+		return false
+	default:
+	}
+
+	s.logger().Log(LogLevelInfo, "stopping listener")
+
+	close(s.stopChan)
+
+	if err := s.listener.Close(); err != nil {
+		s.logger().Log(LogLevelError, "could not close listener", Field{Key: FieldError, Value: err})
+	}
+
+	return true
+}
@@ -1,16 +1,24 @@
 package epp
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
+	"net"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/bombsimon/epp-go/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -33,7 +41,7 @@ func TestServer(t *testing.T) {
 				return []byte(data), nil
 			},
 			Greeting: func(s *Session) ([]byte, error) {
-				return []byte("hello"), nil
+				return testGreeting, nil
 			},
 		},
 		OnStarteds: []func(){
@@ -63,7 +71,11 @@ func TestServer(t *testing.T) {
 	greeting, err := client.Connect(":9889")
 	require.Nil(t, err)
 
-	assert.Equal(t, string(greeting), "hello")
+	assert.Equal(t, string(testGreeting), string(greeting))
+	assert.Equal(t, []string{"1.0"}, client.Versions())
+	assert.True(t, client.SupportsObject("urn:ietf:params:xml:ns:obj1"))
+	assert.True(t, client.SupportsExtension(types.NameSpaceDNSSEC11))
+	assert.NotNil(t, client.DCP().Access.All)
 
 	for i := 0; i < 5; i++ {
 		data := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>
@@ -77,6 +89,960 @@ func TestServer(t *testing.T) {
 	}
 }
 
+func TestServerConcurrentSessions(t *testing.T) {
+	validator, err := NewDefaultValidator()
+	require.Nil(t, err)
+
+	didStart := make(chan struct{})
+	didStop := make(chan struct{})
+
+	srv := Server{
+		Addr: ":9890",
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{generateCertificate()},
+		},
+		SessionConfig: SessionConfig{
+			IdleTimeout:    10 * time.Minute,
+			SessionTimeout: 10 * time.Minute,
+			Validator:      validator,
+			Handler: func(s *Session, in []byte) ([]byte, error) {
+				return in, nil
+			},
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+		OnStarteds: []func(){
+			func() {
+				close(didStart)
+			},
+		},
+	}
+
+	go func() {
+		defer close(didStop)
+
+		if err := srv.ListenAndServe(); err != nil {
+			panic(err)
+		}
+	}()
+
+	<-didStart
+
+	var (
+		wg       sync.WaitGroup
+		sessions = 20
+		messages = 10
+		errs     = make(chan error, sessions*messages)
+	)
+
+	for i := 0; i < sessions; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			client := &Client{
+				TLSConfig: &tls.Config{
+					InsecureSkipVerify: true,
+				},
+			}
+
+			if _, err := client.Connect(":9890"); err != nil {
+				errs <- err
+				return
+			}
+
+			// Every other client disconnects half way through to end
+			// its session while the others are still validating.
+			n := messages
+			if i%2 == 0 {
+				n = messages / 2
+			}
+
+			for j := 0; j < n; j++ {
+				data := []byte(fmt.Sprintf(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><hello>%d-%d</hello></epp>`, i, j))
+
+				response, err := client.Send(data)
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if string(response) != string(data) {
+					errs <- fmt.Errorf("unexpected response %s", string(response))
+				}
+			}
+
+			if i%2 == 0 {
+				_ = client.conn.Close()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+
+	// The validator should still be usable after sessions are closed and
+	// only be freed when the server is stopped.
+	assert.Nil(t, validator.Validate(testGreeting))
+
+	srv.Stop()
+	<-didStop
+
+	assert.NotNil(t, validator.Validate(testGreeting))
+}
+
+func TestServerKeepValidator(t *testing.T) {
+	validator, err := NewDefaultValidator()
+	require.Nil(t, err)
+
+	defer validator.Free()
+
+	for i := 0; i < 2; i++ {
+		l := newPipeListener()
+		didStop := make(chan struct{})
+
+		srv := Server{
+			AllowPlaintext: true,
+			Logger:         NopLogger{},
+			KeepValidator:  true,
+			SessionConfig: SessionConfig{
+				IdleTimeout:    10 * time.Minute,
+				SessionTimeout: 10 * time.Minute,
+				Validator:      validator,
+				Handler: func(s *Session, in []byte) ([]byte, error) {
+					return in, nil
+				},
+				Greeting: func(s *Session) ([]byte, error) {
+					return testGreeting, nil
+				},
+			},
+		}
+
+		go func() {
+			defer close(didStop)
+
+			_ = srv.Serve(l)
+		}()
+
+		conn, err := l.Dial()
+		require.Nil(t, err)
+
+		framer := NewFramer(conn)
+
+		greeting, err := framer.ReadMessage()
+		require.Nil(t, err)
+		assert.Equal(t, testGreeting, greeting)
+
+		require.Nil(t, conn.Close())
+
+		srv.Stop()
+		<-didStop
+
+		// The validator is kept when Serve returns and may be used again.
+		assert.Nil(t, validator.Validate(testGreeting))
+	}
+}
+
+func TestServerValidationError(t *testing.T) {
+	validator, err := NewDefaultValidator()
+	require.Nil(t, err)
+
+	didStart := make(chan struct{})
+
+	srv := Server{
+		Addr: ":9891",
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{generateCertificate()},
+		},
+		SessionConfig: SessionConfig{
+			IdleTimeout:    10 * time.Minute,
+			SessionTimeout: 10 * time.Minute,
+			Validator:      validator,
+			Handler: func(s *Session, in []byte) ([]byte, error) {
+				return in, nil
+			},
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+		OnStarteds: []func(){
+			func() {
+				close(didStart)
+			},
+		},
+	}
+
+	defer srv.Stop()
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil {
+			panic(err)
+		}
+	}()
+
+	<-didStart
+
+	client := &Client{
+		TLSConfig: &tls.Config{
+			InsecureSkipVerify: true,
+		},
+	}
+
+	_, err = client.Connect(":9891")
+	require.Nil(t, err)
+
+	response, err := client.Send([]byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <poll op="invalid"/>
+    <clTRID>ABC-12345</clTRID>
+  </command>
+</epp>`))
+	require.Nil(t, err)
+
+	decoded, err := DecodeResponse(response)
+	require.Nil(t, err)
+
+	assert.Equal(t, EppSyntaxError, decoded.Code())
+	assert.Equal(t, "ABC-12345", decoded.TransactionID.ClientTransactionID)
+	require.NotNil(t, decoded.Result[0].ExternalValue)
+	assert.Contains(t, decoded.Result[0].ExternalValue.Reason, "op")
+
+	// The session should still be usable after an invalid command.
+	hello := []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><hello/></epp>`)
+
+	response, err = client.Send(hello)
+	require.Nil(t, err)
+	assert.Equal(t, string(hello), string(response))
+}
+
+func TestServerFrameSize(t *testing.T) {
+	didStart := make(chan struct{})
+
+	srv := Server{
+		Addr: ":9892",
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{generateCertificate()},
+		},
+		SessionConfig: SessionConfig{
+			IdleTimeout:    10 * time.Minute,
+			SessionTimeout: 10 * time.Minute,
+			MaxFrameSize:   256,
+			Handler: func(s *Session, in []byte) ([]byte, error) {
+				return in, nil
+			},
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+		OnStarteds: []func(){
+			func() {
+				close(didStart)
+			},
+		},
+	}
+
+	defer srv.Stop()
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil {
+			panic(err)
+		}
+	}()
+
+	<-didStart
+
+	client := &Client{
+		TLSConfig: &tls.Config{
+			InsecureSkipVerify: true,
+		},
+	}
+
+	_, err := client.Connect(":9892")
+	require.Nil(t, err)
+
+	// An oversized frame is discarded and the session continues.
+	response, err := client.Send([]byte(fmt.Sprintf(
+		`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><hello>%s</hello></epp>`,
+		strings.Repeat("x", 512),
+	)))
+	require.Nil(t, err)
+
+	decoded, err := DecodeResponse(response)
+	require.Nil(t, err)
+	assert.Equal(t, EppSyntaxError, decoded.Code())
+
+	hello := []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><hello/></epp>`)
+
+	response, err = client.Send(hello)
+	require.Nil(t, err)
+	assert.Equal(t, string(hello), string(response))
+
+	// A frame length smaller than the header closes the session.
+	_, err = client.conn.Write([]byte{0, 0, 0, 2})
+	require.Nil(t, err)
+
+	response, err = client.framer.ReadMessage()
+	require.Nil(t, err)
+
+	decoded, err = DecodeResponse(response)
+	require.Nil(t, err)
+	assert.Equal(t, EppCommandFailedBye, decoded.Code())
+
+	_, err = client.framer.ReadMessage()
+	assert.NotNil(t, err)
+}
+
+func TestServerFrameTimeout(t *testing.T) {
+	l := newPipeListener()
+
+	srv := Server{
+		AllowPlaintext: true,
+		Logger:         NopLogger{},
+		SessionConfig: SessionConfig{
+			IdleTimeout:    10 * time.Minute,
+			SessionTimeout: 10 * time.Minute,
+			Handler: func(s *Session, in []byte) ([]byte, error) {
+				return in, nil
+			},
+			Greeting: func(s *Session) ([]byte, error) {
+				s.framer.ReadTimeout = 100 * time.Millisecond
+
+				return testGreeting, nil
+			},
+		},
+	}
+
+	go func() {
+		_ = srv.Serve(l)
+	}()
+
+	defer srv.Stop()
+
+	conn, err := l.Dial()
+	require.Nil(t, err)
+
+	defer conn.Close()
+
+	require.Nil(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	framer := NewFramer(conn)
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	// The header and only a part of the content is sent so the server can't
+	// continue to read frames from the stream.
+	_, err = conn.Write(append([]byte{0, 0, 0, 100}, "<epp xmlns"...))
+	require.Nil(t, err)
+
+	response, err := framer.ReadMessage()
+	require.Nil(t, err)
+
+	decoded, err := DecodeResponse(response)
+	require.Nil(t, err)
+	assert.Equal(t, EppCommandFailedBye, decoded.Code())
+
+	_, err = framer.ReadMessage()
+	assert.True(t, errors.Is(err, io.EOF))
+}
+
+func TestServerServeListener(t *testing.T) {
+	cases := []struct {
+		description string
+		listener    func(l net.Listener) net.Listener
+		dial        func(conn net.Conn) net.Conn
+		tls         bool
+	}{
+		{
+			description: "plain connections",
+			listener:    func(l net.Listener) net.Listener { return l },
+			dial:        func(conn net.Conn) net.Conn { return conn },
+		},
+		{
+			description: "pre-wrapped TLS listener",
+			listener: func(l net.Listener) net.Listener {
+				return tls.NewListener(l, &tls.Config{
+					Certificates: []tls.Certificate{generateCertificate()},
+				})
+			},
+			dial: func(conn net.Conn) net.Conn {
+				return tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+			},
+			tls: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			l := newPipeListener()
+
+			srv := Server{
+				AllowPlaintext: !tc.tls,
+				SessionConfig: SessionConfig{
+					IdleTimeout:    10 * time.Minute,
+					SessionTimeout: 10 * time.Minute,
+					Handler: func(s *Session, in []byte) ([]byte, error) {
+						assert.Equal(t, tc.tls, s.ConnectionState().HandshakeComplete)
+
+						return in, nil
+					},
+					Greeting: func(s *Session) ([]byte, error) {
+						return testGreeting, nil
+					},
+				},
+			}
+
+			served := make(chan error)
+
+			go func() {
+				served <- srv.Serve(tc.listener(l))
+			}()
+
+			conn, err := l.Dial()
+			require.Nil(t, err)
+
+			framer := NewFramer(tc.dial(conn))
+
+			greeting, err := framer.ReadMessage()
+			require.Nil(t, err)
+			assert.Equal(t, string(testGreeting), string(greeting))
+
+			hello := []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><hello/></epp>`)
+
+			require.Nil(t, framer.WriteMessage(hello))
+
+			response, err := framer.ReadMessage()
+			require.Nil(t, err)
+			assert.Equal(t, string(hello), string(response))
+
+			// Stopping the server closes the listener immediately. The
+			// client is closed since writes to a pipe blocks until read,
+			// e.g. the TLS close notify.
+			srv.Stop()
+			require.Nil(t, conn.Close())
+
+			select {
+			case err := <-served:
+				assert.Nil(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("server did not stop")
+			}
+
+			_, err = l.Dial()
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestServerPlaintext(t *testing.T) {
+	srv := Server{Addr: ":9893"}
+	assert.Equal(t, ErrTLSConfigRequired, srv.ListenAndServe())
+
+	l := newPipeListener()
+
+	srv = Server{
+		Logger: NopLogger{},
+		SessionConfig: SessionConfig{
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+	}
+
+	go func() {
+		_ = srv.Serve(l)
+	}()
+
+	defer srv.Stop()
+
+	// Connections not using TLS are closed without a greeting.
+	conn, err := l.Dial()
+	require.Nil(t, err)
+
+	defer conn.Close()
+
+	_, err = NewFramer(conn).ReadMessage()
+	assert.True(t, errors.Is(err, io.EOF))
+}
+
+func TestServerShutdown(t *testing.T) {
+	l := newPipeListener()
+
+	received := make(chan struct{})
+	release := make(chan struct{})
+
+	srv := Server{
+		AllowPlaintext:  true,
+		ShutdownMessage: "Server is shutting down",
+		SessionConfig: SessionConfig{
+			IdleTimeout:    10 * time.Minute,
+			SessionTimeout: 10 * time.Minute,
+			Handler: func(s *Session, in []byte) ([]byte, error) {
+				close(received)
+				<-release
+
+				return in, nil
+			},
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+	}
+
+	go func() {
+		_ = srv.Serve(l)
+	}()
+
+	conn, err := l.Dial()
+	require.Nil(t, err)
+
+	defer conn.Close()
+
+	framer := NewFramer(conn)
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	hello := []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><hello/></epp>`)
+	require.Nil(t, framer.WriteMessage(hello))
+
+	<-received
+
+	shutdown := make(chan error)
+
+	go func() {
+		shutdown <- srv.Shutdown(context.Background())
+	}()
+
+	// The command being processed is completed before the goodbye.
+	close(release)
+
+	response, err := framer.ReadMessage()
+	require.Nil(t, err)
+	assert.Equal(t, string(hello), string(response))
+
+	response, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	decoded, err := DecodeResponse(response)
+	require.Nil(t, err)
+	assert.Equal(t, EppCommandFailedBye, decoded.Code())
+	assert.Equal(t, "Server is shutting down", decoded.Result[0].Message)
+
+	select {
+	case err := <-shutdown:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not complete")
+	}
+}
+
+func TestServerShutdown_deadline(t *testing.T) {
+	l := newPipeListener()
+
+	received := make(chan struct{})
+	release := make(chan struct{})
+
+	defer close(release)
+
+	srv := Server{
+		AllowPlaintext: true,
+		SessionConfig: SessionConfig{
+			IdleTimeout:    10 * time.Minute,
+			SessionTimeout: 10 * time.Minute,
+			Handler: func(s *Session, in []byte) ([]byte, error) {
+				close(received)
+				<-release
+
+				return in, nil
+			},
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+	}
+
+	go func() {
+		_ = srv.Serve(l)
+	}()
+
+	conn, err := l.Dial()
+	require.Nil(t, err)
+
+	defer conn.Close()
+
+	framer := NewFramer(conn)
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	require.Nil(t, framer.WriteMessage([]byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><hello/></epp>`)))
+
+	<-received
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Shutdown returns when ctx is done even though the handler is stuck.
+	assert.Equal(t, context.DeadlineExceeded, srv.Shutdown(ctx))
+
+	// The connection is closed by the server.
+	_, err = framer.ReadMessage()
+	assert.NotNil(t, err)
+}
+
+func TestServerShutdown_rateLimited(t *testing.T) {
+	l := newPipeListener()
+
+	srv := Server{
+		AllowPlaintext:  true,
+		ShutdownMessage: "Server is shutting down",
+		SessionConfig: SessionConfig{
+			IdleTimeout:    10 * time.Minute,
+			SessionTimeout: 10 * time.Minute,
+			RateLimiter: &RateLimiter{
+				PerSession: map[CommandClass]Rate{
+					CommandClassQuery: {Limit: 0.01, Burst: 1},
+				},
+				MaxDelay: 10 * time.Minute,
+			},
+			Handler: func(s *Session, in []byte) ([]byte, error) {
+				return in, nil
+			},
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+	}
+
+	go func() {
+		_ = srv.Serve(l)
+	}()
+
+	conn, err := l.Dial()
+	require.Nil(t, err)
+
+	defer conn.Close()
+
+	framer := NewFramer(conn)
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	require.Nil(t, framer.WriteMessage(rateLimitCheck))
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	// The second command is delayed for 100 seconds waiting for a token.
+	require.Nil(t, framer.WriteMessage(rateLimitCheck))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shutdown := make(chan error)
+
+	go func() {
+		shutdown <- srv.Shutdown(ctx)
+	}()
+
+	response, err := framer.ReadMessage()
+	require.Nil(t, err)
+
+	decoded, err := DecodeResponse(response)
+	require.Nil(t, err)
+	assert.Equal(t, EppCommandFailedBye, decoded.Code())
+
+	assert.Nil(t, <-shutdown)
+}
+
+func TestServerSessionLimits(t *testing.T) {
+	serverCert := generateCertificate()
+	clientCerts := []tls.Certificate{generateCertificate(), generateCertificate()}
+
+	l := newPipeListener()
+
+	srv := Server{
+		MaxSessions:               3,
+		MaxSessionsPerClient:      1,
+		MaxSessionsPerCertificate: 2,
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientAuth:   tls.RequireAnyClientCert,
+		},
+		SessionConfig: SessionConfig{
+			IdleTimeout:    10 * time.Minute,
+			SessionTimeout: 10 * time.Minute,
+			Handler: func(s *Session, in []byte) ([]byte, error) {
+				code := EppOk
+				if err := s.Login(string(in)); err != nil {
+					code = EppSessionLimitExceededBye
+				}
+
+				return Encode(types.Response{
+					Result: []types.Result{{Code: code.Code(), Message: code.Message()}},
+				}, ServerXMLAttributes())
+			},
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+	}
+
+	go func() {
+		_ = srv.Serve(l)
+	}()
+
+	defer srv.Stop()
+
+	connect := func(cert tls.Certificate) (*Framer, []byte) {
+		conn, err := l.Dial()
+		require.Nil(t, err)
+
+		framer := NewFramer(tls.Client(conn, &tls.Config{
+			InsecureSkipVerify: true,
+			Certificates:       []tls.Certificate{cert},
+		}))
+
+		greeting, err := framer.ReadMessage()
+		require.Nil(t, err)
+
+		return framer, greeting
+	}
+
+	code := func(data []byte) ResultCode {
+		response, err := DecodeResponse(data)
+		require.Nil(t, err)
+
+		return response.Code()
+	}
+
+	login := func(framer *Framer, clientID string) ResultCode {
+		require.Nil(t, framer.WriteMessage([]byte(clientID)))
+
+		response, err := framer.ReadMessage()
+		require.Nil(t, err)
+
+		return code(response)
+	}
+
+	first, greeting := connect(clientCerts[0])
+	assert.Equal(t, string(testGreeting), string(greeting))
+	assert.Equal(t, EppOk, login(first, "client-1"))
+
+	// The second session for the same client ID is closed after the
+	// response.
+	second, _ := connect(clientCerts[0])
+	assert.Equal(t, EppSessionLimitExceededBye, login(second, "client-1"))
+
+	_, err := second.ReadMessage()
+	assert.NotNil(t, err)
+
+	// Wait for the closed session to be released.
+	for i := 0; srv.SessionCounts().Total != 1; i++ {
+		require.True(t, i < 100, "session was not released")
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	third, _ := connect(clientCerts[0])
+	assert.Equal(t, EppOk, login(third, "client-2"))
+
+	// The limit per certificate is reached before login.
+	_, greeting = connect(clientCerts[0])
+	assert.Equal(t, EppSessionLimitExceededBye, code(greeting))
+
+	_, greeting = connect(clientCerts[1])
+	assert.Equal(t, string(testGreeting), string(greeting))
+
+	// The global limit is reached.
+	_, greeting = connect(clientCerts[1])
+	assert.Equal(t, EppSessionLimitExceededBye, code(greeting))
+
+	counts := srv.SessionCounts()
+	assert.Equal(t, 3, counts.Total)
+	assert.Equal(t, map[string]int{"client-1": 1, "client-2": 1}, counts.Clients)
+
+	leaf, err := x509.ParseCertificate(clientCerts[0].Certificate[0])
+	require.Nil(t, err)
+	assert.Equal(t, 2, counts.Certificates[CertificateFingerprint(leaf)])
+}
+
+func TestServerLogout(t *testing.T) {
+	l := newPipeListener()
+
+	srv := Server{
+		AllowPlaintext: true,
+		SessionConfig: SessionConfig{
+			IdleTimeout:    10 * time.Minute,
+			SessionTimeout: 10 * time.Minute,
+			Handler: func(s *Session, in []byte) ([]byte, error) {
+				s.Logout()
+
+				return Encode(types.Response{
+					Result: []types.Result{{Code: EppOkBye.Code(), Message: EppOkBye.Message()}},
+				}, ServerXMLAttributes())
+			},
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+	}
+
+	go func() {
+		_ = srv.Serve(l)
+	}()
+
+	defer srv.Stop()
+
+	conn, err := l.Dial()
+	require.Nil(t, err)
+
+	// Setting a deadline on a pipe fails when the other end is closed, hide
+	// the deadlines since the session is closed right after the response.
+	framer := NewFramer(struct{ io.ReadWriter }{conn})
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	logout, err := os.ReadFile("xml/commands/logout.xml")
+	require.Nil(t, err)
+
+	require.Nil(t, framer.WriteMessage(logout))
+
+	data, err := framer.ReadMessage()
+	require.Nil(t, err)
+
+	response, err := DecodeResponse(data)
+	require.Nil(t, err)
+	assert.Equal(t, EppOkBye, response.Code())
+
+	// The session is closed after the response.
+	_, err = framer.ReadMessage()
+	assert.NotNil(t, err)
+
+	for i := 0; srv.SessionCounts().Total > 0 && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Equal(t, 0, srv.SessionCounts().Total)
+}
+
+func TestServerRateLimit(t *testing.T) {
+	l := newPipeListener()
+
+	srv := Server{
+		AllowPlaintext: true,
+		SessionConfig: SessionConfig{
+			IdleTimeout:    10 * time.Minute,
+			SessionTimeout: 10 * time.Minute,
+			RateLimiter: &RateLimiter{
+				PerSession: map[CommandClass]Rate{
+					CommandClassQuery: {Limit: 0.001, Burst: 1},
+				},
+				Message: "Rate limit exceeded",
+			},
+			Handler: func(s *Session, in []byte) ([]byte, error) {
+				return in, nil
+			},
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+	}
+
+	go func() {
+		_ = srv.Serve(l)
+	}()
+
+	defer srv.Stop()
+
+	conn, err := l.Dial()
+	require.Nil(t, err)
+
+	defer conn.Close()
+
+	framer := NewFramer(conn)
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	require.Nil(t, framer.WriteMessage(rateLimitCheck))
+
+	response, err := framer.ReadMessage()
+	require.Nil(t, err)
+	assert.Equal(t, string(rateLimitCheck), string(response))
+
+	require.Nil(t, framer.WriteMessage(rateLimitCheck))
+
+	response, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	decoded, err := DecodeResponse(response)
+	require.Nil(t, err)
+	assert.Equal(t, EppCommandFailed, decoded.Code())
+	assert.Equal(t, "Rate limit exceeded", decoded.Result[0].Message)
+	assert.Equal(t, "ABC-12345", decoded.TransactionID.ClientTransactionID)
+}
+
+// pipeListener is an in-memory net.Listener where connections are created
+// with net.Pipe.
+type pipeListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+}
+
+// Dial creates a new connection to the listener.
+func (l *pipeListener) Dial() (net.Conn, error) {
+	client, server := net.Pipe()
+
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+	})
+
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
+
 func generateCertificate() tls.Certificate {
 	key, _ := rsa.GenerateKey(rand.Reader, 2048)
 
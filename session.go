@@ -1,13 +1,16 @@
 package epp
 
 import (
+	"context"
 	"crypto/tls"
-	"log"
+	"errors"
+	"io"
 	"net"
+	"sync"
 	"time"
 
+	"github.com/bombsimon/epp-go/types"
 	"github.com/google/uuid"
-	xsd "github.com/lestrrat-go/libxml2/xsd"
 )
 
 // HandlerFunc represents a function for an EPP message.
@@ -29,6 +32,14 @@ type SessionConfig struct {
 	// disconnedted after the current command being processed has finished.
 	SessionTimeout time.Duration
 
+	// MaxFrameSize is the maximum size of a frame, including the four byte
+	// length header, accepted from clients. If a client sends a larger frame
+	// the content is discarded and the client gets a response with the
+	// result code 2001. If the content can't be discarded or the frame header
+	// is invalid the client gets a response with the result code 2500 and
+	// the session is closed. If zero DefaultMaxFrameSize is used.
+	MaxFrameSize int
+
 	// greeting holds the function that will generate the XML printed while
 	// greeting clients connection to the server.
 	Greeting GreetFunc
@@ -40,13 +51,60 @@ type SessionConfig struct {
 	// validator interface should be able to validate XML against an XSD schema
 	// (or any other way). If the validator is a non nil value all incomming
 	// *and* outgoing data will be passed through the validator. Type
-	// implementing this interface using libxml2 bindings is available in the
-	// library.
+	// implementing this interface using libxml2 bindings, or in pure Go when
+	// building with the purego tag, is available in the library.
+	//
+	// The validator is shared by all sessions and must be safe to use from
+	// multiple goroutines. Sessions never free the validator; when used with
+	// a Server the validator is freed when Serve returns after all sessions
+	// have ended, unless Server.KeepValidator is set.
 	Validator Validator
 
 	// OnCommands is a list of functions that will be executed on each command.
 	// This is the place to put external code to handle after each command.
 	OnCommands []func(sess *Session)
+
+	// RateLimiter limits the rate of commands for each session and client
+	// ID. Throttled commands are delayed or rejected before being passed to
+	// the handler. The rate limiter is shared by all sessions. If nil there
+	// is no rate limiting.
+	RateLimiter *RateLimiter
+
+	// Logger is used to log messages from the session. Each message holds
+	// the session ID, the remote address and the client ID when logged in.
+	// Each command is logged with the command path, the transaction IDs, the
+	// result code and the latency. If nil, messages are written with the
+	// standard logger from the log package.
+	Logger Logger
+
+	// Metrics is used to record metrics for the session such as frame sizes,
+	// validation failures and, when using Mux, the commands handled. If nil no
+	// metrics are recorded.
+	Metrics Metrics
+
+	// Tracer is used to create a span for each command. Handlers can create
+	// child spans with StartSpan and the context from Session.Context. If nil
+	// no spans are created.
+	Tracer Tracer
+
+	// TracePropagator is used to extract the span context carried by the
+	// command from the client to use as parent for the span for the command.
+	// If nil each command starts a new trace.
+	TracePropagator TracePropagator
+
+	// AuditSink receives a record for each command with the request and the
+	// response redacted by AuditRedactor. If nil no records are created.
+	AuditSink AuditSink
+
+	// AuditRedactor is used to redact the request and the response for the
+	// audit records. If nil DefaultRedactor is used.
+	AuditRedactor *Redactor
+
+	// Transcript is called when a session starts to create a transcript for
+	// the session. Each message sent and received by the session is written
+	// to the transcript which is closed when the session ends. If nil, or if
+	// the function returns nil, no transcript is written.
+	Transcript func(*Session) (*TranscriptWriter, error)
 }
 
 // Session is an active connection to the EPP server.
@@ -61,33 +119,115 @@ type Session struct {
 	// conn holds the TCP connection with a client.
 	conn net.Conn
 
+	// framer reads and writes frames on the connection.
+	framer *Framer
+
 	// stopChan is used to tell the session to terminate.
 	stopChan chan struct{}
 
+	// shutdownChan is closed to tell the session to say goodbye to the client
+	// with shutdownMessage and terminate after the current command.
+	shutdownChan    chan struct{}
+	shutdownOnce    sync.Once
+	shutdownMessage string
+
+	// counter holds the session counts for the server to enforce the limit
+	// of sessions per client ID when logging in. The session is closed after
+	// the response for a login exceeding the limit.
+	counter            *sessionCounter
+	clientID           string
+	closeAfterResponse bool
+
+	// loggedOut is set by Logout to end the session after the response.
+	loggedOut bool
+
+	// rateLimiter and rateBuckets holds the rate limiter and the token
+	// buckets for the session.
+	rateLimiter *RateLimiter
+	rateBuckets map[CommandClass]*tokenBucket
+
+	// logger is the logger for the session.
+	logger Logger
+
+	// metrics is the metrics for the session and closeReason is the reason
+	// the session ended, set by run.
+	metrics     Metrics
+	closeReason SessionCloseReason
+
+	// tracer and propagator is used to create a span for each command and ctx
+	// is the context holding the span for the current command.
+	tracer     Tracer
+	propagator TracePropagator
+	ctx        context.Context
+
+	// auditSink and redactor is used to audit each command.
+	auditSink AuditSink
+	redactor  *Redactor
+
+	// transcript is the transcript for the session and greeted is true when
+	// the greeting is written.
+	transcriptFunc func(*Session) (*TranscriptWriter, error)
+	transcript     *TranscriptWriter
+	greeted        bool
+
+	// response holds the last message written, used as the response for the
+	// command being handled.
+	response []byte
+
 	// Se configurables details in SessionConfig
 	IdleTimeout    time.Duration
 	SessionTimeout time.Duration
+	MaxFrameSize   int
 	greeting       GreetFunc
 	handler        HandlerFunc
 	onCommands     []func(sess *Session)
 	validator      Validator
 }
 
-// NewSession will create a new Session.
-func NewSession(conn *tls.Conn, cfg SessionConfig) *Session {
+// NewSession will create a new Session. If conn isn't a TLS connection the
+// ConnectionState for the session returns an empty state.
+func NewSession(conn net.Conn, cfg SessionConfig) *Session {
 	sessionID := uuid.New().String()
 
+	connectionState := func() tls.ConnectionState {
+		return tls.ConnectionState{}
+	}
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		connectionState = tlsConn.ConnectionState
+	}
+
 	s := &Session{
 		SessionID:       sessionID,
-		ConnectionState: conn.ConnectionState,
+		ConnectionState: connectionState,
 		conn:            conn,
+		framer:          NewFramer(conn),
 		stopChan:        make(chan struct{}),
+		shutdownChan:    make(chan struct{}),
 		IdleTimeout:     cfg.IdleTimeout,
 		SessionTimeout:  cfg.SessionTimeout,
+		MaxFrameSize:    cfg.MaxFrameSize,
 		greeting:        cfg.Greeting,
 		handler:         cfg.Handler,
 		onCommands:      cfg.OnCommands,
 		validator:       cfg.Validator,
+		rateLimiter:     cfg.RateLimiter,
+		logger:          defaultLogger(cfg.Logger),
+		metrics:         defaultMetrics(cfg.Metrics),
+		tracer:          cfg.Tracer,
+		propagator:      cfg.TracePropagator,
+		ctx:             context.Background(),
+		auditSink:       cfg.AuditSink,
+		redactor:        cfg.AuditRedactor,
+		transcriptFunc:  cfg.Transcript,
+	}
+
+	if s.redactor == nil {
+		s.redactor = DefaultRedactor
+	}
+
+	if s.rateLimiter != nil {
+		s.rateBuckets = s.rateLimiter.newSessionBuckets()
 	}
 
 	return s
@@ -97,6 +237,25 @@ func NewSession(conn *tls.Conn, cfg SessionConfig) *Session {
 func (s *Session) run() error {
 	defer s.conn.Close()
 
+	s.framer.MaxFrameSize = s.MaxFrameSize
+
+	if s.transcriptFunc != nil {
+		transcript, err := s.transcriptFunc(s)
+		if err != nil {
+			return err
+		}
+
+		if transcript != nil {
+			s.transcript = transcript
+
+			defer func() {
+				if err := transcript.Close(); err != nil {
+					s.log(LogLevelError, "could not close transcript", Field{Key: FieldError, Value: err})
+				}
+			}()
+		}
+	}
+
 	// Send the greeting to the client to do a proper greeting process, RFC5730,
 	// 2.4
 	response, err := s.greeting(s)
@@ -107,13 +266,12 @@ func (s *Session) run() error {
 
 	// Before the greeting is returned, ensure it's valid according to the EPP
 	// XSD.
-	if err := s.validate(response); err != nil {
+	if err := s.validate(response, DirectionOutbound); err != nil {
 		return err
 	}
 
 	// Write the greeting on the socket.
-	err = WriteMessage(s.conn, response)
-	if err != nil {
+	if err := s.writeMessage(response); err != nil {
 		return err
 	}
 
@@ -124,15 +282,23 @@ func (s *Session) run() error {
 	for {
 		select {
 		case <-s.stopChan:
-			log.Printf("stopping server, ending session %s", s.SessionID)
+			s.log(LogLevelInfo, "stopping server, ending session")
+			s.closeReason = SessionCloseStop
 
 			return nil
+		case <-s.shutdownChan:
+			s.log(LogLevelInfo, "shutting down server, ending session")
+			s.closeReason = SessionCloseStop
+
+			return s.writeResult(EppCommandFailedBye, s.shutdownMessage)
 		case <-sessionTimeout:
-			log.Printf("session has been active for %v minutes, ending session %s", s.SessionTimeout.Minutes(), s.SessionID)
+			s.log(LogLevelInfo, "session timeout reached, ending session", Field{Key: "session_timeout", Value: s.SessionTimeout})
+			s.closeReason = SessionCloseTimeout
 
 			return nil
 		case <-idleTimeout:
-			log.Printf("session has been idle for %v minutes, ending session %s", s.IdleTimeout.Minutes(), s.SessionID)
+			s.log(LogLevelInfo, "idle timeout reached, ending session", Field{Key: "idle_timeout", Value: s.IdleTimeout})
+			s.closeReason = SessionCloseIdle
 
 			return nil
 		default:
@@ -148,72 +314,289 @@ func (s *Session) run() error {
 		}
 
 		// Read from the socket and ensure that if we get an error we ignore it
-		// if there were no activity on the socket.
-		message, err := ReadMessage(s.conn)
+		// if there were no activity on the socket. A timeout while reading the
+		// content of a frame isn't a net.Error but a *FrameTimeoutError which
+		// ends the session.
+		message, err := s.framer.ReadMessage()
+		started := time.Now()
+
 		if err != nil {
 			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
 				continue
 			}
 
+			if err := s.handleFrameError(err); err != nil {
+				return err
+			}
+
+			idleTimeout = time.After(s.IdleTimeout)
+
+			continue
+		}
+
+		s.metrics.FrameSize(DirectionInbound, len(message)+4)
+		s.record(TranscriptRequest, message)
+
+		if err := s.handleCommand(message, started); err != nil {
 			return err
 		}
 
-		// Before we execute the command, perform all functions defined to be
-		// executed on each command. This might be where the user added rate
-		// limiting or other things to handle before executing the command.
-		for _, f := range s.onCommands {
-			f(s)
+		if s.closeAfterResponse {
+			s.log(LogLevelWarn, "session limit exceeded for client, ending session")
+			s.closeReason = SessionCloseLimit
+
+			return nil
 		}
 
-		// Validate the incomming XML data towards the RFC XSD.
-		if err := s.validate(message); err != nil {
-			return err
+		if s.loggedOut {
+			s.log(LogLevelInfo, "client logged out, ending session")
+			s.closeReason = SessionCloseClient
+
+			return nil
 		}
 
-		// Handle the message by passing it to the handler which may then take
-		// action or route the message.
-		response, err = s.handler(s, message)
+		// Extend the idle timeout.
+		idleTimeout = time.After(s.IdleTimeout)
+	}
+}
+
+// handleCommand will validate the message, pass it to the handler and write
+// the response. Invalid and rate limited commands are responded to without
+// calling the handler. If a tracer is configured the command is handled within
+// a span available from Context.
+func (s *Session) handleCommand(message []byte, started time.Time) (err error) {
+	// The command and the response are only parsed once and the summaries
+	// are shared by logging, metrics, tracing, auditing and rate limiting.
+	var (
+		command = summarizeCommand(message)
+		result  *messageSummary
+	)
+
+	ctx, span := s.startSpan(message, command)
+	s.ctx = ctx
+	s.response = nil
+
+	defer func() {
 		if err != nil {
-			return err
+			span.RecordError(err)
 		}
 
-		// Validate the response to from the handler towards the XSD so we
-		// don'tsend invalid XML to the client.
-		if err := s.validate(response); err != nil {
-			return err
+		// The response was written without being summarized, e.g. when
+		// the command was invalid or rate limited.
+		if result == nil {
+			response := summarizeResponse(s.response)
+			result = &response
 		}
 
-		// Write the message on the socket.
-		err = WriteMessage(s.conn, response)
-		if err != nil {
+		span.SetAttributes(result.fields()...)
+		span.End()
+		s.ctx = context.Background()
+
+		s.audit(message, s.response, command, *result)
+	}()
+
+	// Before we execute the command, perform all functions defined to be
+	// executed on each command. Rate limiting is handled by the RateLimiter
+	// after the command is validated.
+	for _, f := range s.onCommands {
+		f(s)
+	}
+
+	// Validate the incomming XML data towards the RFC XSD. Invalid commands
+	// are responded to with a syntax error holding the validation errors and
+	// the session continues.
+	if err := s.validate(message, DirectionInbound); err != nil {
+		s.log(LogLevelWarn, "invalid command", append(command.fields(), Field{Key: FieldError, Value: err})...)
+		span.RecordError(err)
+
+		return s.writeValidationError(message, err)
+	}
+
+	// Delay or reject the command if the client exceeds the rate limit.
+	if allowed, err := s.limitRate(command); err != nil || !allowed {
+		return err
+	}
+
+	// Handle the message by passing it to the handler which may then take
+	// action or route the message.
+	handlerStarted := time.Now()
+	response, err := s.handler(s, message)
+	handled := time.Since(handlerStarted)
+
+	summary := summarizeResponse(response)
+	s.metrics.CommandHandled(command.command, summary.resultCode, handled)
+
+	if err != nil {
+		return err
+	}
+
+	// Validate the response to from the handler towards the XSD so we don't
+	// send invalid XML to the client.
+	if err := s.validate(response, DirectionOutbound); err != nil {
+		return err
+	}
+
+	// Write the message on the socket.
+	if err := s.writeMessage(response); err != nil {
+		return err
+	}
+
+	result = &summary
+
+	s.log(
+		LogLevelDebug,
+		"command completed",
+		append(commandFields(command, summary), Field{Key: FieldLatency, Value: time.Since(started)})...,
+	)
+
+	return nil
+}
+
+// startSpan will start the span for the command in message with the span
+// context carried by the message as parent. If no tracer is configured a span
+// doing nothing is returned.
+func (s *Session) startSpan(message []byte, command messageSummary) (context.Context, Span) {
+	if s.tracer == nil {
+		return context.Background(), nopSpan{}
+	}
+
+	ctx := ContextWithTracer(context.Background(), s.tracer)
+
+	if s.propagator != nil {
+		if sc, ok := s.propagator.Extract(message); ok {
+			ctx = ContextWithRemoteSpanContext(ctx, sc)
+		}
+	}
+
+	name := command.command
+	if name == "" {
+		name = "unknown"
+	}
+
+	return s.tracer.Start(ctx, name, append(s.fields(), command.fields()...)...)
+}
+
+// audit will send a record for the request and the response, with their
+// summaries, to the audit sink, if any.
+func (s *Session) audit(request, response []byte, command, result messageSummary) {
+	if s.auditSink == nil {
+		return
+	}
+
+	record := newAuditRecord(s.redactor, request, response, command, result)
+	record.SessionID = s.SessionID
+	record.ClientID = s.clientID
+	record.RemoteAddr = s.conn.RemoteAddr().String()
+
+	if peerCertificates := s.ConnectionState().PeerCertificates; len(peerCertificates) > 0 {
+		record.CertificateFingerprint = CertificateFingerprint(peerCertificates[0])
+	}
+
+	if err := s.auditSink.Audit(record); err != nil {
+		s.log(LogLevelError, "could not write audit record", append(commandFields(command, result), Field{Key: FieldError, Value: err})...)
+	}
+}
+
+// Context returns the context for the command currently being handled. If a
+// tracer is configured the context holds the span for the command so handlers
+// can create child spans with StartSpan. Outside of a command a background
+// context is returned.
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+// Login will set the client ID for the session. This should be called by the
+// login handler when the client is authenticated. If the session would exceed
+// the maximum number of sessions for the client ID configured on the server
+// ErrSessionLimitExceeded is returned. The handler should then respond with
+// the result code 2502 and the session is closed after the response is
+// written.
+func (s *Session) Login(clientID string) error {
+	if clientID == s.clientID {
+		return nil
+	}
+
+	if s.counter != nil {
+		if err := s.counter.acquireClient(clientID); err != nil {
+			s.closeAfterResponse = true
+
 			return err
 		}
 
-		// Extend the idle timeout.
-		idleTimeout = time.After(s.IdleTimeout)
+		if s.clientID != "" {
+			s.counter.releaseClient(s.clientID)
+		}
 	}
+
+	s.clientID = clientID
+
+	return nil
+}
+
+// Logout will end the session after the response for the current command is
+// written. This should be called by the logout handler which should respond
+// with the result code 1500.
+func (s *Session) Logout() {
+	s.loggedOut = true
+}
+
+// ClientID returns the client ID set with Login or an empty string if the
+// client hasn't logged in.
+func (s *Session) ClientID() string {
+	return s.clientID
+}
+
+// shutdown will tell the session to write a response with the result code
+// 2500 and the message to the client and then close when the current command
+// is completed. If message is empty the default message for the code is used.
+func (s *Session) shutdown(message string) {
+	s.shutdownOnce.Do(func() {
+		s.shutdownMessage = message
+		close(s.shutdownChan)
+	})
+}
+
+// log will log the message with the fields identifying the session followed
+// by fields.
+func (s *Session) log(level LogLevel, msg string, fields ...Field) {
+	s.logger.Log(level, msg, append(s.fields(), fields...)...)
+}
+
+// fields returns the fields identifying the session, that is the session ID,
+// the remote address and the client ID if logged in.
+func (s *Session) fields() []Field {
+	fields := []Field{
+		{Key: FieldSessionID, Value: s.SessionID},
+		{Key: FieldRemoteAddr, Value: s.conn.RemoteAddr().String()},
+	}
+
+	if s.clientID != "" {
+		fields = append(fields, Field{Key: FieldClientID, Value: s.clientID})
+	}
+
+	return fields
 }
 
 // Close will tell the session to close.
 func (s *Session) Close() error {
 	close(s.stopChan)
 
-	if s.validator != nil {
-		s.validator.Free()
-	}
-
 	return nil
 }
 
-func (s *Session) validate(data []byte) error {
+// validate will validate data with the validator, if any, and record a
+// validation failure for the direction if the data is invalid.
+func (s *Session) validate(data []byte, direction Direction) error {
 	if s.validator == nil {
 		return nil
 	}
 
 	if err := s.validator.Validate(data); err != nil {
-		if xErr, ok := err.(xsd.SchemaValidationError); ok {
-			for _, e := range xErr.Errors() {
-				log.Printf("error: %s", e.Error())
+		s.metrics.ValidationFailed(direction)
+
+		if xErrors, ok := err.(ValidationErrors); ok {
+			for _, e := range xErrors {
+				s.log(LogLevelDebug, "validation error", Field{Key: FieldError, Value: e.Error()})
 			}
 		}
 
@@ -222,3 +605,186 @@ func (s *Session) validate(data []byte) error {
 
 	return nil
 }
+
+// handleFrameError handles errors from reading a frame. If the frame is too
+// large the content is discarded and a response with the result code 2001 is
+// written so the session may continue. For all other errors, or if the
+// content can't be discarded, a response with the result code 2500 is written
+// if possible and an error is returned to end the session.
+func (s *Session) handleFrameError(err error) error {
+	var (
+		sizeErr    *FrameSizeError
+		timeoutErr *FrameTimeoutError
+	)
+
+	switch {
+	case errors.As(err, &sizeErr):
+		s.log(LogLevelWarn, "frame too large, discarding frame", Field{Key: FieldError, Value: sizeErr})
+		s.metrics.FrameSize(DirectionInbound, int(sizeErr.Size))
+
+		if discardErr := s.framer.DiscardFrame(sizeErr.Size); discardErr != nil {
+			_ = s.writeResult(EppCommandFailedBye, "")
+
+			return discardErr
+		}
+
+		return s.writeResult(EppSyntaxError, "")
+	case errors.Is(err, ErrInvalidFrameHeader):
+		s.log(LogLevelWarn, "invalid frame header, ending session", Field{Key: FieldError, Value: err})
+
+		_ = s.writeResult(EppCommandFailedBye, "")
+
+		return err
+	case errors.As(err, &timeoutErr):
+		s.log(LogLevelWarn, "timeout reading frame, ending session", Field{Key: FieldError, Value: err})
+
+		_ = s.writeResult(EppCommandFailedBye, "")
+
+		return err
+	default:
+		return err
+	}
+}
+
+// writeMessage writes a frame with data to the client and records the frame
+// size.
+func (s *Session) writeMessage(data []byte) error {
+	if err := s.framer.WriteMessage(data); err != nil {
+		return err
+	}
+
+	s.metrics.FrameSize(DirectionOutbound, len(data)+4)
+	s.response = data
+
+	if s.greeted {
+		s.record(TranscriptResponse, data)
+	} else {
+		s.record(TranscriptGreeting, data)
+		s.greeted = true
+	}
+
+	return nil
+}
+
+// record will write the message to the transcript, if any.
+func (s *Session) record(kind TranscriptEntryKind, data []byte) {
+	if s.transcript == nil {
+		return
+	}
+
+	if err := s.transcript.Write(kind, data); err != nil {
+		s.log(LogLevelError, "could not write transcript", Field{Key: FieldError, Value: err})
+	}
+}
+
+// sessionCloseReason returns the reason the session was closed after run
+// returned err.
+func (s *Session) sessionCloseReason(err error) SessionCloseReason {
+	switch {
+	case s.closeReason != "":
+		return s.closeReason
+	case err == nil:
+		return SessionCloseStop
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrClosedPipe):
+		return SessionCloseClient
+	default:
+		return SessionCloseError
+	}
+}
+
+// writeResult writes a response with a single result with the code and the
+// message to the client. If message is empty the default message for the code
+// is used.
+func (s *Session) writeResult(code ResultCode, message string) error {
+	if message == "" {
+		message = code.Message()
+	}
+
+	response := types.Response{
+		Result: []types.Result{
+			{
+				Code:    code.Code(),
+				Message: message,
+			},
+		},
+		TransactionID: types.TransactionID{
+			ServerTransactionID: uuid.New().String(),
+		},
+	}
+
+	data, err := Encode(response, ServerXMLAttributes())
+	if err != nil {
+		return err
+	}
+
+	return s.writeMessage(data)
+}
+
+// limitRate will wait until the command is allowed by the rate limiter or
+// write a response rejecting the command and return false. An error is
+// returned if the response can't be written. If the session is stopped while
+// waiting the command is rejected without a response.
+func (s *Session) limitRate(command messageSummary) (bool, error) {
+	if s.rateLimiter == nil {
+		return true, nil
+	}
+
+	delay, done, ok := s.rateLimiter.reserve(s.rateBuckets, s.clientID, command.class)
+	if !ok {
+		s.log(LogLevelWarn, "rate limit exceeded, rejecting command", command.fields()...)
+
+		code, msg := s.rateLimiter.result()
+
+		response := types.Response{
+			Result: []types.Result{
+				{
+					Code:    code.Code(),
+					Message: msg,
+				},
+			},
+			TransactionID: types.TransactionID{
+				ClientTransactionID: command.clientTransactionID,
+				ServerTransactionID: uuid.New().String(),
+			},
+		}
+
+		data, err := Encode(response, ServerXMLAttributes())
+		if err != nil {
+			return false, err
+		}
+
+		return false, s.writeMessage(data)
+	}
+
+	defer done()
+
+	if delay <= 0 {
+		return true, nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-s.stopChan:
+		return false, nil
+	case <-s.shutdownChan:
+		return false, nil
+	}
+}
+
+// writeValidationError writes a response with the result code 2001 holding
+// the validation errors for the message to the client.
+func (s *Session) writeValidationError(message []byte, err error) error {
+	response := CreateValidationErrorResponse(message, err)
+	response.TransactionID.ServerTransactionID = uuid.New().String()
+
+	data, err := Encode(response, ServerXMLAttributes())
+	if err != nil {
+		return err
+	}
+
+	return s.writeMessage(data)
+}
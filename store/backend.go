@@ -0,0 +1,79 @@
+package store
+
+// Key identifies a record in a Backend. The name of a record is unique for
+// the kind. A key with an empty name refers to the kind itself, see Changes.
+type Key struct {
+	Kind string
+	Name string
+}
+
+// Record is the encoded data for an object in a Backend. The version is set
+// by the backend when the record is written and must increase with each
+// write, also after the record has been deleted and created again. The
+// version of a record that doesn't exist is zero.
+type Record struct {
+	Key
+	Version int64
+	Data    []byte
+}
+
+// Changes holds the changes made in a transaction.
+type Changes struct {
+	// Reads holds the versions of the records read in the transaction,
+	// zero for records that didn't exist. A key with an empty name holds the
+	// version of the kind as returned by List.
+	Reads map[Key]int64
+
+	// Writes holds the data for records created or replaced in the
+	// transaction and nil for deleted records.
+	Writes map[Key][]byte
+}
+
+// Backend holds the records for a Store. All methods must be safe to use
+// from multiple goroutines.
+type Backend interface {
+	// Get returns the record with the key. A record that doesn't exist is
+	// returned with version zero and no data.
+	Get(key Key) (Record, error)
+
+	// List returns all records of the kind sorted by name together with the
+	// version of the kind. The version of a kind must increase each time a
+	// record of the kind is written.
+	List(kind string) ([]Record, int64, error)
+
+	// Apply atomically applies the writes if the versions of all records
+	// and kinds read are unchanged and returns ErrConflict if they're not.
+	Apply(changes Changes) error
+
+	// Next increments the sequence with the name and returns the new value,
+	// starting at one. Sequences aren't part of transactions.
+	Next(sequence string) (int64, error)
+
+	// Close closes the backend.
+	Close() error
+}
+
+// New creates a Store on top of the backend.
+func New(backend Backend) Store {
+	return &store{backend: backend}
+}
+
+// store is a Store using a Backend.
+type store struct {
+	backend Backend
+}
+
+// Begin implements Store.
+func (s *store) Begin() (Tx, error) {
+	return &tx{
+		backend: s.backend,
+		reads:   map[Key]int64{},
+		cache:   map[Key][]byte{},
+		writes:  map[Key][]byte{},
+	}, nil
+}
+
+// Close implements Store.
+func (s *store) Close() error {
+	return s.backend.Close()
+}
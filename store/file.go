@@ -0,0 +1,67 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// OpenFile opens a Store keeping all objects in memory and in a JSON file at
+// the path. The file is created if it doesn't exist. Each commit rewrites the
+// whole file to a temporary file which is then renamed so the file always
+// holds the state after a complete commit. The file must only be used by one
+// Store at a time.
+func OpenFile(path string) (Store, error) {
+	s := newState()
+
+	data, err := os.ReadFile(path)
+
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, s); err != nil {
+			return nil, err
+		}
+	}
+
+	return New(&memoryBackend{
+		state: s,
+		save: func(s *state) error {
+			return writeFile(path, s)
+		},
+	}), nil
+}
+
+// writeFile writes the state to the path by writing it to a temporary file
+// in the same directory and renaming it.
+func writeFile(path string, s *state) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
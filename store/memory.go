@@ -0,0 +1,211 @@
+package store
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// state holds all records and sequences for the memory and the file
+// backends. The data for each record is JSON as written by the Store.
+type state struct {
+	Version   int64                 `json:"version"`
+	Kinds     map[string]*kindState `json:"kinds"`
+	Sequences map[string]int64      `json:"sequences"`
+}
+
+// kindState holds the records of a kind and the version of the kind.
+type kindState struct {
+	Version int64                  `json:"version"`
+	Records map[string]recordState `json:"records"`
+}
+
+type recordState struct {
+	Version int64           `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func newState() *state {
+	return &state{
+		Kinds:     map[string]*kindState{},
+		Sequences: map[string]int64{},
+	}
+}
+
+// version returns the version of the record with the key or the version of
+// the kind if the name is empty.
+func (s *state) version(key Key) int64 {
+	k, ok := s.Kinds[key.Kind]
+	if !ok {
+		return 0
+	}
+
+	if key.Name == "" {
+		return k.Version
+	}
+
+	return k.Records[key.Name].Version
+}
+
+// apply applies the changes if there are no conflicts.
+func (s *state) apply(changes Changes) error {
+	for key, version := range changes.Reads {
+		if s.version(key) != version {
+			return ErrConflict
+		}
+	}
+
+	s.Version++
+
+	for key, data := range changes.Writes {
+		k, ok := s.Kinds[key.Kind]
+		if !ok {
+			k = &kindState{Records: map[string]recordState{}}
+			s.Kinds[key.Kind] = k
+		}
+
+		k.Version = s.Version
+
+		if data == nil {
+			delete(k.Records, key.Name)
+			continue
+		}
+
+		k.Records[key.Name] = recordState{Version: s.Version, Data: data}
+	}
+
+	return nil
+}
+
+// clone returns a copy of the state. The data of the records is shared since
+// it's never modified.
+func (s *state) clone() *state {
+	c := newState()
+	c.Version = s.Version
+
+	for name, k := range s.Kinds {
+		records := make(map[string]recordState, len(k.Records))
+		for recordName, r := range k.Records {
+			records[recordName] = r
+		}
+
+		c.Kinds[name] = &kindState{Version: k.Version, Records: records}
+	}
+
+	for name, value := range s.Sequences {
+		c.Sequences[name] = value
+	}
+
+	return c
+}
+
+// memoryBackend is a Backend holding the state in memory. If save is set
+// it's called with the new state before each change, and the change is only
+// made if it succeeds.
+type memoryBackend struct {
+	mu    sync.Mutex
+	state *state
+	save  func(s *state) error
+}
+
+// NewMemory creates a Store keeping all objects in memory.
+func NewMemory() Store {
+	return New(&memoryBackend{state: newState()})
+}
+
+// Get implements Backend.
+func (b *memoryBackend) Get(key Key) (Record, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	record := Record{Key: key}
+
+	if k, ok := b.state.Kinds[key.Kind]; ok {
+		if r, ok := k.Records[key.Name]; ok {
+			record.Version, record.Data = r.Version, r.Data
+		}
+	}
+
+	return record, nil
+}
+
+// List implements Backend.
+func (b *memoryBackend) List(kind string) ([]Record, int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	k, ok := b.state.Kinds[kind]
+	if !ok {
+		return nil, 0, nil
+	}
+
+	records := make([]Record, 0, len(k.Records))
+
+	for name, r := range k.Records {
+		records = append(records, Record{
+			Key:     Key{Kind: kind, Name: name},
+			Version: r.Version,
+			Data:    r.Data,
+		})
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Name < records[j].Name
+	})
+
+	return records, k.Version, nil
+}
+
+// Apply implements Backend.
+func (b *memoryBackend) Apply(changes Changes) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.change(func(s *state) error {
+		return s.apply(changes)
+	})
+}
+
+// Next implements Backend.
+func (b *memoryBackend) Next(sequence string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var value int64
+
+	err := b.change(func(s *state) error {
+		s.Sequences[sequence]++
+		value = s.Sequences[sequence]
+
+		return nil
+	})
+
+	return value, err
+}
+
+// change calls f to change the state. If the state is saved f is called
+// with a copy of the state which replaces the state once it's saved.
+func (b *memoryBackend) change(f func(s *state) error) error {
+	if b.save == nil {
+		return f(b.state)
+	}
+
+	s := b.state.clone()
+
+	if err := f(s); err != nil {
+		return err
+	}
+
+	if err := b.save(s); err != nil {
+		return err
+	}
+
+	b.state = s
+
+	return nil
+}
+
+// Close implements Backend.
+func (b *memoryBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,258 @@
+// Package sqlite implements a store.Backend using an embedded SQLite
+// database. Records are stored as JSON in a single table together with their
+// versions, which makes it possible for multiple processes to share the same
+// database file. The pure Go driver modernc.org/sqlite is used so cgo isn't
+// required.
+//
+//	s, err := sqlite.Open("registry.db")
+//	if err != nil {
+//	    return err
+//	}
+//
+//	defer s.Close()
+//
+//	r := registry.New()
+//	r.Store = s
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/bombsimon/epp-go/store"
+
+	// Registers the sqlite driver.
+	_ "modernc.org/sqlite"
+)
+
+// schema creates the tables if they don't exist. The version of a kind is
+// the version of the latest commit writing a record of the kind, and the
+// version of a commit is one more than the highest version of any kind.
+const schema = `
+CREATE TABLE IF NOT EXISTS records (
+	kind    TEXT    NOT NULL,
+	name    TEXT    NOT NULL,
+	version INTEGER NOT NULL,
+	data    BLOB    NOT NULL,
+	PRIMARY KEY (kind, name)
+);
+
+CREATE TABLE IF NOT EXISTS kinds (
+	kind    TEXT    PRIMARY KEY,
+	version INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sequences (
+	name  TEXT    PRIMARY KEY,
+	value INTEGER NOT NULL
+);
+`
+
+// Backend is a store.Backend using a SQLite database.
+type Backend struct {
+	db *sql.DB
+}
+
+// Open opens a store.Store with a Backend for the SQLite database at the
+// path. The database is created if it doesn't exist.
+func Open(path string) (store.Store, error) {
+	b, err := OpenBackend(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.New(b), nil
+}
+
+// OpenBackend opens a Backend for the SQLite database at the path. The
+// database is created if it doesn't exist.
+func OpenBackend(path string) (*Backend, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Backend{db: db}, nil
+}
+
+// Get implements store.Backend.
+func (b *Backend) Get(key store.Key) (store.Record, error) {
+	record := store.Record{Key: key}
+
+	err := b.db.QueryRow(
+		"SELECT version, data FROM records WHERE kind = ? AND name = ?",
+		key.Kind, key.Name,
+	).Scan(&record.Version, &record.Data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return record, nil
+	}
+
+	return record, err
+}
+
+// List implements store.Backend. The records and the version of the kind
+// are read in a transaction to get a consistent result.
+func (b *Backend) List(kind string) ([]store.Record, int64, error) {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer tx.Rollback()
+
+	var version int64
+
+	err = tx.QueryRow("SELECT version FROM kinds WHERE kind = ?", kind).Scan(&version)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, err
+	}
+
+	rows, err := tx.Query("SELECT name, version, data FROM records WHERE kind = ? ORDER BY name", kind)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	records := []store.Record{}
+
+	for rows.Next() {
+		r := store.Record{Key: store.Key{Kind: kind}}
+
+		if err := rows.Scan(&r.Name, &r.Version, &r.Data); err != nil {
+			return nil, 0, err
+		}
+
+		records = append(records, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return records, version, nil
+}
+
+// Apply implements store.Backend. The changes are applied in a transaction
+// started with BEGIN IMMEDIATE which prevents other writers from changing
+// the versions between checking and writing.
+func (b *Backend) Apply(changes store.Changes) error {
+	ctx := context.Background()
+
+	conn, err := b.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return err
+	}
+
+	if err := apply(ctx, conn, changes); err != nil {
+		if _, rollbackErr := conn.ExecContext(ctx, "ROLLBACK"); rollbackErr != nil {
+			return rollbackErr
+		}
+
+		return err
+	}
+
+	_, err = conn.ExecContext(ctx, "COMMIT")
+
+	return err
+}
+
+// apply checks the versions read and writes the changes.
+func apply(ctx context.Context, conn *sql.Conn, changes store.Changes) error {
+	for key, version := range changes.Reads {
+		current, err := currentVersion(ctx, conn, key)
+		if err != nil {
+			return err
+		}
+
+		if current != version {
+			return store.ErrConflict
+		}
+	}
+
+	var version int64
+
+	if err := conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) + 1 FROM kinds").Scan(&version); err != nil {
+		return err
+	}
+
+	for key, data := range changes.Writes {
+		var err error
+
+		if data == nil {
+			_, err = conn.ExecContext(ctx, "DELETE FROM records WHERE kind = ? AND name = ?", key.Kind, key.Name)
+		} else {
+			_, err = conn.ExecContext(ctx,
+				`INSERT INTO records (kind, name, version, data) VALUES (?, ?, ?, ?)
+				ON CONFLICT (kind, name) DO UPDATE SET version = excluded.version, data = excluded.data`,
+				key.Kind, key.Name, version, data,
+			)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		_, err = conn.ExecContext(ctx,
+			`INSERT INTO kinds (kind, version) VALUES (?, ?)
+			ON CONFLICT (kind) DO UPDATE SET version = excluded.version`,
+			key.Kind, version,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// currentVersion returns the version of the record or the kind if the name
+// of the key is empty.
+func currentVersion(ctx context.Context, conn *sql.Conn, key store.Key) (int64, error) {
+	var row *sql.Row
+
+	if key.Name == "" {
+		row = conn.QueryRowContext(ctx, "SELECT version FROM kinds WHERE kind = ?", key.Kind)
+	} else {
+		row = conn.QueryRowContext(ctx, "SELECT version FROM records WHERE kind = ? AND name = ?", key.Kind, key.Name)
+	}
+
+	var version int64
+
+	if err := row.Scan(&version); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// Next implements store.Backend.
+func (b *Backend) Next(sequence string) (int64, error) {
+	var value int64
+
+	err := b.db.QueryRow(
+		`INSERT INTO sequences (name, value) VALUES (?, 1)
+		ON CONFLICT (name) DO UPDATE SET value = value + 1
+		RETURNING value`,
+		sequence,
+	).Scan(&value)
+
+	return value, err
+}
+
+// Close implements store.Backend.
+func (b *Backend) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bombsimon/epp-go/store"
+	"github.com/bombsimon/epp-go/store/storetest"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s, err := Open(filepath.Join(t.TempDir(), "registry.db"))
+		require.Nil(t, err)
+
+		return s
+	})
+}
+
+func TestSharedDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.db")
+
+	s1, err := Open(path)
+	require.Nil(t, err)
+
+	defer s1.Close()
+
+	s2, err := Open(path)
+	require.Nil(t, err)
+
+	defer s2.Close()
+
+	tx1, err := s1.Begin()
+	require.Nil(t, err)
+
+	tx2, err := s2.Begin()
+	require.Nil(t, err)
+
+	roid, err := tx1.NewROID("D", "EPP")
+	require.Nil(t, err)
+	assert.Equal(t, "D1-EPP", roid)
+
+	roid, err = tx2.NewROID("D", "EPP")
+	require.Nil(t, err)
+	assert.Equal(t, "D2-EPP", roid)
+
+	// The stores share the database and conflicts are detected between
+	// them.
+	for _, tx := range []store.Tx{tx1, tx2} {
+		_, err := tx.Domain("example.se")
+		require.Equal(t, store.ErrNotFound, err)
+		require.Nil(t, tx.PutDomain(&types.DomainInfoData{Name: "example.se"}))
+	}
+
+	require.Nil(t, tx1.Commit())
+	assert.Equal(t, store.ErrConflict, tx2.Commit())
+
+	tx2, err = s2.Begin()
+	require.Nil(t, err)
+
+	defer tx2.Rollback()
+
+	_, err = tx2.Domain("example.se")
+	assert.Nil(t, err)
+}
@@ -0,0 +1,186 @@
+// Package store persists the objects of a registry: domains, contacts, hosts,
+// transfers and poll messages. Objects are loaded and saved as the types used
+// for the info responses, e.g. types.DomainInfoData, in transactions with
+// optimistic concurrency control. A transaction doesn't lock anything, instead
+// Commit fails with ErrConflict if any object read or written in the
+// transaction was changed by another transaction after it was read.
+//
+//	tx, err := s.Begin()
+//	if err != nil {
+//	    return err
+//	}
+//
+//	defer tx.Rollback()
+//
+//	d, err := tx.Domain("example.se")
+//	if err != nil {
+//	    return err
+//	}
+//
+//	d.ClientID = "registrar-2"
+//
+//	if err := tx.PutDomain(d); err != nil {
+//	    return err
+//	}
+//
+//	return tx.Commit()
+//
+// A Store is created on top of a Backend which holds the data. Backends for
+// memory and files are included in this package, a SQLite backend is found in
+// the package store/sqlite.
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bombsimon/epp-go/types"
+)
+
+var (
+	// ErrNotFound is returned when an object doesn't exist.
+	ErrNotFound = errors.New("store: object not found")
+
+	// ErrConflict is returned by Commit if an object read or written in the
+	// transaction was changed by another transaction. The transaction may be
+	// retried.
+	ErrConflict = errors.New("store: transaction conflict")
+
+	// ErrTxDone is returned when a transaction is used after it has been
+	// committed or rolled back.
+	ErrTxDone = errors.New("store: transaction has already been committed or rolled back")
+)
+
+// Kinds of objects that can be transferred.
+const (
+	KindContact = "contact"
+	KindDomain  = "domain"
+)
+
+// Store is a store for registry objects. All access is done in transactions.
+type Store interface {
+	// Begin starts a new transaction.
+	Begin() (Tx, error)
+
+	// Close closes the store.
+	Close() error
+}
+
+// Tx is a transaction. Objects returned are copies which may be modified and
+// saved with the corresponding Put method. Changes are only visible to other
+// transactions after Commit. Objects are read with repeatable reads, an
+// object read twice in the same transaction is the same unless it's changed
+// in the transaction. A transaction must not be used concurrently.
+type Tx interface {
+	// Domain returns the domain with the name or ErrNotFound.
+	Domain(name string) (*types.DomainInfoData, error)
+
+	// Domains returns all domains sorted by name.
+	Domains() ([]*types.DomainInfoData, error)
+
+	// PutDomain creates or replaces the domain with the same name.
+	PutDomain(d *types.DomainInfoData) error
+
+	// DeleteDomain deletes the domain with the name or returns ErrNotFound.
+	DeleteDomain(name string) error
+
+	// Contact returns the contact with the ID or ErrNotFound.
+	Contact(id string) (*types.ContactInfoData, error)
+
+	// Contacts returns all contacts sorted by ID.
+	Contacts() ([]*types.ContactInfoData, error)
+
+	// PutContact creates or replaces the contact with the same ID.
+	PutContact(c *types.ContactInfoData) error
+
+	// DeleteContact deletes the contact with the ID or returns ErrNotFound.
+	DeleteContact(id string) error
+
+	// Host returns the host with the name or ErrNotFound.
+	Host(name string) (*types.HostInfoData, error)
+
+	// Hosts returns all hosts sorted by name.
+	Hosts() ([]*types.HostInfoData, error)
+
+	// PutHost creates or replaces the host with the same name.
+	PutHost(h *types.HostInfoData) error
+
+	// DeleteHost deletes the host with the name or returns ErrNotFound.
+	DeleteHost(name string) error
+
+	// Transfer returns the latest transfer for the object with the ROID or
+	// ErrNotFound.
+	Transfer(roid string) (*Transfer, error)
+
+	// Transfers returns all transfers sorted by ROID.
+	Transfers() ([]*Transfer, error)
+
+	// PutTransfer creates or replaces the transfer for the object with the
+	// same ROID.
+	PutTransfer(t *Transfer) error
+
+	// DeleteTransfer deletes the transfer for the object with the ROID or
+	// returns ErrNotFound.
+	DeleteTransfer(roid string) error
+
+	// Messages returns the poll messages for the client, oldest first.
+	Messages(clientID string) ([]*Message, error)
+
+	// AddMessage adds a poll message for the client in the message and sets
+	// the ID of the message to a new unique ID.
+	AddMessage(m *Message) error
+
+	// DeleteMessage deletes the poll message for the client with the ID or
+	// returns ErrNotFound.
+	DeleteMessage(clientID, id string) error
+
+	// NewROID returns a new unique ROID with the prefix and the suffix, e.g.
+	// D1-EPP for the prefix D and the suffix EPP. ROIDs are unique even if
+	// the transaction is rolled back.
+	NewROID(prefix, suffix string) (string, error)
+
+	// Commit commits the transaction. ErrConflict is returned if the
+	// transaction conflicts with another transaction.
+	Commit() error
+
+	// Rollback aborts the transaction. Calling Rollback after Commit is a
+	// no-op which makes it suitable to defer.
+	Rollback() error
+}
+
+// Transfer is the latest transfer request for a domain or a contact.
+type Transfer struct {
+	// ROID is the ROID of the object being transferred.
+	ROID string `json:"roid"`
+
+	// Kind is either KindDomain or KindContact and Name the name of the
+	// domain or the ID of the contact.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+
+	// Status is the transfer status, e.g. pending or clientApproved.
+	Status         string    `json:"status"`
+	RequestingID   string    `json:"requesting_id"`
+	RequestingDate time.Time `json:"requesting_date"`
+	ActingID       string    `json:"acting_id"`
+	ActingDate     time.Time `json:"acting_date"`
+
+	// Period and ExpireDate are only set for domains and holds the period
+	// to extend the registration with and the expiry date after the
+	// transfer.
+	Period     types.Period `json:"period"`
+	ExpireDate *time.Time   `json:"expire_date,omitempty"`
+}
+
+// Message is a poll message queued for a client.
+type Message struct {
+	ID        string    `json:"id"`
+	ClientID  string    `json:"client_id"`
+	QueueDate time.Time `json:"queue_date"`
+	Text      string    `json:"text"`
+	Language  string    `json:"language,omitempty"`
+
+	// Data is the encoded content of the resData element for the message,
+	// if any.
+	Data []byte `json:"data,omitempty"`
+}
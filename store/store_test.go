@@ -0,0 +1,67 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bombsimon/epp-go/store"
+	"github.com/bombsimon/epp-go/store/storetest"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return store.NewMemory()
+	})
+}
+
+func TestFile(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s, err := store.OpenFile(filepath.Join(t.TempDir(), "store.json"))
+		require.Nil(t, err)
+
+		return s
+	})
+}
+
+func TestFileReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+
+	s, err := store.OpenFile(path)
+	require.Nil(t, err)
+
+	tx, err := s.Begin()
+	require.Nil(t, err)
+
+	roid, err := tx.NewROID("D", "EPP")
+	require.Nil(t, err)
+	require.Nil(t, tx.PutDomain(&types.DomainInfoData{Name: "example.se", ROID: roid}))
+	require.Nil(t, tx.Commit())
+	require.Nil(t, s.Close())
+
+	s, err = store.OpenFile(path)
+	require.Nil(t, err)
+
+	defer s.Close()
+
+	tx, err = s.Begin()
+	require.Nil(t, err)
+
+	defer tx.Rollback()
+
+	d, err := tx.Domain("example.se")
+	require.Nil(t, err)
+	assert.Equal(t, "D1-EPP", d.ROID)
+
+	roid, err = tx.NewROID("D", "EPP")
+	require.Nil(t, err)
+	assert.Equal(t, "D2-EPP", roid)
+
+	// Only the file itself remains in the directory.
+	files, err := os.ReadDir(filepath.Dir(path))
+	require.Nil(t, err)
+	assert.Len(t, files, 1)
+}
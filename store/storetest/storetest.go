@@ -0,0 +1,478 @@
+// Package storetest tests implementations of store.Store. The tests are the
+// same for all backends and are used by the tests for the backends in this
+// repository and may be used for other backends.
+package storetest
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/epp-go/store"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs all tests with new empty stores created by newStore.
+func Run(t *testing.T, newStore func(t *testing.T) store.Store) {
+	for name, test := range map[string]func(t *testing.T, s store.Store){
+		"Objects":      testObjects,
+		"Isolation":    testIsolation,
+		"Conflicts":    testConflicts,
+		"ListConflict": testListConflict,
+		"Transfers":    testTransfers,
+		"Messages":     testMessages,
+		"ROID":         testROID,
+		"Done":         testDone,
+		"Concurrent":   testConcurrent,
+	} {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+			defer s.Close()
+
+			test(t, s)
+		})
+	}
+}
+
+// begin starts a transaction.
+func begin(t *testing.T, s store.Store) store.Tx {
+	t.Helper()
+
+	tx, err := s.Begin()
+	require.Nil(t, err)
+
+	return tx
+}
+
+// commit runs f in a transaction and requires it to be committed.
+func commit(t *testing.T, s store.Store, f func(tx store.Tx)) {
+	t.Helper()
+
+	tx := begin(t, s)
+	f(tx)
+	require.Nil(t, tx.Commit())
+}
+
+func testObjects(t *testing.T, s store.Store) {
+	date := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	domain := &types.DomainInfoData{
+		Name:       "example.se",
+		ROID:       "D1-EPP",
+		Status:     []types.DomainStatus{{DomainStatusType: types.DomainStatusClientHold, Status: "Unpaid", Language: "en"}},
+		Registrant: "contact-1",
+		Contact:    []types.Contact{{Name: "contact-1", Type: "admin"}},
+		NameServer: &types.NameServer{HostObject: []string{"ns1.example.se"}},
+		ClientID:   "registrar-1",
+		CreateDate: &date,
+		ExpireDate: &date,
+		AuthInfo:   &types.AuthInfo{Password: "secret"},
+	}
+
+	contact := &types.ContactInfoData{
+		Name:       "contact-1",
+		ROID:       "C2-EPP",
+		PostalInfo: []types.PostalInfo{{Name: "John Doe", Type: types.PostalInfoInternational}},
+		Voice:      types.E164Type{Value: "+46.812345678", X: "1234"},
+		Email:      "jdoe@example.se",
+		ClientID:   "registrar-1",
+		CreateDate: date,
+		Disclose:   types.Disclose{Voice: true},
+	}
+
+	host := &types.HostInfoData{
+		Name:       "ns1.example.se",
+		ROID:       "H3-EPP",
+		Address:    []types.HostAddress{{Address: "192.0.2.1", IP: types.HostIPv4}},
+		ClientID:   "registrar-1",
+		CreateDate: date,
+	}
+
+	commit(t, s, func(tx store.Tx) {
+		require.Nil(t, tx.PutDomain(domain))
+		require.Nil(t, tx.PutContact(contact))
+		require.Nil(t, tx.PutHost(host))
+		require.Nil(t, tx.PutDomain(&types.DomainInfoData{Name: "a.se"}))
+	})
+
+	tx := begin(t, s)
+	defer tx.Rollback()
+
+	d, err := tx.Domain("example.se")
+	require.Nil(t, err)
+	assert.Equal(t, domain, d)
+
+	c, err := tx.Contact("contact-1")
+	require.Nil(t, err)
+	assert.Equal(t, contact, c)
+
+	h, err := tx.Host("ns1.example.se")
+	require.Nil(t, err)
+	assert.Equal(t, host, h)
+
+	domains, err := tx.Domains()
+	require.Nil(t, err)
+	require.Len(t, domains, 2)
+	assert.Equal(t, "a.se", domains[0].Name)
+	assert.Equal(t, domain, domains[1])
+
+	contacts, err := tx.Contacts()
+	require.Nil(t, err)
+	assert.Equal(t, []*types.ContactInfoData{contact}, contacts)
+
+	hosts, err := tx.Hosts()
+	require.Nil(t, err)
+	assert.Equal(t, []*types.HostInfoData{host}, hosts)
+
+	// Objects are copies.
+	d.ClientID = "registrar-2"
+
+	d, err = tx.Domain("example.se")
+	require.Nil(t, err)
+	assert.Equal(t, "registrar-1", d.ClientID)
+
+	// Deleted objects are removed from lists in the transaction.
+	require.Nil(t, tx.DeleteDomain("a.se"))
+
+	domains, err = tx.Domains()
+	require.Nil(t, err)
+	assert.Len(t, domains, 1)
+
+	require.Nil(t, tx.DeleteContact("contact-1"))
+	require.Nil(t, tx.DeleteHost("ns1.example.se"))
+	require.Nil(t, tx.Commit())
+
+	tx = begin(t, s)
+	defer tx.Rollback()
+
+	_, err = tx.Domain("a.se")
+	assert.Equal(t, store.ErrNotFound, err)
+
+	_, err = tx.Contact("contact-1")
+	assert.Equal(t, store.ErrNotFound, err)
+
+	_, err = tx.Host("ns1.example.se")
+	assert.Equal(t, store.ErrNotFound, err)
+
+	assert.Equal(t, store.ErrNotFound, tx.DeleteDomain("a.se"))
+	assert.Equal(t, store.ErrNotFound, tx.DeleteContact("contact-1"))
+	assert.Equal(t, store.ErrNotFound, tx.DeleteHost("ns1.example.se"))
+	assert.NotNil(t, tx.PutDomain(&types.DomainInfoData{}))
+}
+
+func testIsolation(t *testing.T, s store.Store) {
+	tx1 := begin(t, s)
+	defer tx1.Rollback()
+
+	tx2 := begin(t, s)
+	defer tx2.Rollback()
+
+	require.Nil(t, tx1.PutDomain(&types.DomainInfoData{Name: "example.se", ClientID: "registrar-1"}))
+
+	// The transaction sees its own writes but others don't.
+	d, err := tx1.Domain("example.se")
+	require.Nil(t, err)
+	assert.Equal(t, "registrar-1", d.ClientID)
+
+	domains, err := tx1.Domains()
+	require.Nil(t, err)
+	assert.Len(t, domains, 1)
+
+	_, err = tx2.Domain("example.se")
+	assert.Equal(t, store.ErrNotFound, err)
+
+	domains, err = tx2.Domains()
+	require.Nil(t, err)
+	assert.Empty(t, domains)
+
+	require.Nil(t, tx1.Commit())
+
+	// Reads are repeatable.
+	_, err = tx2.Domain("example.se")
+	assert.Equal(t, store.ErrNotFound, err)
+
+	tx3 := begin(t, s)
+	defer tx3.Rollback()
+
+	d, err = tx3.Domain("example.se")
+	require.Nil(t, err)
+	assert.Equal(t, "registrar-1", d.ClientID)
+
+	// Rolled back changes are never visible.
+	tx4 := begin(t, s)
+	require.Nil(t, tx4.DeleteDomain("example.se"))
+	require.Nil(t, tx4.Rollback())
+
+	tx5 := begin(t, s)
+	defer tx5.Rollback()
+
+	_, err = tx5.Domain("example.se")
+	assert.Nil(t, err)
+}
+
+func testConflicts(t *testing.T, s store.Store) {
+	commit(t, s, func(tx store.Tx) {
+		require.Nil(t, tx.PutDomain(&types.DomainInfoData{Name: "example.se", ClientID: "registrar-1"}))
+		require.Nil(t, tx.PutDomain(&types.DomainInfoData{Name: "example2.se", ClientID: "registrar-1"}))
+	})
+
+	// Both transactions update the same domain.
+	tx1, tx2 := begin(t, s), begin(t, s)
+
+	for _, tx := range []store.Tx{tx1, tx2} {
+		d, err := tx.Domain("example.se")
+		require.Nil(t, err)
+
+		d.ClientID = "registrar-2"
+		require.Nil(t, tx.PutDomain(d))
+	}
+
+	require.Nil(t, tx1.Commit())
+	assert.Equal(t, store.ErrConflict, tx2.Commit())
+
+	// Both transactions create the same domain without reading it.
+	tx1, tx2 = begin(t, s), begin(t, s)
+	require.Nil(t, tx1.PutDomain(&types.DomainInfoData{Name: "example3.se"}))
+	require.Nil(t, tx2.PutDomain(&types.DomainInfoData{Name: "example3.se"}))
+	require.Nil(t, tx1.Commit())
+	assert.Equal(t, store.ErrConflict, tx2.Commit())
+
+	// A domain read by one transaction is deleted and created again.
+	tx1 = begin(t, s)
+	_, err := tx1.Domain("example.se")
+	require.Nil(t, err)
+
+	commit(t, s, func(tx store.Tx) {
+		require.Nil(t, tx.DeleteDomain("example.se"))
+	})
+
+	commit(t, s, func(tx store.Tx) {
+		require.Nil(t, tx.PutDomain(&types.DomainInfoData{Name: "example.se"}))
+	})
+
+	require.Nil(t, tx1.PutDomain(&types.DomainInfoData{Name: "example4.se"}))
+	assert.Equal(t, store.ErrConflict, tx1.Commit())
+
+	// Transactions changing different domains don't conflict.
+	tx1, tx2 = begin(t, s), begin(t, s)
+
+	for i, tx := range []store.Tx{tx1, tx2} {
+		d, err := tx.Domain([]string{"example.se", "example2.se"}[i])
+		require.Nil(t, err)
+		require.Nil(t, tx.PutDomain(d))
+	}
+
+	require.Nil(t, tx1.Commit())
+	assert.Nil(t, tx2.Commit())
+}
+
+func testListConflict(t *testing.T, s store.Store) {
+	tx1 := begin(t, s)
+
+	domains, err := tx1.Domains()
+	require.Nil(t, err)
+	assert.Empty(t, domains)
+
+	// A domain is created after the domains were listed.
+	commit(t, s, func(tx store.Tx) {
+		require.Nil(t, tx.PutDomain(&types.DomainInfoData{Name: "example.se"}))
+	})
+
+	require.Nil(t, tx1.PutHost(&types.HostInfoData{Name: "ns1.example.net"}))
+	assert.Equal(t, store.ErrConflict, tx1.Commit())
+
+	// Changes to other kinds don't conflict.
+	tx2 := begin(t, s)
+
+	_, err = tx2.Domains()
+	require.Nil(t, err)
+
+	commit(t, s, func(tx store.Tx) {
+		require.Nil(t, tx.PutHost(&types.HostInfoData{Name: "ns2.example.net"}))
+	})
+
+	require.Nil(t, tx2.PutContact(&types.ContactInfoData{Name: "contact-1"}))
+	assert.Nil(t, tx2.Commit())
+}
+
+func testTransfers(t *testing.T, s store.Store) {
+	date := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expireDate := date.AddDate(2, 0, 0)
+
+	transfer := &store.Transfer{
+		ROID:           "D1-EPP",
+		Kind:           store.KindDomain,
+		Name:           "example.se",
+		Status:         "pending",
+		RequestingID:   "registrar-2",
+		RequestingDate: date,
+		ActingID:       "registrar-1",
+		ActingDate:     date.Add(5 * 24 * time.Hour),
+		Period:         types.Period{Value: 1, Unit: "y"},
+		ExpireDate:     &expireDate,
+	}
+
+	commit(t, s, func(tx store.Tx) {
+		require.Nil(t, tx.PutTransfer(transfer))
+		require.Nil(t, tx.PutTransfer(&store.Transfer{ROID: "C2-EPP", Kind: store.KindContact}))
+	})
+
+	tx := begin(t, s)
+	defer tx.Rollback()
+
+	tr, err := tx.Transfer("D1-EPP")
+	require.Nil(t, err)
+	assert.Equal(t, transfer, tr)
+
+	transfers, err := tx.Transfers()
+	require.Nil(t, err)
+	require.Len(t, transfers, 2)
+	assert.Equal(t, "C2-EPP", transfers[0].ROID)
+
+	require.Nil(t, tx.DeleteTransfer("D1-EPP"))
+
+	_, err = tx.Transfer("D1-EPP")
+	assert.Equal(t, store.ErrNotFound, err)
+}
+
+func testMessages(t *testing.T, s store.Store) {
+	date := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	messages := []*store.Message{
+		{ClientID: "registrar-1", QueueDate: date.Add(time.Hour), Text: "second"},
+		{ClientID: "registrar-1", QueueDate: date, Text: "first", Language: "en", Data: []byte("<data/>")},
+		{ClientID: "registrar-1", QueueDate: date.Add(time.Hour), Text: "third"},
+		{ClientID: "registrar-2", QueueDate: date, Text: "other"},
+	}
+
+	commit(t, s, func(tx store.Tx) {
+		for _, m := range messages {
+			require.Nil(t, tx.AddMessage(m))
+			assert.NotEmpty(t, m.ID)
+		}
+	})
+
+	tx := begin(t, s)
+	defer tx.Rollback()
+
+	queued, err := tx.Messages("registrar-1")
+	require.Nil(t, err)
+	assert.Equal(t, []*store.Message{messages[1], messages[0], messages[2]}, queued)
+
+	require.Nil(t, tx.DeleteMessage("registrar-1", messages[1].ID))
+	assert.Equal(t, store.ErrNotFound, tx.DeleteMessage("registrar-1", messages[3].ID))
+
+	queued, err = tx.Messages("registrar-1")
+	require.Nil(t, err)
+	assert.Equal(t, []*store.Message{messages[0], messages[2]}, queued)
+
+	queued, err = tx.Messages("registrar-3")
+	require.Nil(t, err)
+	assert.Empty(t, queued)
+}
+
+func testROID(t *testing.T, s store.Store) {
+	tx := begin(t, s)
+
+	roid, err := tx.NewROID("D", "EPP")
+	require.Nil(t, err)
+	assert.Equal(t, "D1-EPP", roid)
+
+	require.Nil(t, tx.Rollback())
+
+	// ROIDs aren't reused after a rollback.
+	tx = begin(t, s)
+	defer tx.Rollback()
+
+	roid, err = tx.NewROID("C", "SE")
+	require.Nil(t, err)
+	assert.Equal(t, "C2-SE", roid)
+}
+
+func testDone(t *testing.T, s store.Store) {
+	tx := begin(t, s)
+	require.Nil(t, tx.PutDomain(&types.DomainInfoData{Name: "example.se"}))
+	require.Nil(t, tx.Commit())
+
+	_, err := tx.Domain("example.se")
+	assert.Equal(t, store.ErrTxDone, err)
+	assert.Equal(t, store.ErrTxDone, tx.PutDomain(&types.DomainInfoData{Name: "example.se"}))
+	assert.Equal(t, store.ErrTxDone, tx.Commit())
+	assert.Nil(t, tx.Rollback())
+
+	_, err = tx.NewROID("D", "EPP")
+	assert.Equal(t, store.ErrTxDone, err)
+}
+
+func testConcurrent(t *testing.T, s store.Store) {
+	const writers = 10
+
+	commit(t, s, func(tx store.Tx) {
+		require.Nil(t, tx.PutDomain(&types.DomainInfoData{Name: "example.se"}))
+	})
+
+	// Each writer adds a host to the domain and retries on conflicts. No
+	// update may be lost.
+	var wg sync.WaitGroup
+
+	errs := make(chan error, writers)
+
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+
+		go func(host string) {
+			defer wg.Done()
+
+			for {
+				err := addHost(s, host)
+				if !errors.Is(err, store.ErrConflict) {
+					errs <- err
+					return
+				}
+			}
+		}("ns" + strconv.Itoa(i) + ".example.se")
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.Nil(t, err)
+	}
+
+	tx := begin(t, s)
+	defer tx.Rollback()
+
+	d, err := tx.Domain("example.se")
+	require.Nil(t, err)
+	assert.Len(t, d.Host, writers)
+}
+
+// addHost adds the host to the domain example.se.
+func addHost(s store.Store, host string) error {
+	tx, err := s.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	d, err := tx.Domain("example.se")
+	if err != nil {
+		return err
+	}
+
+	d.Host = append(d.Host, host)
+
+	if err := tx.PutDomain(d); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,421 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/bombsimon/epp-go/types"
+)
+
+// Kinds of records used by the transaction. Messages are stored with one
+// kind per client.
+const (
+	recordContact  = "contact"
+	recordDomain   = "domain"
+	recordHost     = "host"
+	recordTransfer = "transfer"
+	recordMessage  = "message/"
+)
+
+// Sequences used by the transaction.
+const (
+	sequenceMessage = "message"
+	sequenceROID    = "roid"
+)
+
+// errNoName is returned when saving an object without a name or ID.
+var errNoName = errors.New("store: object has no name")
+
+// tx is a Tx for a Backend. Records are cached when they're first read which
+// gives repeatable reads and writes are kept until the transaction is
+// committed.
+type tx struct {
+	backend Backend
+	done    bool
+
+	// reads holds the version of each record and kind read from the
+	// backend, cache the data for each record read or written with nil for
+	// records that doesn't exist and writes the data for each record written.
+	reads  map[Key]int64
+	cache  map[Key][]byte
+	writes map[Key][]byte
+}
+
+// get returns the data for the record with the key or nil if it doesn't
+// exist.
+func (t *tx) get(key Key) ([]byte, error) {
+	if t.done {
+		return nil, ErrTxDone
+	}
+
+	if data, ok := t.cache[key]; ok {
+		return data, nil
+	}
+
+	record, err := t.backend.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	t.reads[key], t.cache[key] = record.Version, record.Data
+
+	return record.Data, nil
+}
+
+// list returns the data for all records of the kind sorted by name.
+func (t *tx) list(kind string) ([][]byte, error) {
+	if t.done {
+		return nil, ErrTxDone
+	}
+
+	kindKey := Key{Kind: kind}
+
+	if _, ok := t.reads[kindKey]; !ok {
+		records, version, err := t.backend.List(kind)
+		if err != nil {
+			return nil, err
+		}
+
+		t.reads[kindKey] = version
+
+		for _, r := range records {
+			if _, ok := t.cache[r.Key]; !ok {
+				t.reads[r.Key], t.cache[r.Key] = r.Version, r.Data
+			}
+		}
+	}
+
+	// All records of the kind are cached after they're listed, including
+	// records created in the transaction.
+	names := []string{}
+
+	for key, data := range t.cache {
+		if key.Kind == kind && data != nil {
+			names = append(names, key.Name)
+		}
+	}
+
+	sort.Strings(names)
+
+	result := make([][]byte, 0, len(names))
+	for _, name := range names {
+		result = append(result, t.cache[Key{Kind: kind, Name: name}])
+	}
+
+	return result, nil
+}
+
+// put encodes the value and writes it to the record with the key.
+func (t *tx) put(key Key, v interface{}) error {
+	if key.Name == "" {
+		return errNoName
+	}
+
+	// The record is read to know which version it's replacing.
+	if _, err := t.get(key); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	t.cache[key], t.writes[key] = data, data
+
+	return nil
+}
+
+// remove deletes the record with the key.
+func (t *tx) remove(key Key) error {
+	data, err := t.get(key)
+	if err != nil {
+		return err
+	}
+
+	if data == nil {
+		return ErrNotFound
+	}
+
+	t.cache[key], t.writes[key] = nil, nil
+
+	return nil
+}
+
+// load decodes the record with the key to v.
+func (t *tx) load(key Key, v interface{}) error {
+	data, err := t.get(key)
+	if err != nil {
+		return err
+	}
+
+	if data == nil {
+		return ErrNotFound
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// Domain implements Tx.
+func (t *tx) Domain(name string) (*types.DomainInfoData, error) {
+	d := &types.DomainInfoData{}
+
+	if err := t.load(Key{Kind: recordDomain, Name: name}, d); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Domains implements Tx.
+func (t *tx) Domains() ([]*types.DomainInfoData, error) {
+	list, err := t.list(recordDomain)
+	if err != nil {
+		return nil, err
+	}
+
+	domains := make([]*types.DomainInfoData, len(list))
+
+	for i, data := range list {
+		domains[i] = &types.DomainInfoData{}
+
+		if err := json.Unmarshal(data, domains[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return domains, nil
+}
+
+// PutDomain implements Tx.
+func (t *tx) PutDomain(d *types.DomainInfoData) error {
+	return t.put(Key{Kind: recordDomain, Name: d.Name}, d)
+}
+
+// DeleteDomain implements Tx.
+func (t *tx) DeleteDomain(name string) error {
+	return t.remove(Key{Kind: recordDomain, Name: name})
+}
+
+// Contact implements Tx.
+func (t *tx) Contact(id string) (*types.ContactInfoData, error) {
+	c := &types.ContactInfoData{}
+
+	if err := t.load(Key{Kind: recordContact, Name: id}, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Contacts implements Tx.
+func (t *tx) Contacts() ([]*types.ContactInfoData, error) {
+	list, err := t.list(recordContact)
+	if err != nil {
+		return nil, err
+	}
+
+	contacts := make([]*types.ContactInfoData, len(list))
+
+	for i, data := range list {
+		contacts[i] = &types.ContactInfoData{}
+
+		if err := json.Unmarshal(data, contacts[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return contacts, nil
+}
+
+// PutContact implements Tx.
+func (t *tx) PutContact(c *types.ContactInfoData) error {
+	return t.put(Key{Kind: recordContact, Name: c.Name}, c)
+}
+
+// DeleteContact implements Tx.
+func (t *tx) DeleteContact(id string) error {
+	return t.remove(Key{Kind: recordContact, Name: id})
+}
+
+// Host implements Tx.
+func (t *tx) Host(name string) (*types.HostInfoData, error) {
+	h := &types.HostInfoData{}
+
+	if err := t.load(Key{Kind: recordHost, Name: name}, h); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Hosts implements Tx.
+func (t *tx) Hosts() ([]*types.HostInfoData, error) {
+	list, err := t.list(recordHost)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make([]*types.HostInfoData, len(list))
+
+	for i, data := range list {
+		hosts[i] = &types.HostInfoData{}
+
+		if err := json.Unmarshal(data, hosts[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return hosts, nil
+}
+
+// PutHost implements Tx.
+func (t *tx) PutHost(h *types.HostInfoData) error {
+	return t.put(Key{Kind: recordHost, Name: h.Name}, h)
+}
+
+// DeleteHost implements Tx.
+func (t *tx) DeleteHost(name string) error {
+	return t.remove(Key{Kind: recordHost, Name: name})
+}
+
+// Transfer implements Tx.
+func (t *tx) Transfer(roid string) (*Transfer, error) {
+	tr := &Transfer{}
+
+	if err := t.load(Key{Kind: recordTransfer, Name: roid}, tr); err != nil {
+		return nil, err
+	}
+
+	return tr, nil
+}
+
+// Transfers implements Tx.
+func (t *tx) Transfers() ([]*Transfer, error) {
+	list, err := t.list(recordTransfer)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := make([]*Transfer, len(list))
+
+	for i, data := range list {
+		transfers[i] = &Transfer{}
+
+		if err := json.Unmarshal(data, transfers[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return transfers, nil
+}
+
+// PutTransfer implements Tx.
+func (t *tx) PutTransfer(tr *Transfer) error {
+	return t.put(Key{Kind: recordTransfer, Name: tr.ROID}, tr)
+}
+
+// DeleteTransfer implements Tx.
+func (t *tx) DeleteTransfer(roid string) error {
+	return t.remove(Key{Kind: recordTransfer, Name: roid})
+}
+
+// Messages implements Tx.
+func (t *tx) Messages(clientID string) ([]*Message, error) {
+	list, err := t.list(recordMessage + clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*Message, len(list))
+
+	for i, data := range list {
+		messages[i] = &Message{}
+
+		if err := json.Unmarshal(data, messages[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	// IDs are increasing numbers which orders messages queued at the same
+	// time.
+	sort.SliceStable(messages, func(i, j int) bool {
+		a, b := messages[i], messages[j]
+
+		if !a.QueueDate.Equal(b.QueueDate) {
+			return a.QueueDate.Before(b.QueueDate)
+		}
+
+		if len(a.ID) != len(b.ID) {
+			return len(a.ID) < len(b.ID)
+		}
+
+		return a.ID < b.ID
+	})
+
+	return messages, nil
+}
+
+// AddMessage implements Tx.
+func (t *tx) AddMessage(m *Message) error {
+	if m.ClientID == "" {
+		return errNoName
+	}
+
+	if t.done {
+		return ErrTxDone
+	}
+
+	id, err := t.backend.Next(sequenceMessage)
+	if err != nil {
+		return err
+	}
+
+	m.ID = strconv.FormatInt(id, 10)
+
+	return t.put(Key{Kind: recordMessage + m.ClientID, Name: m.ID}, m)
+}
+
+// DeleteMessage implements Tx.
+func (t *tx) DeleteMessage(clientID, id string) error {
+	return t.remove(Key{Kind: recordMessage + clientID, Name: id})
+}
+
+// NewROID implements Tx.
+func (t *tx) NewROID(prefix, suffix string) (string, error) {
+	if t.done {
+		return "", ErrTxDone
+	}
+
+	id, err := t.backend.Next(sequenceROID)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s%d-%s", prefix, id, suffix), nil
+}
+
+// Commit implements Tx. Transactions without writes are always committed.
+func (t *tx) Commit() error {
+	if t.done {
+		return ErrTxDone
+	}
+
+	t.done = true
+
+	if len(t.writes) == 0 {
+		return nil
+	}
+
+	return t.backend.Apply(Changes{Reads: t.reads, Writes: t.writes})
+}
+
+// Rollback implements Tx.
+func (t *tx) Rollback() error {
+	t.done = true
+
+	return nil
+}
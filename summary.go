@@ -0,0 +1,99 @@
+package epp
+
+import (
+	"aqwari.net/xml/xmltree"
+)
+
+// messageSummary holds the values describing a command or a response. Each
+// message is parsed once when summarized and the summary is shared by
+// logging, metrics, tracing, auditing and rate limiting. Values not found in
+// the message are empty.
+type messageSummary struct {
+	// command is the command path as used by Mux and class is the class of
+	// the command used for rate limiting, both only set for commands.
+	command string
+	class   CommandClass
+
+	// clientTransactionID is only set for commands and serverTransactionID
+	// and resultCode only for responses.
+	clientTransactionID string
+	serverTransactionID string
+	resultCode          string
+}
+
+// summarizeCommand returns the summary of the command in data.
+func summarizeCommand(data []byte) messageSummary {
+	var summary messageSummary
+
+	root, err := xmltree.Parse(data)
+	if err != nil {
+		return summary
+	}
+
+	if path, err := (&Mux{}).buildPath(root); err == nil {
+		summary.command = path
+	}
+
+	summary.class = commandClassOf(root)
+
+	if clTRID := root.Search(nsEPP, "clTRID"); len(clTRID) > 0 {
+		summary.clientTransactionID = string(clTRID[0].Content)
+	}
+
+	return summary
+}
+
+// summarizeResponse returns the summary of the response in data. No parsing
+// is done if data is empty.
+func summarizeResponse(data []byte) messageSummary {
+	var summary messageSummary
+
+	if len(data) == 0 {
+		return summary
+	}
+
+	root, err := xmltree.Parse(data)
+	if err != nil {
+		return summary
+	}
+
+	if svTRID := root.Search(nsEPP, "svTRID"); len(svTRID) > 0 {
+		summary.serverTransactionID = string(svTRID[0].Content)
+	}
+
+	if result := root.Search(nsEPP, "result"); len(result) > 0 {
+		summary.resultCode = result[0].Attr("", "code")
+	}
+
+	return summary
+}
+
+// fields returns the fields for the values in the summary, that is the
+// command path, the client and server transaction IDs and the result code.
+// Empty values are omitted.
+func (m messageSummary) fields() []Field {
+	var fields []Field
+
+	if m.command != "" {
+		fields = append(fields, Field{Key: FieldCommand, Value: m.command})
+	}
+
+	if m.clientTransactionID != "" {
+		fields = append(fields, Field{Key: FieldClientTransactionID, Value: m.clientTransactionID})
+	}
+
+	if m.serverTransactionID != "" {
+		fields = append(fields, Field{Key: FieldServerTransactionID, Value: m.serverTransactionID})
+	}
+
+	if m.resultCode != "" {
+		fields = append(fields, Field{Key: FieldResultCode, Value: m.resultCode})
+	}
+
+	return fields
+}
+
+// commandFields returns the fields describing a command and its response.
+func commandFields(command, response messageSummary) []Field {
+	return append(command.fields(), response.fields()...)
+}
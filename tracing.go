@@ -0,0 +1,131 @@
+package epp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// SpanContext identifies a span and the trace it belongs to. The IDs are hex
+// encoded as in the W3C trace context, 32 characters for the trace ID and 16
+// characters for the span ID.
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// IsValid returns true if both the trace ID and the span ID are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != "" && sc.SpanID != ""
+}
+
+// Span represents a single operation within a trace, e.g. a command sent by a
+// client or handled by a server.
+type Span interface {
+	// SpanContext returns the IDs identifying the span.
+	SpanContext() SpanContext
+
+	// SetAttributes adds the fields to the span.
+	SetAttributes(fields ...Field)
+
+	// RecordError records an error for the span.
+	RecordError(err error)
+
+	// End completes the span. No other methods should be called after End.
+	End()
+}
+
+// Tracer is the interface used by Session and Client to create spans. Start
+// creates a span with name as a child of the span in ctx, if any, and returns
+// a context holding the new span. Implementations should use
+// ParentSpanContext to find the parent and ContextWithSpan to create the
+// returned context. Use SpanRecorder to record spans in memory.
+type Tracer interface {
+	Start(ctx context.Context, name string, fields ...Field) (context.Context, Span)
+}
+
+type (
+	spanContextKey       struct{}
+	remoteSpanContextKey struct{}
+	tracerContextKey     struct{}
+)
+
+// ContextWithSpan returns a copy of ctx holding span.
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// SpanFromContext returns the span in ctx or nil if ctx holds no span.
+func SpanFromContext(ctx context.Context) Span {
+	span, _ := ctx.Value(spanContextKey{}).(Span)
+
+	return span
+}
+
+// ContextWithRemoteSpanContext returns a copy of ctx holding a span context
+// received from a remote peer, used as parent for the next span started.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteSpanContextKey{}, sc)
+}
+
+// ParentSpanContext returns the span context of the span in ctx or, if there
+// is none, the remote span context in ctx. The returned span context isn't
+// valid if ctx holds neither.
+func ParentSpanContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+
+	sc, _ := ctx.Value(remoteSpanContextKey{}).(SpanContext)
+
+	return sc
+}
+
+// ContextWithTracer returns a copy of ctx holding tracer, used by StartSpan.
+func ContextWithTracer(ctx context.Context, tracer Tracer) context.Context {
+	return context.WithValue(ctx, tracerContextKey{}, tracer)
+}
+
+// StartSpan starts a span with the tracer in ctx as a child of the span in
+// ctx. Handlers can use this with Session.Context to create spans for their
+// own operations.
+//
+//	ctx, span := epp.StartSpan(s.Context(), "load domain")
+//	defer span.End()
+//
+// If ctx holds no tracer a span doing nothing is returned.
+func StartSpan(ctx context.Context, name string, fields ...Field) (context.Context, Span) {
+	tracer, ok := ctx.Value(tracerContextKey{}).(Tracer)
+	if !ok {
+		return ctx, nopSpan{}
+	}
+
+	return tracer.Start(ctx, name, fields...)
+}
+
+// NewTraceID returns a new random trace ID.
+func NewTraceID() string {
+	return randomHex(16)
+}
+
+// NewSpanID returns a new random span ID.
+func NewSpanID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+
+	// Reading from crypto/rand never fails on supported platforms.
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// nopSpan is a Span doing nothing, used when no tracer is configured.
+type nopSpan struct{}
+
+func (nopSpan) SpanContext() SpanContext { return SpanContext{} }
+func (nopSpan) SetAttributes(...Field)   {}
+func (nopSpan) RecordError(error)        {}
+func (nopSpan) End()                     {}
@@ -0,0 +1,299 @@
+package epp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"aqwari.net/xml/xmltree"
+)
+
+// TracePropagator carries a span context from a client to a server within
+// the EPP command.
+type TracePropagator interface {
+	// Inject returns a copy of the command in data carrying sc.
+	Inject(data []byte, sc SpanContext) ([]byte, error)
+
+	// Extract returns the span context carried by the command in data and
+	// true or false if the command carries no span context.
+	Extract(data []byte) (SpanContext, bool)
+}
+
+var (
+	clientTransactionIDTraceRe = regexp.MustCompile(`^([0-9a-f]{32})-([0-9a-f]{16})$`)
+	traceParentRe              = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+)
+
+// ClientTransactionIDPropagator carries the span context in the clTRID element
+// as the trace ID and the span ID separated by a dash. Commands without a
+// clTRID gets one added. Commands already holding a clTRID are left as is
+// unless Overwrite is true.
+type ClientTransactionIDPropagator struct {
+	Overwrite bool
+}
+
+// Inject implements TracePropagator.
+func (p ClientTransactionIDPropagator) Inject(data []byte, sc SpanContext) ([]byte, error) {
+	if !sc.IsValid() {
+		return data, nil
+	}
+
+	clTRID := sc.TraceID + "-" + sc.SpanID
+
+	el, ok, err := findElement(data, nsEPP, "clTRID")
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		if !p.Overwrite {
+			return data, nil
+		}
+
+		return el.replaceContent(data, clTRID), nil
+	}
+
+	command, ok, err := findElement(data, nsEPP, "command")
+	if err != nil || !ok {
+		return data, err
+	}
+
+	return insert(data, command.contentEnd, command.wrap("clTRID", clTRID)), nil
+}
+
+// Extract implements TracePropagator.
+func (ClientTransactionIDPropagator) Extract(data []byte) (SpanContext, bool) {
+	m := clientTransactionIDTraceRe.FindStringSubmatch(ClientTransactionID(data))
+	if m == nil {
+		return SpanContext{}, false
+	}
+
+	return SpanContext{TraceID: m[1], SpanID: m[2]}, true
+}
+
+// ExtensionPropagator carries the span context in a traceparent element, with
+// the format of the W3C trace context traceparent header, in the extension
+// element of the command. The element is in the namespace Namespace which
+// must be set. Servers validating commands must accept the extension.
+type ExtensionPropagator struct {
+	Namespace string
+}
+
+// Inject implements TracePropagator.
+func (p ExtensionPropagator) Inject(data []byte, sc SpanContext) ([]byte, error) {
+	if p.Namespace == "" {
+		return nil, errors.New("missing namespace for trace extension")
+	}
+
+	if !sc.IsValid() {
+		return data, nil
+	}
+
+	traceParent := fmt.Sprintf("00-%s-%s-01", sc.TraceID, sc.SpanID)
+
+	el, ok, err := findElement(data, p.Namespace, "traceparent")
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		return el.replaceContent(data, traceParent), nil
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString(`<trace:traceparent xmlns:trace="`)
+	_ = xml.EscapeText(&buf, []byte(p.Namespace))
+	buf.WriteString(`">`)
+	buf.WriteString(traceParent)
+	buf.WriteString(`</trace:traceparent>`)
+
+	extension, ok, err := findElement(data, nsEPP, "extension")
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		if extension.selfClosing() {
+			return extension.replaceContent(data, buf.String()), nil
+		}
+
+		return insert(data, extension.contentEnd, buf.String()), nil
+	}
+
+	command, ok, err := findElement(data, nsEPP, "command")
+	if err != nil || !ok {
+		return data, err
+	}
+
+	// The extension must be placed before the clTRID if there is one.
+	offset := command.contentEnd
+	if clTRID, ok, _ := findElement(data, nsEPP, "clTRID"); ok {
+		offset = clTRID.start
+	}
+
+	return insert(data, offset, command.wrap("extension", buf.String())), nil
+}
+
+// Extract implements TracePropagator.
+func (p ExtensionPropagator) Extract(data []byte) (SpanContext, bool) {
+	root, err := xmltree.Parse(data)
+	if err != nil {
+		return SpanContext{}, false
+	}
+
+	elements := root.Search(p.Namespace, "traceparent")
+	if len(elements) == 0 {
+		return SpanContext{}, false
+	}
+
+	m := traceParentRe.FindStringSubmatch(string(bytes.TrimSpace(elements[0].Content)))
+	if m == nil {
+		return SpanContext{}, false
+	}
+
+	return SpanContext{TraceID: m[1], SpanID: m[2]}, true
+}
+
+// elementPosition holds the offsets of an element in a document. The start
+// tag is at start to contentStart and the end tag at contentEnd to end. For
+// self closing elements contentStart, contentEnd and end are equal.
+type elementPosition struct {
+	start        int
+	contentStart int
+	contentEnd   int
+	end          int
+	prefix       string
+}
+
+// findElement returns the position of the first element in the namespace ns
+// with the local name.
+func findElement(data []byte, ns, local string) (elementPosition, bool, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+
+	var (
+		pos   elementPosition
+		found bool
+		depth int
+	)
+
+	for {
+		offset := int(d.InputOffset())
+
+		tok, err := d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return elementPosition{}, false, nil
+			}
+
+			return elementPosition{}, false, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if found {
+				depth++
+
+				continue
+			}
+
+			if t.Name.Space == ns && t.Name.Local == local {
+				found = true
+				pos.start = offset
+				pos.contentStart = int(d.InputOffset())
+				pos.prefix = tagPrefix(data[offset:])
+			}
+		case xml.EndElement:
+			if !found {
+				continue
+			}
+
+			if depth > 0 {
+				depth--
+
+				continue
+			}
+
+			pos.contentEnd = offset
+			pos.end = int(d.InputOffset())
+
+			return pos, true, nil
+		}
+	}
+}
+
+// selfClosing returns true if the element has no end tag.
+func (p elementPosition) selfClosing() bool {
+	return p.contentStart == p.end
+}
+
+// replaceContent returns a copy of data with the content of the element
+// replaced with the raw content. Self closing elements are expanded.
+func (p elementPosition) replaceContent(data []byte, content string) []byte {
+	if p.selfClosing() {
+		return replace(data, p.start, p.end, p.wrap(p.localName(data), content))
+	}
+
+	return replace(data, p.contentStart, p.contentEnd, content)
+}
+
+// localName returns the local name of the element from its start tag.
+func (p elementPosition) localName(data []byte) string {
+	name := tagName(data[p.start:])
+	if p.prefix != "" {
+		return name[len(p.prefix)+1:]
+	}
+
+	return name
+}
+
+// wrap returns an element with the local name and the raw content using the
+// same prefix as the element at p.
+func (p elementPosition) wrap(local, content string) string {
+	name := local
+	if p.prefix != "" {
+		name = p.prefix + ":" + local
+	}
+
+	return "<" + name + ">" + content + "</" + name + ">"
+}
+
+// tagName returns the qualified name of the start tag at the beginning of
+// data.
+func tagName(data []byte) string {
+	end := bytes.IndexAny(data, " \t\r\n/>")
+	if end < 1 {
+		return ""
+	}
+
+	return string(data[1:end])
+}
+
+// tagPrefix returns the namespace prefix of the start tag at the beginning of
+// data or an empty string if there is none.
+func tagPrefix(data []byte) string {
+	name := tagName(data)
+
+	if i := strings.IndexByte(name, ':'); i > 0 {
+		return name[:i]
+	}
+
+	return ""
+}
+
+func insert(data []byte, offset int, s string) []byte {
+	return replace(data, offset, offset, s)
+}
+
+func replace(data []byte, start, end int, s string) []byte {
+	result := make([]byte, 0, len(data)-(end-start)+len(s))
+	result = append(result, data[:start]...)
+	result = append(result, s...)
+	result = append(result, data[end:]...)
+
+	return result
+}
@@ -0,0 +1,135 @@
+package epp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RecordedSpan is a completed span recorded by SpanRecorder.
+type RecordedSpan struct {
+	Name        string
+	SpanContext SpanContext
+
+	// Parent is the span context of the parent span. It isn't valid for root
+	// spans.
+	Parent SpanContext
+
+	Fields    []Field
+	Errors    []error
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// Field returns the value of the last field with key and true or nil and
+// false if the span has no such field.
+func (s RecordedSpan) Field(key string) (interface{}, bool) {
+	for i := len(s.Fields) - 1; i >= 0; i-- {
+		if s.Fields[i].Key == key {
+			return s.Fields[i].Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// SpanRecorder is a Tracer recording all spans in memory, e.g. to test
+// tracing without a collector.
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+}
+
+// NewSpanRecorder creates a new SpanRecorder.
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+// Start implements Tracer.
+func (r *SpanRecorder) Start(ctx context.Context, name string, fields ...Field) (context.Context, Span) {
+	parent := ParentSpanContext(ctx)
+
+	traceID := parent.TraceID
+	if traceID == "" {
+		traceID = NewTraceID()
+	}
+
+	span := &recordingSpan{
+		recorder: r,
+		span: RecordedSpan{
+			Name: name,
+			SpanContext: SpanContext{
+				TraceID: traceID,
+				SpanID:  NewSpanID(),
+			},
+			Parent:    parent,
+			Fields:    append([]Field{}, fields...),
+			StartTime: time.Now(),
+		},
+	}
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// Spans returns all ended spans in the order they ended.
+func (r *SpanRecorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]RecordedSpan{}, r.spans...)
+}
+
+// Reset removes all recorded spans.
+func (r *SpanRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = nil
+}
+
+// recordingSpan is the Span created by SpanRecorder.
+type recordingSpan struct {
+	mu       sync.Mutex
+	recorder *SpanRecorder
+	span     RecordedSpan
+	ended    bool
+}
+
+func (s *recordingSpan) SpanContext() SpanContext {
+	return s.span.SpanContext
+}
+
+func (s *recordingSpan) SetAttributes(fields ...Field) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.span.Fields = append(s.span.Fields, fields...)
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.span.Errors = append(s.span.Errors, err)
+}
+
+func (s *recordingSpan) End() {
+	s.mu.Lock()
+
+	if s.ended {
+		s.mu.Unlock()
+
+		return
+	}
+
+	s.ended = true
+	s.span.EndTime = time.Now()
+	span := s.span
+
+	s.mu.Unlock()
+
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	s.recorder.spans = append(s.recorder.spans, span)
+}
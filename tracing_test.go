@@ -0,0 +1,277 @@
+package epp
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"aqwari.net/xml/xmltree"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSpanContext = SpanContext{
+	TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+	SpanID:  "00f067aa0ba902b7",
+}
+
+func TestSpanRecorder(t *testing.T) {
+	recorder := NewSpanRecorder()
+
+	ctx, root := recorder.Start(context.Background(), "root", Field{Key: "a", Value: 1})
+	ctx = ContextWithTracer(ctx, recorder)
+
+	_, child := StartSpan(ctx, "child")
+	child.SetAttributes(Field{Key: "a", Value: 2})
+	child.End()
+	root.End()
+	root.End()
+
+	spans := recorder.Spans()
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, root.SpanContext(), spans[0].Parent)
+	assert.Equal(t, root.SpanContext().TraceID, spans[0].SpanContext.TraceID)
+	assert.Len(t, spans[0].SpanContext.TraceID, 32)
+	assert.Len(t, spans[0].SpanContext.SpanID, 16)
+
+	assert.Equal(t, "root", spans[1].Name)
+	assert.False(t, spans[1].Parent.IsValid())
+
+	value, ok := spans[1].Field("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	// Without a tracer in the context a span doing nothing is returned.
+	_, span := StartSpan(context.Background(), "nothing")
+	span.End()
+
+	assert.False(t, span.SpanContext().IsValid())
+	assert.Len(t, recorder.Spans(), 2)
+
+	// A remote span context is used as parent.
+	_, remote := recorder.Start(ContextWithRemoteSpanContext(context.Background(), testSpanContext), "remote")
+	assert.Equal(t, testSpanContext.TraceID, remote.SpanContext().TraceID)
+}
+
+func TestClientTransactionIDPropagator(t *testing.T) {
+	hello, err := os.ReadFile("xml/commands/hello.xml")
+	require.Nil(t, err)
+
+	infoDomain, err := os.ReadFile("xml/commands/info-domain.xml")
+	require.Nil(t, err)
+
+	withoutClTRID := []byte(`<epp:epp xmlns:epp="urn:ietf:params:xml:ns:epp-1.0"><epp:command><epp:logout/></epp:command></epp:epp>`)
+	clTRID := testSpanContext.TraceID + "-" + testSpanContext.SpanID
+
+	cases := []struct {
+		description string
+		propagator  ClientTransactionIDPropagator
+		data        []byte
+		clTRID      string
+	}{
+		{
+			description: "not a command",
+			data:        hello,
+		},
+		{
+			description: "existing clTRID is kept",
+			data:        infoDomain,
+			clTRID:      "ABC-12345",
+		},
+		{
+			description: "existing clTRID is overwritten",
+			propagator:  ClientTransactionIDPropagator{Overwrite: true},
+			data:        infoDomain,
+			clTRID:      clTRID,
+		},
+		{
+			description: "missing clTRID is added with prefix",
+			data:        withoutClTRID,
+			clTRID:      clTRID,
+		},
+		{
+			description: "self closing clTRID is expanded",
+			propagator:  ClientTransactionIDPropagator{Overwrite: true},
+			data:        []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><command><logout/><clTRID/></command></epp>`),
+			clTRID:      clTRID,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			data, err := tc.propagator.Inject(tc.data, testSpanContext)
+			require.Nil(t, err)
+
+			_, err = xmltree.Parse(data)
+			require.Nil(t, err)
+
+			assert.Equal(t, tc.clTRID, ClientTransactionID(data))
+
+			sc, ok := tc.propagator.Extract(data)
+			assert.Equal(t, tc.clTRID == clTRID, ok)
+
+			if ok {
+				assert.Equal(t, testSpanContext, sc)
+			}
+		})
+	}
+}
+
+func TestExtensionPropagator(t *testing.T) {
+	propagator := ExtensionPropagator{Namespace: "urn:example:trace-1.0"}
+
+	for _, file := range []string{"info-domain.xml", "transfer-domain.xml", "logout.xml"} {
+		t.Run(file, func(t *testing.T) {
+			original, err := os.ReadFile("xml/commands/" + file)
+			require.Nil(t, err)
+
+			data, err := propagator.Inject(original, testSpanContext)
+			require.Nil(t, err)
+
+			root, err := xmltree.Parse(data)
+			require.Nil(t, err)
+
+			// The extension is the last element before the clTRID.
+			command := root.Children[0]
+			for i, child := range command.Children {
+				if child.Name.Local == "extension" {
+					assert.Equal(t, "traceparent", child.Children[len(child.Children)-1].Name.Local)
+
+					if i < len(command.Children)-1 {
+						assert.Equal(t, "clTRID", command.Children[i+1].Name.Local)
+					}
+				}
+			}
+
+			assert.Equal(t, summarizeCommand(original).command, summarizeCommand(data).command)
+			assert.Equal(t, ClientTransactionID(original), ClientTransactionID(data))
+
+			sc, ok := propagator.Extract(data)
+			require.True(t, ok)
+			assert.Equal(t, testSpanContext, sc)
+
+			// Injecting again replaces the span context.
+			other := SpanContext{TraceID: NewTraceID(), SpanID: NewSpanID()}
+
+			data, err = propagator.Inject(data, other)
+			require.Nil(t, err)
+
+			sc, ok = propagator.Extract(data)
+			require.True(t, ok)
+			assert.Equal(t, other, sc)
+		})
+	}
+
+	_, err := ExtensionPropagator{}.Inject([]byte("<epp/>"), testSpanContext)
+	assert.NotNil(t, err)
+}
+
+func TestServerTracing(t *testing.T) {
+	l := newPipeListener()
+	recorder := NewSpanRecorder()
+	propagator := ClientTransactionIDPropagator{Overwrite: true}
+
+	srv := Server{
+		AllowPlaintext: true,
+		Logger:         NopLogger{},
+		SessionConfig: SessionConfig{
+			IdleTimeout:     10 * time.Minute,
+			SessionTimeout:  10 * time.Minute,
+			Tracer:          recorder,
+			TracePropagator: propagator,
+			Handler: func(s *Session, in []byte) ([]byte, error) {
+				_, span := StartSpan(s.Context(), "lookup")
+				span.End()
+
+				return Encode(types.Response{
+					Result: []types.Result{
+						{
+							Code:    EppOk.Code(),
+							Message: EppOk.Message(),
+						},
+					},
+					TransactionID: types.TransactionID{
+						ClientTransactionID: ClientTransactionID(in),
+						ServerTransactionID: "SRV-1",
+					},
+				}, ServerXMLAttributes())
+			},
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+	}
+
+	served := make(chan error)
+
+	go func() {
+		served <- srv.Serve(l)
+	}()
+
+	conn, err := l.Dial()
+	require.Nil(t, err)
+
+	client := &Client{
+		Logger:          NopLogger{},
+		Tracer:          recorder,
+		TracePropagator: propagator,
+		conn:            conn,
+		framer:          NewFramer(conn),
+	}
+
+	_, err = client.framer.ReadMessage()
+	require.Nil(t, err)
+
+	request, err := os.ReadFile("xml/commands/logout.xml")
+	require.Nil(t, err)
+
+	ctx, parent := recorder.Start(context.Background(), "registrar")
+
+	response, err := client.SendContext(ctx, request)
+	require.Nil(t, err)
+
+	parent.End()
+
+	srv.Stop()
+	require.Nil(t, conn.Close())
+	require.Nil(t, <-served)
+
+	spans := map[string][]RecordedSpan{}
+	for _, span := range recorder.Spans() {
+		spans[span.Name] = append(spans[span.Name], span)
+	}
+
+	require.Len(t, spans["registrar"], 1)
+	require.Len(t, spans["command/logout"], 2)
+	require.Len(t, spans["lookup"], 1)
+
+	// Only the server span has a session ID.
+	serverSpan, clientSpan := spans["command/logout"][0], spans["command/logout"][1]
+	if _, ok := clientSpan.Field(FieldSessionID); ok {
+		serverSpan, clientSpan = clientSpan, serverSpan
+	}
+
+	assert.Equal(t, spans["registrar"][0].SpanContext, clientSpan.Parent)
+	assert.Equal(t, clientSpan.SpanContext, serverSpan.Parent)
+	assert.Equal(t, serverSpan.SpanContext, spans["lookup"][0].Parent)
+
+	traceID := spans["registrar"][0].SpanContext.TraceID
+	assert.Equal(t, traceID+"-"+clientSpan.SpanContext.SpanID, ClientTransactionID(response))
+
+	for _, span := range []RecordedSpan{serverSpan, clientSpan} {
+		assert.Equal(t, traceID, span.SpanContext.TraceID)
+
+		code, _ := span.Field(FieldResultCode)
+		assert.Equal(t, "1000", code)
+
+		svTRID, _ := span.Field(FieldServerTransactionID)
+		assert.Equal(t, "SRV-1", svTRID)
+	}
+
+	sessionID, _ := serverSpan.Field(FieldSessionID)
+	assert.NotEmpty(t, sessionID)
+}
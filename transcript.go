@@ -0,0 +1,164 @@
+package epp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// TranscriptEntryKind represents the kind of a message in a transcript.
+type TranscriptEntryKind string
+
+// Constants representing the kinds of messages in a transcript.
+const (
+	TranscriptGreeting TranscriptEntryKind = "greeting"
+	TranscriptRequest  TranscriptEntryKind = "request"
+	TranscriptResponse TranscriptEntryKind = "response"
+)
+
+// TranscriptEntry is a single message in a transcript.
+type TranscriptEntry struct {
+	Kind TranscriptEntryKind
+
+	// Elapsed is the time from the start of the transcript until the message
+	// was sent or received.
+	Elapsed time.Duration
+
+	Data []byte
+}
+
+// transcriptHeader is the header frame written before each message.
+type transcriptHeader struct {
+	Kind    TranscriptEntryKind `json:"kind"`
+	Elapsed int64               `json:"elapsed_ns"`
+}
+
+// TranscriptWriter writes a transcript of a session. Each entry is written as
+// two frames using the EPP framing, first a header in JSON with the kind and
+// the elapsed time followed by the message itself. Messages are redacted
+// before they're written so passwords don't end up in the transcript. A
+// TranscriptWriter is safe to use from multiple goroutines.
+type TranscriptWriter struct {
+	// Redactor is used to redact each message before it's written. If nil
+	// DefaultRedactor is used. Messages that can't be parsed are replaced
+	// since they can't be redacted safely. Set it to a Redactor without
+	// rules, e.g. &Redactor{}, to write the messages as they are.
+	Redactor *Redactor
+
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+}
+
+// NewTranscriptWriter creates a new TranscriptWriter writing to w. The elapsed
+// time for each entry is measured from when the writer is created.
+func NewTranscriptWriter(w io.Writer) *TranscriptWriter {
+	return &TranscriptWriter{
+		w:     w,
+		start: time.Now(),
+	}
+}
+
+// Write writes a message of the kind to the transcript after redacting it.
+func (t *TranscriptWriter) Write(kind TranscriptEntryKind, data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	redactor := t.Redactor
+	if redactor == nil {
+		redactor = DefaultRedactor
+	}
+
+	data = []byte(redactMessage(redactor, data))
+
+	header, err := json.Marshal(transcriptHeader{
+		Kind:    kind,
+		Elapsed: int64(time.Since(t.start)),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := WriteMessage(t.w, header); err != nil {
+		return err
+	}
+
+	return WriteMessage(t.w, data)
+}
+
+// Close closes the underlying writer if it implements io.Closer.
+func (t *TranscriptWriter) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if closer, ok := t.w.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// TranscriptReader reads a transcript written by TranscriptWriter.
+type TranscriptReader struct {
+	r io.Reader
+}
+
+// NewTranscriptReader creates a new TranscriptReader reading from r.
+func NewTranscriptReader(r io.Reader) *TranscriptReader {
+	return &TranscriptReader{
+		r: r,
+	}
+}
+
+// Next returns the next entry in the transcript. At the end of the transcript
+// io.EOF is returned.
+func (t *TranscriptReader) Next() (TranscriptEntry, error) {
+	header, err := ReadMessage(t.r)
+	if err != nil {
+		return TranscriptEntry{}, err
+	}
+
+	var h transcriptHeader
+
+	if err := json.Unmarshal(header, &h); err != nil {
+		return TranscriptEntry{}, fmt.Errorf("invalid transcript header: %w", err)
+	}
+
+	data, err := ReadMessage(t.r)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return TranscriptEntry{}, err
+	}
+
+	return TranscriptEntry{
+		Kind:    h.Kind,
+		Elapsed: time.Duration(h.Elapsed),
+		Data:    data,
+	}, nil
+}
+
+// ReadTranscript reads all entries from the transcript in r.
+func ReadTranscript(r io.Reader) ([]TranscriptEntry, error) {
+	reader := NewTranscriptReader(r)
+
+	var entries []TranscriptEntry
+
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+}
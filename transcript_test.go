@@ -0,0 +1,498 @@
+package epp
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscript(t *testing.T) {
+	var buf bytes.Buffer
+
+	w := NewTranscriptWriter(&buf)
+
+	require.Nil(t, w.Write(TranscriptGreeting, []byte("<greeting/>")))
+	require.Nil(t, w.Write(TranscriptRequest, []byte("<request/>")))
+	require.Nil(t, w.Write(TranscriptResponse, []byte("<response/>")))
+	require.Nil(t, w.Close())
+
+	entries, err := ReadTranscript(bytes.NewReader(buf.Bytes()))
+	require.Nil(t, err)
+	require.Len(t, entries, 3)
+
+	for i, want := range []TranscriptEntry{
+		{Kind: TranscriptGreeting, Data: []byte("<greeting/>")},
+		{Kind: TranscriptRequest, Data: []byte("<request/>")},
+		{Kind: TranscriptResponse, Data: []byte("<response/>")},
+	} {
+		assert.Equal(t, want.Kind, entries[i].Kind)
+		assert.Equal(t, want.Data, entries[i].Data)
+
+		if i > 0 {
+			assert.True(t, entries[i].Elapsed >= entries[i-1].Elapsed)
+		}
+	}
+
+	// A transcript ending after a header is truncated.
+	_, err = ReadTranscript(bytes.NewReader(buf.Bytes()[:buf.Len()-len("<response/>")-4]))
+	assert.NotNil(t, err)
+}
+
+func TestTranscriptRedaction(t *testing.T) {
+	login := []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><command><login>
+<clID>registrar-1</clID><pw>secret</pw><newPW>new-secret</newPW></login></command></epp>`)
+
+	record := func(redactor *Redactor, data []byte) []byte {
+		var buf bytes.Buffer
+
+		w := NewTranscriptWriter(&buf)
+		w.Redactor = redactor
+
+		require.Nil(t, w.Write(TranscriptRequest, data))
+
+		entries, err := ReadTranscript(&buf)
+		require.Nil(t, err)
+		require.Len(t, entries, 1)
+
+		return entries[0].Data
+	}
+
+	// Passwords are redacted by default.
+	redacted := string(record(nil, login))
+	assert.Contains(t, redacted, "<clID>registrar-1</clID>")
+	assert.Contains(t, redacted, "<pw>REDACTED</pw>")
+	assert.Contains(t, redacted, "<newPW>REDACTED</newPW>")
+	assert.NotContains(t, redacted, "secret")
+
+	// Messages that can't be parsed can't be redacted safely.
+	assert.Equal(t, redactedUnparsable, string(record(nil, []byte("<pw>secret"))))
+
+	// A redactor without rules keeps the messages as they are.
+	assert.Equal(t, login, record(&Redactor{}, login))
+	assert.Equal(t, "<pw>secret", string(record(&Redactor{}, []byte("<pw>secret"))))
+}
+
+func TestNormalizeMessage(t *testing.T) {
+	a := `<epp>
+  <response>
+    <resData>
+      <domain:infData>
+        <domain:roid>EXAMPLE1-REP</domain:roid>
+        <domain:crDate>1999-04-03T22:00:00.0Z</domain:crDate>
+        <domain:pw roid="SH8013-REP">2fooBAR</domain:pw>
+      </domain:infData>
+    </resData>
+    <trID><clTRID>ABC-12345</clTRID><svTRID>54322-XYZ</svTRID></trID>
+  </response>
+</epp>`
+
+	b := `<epp><response><resData><domain:infData><domain:roid>OTHER-REP</domain:roid>` +
+		`<domain:crDate>2021-01-01T10:00:00+02:00</domain:crDate><domain:pw roid="X-REP">2fooBAR</domain:pw>` +
+		`</domain:infData></resData><trID><clTRID>ABC-12345</clTRID><svTRID>1</svTRID></trID></response></epp>`
+
+	assert.Equal(t, string(NormalizeMessage([]byte(a))), string(NormalizeMessage([]byte(b))))
+	assert.Contains(t, string(NormalizeMessage([]byte(b))), "<svTRID>SVTRID</svTRID>")
+	assert.Contains(t, string(NormalizeMessage([]byte(b))), "<clTRID>ABC-12345</clTRID>")
+}
+
+func TestDiffLines(t *testing.T) {
+	diff := diffLines(
+		[]string{"<a>", "<b>1</b>", "<c/>", "</a>"},
+		[]string{"<a>", "<b>2</b>", "<c/>", "<d/>", "</a>"},
+	)
+
+	assert.Equal(t, "  <a>\n- <b>1</b>\n+ <b>2</b>\n  <c/>\n+ <d/>\n  </a>\n", diff)
+}
+
+func TestServerTranscriptReplay(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		transcript bytes.Buffer
+		result     = EppOk
+	)
+
+	srv := Server{
+		AllowPlaintext: true,
+		Logger:         NopLogger{},
+		SessionConfig: SessionConfig{
+			IdleTimeout:    10 * time.Minute,
+			SessionTimeout: 10 * time.Minute,
+			Transcript: func(s *Session) (*TranscriptWriter, error) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				// Only record the first session.
+				if transcript.Len() > 0 {
+					return nil, nil
+				}
+
+				return NewTranscriptWriter(&lockedWriter{mu: &mu, w: &transcript}), nil
+			},
+			Handler: func(s *Session, in []byte) ([]byte, error) {
+				mu.Lock()
+				code := result
+				mu.Unlock()
+
+				return Encode(types.Response{
+					Result: []types.Result{
+						{
+							Code:    code.Code(),
+							Message: code.Message(),
+						},
+					},
+					TransactionID: types.TransactionID{
+						ClientTransactionID: ClientTransactionID(in),
+						ServerTransactionID: NewSpanID(),
+					},
+				}, ServerXMLAttributes())
+			},
+			Greeting: func(s *Session) ([]byte, error) {
+				return testGreeting, nil
+			},
+		},
+	}
+
+	l := newPipeListener()
+	served := make(chan error)
+
+	go func() {
+		served <- srv.Serve(l)
+	}()
+
+	defer func() {
+		srv.Stop()
+		require.Nil(t, <-served)
+	}()
+
+	conn, err := l.Dial()
+	require.Nil(t, err)
+
+	framer := NewFramer(conn)
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	for _, file := range []string{"info-domain.xml", "logout.xml"} {
+		request, err := os.ReadFile(filepath.Join("xml", "commands", file))
+		require.Nil(t, err)
+
+		require.Nil(t, framer.WriteMessage(request))
+
+		_, err = framer.ReadMessage()
+		require.Nil(t, err)
+	}
+
+	require.Nil(t, conn.Close())
+
+	// Wait for the transcript to be closed with the session.
+	for i := 0; srv.SessionCounts().Total > 0 && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	entries, err := ReadTranscript(bytes.NewReader(transcript.Bytes()))
+	mu.Unlock()
+
+	require.Nil(t, err)
+	require.Len(t, entries, 5)
+
+	kinds := []TranscriptEntryKind{}
+	for _, e := range entries {
+		kinds = append(kinds, e.Kind)
+	}
+
+	assert.Equal(t, []TranscriptEntryKind{
+		TranscriptGreeting,
+		TranscriptRequest, TranscriptResponse,
+		TranscriptRequest, TranscriptResponse,
+	}, kinds)
+
+	replay := func() []ReplayDifference {
+		conn, err := l.Dial()
+		require.Nil(t, err)
+
+		defer conn.Close()
+
+		differences, err := (&Replayer{}).Replay(conn, entries)
+		require.Nil(t, err)
+
+		return differences
+	}
+
+	// The server transaction IDs differs but are normalized.
+	assert.Empty(t, replay())
+
+	mu.Lock()
+	result = EppObjectDoesNotExist
+	mu.Unlock()
+
+	differences := replay()
+	require.Len(t, differences, 2)
+
+	assert.Equal(t, 2, differences[0].Index)
+	assert.Equal(t, entries[1].Data, differences[0].Request)
+	assert.True(t, strings.Contains(differences[0].Diff, `- <result code="1000">`))
+	assert.True(t, strings.Contains(differences[0].Diff, `+ <result code="2303">`))
+}
+
+func TestReplayServerBye(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		transcript bytes.Buffer
+	)
+
+	newServer := func(record bool) (*Server, *pipeListener) {
+		srv := &Server{
+			AllowPlaintext: true,
+			Logger:         NopLogger{},
+			SessionConfig: SessionConfig{
+				IdleTimeout:    10 * time.Minute,
+				SessionTimeout: 10 * time.Minute,
+				Handler: func(s *Session, in []byte) ([]byte, error) {
+					return in, nil
+				},
+				Greeting: func(s *Session) ([]byte, error) {
+					return testGreeting, nil
+				},
+			},
+		}
+
+		if record {
+			srv.SessionConfig.Transcript = func(s *Session) (*TranscriptWriter, error) {
+				return NewTranscriptWriter(&lockedWriter{mu: &mu, w: &transcript}), nil
+			}
+		}
+
+		l := newPipeListener()
+
+		go func() {
+			_ = srv.Serve(l)
+		}()
+
+		return srv, l
+	}
+
+	// Record a session ending with the server saying goodbye when it's shut
+	// down.
+	srv, l := newServer(true)
+
+	conn, err := l.Dial()
+	require.Nil(t, err)
+
+	defer conn.Close()
+
+	framer := NewFramer(conn)
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	hello := []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><hello/></epp>`)
+	require.Nil(t, framer.WriteMessage(hello))
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	shutdown := make(chan error)
+
+	go func() {
+		shutdown <- srv.Shutdown(context.Background())
+	}()
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+	require.Nil(t, <-shutdown)
+
+	mu.Lock()
+	entries, err := ReadTranscript(bytes.NewReader(transcript.Bytes()))
+	mu.Unlock()
+
+	require.Nil(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, TranscriptResponse, entries[3].Kind)
+
+	bye, err := DecodeResponse(entries[3].Data)
+	require.Nil(t, err)
+	assert.Equal(t, EppCommandFailedBye, bye.Code())
+
+	// The goodbye wasn't a response to a request and is skipped when
+	// replaying.
+	srv, l = newServer(false)
+	defer srv.Stop()
+
+	conn, err = l.Dial()
+	require.Nil(t, err)
+
+	defer conn.Close()
+
+	differences, err := (&Replayer{}).Replay(conn, entries)
+	require.Nil(t, err)
+	assert.Empty(t, differences)
+}
+
+// lockedWriter is a writer holding mu while writing to w.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  *bytes.Buffer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.w.Write(p)
+}
+
+func TestReplayRedactedCredentials(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		transcript bytes.Buffer
+	)
+
+	login := []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><command><login>
+<clID>registrar-1</clID><pw>secret</pw></login><clTRID>ABC-1</clTRID></command></epp>`)
+	transfer := []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><command><transfer op="request">
+<domain:transfer xmlns:domain="urn:ietf:params:xml:ns:domain-1.0"><domain:name>example.se</domain:name>
+<domain:authInfo><domain:pw>auth-secret</domain:pw></domain:authInfo></domain:transfer>
+</transfer><clTRID>ABC-2</clTRID></command></epp>`)
+
+	// The server only accepts the commands with the right passwords.
+	newServer := func(record bool) (*Server, *pipeListener) {
+		srv := &Server{
+			AllowPlaintext: true,
+			Logger:         NopLogger{},
+			SessionConfig: SessionConfig{
+				IdleTimeout:    10 * time.Minute,
+				SessionTimeout: 10 * time.Minute,
+				Handler: func(s *Session, in []byte) ([]byte, error) {
+					code := EppAuthenticationError
+
+					if bytes.Contains(in, []byte("<pw>secret</pw>")) ||
+						bytes.Contains(in, []byte("<domain:pw>auth-secret</domain:pw>")) {
+						code = EppOk
+					}
+
+					return Encode(types.Response{
+						Result: []types.Result{
+							{
+								Code:    code.Code(),
+								Message: code.Message(),
+							},
+						},
+						TransactionID: types.TransactionID{
+							ClientTransactionID: ClientTransactionID(in),
+						},
+					}, ServerXMLAttributes())
+				},
+				Greeting: func(s *Session) ([]byte, error) {
+					return testGreeting, nil
+				},
+			},
+		}
+
+		if record {
+			srv.SessionConfig.Transcript = func(s *Session) (*TranscriptWriter, error) {
+				return NewTranscriptWriter(&lockedWriter{mu: &mu, w: &transcript}), nil
+			}
+		}
+
+		l := newPipeListener()
+
+		go func() {
+			_ = srv.Serve(l)
+		}()
+
+		return srv, l
+	}
+
+	srv, l := newServer(true)
+
+	conn, err := l.Dial()
+	require.Nil(t, err)
+
+	framer := NewFramer(conn)
+
+	_, err = framer.ReadMessage()
+	require.Nil(t, err)
+
+	for _, request := range [][]byte{login, transfer} {
+		require.Nil(t, framer.WriteMessage(request))
+
+		response, err := framer.ReadMessage()
+		require.Nil(t, err)
+
+		decoded, err := DecodeResponse(response)
+		require.Nil(t, err)
+		require.Equal(t, EppOk, decoded.Code())
+	}
+
+	require.Nil(t, conn.Close())
+	require.Nil(t, srv.Shutdown(context.Background()))
+
+	mu.Lock()
+	entries, err := ReadTranscript(bytes.NewReader(transcript.Bytes()))
+	mu.Unlock()
+
+	require.Nil(t, err)
+	require.Len(t, entries, 5)
+	assert.NotContains(t, string(entries[1].Data), "secret")
+	assert.NotContains(t, string(entries[3].Data), "auth-secret")
+
+	replay := func(replayer *Replayer) []ReplayDifference {
+		srv, l := newServer(false)
+		defer srv.Stop()
+
+		conn, err := l.Dial()
+		require.Nil(t, err)
+
+		defer conn.Close()
+
+		differences, err := replayer.Replay(conn, entries)
+		require.Nil(t, err)
+
+		return differences
+	}
+
+	// The redacted passwords are rejected by the server.
+	assert.Len(t, replay(&Replayer{}), 2)
+
+	credentials := ReplayCredentials{
+		Password: "secret",
+		AuthInfo: "auth-secret",
+	}
+
+	assert.Empty(t, replay(&Replayer{Rewrite: credentials.Rewrite}))
+}
+
+func TestReplayCredentials(t *testing.T) {
+	credentials := ReplayCredentials{
+		ClientID:    "registrar-2",
+		Password:    "secret",
+		NewPassword: "new <secret>",
+		AuthInfo:    "auth-secret",
+	}
+
+	assert.Equal(
+		t,
+		`<login><clID>registrar-2</clID><pw>secret</pw><newPW>new &lt;secret&gt;</newPW></login>`,
+		string(credentials.Rewrite([]byte(`<login><clID>registrar-1</clID><pw>REDACTED</pw><newPW>REDACTED</newPW></login>`))),
+	)
+
+	assert.Equal(
+		t,
+		`<contact:authInfo><contact:pw>auth-secret</contact:pw></contact:authInfo>`,
+		string(credentials.Rewrite([]byte(`<contact:authInfo>REDACTED</contact:authInfo>`))),
+	)
+
+	// Values which aren't redacted are kept.
+	assert.Equal(t, `<pw>other</pw>`, string(credentials.Rewrite([]byte(`<pw>other</pw>`))))
+	assert.Equal(t, `<pw>REDACTED</pw>`, string(ReplayCredentials{}.Rewrite([]byte(`<pw>REDACTED</pw>`))))
+}
@@ -70,7 +70,7 @@ type ContactTransferType struct {
 
 // ContactUpdateType represents a contact update command.
 type ContactUpdateType struct {
-	Update ContactUpdate `xml:"urn:ietf:params:xml:ns:contact-1.0 command>transfer>transfer"`
+	Update ContactUpdate `xml:"urn:ietf:params:xml:ns:contact-1.0 command>update>update"`
 }
 
 // ContactCheckDataType represents contact check data.
@@ -137,7 +137,7 @@ type ContactUpdate struct {
 	Name   string            `xml:"id"`
 	Add    *ContactAddRemove `xml:"add,omitempty"`
 	Remove *ContactAddRemove `xml:"rem,omitempty"`
-	Change *ContactChange    `xml:"chg>name,omitempty"`
+	Change *ContactChange    `xml:"chg,omitempty"`
 }
 
 // ContactCheckData represents the data returned from a contact check command.
@@ -216,7 +216,7 @@ type ContactChange struct {
 type ContactStatus struct {
 	Status            string            `xml:",chardata"`
 	ContactStatusType ContactStatusType `xml:"s,attr"`
-	Language          string            `xml:"lang,attr"`
+	Language          string            `xml:"lang,attr,omitempty"`
 }
 
 // PostalInfo represents potal information for a contact.
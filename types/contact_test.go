@@ -0,0 +1,36 @@
+package types_test
+
+import (
+	"testing"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContactUpdateType(t *testing.T) {
+	data := []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <update>
+      <contact:update xmlns:contact="urn:ietf:params:xml:ns:contact-1.0">
+        <contact:id>sh8013</contact:id>
+        <contact:chg>
+          <contact:voice>+1.7034444444</contact:voice>
+          <contact:email>jdoe@example.com</contact:email>
+        </contact:chg>
+      </contact:update>
+    </update>
+  </command>
+</epp>`)
+
+	// The update was decoded from a transfer element and the changes from
+	// a name element in chg.
+	update := types.ContactUpdateType{}
+	require.Nil(t, epp.Decode(data, &update))
+
+	assert.Equal(t, "sh8013", update.Update.Name)
+	require.NotNil(t, update.Update.Change)
+	assert.Equal(t, "+1.7034444444", update.Update.Change.Voice.Value)
+	assert.Equal(t, "jdoe@example.com", update.Update.Change.Email)
+}
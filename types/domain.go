@@ -62,7 +62,7 @@ type DomainCreateType struct {
 
 // DomainDeleteType implements extension for delete from domain-1.0.
 type DomainDeleteType struct {
-	Delete DomainDelete `xml:"urn:ietf:params:xml:ns:domain-1.0 command>create>delete"`
+	Delete DomainDelete `xml:"urn:ietf:params:xml:ns:domain-1.0 command>delete>delete"`
 }
 
 // DomainInfoType implements extension for info from domain-1.0.
@@ -157,17 +157,17 @@ type DomainRenew struct {
 
 // DomainTransfer represents a domain transfer command.
 type DomainTransfer struct {
-	Name     string    `xml:"command>transfer>transfer>name"`
-	Period   Period    `xml:"command>transfer>transfer>period,omitempty"`
-	Authinfo *AuthInfo `xml:"command>transfer>transfer>authInfo,omitempty"`
+	Name     string    `xml:"name"`
+	Period   Period    `xml:"period,omitempty"`
+	Authinfo *AuthInfo `xml:"authInfo,omitempty"`
 }
 
 // DomainUpdate represents a domain update command.
 type DomainUpdate struct {
-	Name   string           `xml:"command>update>update>name"`
-	Add    *DomainAddRemove `xml:"command>update>update>add,omitempty"`
-	Remove *DomainAddRemove `xml:"command>update>update>rem,omitempty"`
-	Change *DomainChange    `xml:"command>update>update>chg,omitempty"`
+	Name   string           `xml:"name"`
+	Add    *DomainAddRemove `xml:"add,omitempty"`
+	Remove *DomainAddRemove `xml:"rem,omitempty"`
+	Change *DomainChange    `xml:"chg,omitempty"`
 }
 
 // DomainAddRemove ...
@@ -0,0 +1,84 @@
+package types_test
+
+import (
+	"testing"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDomainDeleteType(t *testing.T) {
+	data := []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <delete>
+      <domain:delete xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.com</domain:name>
+      </domain:delete>
+    </delete>
+  </command>
+</epp>`)
+
+	// The delete was decoded from a create element.
+	request := types.DomainDeleteType{}
+	require.Nil(t, epp.Decode(data, &request))
+
+	assert.Equal(t, "example.com", request.Delete.Name)
+}
+
+func TestDomainTransferType(t *testing.T) {
+	data := []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <transfer op="request">
+      <domain:transfer xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.com</domain:name>
+        <domain:period unit="y">1</domain:period>
+        <domain:authInfo>
+          <domain:pw>2fooBAR</domain:pw>
+        </domain:authInfo>
+      </domain:transfer>
+    </transfer>
+  </command>
+</epp>`)
+
+	// The fields of the transfer repeated the path to the transfer element
+	// so nothing was decoded.
+	request := types.DomainTransferType{}
+	require.Nil(t, epp.Decode(data, &request))
+
+	assert.Equal(t, "example.com", request.Transfer.Name)
+	assert.Equal(t, types.Period{Value: 1, Unit: "y"}, request.Transfer.Period)
+	require.NotNil(t, request.Transfer.Authinfo)
+	assert.Equal(t, "2fooBAR", request.Transfer.Authinfo.Password)
+}
+
+func TestDomainUpdateType(t *testing.T) {
+	data := []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <update>
+      <domain:update xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.com</domain:name>
+        <domain:add>
+          <domain:status s="clientHold"/>
+        </domain:add>
+        <domain:chg>
+          <domain:registrant>sh8013</domain:registrant>
+        </domain:chg>
+      </domain:update>
+    </update>
+  </command>
+</epp>`)
+
+	// The fields of the update repeated the path to the update element so
+	// nothing was decoded.
+	request := types.DomainUpdateType{}
+	require.Nil(t, epp.Decode(data, &request))
+
+	assert.Equal(t, "example.com", request.Update.Name)
+	require.NotNil(t, request.Update.Add)
+	require.Len(t, request.Update.Add.Status, 1)
+	assert.Equal(t, types.DomainStatusClientHold, request.Update.Add.Status[0].DomainStatusType)
+	require.NotNil(t, request.Update.Change)
+	assert.Equal(t, "sh8013", request.Update.Change.Registrant)
+}
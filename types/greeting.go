@@ -17,15 +17,15 @@ type Greeting struct {
 
 // ServiceMenu represents tags that may occur in the greeting service tag.
 type ServiceMenu struct {
-	Version           []string           `xml:"version"`
-	Language          []string           `xml:"lang"`
-	ObjectURI         []string           `xml:"objURI"`
-	ServiceExtentions []ServiceExtension `xml:"svcExtention"`
+	Version          []string          `xml:"version"`
+	Language         []string          `xml:"lang"`
+	ObjectURI        []string          `xml:"objURI"`
+	ServiceExtension *ServiceExtension `xml:"svcExtension,omitempty"`
 }
 
-// ServiceExtension represent extension to the service.
+// ServiceExtension represent extensions to the service.
 type ServiceExtension struct {
-	ExtensionURI string `xml:"extURI"`
+	ExtensionURI []string `xml:"extURI"`
 }
 
 // DCP (data collection policy) represents the policy declared in the greeting
@@ -48,7 +48,7 @@ type DCPAccess struct {
 
 // DCPExpiry represent DCP expiry.
 type DCPExpiry struct {
-	Absolute *time.Time `xml:"absoulte"`
+	Absolute *time.Time `xml:"absolute,omitempty"`
 	Relative string     `xml:"relative,omitempty"` // Format "PnYnMnDTnHnMnS"
 }
 
@@ -89,3 +89,41 @@ type DCPRetention struct {
 	None       *EmptyTag `xml:"none"`
 	Stated     *EmptyTag `xml:"stated"`
 }
+
+// Versions returns the protocol versions supported by the server.
+func (g *Greeting) Versions() []string {
+	return g.ServiceMenu.Version
+}
+
+// Languages returns the languages supported by the server.
+func (g *Greeting) Languages() []string {
+	return g.ServiceMenu.Language
+}
+
+// SupportsObject returns true if the server announced the object namespace in
+// the greeting.
+func (g *Greeting) SupportsObject(ns string) bool {
+	for _, uri := range g.ServiceMenu.ObjectURI {
+		if uri == ns {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SupportsExtension returns true if the server announced the extension
+// namespace in the greeting.
+func (g *Greeting) SupportsExtension(ns string) bool {
+	if g.ServiceMenu.ServiceExtension == nil {
+		return false
+	}
+
+	for _, uri := range g.ServiceMenu.ServiceExtension.ExtensionURI {
+		if uri == ns {
+			return true
+		}
+	}
+
+	return false
+}
@@ -130,7 +130,7 @@ type HostAddRemove struct {
 
 // HostAddress represents an IP address beloning to a host.
 type HostAddress struct {
-	Address string `xml:",chardata,omitempty"`
+	Address string `xml:",chardata"`
 	IP      IPType `xml:"ip,attr"`
 }
 
@@ -138,5 +138,5 @@ type HostAddress struct {
 type HostStatus struct {
 	Status         string         `xml:",chardata"`
 	HostStatusType HostStatusType `xml:"s,attr"`
-	Language       string         `xml:"lang,attr"`
+	Language       string         `xml:"lang,attr,omitempty"`
 }
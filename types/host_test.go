@@ -0,0 +1,31 @@
+package types_test
+
+import (
+	"encoding/xml"
+	"testing"
+
+	epp "github.com/bombsimon/epp-go"
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostAddress(t *testing.T) {
+	// The omitempty option isn't allowed for chardata and made marshalling
+	// every type with a host address fail.
+	data, err := xml.Marshal(types.HostCreateType{
+		Create: types.HostCreate{
+			Name:    "ns1.example.com",
+			Address: types.HostAddress{Address: "192.0.2.2", IP: types.HostIPv4},
+		},
+	})
+	require.Nil(t, err)
+
+	assert.Contains(t, string(data), `<addr ip="v4">192.0.2.2</addr>`)
+
+	request := types.HostCreateType{}
+	require.Nil(t, epp.Decode(data, &request))
+
+	assert.Equal(t, "192.0.2.2", request.Create.Address.Address)
+	assert.Equal(t, types.HostIPv4, request.Create.Address.IP)
+}
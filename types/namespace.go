@@ -0,0 +1,91 @@
+package types
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DefaultNamespaceRegistry is the registry used when encoding documents,
+// routing messages in the mux and resolving aliases with AliasToNameSpace.
+// Register any registry specific extensions here to get the preferred alias
+// for them.
+var DefaultNamespaceRegistry = NewNamespaceRegistry()
+
+// NamespaceRegistry holds known namespaces and their preferred alias. The
+// registry is safe to use from multiple goroutines.
+type NamespaceRegistry struct {
+	mu         sync.RWMutex
+	aliases    map[string]string
+	namespaces map[string]string
+}
+
+// NewNamespaceRegistry will create a new registry with all the namespaces
+// implemented in this package registered.
+func NewNamespaceRegistry() *NamespaceRegistry {
+	r := &NamespaceRegistry{
+		aliases:    map[string]string{},
+		namespaces: map[string]string{},
+	}
+
+	for ns, alias := range map[string]string{
+		NameSpaceDomain:   "domain",
+		NameSpaceHost:     "host",
+		NameSpaceContact:  "contact",
+		NameSpaceDNSSEC10: "sed",
+		NameSpaceDNSSEC11: "sec",
+		NameSpaceRGP10:    "rgp",
+		NameSpaceIIS12:    "iis",
+	} {
+		r.aliases[ns] = alias
+		r.namespaces[alias] = ns
+	}
+
+	return r
+}
+
+// Register will add the namespace with the preferred alias. If the namespace is
+// already registered the alias will be replaced. An error is returned if the
+// alias is already used by another namespace.
+func (r *NamespaceRegistry) Register(ns, alias string) error {
+	if ns == "" || alias == "" {
+		return fmt.Errorf("both namespace and alias must be set")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existingNS, ok := r.namespaces[alias]; ok && existingNS != ns {
+		return fmt.Errorf("alias '%s' is already used for namespace '%s'", alias, existingNS)
+	}
+
+	if oldAlias, ok := r.aliases[ns]; ok {
+		delete(r.namespaces, oldAlias)
+	}
+
+	r.aliases[ns] = alias
+	r.namespaces[alias] = ns
+
+	return nil
+}
+
+// Alias returns the preferred alias for the namespace and true if the
+// namespace is registered.
+func (r *NamespaceRegistry) Alias(ns string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	alias, ok := r.aliases[ns]
+
+	return alias, ok
+}
+
+// Namespace returns the namespace for the alias and true if the alias is
+// registered.
+func (r *NamespaceRegistry) Namespace(alias string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ns, ok := r.namespaces[alias]
+
+	return ns, ok
+}
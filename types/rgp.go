@@ -0,0 +1,82 @@
+package types
+
+import "time"
+
+// Name space constant for the extension.
+const (
+	NameSpaceRGP10 = "urn:ietf:params:xml:ns:rgp-1.0"
+)
+
+// RGPStatusType represents available registry grace period status values.
+type RGPStatusType string
+
+// Constants representing the string value of registry grace period status
+// value types.
+const (
+	RGPStatusAddPeriod        RGPStatusType = "addPeriod"
+	RGPStatusAutoRenewPeriod  RGPStatusType = "autoRenewPeriod"
+	RGPStatusRenewPeriod      RGPStatusType = "renewPeriod"
+	RGPStatusTransferPeriod   RGPStatusType = "transferPeriod"
+	RGPStatusPendingDelete    RGPStatusType = "pendingDelete"
+	RGPStatusPendingRestore   RGPStatusType = "pendingRestore"
+	RGPStatusRedemptionPeriod RGPStatusType = "redemptionPeriod"
+)
+
+// RGPOperationType represents the operation for a restore command.
+type RGPOperationType string
+
+// Constants representing available restore operations.
+const (
+	RGPOperationRequest RGPOperationType = "request"
+	RGPOperationReport  RGPOperationType = "report"
+)
+
+// RGPExtensionUpdateType implements extension for update from rgp-1.0.
+type RGPExtensionUpdateType struct {
+	Update RGPExtensionUpdate `xml:"urn:ietf:params:xml:ns:rgp-1.0 command>extension>update"`
+}
+
+// RGPExtensionInfoDataType implements extension for info data from rgp-1.0.
+type RGPExtensionInfoDataType struct {
+	InfoData RGPExtensionData `xml:"urn:ietf:params:xml:ns:rgp-1.0 infData"`
+}
+
+// RGPExtensionUpdateDataType implements extension for update data from
+// rgp-1.0.
+type RGPExtensionUpdateDataType struct {
+	UpdateData RGPExtensionData `xml:"urn:ietf:params:xml:ns:rgp-1.0 upData"`
+}
+
+// RGPExtensionUpdate represents the extension data for update.
+type RGPExtensionUpdate struct {
+	Restore RGPRestore `xml:"restore"`
+}
+
+// RGPRestore represents a restore request or report.
+type RGPRestore struct {
+	Operation RGPOperationType `xml:"op,attr"`
+	Report    *RGPReport       `xml:"report,omitempty"`
+}
+
+// RGPReport represents the restore report sent after a restore request.
+type RGPReport struct {
+	PreData       string    `xml:"preData"`
+	PostData      string    `xml:"postData"`
+	DeleteTime    time.Time `xml:"delTime"`
+	RestoreTime   time.Time `xml:"resTime"`
+	RestoreReason string    `xml:"resReason"`
+	Statement     []string  `xml:"statement"`
+	Other         string    `xml:"other,omitempty"`
+}
+
+// RGPExtensionData represents the response data for info and update.
+type RGPExtensionData struct {
+	Status []RGPStatus `xml:"rgpStatus"`
+}
+
+// RGPStatus represents a registry grace period status for a domain.
+type RGPStatus struct {
+	Status        string        `xml:",chardata"`
+	RGPStatusType RGPStatusType `xml:"s,attr"`
+	Language      string        `xml:"lang,attr,omitempty"`
+}
@@ -18,18 +18,12 @@ const (
 	NameSpaceHost    = "urn:ietf:params:xml:ns:host-1.0"
 )
 
-// AliasToNameSpace space will return the full name sapce for a name space alias.
+// AliasToNameSpace space will return the full name sapce for a name space alias
+// registered in DefaultNamespaceRegistry.
 func AliasToNameSpace(alias string) string {
-	switch alias {
-	case "contact":
-		return NameSpaceContact
-	case "domain":
-		return NameSpaceDomain
-	case "host":
-		return NameSpaceHost
-	}
-
-	return ""
+	ns, _ := DefaultNamespaceRegistry.Namespace(alias)
+
+	return ns
 }
 
 // Empty returns a non-nil value to use as an empty tag where the tag is defined
@@ -0,0 +1,461 @@
+package types
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Limits for domain and host names as defined in RFC 1035.
+const (
+	maxLabelLength = 63
+	maxNameLength  = 253
+)
+
+// maxE164Digits is the maximum number of digits, including the country code,
+// in an E.164 number.
+const maxE164Digits = 15
+
+// digestLengths holds the length in hex characters of the digest for each DS
+// digest type in the IANA registry.
+var digestLengths = map[uint]int{
+	1: 40, // SHA-1
+	2: 64, // SHA-256
+	3: 64, // GOST R 34.11-94
+	4: 96, // SHA-384
+}
+
+var e164Pattern = regexp.MustCompile(`^\+([0-9]{1,3})\.([0-9]{1,14})$`)
+
+// ValueError represents a value in a request that is valid according to the
+// XSD schema but isn't a valid value for the element, e.g. a domain name with
+// a label longer than 63 characters.
+type ValueError struct {
+	// Element is the namespace and local name of the element holding the
+	// value.
+	Element xml.Name
+
+	// Value is the offending value.
+	Value string
+
+	// Reason is a human readable description of why the value isn't valid.
+	Reason string
+
+	// OutOfRange is true if the value is well formed but outside of the range
+	// accepted, e.g. too long. Otherwise the value has an invalid syntax.
+	OutOfRange bool
+}
+
+// Error implements the error interface.
+func (e *ValueError) Error() string {
+	return fmt.Sprintf("%s: %q: %s", e.Element.Local, e.Value, e.Reason)
+}
+
+// ValueErrors is the error returned by Validate holding all invalid values
+// found in a request.
+type ValueErrors []*ValueError
+
+// Error implements the error interface.
+func (e ValueErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// valueChecker collects value errors while validating a request.
+type valueChecker struct {
+	errors ValueErrors
+}
+
+func (c *valueChecker) syntax(ns, local, value, reason string) {
+	c.errors = append(c.errors, &ValueError{
+		Element: xml.Name{Space: ns, Local: local},
+		Value:   value,
+		Reason:  reason,
+	})
+}
+
+func (c *valueChecker) outOfRange(ns, local, value, reason string) {
+	c.errors = append(c.errors, &ValueError{
+		Element:    xml.Name{Space: ns, Local: local},
+		Value:      value,
+		Reason:     reason,
+		OutOfRange: true,
+	})
+}
+
+// err returns the collected errors or nil if no errors was found.
+func (c *valueChecker) err() error {
+	if len(c.errors) == 0 {
+		return nil
+	}
+
+	return c.errors
+}
+
+// domainName validates a domain or host name. Each label must follow the LDH
+// rule and labels starting with xn-- must be valid IDNA A-labels.
+func (c *valueChecker) domainName(ns, local, name string) {
+	if name == "" {
+		return
+	}
+
+	trimmed := strings.TrimSuffix(name, ".")
+
+	if len(trimmed) > maxNameLength {
+		c.outOfRange(ns, local, name, fmt.Sprintf("name is longer than %d characters", maxNameLength))
+		return
+	}
+
+	for _, label := range strings.Split(trimmed, ".") {
+		if label == "" {
+			c.syntax(ns, local, name, "name contains an empty label")
+			return
+		}
+
+		if len(label) > maxLabelLength {
+			c.outOfRange(ns, local, name, fmt.Sprintf("label %q is longer than %d characters", label, maxLabelLength))
+			return
+		}
+
+		if reason := checkLabel(label); reason != "" {
+			c.syntax(ns, local, name, reason)
+			return
+		}
+	}
+}
+
+// checkLabel returns the reason a label isn't valid or an empty string if the
+// label is valid.
+func checkLabel(label string) string {
+	for _, r := range label {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-':
+		default:
+			return fmt.Sprintf("label %q contains characters other than letters, digits and hyphen", label)
+		}
+	}
+
+	if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+		return fmt.Sprintf("label %q starts or ends with a hyphen", label)
+	}
+
+	if len(label) < 4 || label[2:4] != "--" {
+		return ""
+	}
+
+	if !strings.EqualFold(label[:2], "xn") {
+		return fmt.Sprintf("label %q has hyphens in the third and fourth position but isn't an A-label", label)
+	}
+
+	unicode, err := idna.Registration.ToUnicode(label)
+	if err != nil || unicode == strings.ToLower(label) {
+		return fmt.Sprintf("label %q isn't a valid IDNA A-label", label)
+	}
+
+	return ""
+}
+
+// e164 validates a phone or fax number. The number must have a country code
+// not starting with zero and may have at most 15 digits in total.
+func (c *valueChecker) e164(ns, local string, number E164Type) {
+	if number.Value == "" {
+		return
+	}
+
+	matches := e164Pattern.FindStringSubmatch(number.Value)
+	if matches == nil {
+		c.syntax(ns, local, number.Value, "number isn't in the format +CC.NUMBER")
+		return
+	}
+
+	if strings.HasPrefix(matches[1], "0") {
+		c.syntax(ns, local, number.Value, "country code can't start with 0")
+		return
+	}
+
+	if len(matches[1])+len(matches[2]) > maxE164Digits {
+		c.outOfRange(ns, local, number.Value, fmt.Sprintf("number has more than %d digits", maxE164Digits))
+		return
+	}
+
+	for _, r := range number.X {
+		if r < '0' || r > '9' {
+			c.syntax(ns, local, number.Value, fmt.Sprintf("extension %q contains characters other than digits", number.X))
+			return
+		}
+	}
+}
+
+// postalInfo validates the country code for each address.
+func (c *valueChecker) postalInfo(ns string, postalInfo []PostalInfo) {
+	for _, pi := range postalInfo {
+		cc := pi.Address.CountryCode
+		if cc == "" {
+			continue
+		}
+
+		if _, ok := countryCodes[cc]; !ok {
+			c.syntax(ns, "cc", cc, "not an ISO 3166-1 alpha-2 country code")
+		}
+	}
+}
+
+// hostAddress validates that the address is an IP address of the version in
+// the ip attribute. Version 4 is used if the attribute isn't set.
+func (c *valueChecker) hostAddress(ns string, address HostAddress) {
+	if address.Address == "" {
+		return
+	}
+
+	ip := net.ParseIP(address.Address)
+	if ip == nil {
+		c.syntax(ns, "addr", address.Address, "not an IP address")
+		return
+	}
+
+	isV4 := ip.To4() != nil && !strings.Contains(address.Address, ":")
+
+	switch address.IP {
+	case HostIPv6:
+		if isV4 {
+			c.syntax(ns, "addr", address.Address, "IPv4 address with ip set to v6")
+		}
+	default:
+		if !isV4 {
+			c.syntax(ns, "addr", address.Address, "IPv6 address with ip set to v4")
+		}
+	}
+}
+
+// nameServer validates host objects and host attributes for a domain.
+func (c *valueChecker) nameServer(ns NameServer) {
+	for _, host := range ns.HostObject {
+		c.domainName(NameSpaceDomain, "hostObj", host)
+	}
+
+	for _, host := range ns.HostAttribute {
+		c.domainName(NameSpaceDomain, "hostName", host.HostName)
+
+		for _, address := range host.HostAddress {
+			c.hostAddress(NameSpaceDomain, address)
+		}
+	}
+}
+
+// dsData validates that the digest is hex encoded and that the length of the
+// digest matches the digest type.
+func (c *valueChecker) dsData(data []DNSSEC) {
+	for _, ds := range data {
+		length, ok := digestLengths[ds.DigestType]
+		if !ok {
+			c.outOfRange(NameSpaceDNSSEC11, "digestType", fmt.Sprintf("%d", ds.DigestType), "unknown digest type")
+			continue
+		}
+
+		if !isHex(ds.Digest) {
+			c.syntax(NameSpaceDNSSEC11, "digest", ds.Digest, "digest contains characters other than hex digits")
+			continue
+		}
+
+		if len(ds.Digest) != length {
+			c.syntax(
+				NameSpaceDNSSEC11, "digest", ds.Digest,
+				fmt.Sprintf("digest for digest type %d must be %d hex characters, got %d", ds.DigestType, length, len(ds.Digest)),
+			)
+		}
+	}
+}
+
+// isHex returns true if the string only contains hex digits.
+func isHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') && (r < 'A' || r > 'F') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Validate validates the domain names.
+func (d *DomainCheckType) Validate() error {
+	c := &valueChecker{}
+
+	for _, name := range d.Check.Names {
+		c.domainName(NameSpaceDomain, "name", name)
+	}
+
+	return c.err()
+}
+
+// Validate validates the domain name and the name servers.
+func (d *DomainCreateType) Validate() error {
+	c := &valueChecker{}
+
+	c.domainName(NameSpaceDomain, "name", d.Create.Name)
+	c.nameServer(d.Create.NameServer)
+
+	return c.err()
+}
+
+// Validate validates the domain name.
+func (d *DomainDeleteType) Validate() error {
+	c := &valueChecker{}
+
+	c.domainName(NameSpaceDomain, "name", d.Delete.Name)
+
+	return c.err()
+}
+
+// Validate validates the domain name.
+func (d *DomainInfoType) Validate() error {
+	c := &valueChecker{}
+
+	c.domainName(NameSpaceDomain, "name", d.Info.Name.Name)
+
+	return c.err()
+}
+
+// Validate validates the domain name.
+func (d *DomainRenewType) Validate() error {
+	c := &valueChecker{}
+
+	c.domainName(NameSpaceDomain, "name", d.Renew.Name)
+
+	return c.err()
+}
+
+// Validate validates the domain name.
+func (d *DomainTransferType) Validate() error {
+	c := &valueChecker{}
+
+	c.domainName(NameSpaceDomain, "name", d.Transfer.Name)
+
+	return c.err()
+}
+
+// Validate validates the domain name and the name servers to add or remove.
+func (d *DomainUpdateType) Validate() error {
+	c := &valueChecker{}
+
+	c.domainName(NameSpaceDomain, "name", d.Update.Name)
+
+	for _, addRemove := range []*DomainAddRemove{d.Update.Add, d.Update.Remove} {
+		if addRemove != nil {
+			c.nameServer(addRemove.NameServer)
+		}
+	}
+
+	return c.err()
+}
+
+// Validate validates the host names.
+func (h *HostCheckType) Validate() error {
+	c := &valueChecker{}
+
+	for _, name := range h.Check.Names {
+		c.domainName(NameSpaceHost, "name", name)
+	}
+
+	return c.err()
+}
+
+// Validate validates the host name and the address.
+func (h *HostCreateType) Validate() error {
+	c := &valueChecker{}
+
+	c.domainName(NameSpaceHost, "name", h.Create.Name)
+	c.hostAddress(NameSpaceHost, h.Create.Address)
+
+	return c.err()
+}
+
+// Validate validates the host name.
+func (h *HostDeleteType) Validate() error {
+	c := &valueChecker{}
+
+	c.domainName(NameSpaceHost, "name", h.Delete.Name)
+
+	return c.err()
+}
+
+// Validate validates the host name.
+func (h *HostInfoType) Validate() error {
+	c := &valueChecker{}
+
+	c.domainName(NameSpaceHost, "name", h.Info.Name)
+
+	return c.err()
+}
+
+// Validate validates the host names and the addresses to add or remove.
+func (h *HostUpdateType) Validate() error {
+	c := &valueChecker{}
+
+	c.domainName(NameSpaceHost, "name", h.Update.Name)
+	c.domainName(NameSpaceHost, "name", h.Update.Change)
+
+	for _, addRemove := range []*HostAddRemove{h.Update.Add, h.Update.Remove} {
+		if addRemove == nil {
+			continue
+		}
+
+		for _, address := range addRemove.Address {
+			c.hostAddress(NameSpaceHost, address)
+		}
+	}
+
+	return c.err()
+}
+
+// Validate validates the country codes and the phone and fax numbers.
+func (co *ContactCreateType) Validate() error {
+	c := &valueChecker{}
+
+	c.postalInfo(NameSpaceContact, co.Create.PostalInfo)
+	c.e164(NameSpaceContact, "voice", co.Create.Voice)
+	c.e164(NameSpaceContact, "fax", co.Create.Fax)
+
+	return c.err()
+}
+
+// Validate validates the changed country codes and phone and fax numbers.
+func (co *ContactUpdateType) Validate() error {
+	c := &valueChecker{}
+
+	if change := co.Update.Change; change != nil {
+		c.postalInfo(NameSpaceContact, change.PostalInfo)
+		c.e164(NameSpaceContact, "voice", change.Voice)
+		c.e164(NameSpaceContact, "fax", change.Fax)
+	}
+
+	return c.err()
+}
+
+// Validate validates that the digests matches the digest types.
+func (d *DNSSECExtensionCreateType) Validate() error {
+	c := &valueChecker{}
+
+	c.dsData(d.Create.DNSSECData)
+
+	return c.err()
+}
+
+// Validate validates that the digests to add or remove matches the digest
+// types.
+func (d *DNSSECExtensionUpdateType) Validate() error {
+	c := &valueChecker{}
+
+	c.dsData(d.Update.Remove.DNSSECdata)
+	c.dsData(d.Update.Add.DNSSECData)
+
+	return c.err()
+}
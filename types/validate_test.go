@@ -0,0 +1,197 @@
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requireValueError requires that the checker found exactly one error with
+// the reason containing the text, or no error if the text is empty.
+func requireValueError(t *testing.T, c *valueChecker, reason string, outOfRange bool) {
+	t.Helper()
+
+	if reason == "" {
+		assert.Empty(t, c.errors)
+		return
+	}
+
+	require.Len(t, c.errors, 1)
+	assert.Contains(t, c.errors[0].Reason, reason)
+	assert.Equal(t, outOfRange, c.errors[0].OutOfRange)
+}
+
+func TestValueChecker_domainName(t *testing.T) {
+	cases := []struct {
+		description string
+		name        string
+		reason      string
+		outOfRange  bool
+	}{
+		{"empty", "", "", false},
+		{"valid", "example.se", "", false},
+		{"trailing dot", "example.se.", "", false},
+		{"upper case", "EXAMPLE.SE", "", false},
+		{"digits and hyphen", "ex-4mple.se", "", false},
+		{"A-label", "xn--rksmrgs-5wao1o.se", "", false},
+		{"empty label", "example..se", "empty label", false},
+		{"underscore", "ex_ample.se", "characters other than", false},
+		{"leading hyphen", "-example.se", "starts or ends with a hyphen", false},
+		{"trailing hyphen", "example-.se", "starts or ends with a hyphen", false},
+		{"hyphens not A-label", "ab--example.se", "isn't an A-label", false},
+		{"invalid A-label", "xn--example.se", "isn't a valid IDNA A-label", false},
+		{"long label", strings.Repeat("a", 64) + ".se", "longer than 63", true},
+		{"long name", strings.Repeat(strings.Repeat("a", 63)+".", 4) + "se", "longer than 253", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			c := &valueChecker{}
+			c.domainName(NameSpaceDomain, "name", tc.name)
+
+			requireValueError(t, c, tc.reason, tc.outOfRange)
+		})
+	}
+}
+
+func TestValueChecker_e164(t *testing.T) {
+	cases := []struct {
+		description string
+		number      E164Type
+		reason      string
+		outOfRange  bool
+	}{
+		{"empty", E164Type{}, "", false},
+		{"valid", E164Type{Value: "+46.123456789"}, "", false},
+		{"extension", E164Type{Value: "+46.123456789", X: "1234"}, "", false},
+		{"no plus", E164Type{Value: "46.123456789"}, "format", false},
+		{"no dot", E164Type{Value: "+46123456789"}, "format", false},
+		{"letters", E164Type{Value: "+46.12345abc"}, "format", false},
+		{"zero country code", E164Type{Value: "+046.12345"}, "can't start with 0", false},
+		{"too many digits", E164Type{Value: "+46.12345678901234"}, "more than 15 digits", true},
+		{"invalid extension", E164Type{Value: "+46.123456789", X: "12a"}, "extension", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			c := &valueChecker{}
+			c.e164(NameSpaceContact, "voice", tc.number)
+
+			requireValueError(t, c, tc.reason, tc.outOfRange)
+		})
+	}
+}
+
+func TestValueChecker_postalInfo(t *testing.T) {
+	cases := []struct {
+		description string
+		countryCode string
+		reason      string
+	}{
+		{"empty", "", ""},
+		{"valid", "SE", ""},
+		{"not assigned", "EN", "ISO 3166-1"},
+		{"lower case", "se", "ISO 3166-1"},
+		{"alpha-3", "SWE", "ISO 3166-1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			c := &valueChecker{}
+			c.postalInfo(NameSpaceContact, []PostalInfo{
+				{Address: Address{CountryCode: tc.countryCode}},
+			})
+
+			requireValueError(t, c, tc.reason, false)
+		})
+	}
+}
+
+func TestValueChecker_hostAddress(t *testing.T) {
+	cases := []struct {
+		description string
+		address     HostAddress
+		reason      string
+	}{
+		{"empty", HostAddress{}, ""},
+		{"IPv4", HostAddress{Address: "192.0.2.1", IP: HostIPv4}, ""},
+		{"IPv4 without version", HostAddress{Address: "192.0.2.1"}, ""},
+		{"IPv6", HostAddress{Address: "2001:db8::1", IP: HostIPv6}, ""},
+		{"not an address", HostAddress{Address: "ns1.example.se"}, "not an IP address"},
+		{"IPv4 as v6", HostAddress{Address: "192.0.2.1", IP: HostIPv6}, "ip set to v6"},
+		{"IPv6 as v4", HostAddress{Address: "2001:db8::1", IP: HostIPv4}, "ip set to v4"},
+		{"IPv6 without version", HostAddress{Address: "2001:db8::1"}, "ip set to v4"},
+		{"IPv4-mapped IPv6 as v4", HostAddress{Address: "::ffff:192.0.2.1", IP: HostIPv4}, "ip set to v4"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			c := &valueChecker{}
+			c.hostAddress(NameSpaceHost, tc.address)
+
+			requireValueError(t, c, tc.reason, false)
+		})
+	}
+}
+
+func TestValueChecker_dsData(t *testing.T) {
+	sha1 := strings.Repeat("a", 40)
+	sha256 := strings.Repeat("B", 64)
+
+	cases := []struct {
+		description string
+		ds          DNSSEC
+		reason      string
+		outOfRange  bool
+	}{
+		{"SHA-1", DNSSEC{DigestType: 1, Digest: sha1}, "", false},
+		{"SHA-256", DNSSEC{DigestType: 2, Digest: sha256}, "", false},
+		{"SHA-384", DNSSEC{DigestType: 4, Digest: strings.Repeat("0", 96)}, "", false},
+		{"unknown digest type", DNSSEC{DigestType: 5, Digest: sha256}, "unknown digest type", true},
+		{"too short", DNSSEC{DigestType: 2, Digest: sha1}, "must be 64 hex characters, got 40", false},
+		{"too long", DNSSEC{DigestType: 1, Digest: sha256}, "must be 40 hex characters, got 64", false},
+		{"not hex", DNSSEC{DigestType: 1, Digest: strings.Repeat("g", 40)}, "other than hex digits", false},
+		{"not hex and wrong length", DNSSEC{DigestType: 2, Digest: "xyz"}, "other than hex digits", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			c := &valueChecker{}
+			c.dsData([]DNSSEC{tc.ds})
+
+			requireValueError(t, c, tc.reason, tc.outOfRange)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	err := (&DomainCreateType{
+		Create: DomainCreate{
+			Name: "-example.se",
+			NameServer: NameServer{
+				HostObject: []string{"ns1.example.se", "ns_2.example.se"},
+			},
+		},
+	}).Validate()
+
+	var errs ValueErrors
+
+	require.Error(t, err)
+	require.IsType(t, errs, err)
+
+	errs = err.(ValueErrors)
+	require.Len(t, errs, 2)
+	assert.Equal(t, NameSpaceDomain, errs[0].Element.Space)
+	assert.Equal(t, "name", errs[0].Element.Local)
+	assert.Equal(t, "hostObj", errs[1].Element.Local)
+	assert.Equal(t, "ns_2.example.se", errs[1].Value)
+
+	assert.Nil(t, (&HostCreateType{
+		Create: HostCreate{
+			Name:    "ns1.example.se",
+			Address: HostAddress{Address: "192.0.2.1", IP: HostIPv4},
+		},
+	}).Validate())
+}
@@ -1,12 +1,12 @@
 package epp
 
 import (
-	"io/ioutil"
-	"os"
-	"path"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
 
-	"github.com/lestrrat-go/libxml2"
-	xsd "github.com/lestrrat-go/libxml2/xsd"
+	"aqwari.net/xml/xmltree"
 )
 
 // Validator represents the interface to validate XML.
@@ -15,66 +15,101 @@ type Validator interface {
 	Free()
 }
 
-// XMLValidator represents a validator holding the XSD schema to calidate against.
-type XMLValidator struct {
-	Schema *xsd.Schema
+// ValidationError represents a single error found when validating a document
+// towards the XSD schema.
+type ValidationError struct {
+	// Line is the line in the document where the element starts.
+	Line int
+
+	// Path is an XPath-like location to the element or attribute, using the
+	// qualified names from the document, e.g.
+	// /epp/command/create/domain:create/domain:name[2] or
+	// /epp/command/poll/@op.
+	Path string
+
+	// Element is the namespace and local name of the offending element.
+	Element xml.Name
+
+	// Message is the human readable reason from the validator.
+	Message string
 }
 
-// NewValidator creates a new validator.
-func NewValidator(rootXSD string) (*XMLValidator, error) {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return nil, err
-	}
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Path, e.Message)
+}
 
-	defer func() {
-		_ = os.Chdir(cwd)
-	}()
+// ValidationErrors is the error returned by XMLValidator when a document
+// doesn't validate, holding all errors found in the document.
+type ValidationErrors []*ValidationError
 
-	// Change path to the root directory so include works. This assumes that the
-	// path of included XSD files is always the same as the root XSD.
-	path, file := path.Split(rootXSD)
-	if err := os.Chdir(path); err != nil {
-		return nil, err
-	}
+// Error implements the error interface.
+func (e ValidationErrors) Error() string {
+	return "schema validation failed"
+}
 
-	f, err := os.Open(file)
-	if err != nil {
-		return nil, err
+// findByPath returns the element in the document at the XPath-like path used by
+// ValidationError. If the path points to an attribute the element owning the
+// attribute is returned.
+func findByPath(document *xmltree.Element, path string) *xmltree.Element {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+
+	if name, _ := parsePathSegment(segments[0]); qualifiedElementName(document) != name {
+		return nil
 	}
 
-	defer f.Close()
+	current := document
 
-	buf, err := ioutil.ReadAll(f)
-	if err != nil {
-		return nil, err
-	}
+	for _, segment := range segments[1:] {
+		if strings.HasPrefix(segment, "@") {
+			break
+		}
 
-	schema, err := xsd.Parse(buf)
-	if err != nil {
-		return nil, err
+		name, position := parsePathSegment(segment)
+
+		var found *xmltree.Element
+
+		for i := range current.Children {
+			if qualifiedElementName(&current.Children[i]) != name {
+				continue
+			}
+
+			position--
+
+			if position == 0 {
+				found = &current.Children[i]
+				break
+			}
+		}
+
+		if found == nil {
+			return nil
+		}
+
+		current = found
 	}
 
-	return &XMLValidator{
-		Schema: schema,
-	}, nil
+	return current
 }
 
-// Validate will validate XML towards the XSD schema.
-func (v *XMLValidator) Validate(xml []byte) error {
-	d, err := libxml2.Parse(xml)
-	if err != nil {
-		return err
+// parsePathSegment returns the qualified name and the position for a path
+// segment such as domain:name[2]. The position is 1 if not set.
+func parsePathSegment(segment string) (string, int) {
+	i := strings.Index(segment, "[")
+	if i < 0 || !strings.HasSuffix(segment, "]") {
+		return segment, 1
 	}
 
-	if err := v.Schema.Validate(d); err != nil {
-		return err
+	position, err := strconv.Atoi(segment[i+1 : len(segment)-1])
+	if err != nil {
+		return segment, 1
 	}
 
-	return nil
+	return segment[:i], position
 }
 
-// Free frees the XSD C struct.
-func (v *XMLValidator) Free() {
-	v.Schema.Free()
+// qualifiedElementName returns the name of the element with the prefix used in
+// the document.
+func qualifiedElementName(el *xmltree.Element) string {
+	return el.Prefix(el.Name)
 }
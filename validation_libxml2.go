@@ -0,0 +1,289 @@
+//go:build !purego
+// +build !purego
+
+package epp
+
+/*
+#cgo pkg-config: libxml-2.0
+#include <stdint.h>
+#include <libxml/parser.h>
+#include <libxml/xmlschemas.h>
+
+extern void eppValidationError(uintptr_t handle, xmlErrorPtr err);
+
+static void eppValidationErrorCallback(void *handle, xmlErrorPtr err) {
+	eppValidationError((uintptr_t)handle, err);
+}
+
+static int eppValidateDocument(uintptr_t schema, uintptr_t doc, uintptr_t handle) {
+	int ret;
+	xmlSchemaValidCtxtPtr ctxt = xmlSchemaNewValidCtxt((xmlSchemaPtr)schema);
+
+	if (ctxt == NULL) {
+		return -1;
+	}
+
+	xmlSchemaSetValidStructuredErrors(ctxt, eppValidationErrorCallback, (void *)handle);
+	ret = xmlSchemaValidateDoc(ctxt, (xmlDocPtr)doc);
+	xmlSchemaFreeValidCtxt(ctxt);
+
+	return ret;
+}
+*/
+import "C"
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/lestrrat-go/libxml2"
+	xsd "github.com/lestrrat-go/libxml2/xsd"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	// libxml2 must be initialized once before being used from multiple
+	// threads.
+	C.xmlInitParser()
+}
+
+// XMLValidator represents a validator holding the XSD schema to calidate against.
+// The validator is safe to use from multiple goroutines. Each validation uses
+// its own validation context towards the shared, read only, schema.
+type XMLValidator struct {
+	Schema *xsd.Schema
+
+	// mu ensures the schema isn't freed while validating.
+	mu    sync.RWMutex
+	freed bool
+}
+
+// NewValidator creates a new validator. Included and imported schemas are
+// resolved relative to the path of the root XSD.
+func NewValidator(rootXSD string) (*XMLValidator, error) {
+	schema, err := xsd.ParseFromFile(rootXSD)
+	if err != nil {
+		return nil, err
+	}
+
+	return &XMLValidator{
+		Schema: schema,
+	}, nil
+}
+
+// NewValidator creates a new validator from the schemas in the set. All
+// included and imported schemas are resolved from the set.
+func (s *SchemaSet) NewValidator() (*XMLValidator, error) {
+	uri, unregister, err := registerSchemaSet(s)
+	if err != nil {
+		return nil, err
+	}
+
+	defer unregister()
+
+	schema, err := xsd.ParseFromFile(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return &XMLValidator{
+		Schema: schema,
+	}, nil
+}
+
+// Validate will validate XML towards the XSD schema. If the document doesn't
+// validate ValidationErrors is returned. An error is returned if the validator
+// has been freed.
+func (v *XMLValidator) Validate(xml []byte) error {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if v.freed {
+		return errors.New("validator is freed")
+	}
+
+	d, err := libxml2.Parse(xml)
+	if err != nil {
+		return err
+	}
+
+	defer d.Free()
+
+	handle := registerValidation()
+	defer unregisterValidation(handle)
+
+	ret := C.eppValidateDocument(C.uintptr_t(v.Schema.Pointer()), C.uintptr_t(d.Pointer()), C.uintptr_t(handle))
+	if ret < 0 {
+		return errors.New("failed to build validator")
+	}
+
+	if ret == 0 {
+		return nil
+	}
+
+	errs := validationResult(handle)
+	if len(errs) == 0 {
+		// Internal errors aren't reported as validation errors.
+		return errors.Errorf("validation failed with code %d", int(ret))
+	}
+
+	return errs
+}
+
+var (
+	validationsMu        sync.Mutex
+	validations          = map[uintptr]ValidationErrors{}
+	nextValidationHandle uintptr
+)
+
+// registerValidation returns a handle to collect the errors for a validation
+// reported by libxml2.
+func registerValidation() uintptr {
+	validationsMu.Lock()
+	defer validationsMu.Unlock()
+
+	nextValidationHandle++
+	validations[nextValidationHandle] = ValidationErrors{}
+
+	return nextValidationHandle
+}
+
+func unregisterValidation(handle uintptr) {
+	validationsMu.Lock()
+	defer validationsMu.Unlock()
+
+	delete(validations, handle)
+}
+
+func validationResult(handle uintptr) ValidationErrors {
+	validationsMu.Lock()
+	defer validationsMu.Unlock()
+
+	return validations[handle]
+}
+
+// addValidationError converts the structured error from libxml2 and adds it to
+// the validation with the handle.
+func addValidationError(handle uintptr, err *C.xmlError) {
+	e := &ValidationError{
+		Line:    int(err.line),
+		Message: strings.TrimSpace(C.GoString(err.message)),
+	}
+
+	if err.node != nil {
+		e.Element, e.Path = nodeLocation((*C.xmlNode)(err.node))
+	}
+
+	// Errors for attributes are reported on the element so the attribute is
+	// taken from the message, formatted as "Element 'x', attribute 'y': ".
+	const attributeMarker = "', attribute '"
+
+	if i := strings.Index(e.Message, attributeMarker); i >= 0 {
+		attr := e.Message[i+len(attributeMarker):]
+
+		if end := strings.Index(attr, "'"); end > 0 {
+			e.Path += "/@" + attr[:end]
+		}
+	}
+
+	validationsMu.Lock()
+	defer validationsMu.Unlock()
+
+	if errs, ok := validations[handle]; ok {
+		validations[handle] = append(errs, e)
+	}
+}
+
+// nodeLocation returns the name of the element and the XPath-like path for the
+// node. If the node is an attribute the element is the owner of the attribute.
+func nodeLocation(node *C.xmlNode) (xml.Name, string) {
+	var (
+		element  xml.Name
+		segments []string
+	)
+
+	for n := node; n != nil && n._type != C.XML_DOCUMENT_NODE; n = n.parent {
+		name := nodeName(n)
+
+		if n._type == C.XML_ATTRIBUTE_NODE {
+			segments = append(segments, "@"+qualifiedNodeName(n))
+			continue
+		}
+
+		if element.Local == "" {
+			element = name
+		}
+
+		segment := qualifiedNodeName(n)
+
+		// Add the position if the element has siblings with the same
+		// name.
+		if n.parent != nil {
+			count, position := 0, 0
+
+			for sibling := n.parent.children; sibling != nil; sibling = sibling.next {
+				if sibling._type != C.XML_ELEMENT_NODE || nodeName(sibling) != name {
+					continue
+				}
+
+				count++
+
+				if sibling == n {
+					position = count
+				}
+			}
+
+			if count > 1 {
+				segment = fmt.Sprintf("%s[%d]", segment, position)
+			}
+		}
+
+		segments = append(segments, segment)
+	}
+
+	path := ""
+	for i := len(segments) - 1; i >= 0; i-- {
+		path += "/" + segments[i]
+	}
+
+	return element, path
+}
+
+func nodeName(n *C.xmlNode) xml.Name {
+	name := xml.Name{
+		Local: C.GoString((*C.char)(unsafe.Pointer(n.name))),
+	}
+
+	if n.ns != nil && n.ns.href != nil {
+		name.Space = C.GoString((*C.char)(unsafe.Pointer(n.ns.href)))
+	}
+
+	return name
+}
+
+func qualifiedNodeName(n *C.xmlNode) string {
+	local := C.GoString((*C.char)(unsafe.Pointer(n.name)))
+
+	if n.ns == nil || n.ns.prefix == nil {
+		return local
+	}
+
+	return C.GoString((*C.char)(unsafe.Pointer(n.ns.prefix))) + ":" + local
+}
+
+// Free frees the XSD C struct. Free waits for ongoing validations to finish
+// and may be called multiple times.
+func (v *XMLValidator) Free() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.freed {
+		return
+	}
+
+	v.freed = true
+	v.Schema.Free()
+}
@@ -0,0 +1,6 @@
+//go:build !purego
+// +build !purego
+
+package epp
+
+const emptyDocumentError = "failed to create parse context"
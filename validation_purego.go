@@ -0,0 +1,89 @@
+//go:build purego
+// +build purego
+
+package epp
+
+import (
+	"os"
+	"sync/atomic"
+
+	"github.com/bombsimon/epp-go/schema"
+	"github.com/pkg/errors"
+)
+
+// XMLValidator represents a validator holding the XSD schema to validate
+// against. This validator is implemented in pure Go and only supports the
+// subset of XSD used by the EPP schemas. The validator is safe to use from
+// multiple goroutines.
+type XMLValidator struct {
+	Schema *schema.Schema
+
+	freed int32
+}
+
+// NewValidator creates a new validator. Included and imported schemas are
+// resolved relative to the path of the root XSD.
+func NewValidator(rootXSD string) (*XMLValidator, error) {
+	s, err := schema.Parse(rootXSD, os.ReadFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &XMLValidator{
+		Schema: s,
+	}, nil
+}
+
+// NewValidator creates a new validator from the schemas in the set. All
+// included and imported schemas are resolved from the set.
+func (s *SchemaSet) NewValidator() (*XMLValidator, error) {
+	parsed, err := schema.Parse(schemaRootName, func(name string) ([]byte, error) {
+		xsd, ok := s.Schema(name)
+		if !ok {
+			return nil, errors.Errorf("schema %s not found in set", name)
+		}
+
+		return xsd, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &XMLValidator{
+		Schema: parsed,
+	}, nil
+}
+
+// Validate will validate XML towards the XSD schema. If the document doesn't
+// validate ValidationErrors is returned. An error is returned if the validator
+// has been freed.
+func (v *XMLValidator) Validate(xml []byte) error {
+	if atomic.LoadInt32(&v.freed) == 1 {
+		return errors.New("validator is freed")
+	}
+
+	err := v.Schema.Validate(xml)
+
+	xErrors, ok := err.(schema.Errors)
+	if !ok {
+		return err
+	}
+
+	errs := make(ValidationErrors, len(xErrors))
+	for i, e := range xErrors {
+		errs[i] = &ValidationError{
+			Line:    e.Line,
+			Path:    e.Path,
+			Element: e.Element,
+			Message: e.Message,
+		}
+	}
+
+	return errs
+}
+
+// Free marks the validator as freed. The schema itself is handled by the
+// garbage collector.
+func (v *XMLValidator) Free() {
+	atomic.StoreInt32(&v.freed, 1)
+}
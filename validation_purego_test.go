@@ -0,0 +1,68 @@
+//go:build purego
+// +build purego
+
+package epp
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/bombsimon/epp-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const emptyDocumentError = "document is empty"
+
+func TestXMLValidator_errorLocationPurego(t *testing.T) {
+	validator, err := NewDefaultValidator()
+	require.Nil(t, err)
+
+	defer validator.Free()
+
+	// The location is the XPath reported by the schema package, including
+	// attributes of nested elements and the position among siblings with
+	// the same name.
+	err = validator.Validate([]byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <create>
+      <domain:create xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.se</domain:name>
+        <domain:period unit="d">1</domain:period>
+        <domain:authInfo>
+          <domain:pw>secret</domain:pw>
+        </domain:authInfo>
+      </domain:create>
+    </create>
+  </command>
+</epp>`))
+	require.NotNil(t, err)
+
+	xErrors, ok := err.(ValidationErrors)
+	require.True(t, ok)
+	require.Len(t, xErrors, 1)
+
+	assert.Equal(t, 6, xErrors[0].Line)
+	assert.Equal(t, "/epp/command/create/domain:create/domain:period/@unit", xErrors[0].Path)
+	assert.Equal(t, xml.Name{Space: types.NameSpaceDomain, Local: "period"}, xErrors[0].Element)
+
+	err = validator.Validate([]byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <check>
+      <domain:check xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.se</domain:name>
+        <domain:name>example.com</domain:name>
+        <domain:name></domain:name>
+      </domain:check>
+    </check>
+  </command>
+</epp>`))
+	require.NotNil(t, err)
+
+	xErrors, ok = err.(ValidationErrors)
+	require.True(t, ok)
+	require.Len(t, xErrors, 1)
+
+	assert.Equal(t, 7, xErrors[0].Line)
+	assert.Equal(t, "/epp/command/check/domain:check/domain:name[3]", xErrors[0].Path)
+}
@@ -1,19 +1,36 @@
 package epp
 
 import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 
+	"github.com/bombsimon/epp-go/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-
-	xsd "github.com/lestrrat-go/libxml2/xsd"
 )
 
 func TestValidator_setupSchema(t *testing.T) {
-	validator, err := NewValidator("xml/index.xsd")
+	fileValidator, err := NewValidator("xml/index.xsd")
+
+	require.Nil(t, err)
+	require.NotNil(t, fileValidator)
+
+	defer fileValidator.Free()
+
+	defaultValidator, err := NewDefaultValidator()
 
 	require.Nil(t, err)
-	require.NotNil(t, validator)
+	require.NotNil(t, defaultValidator)
+
+	defer defaultValidator.Free()
+
+	validators := map[string]*XMLValidator{
+		"file":    fileValidator,
+		"default": defaultValidator,
+	}
 
 	cases := []struct {
 		description string
@@ -24,7 +41,7 @@ func TestValidator_setupSchema(t *testing.T) {
 		{
 			description: "no xml should not be valid",
 			xml:         []byte{},
-			errContains: "failed to create parse context",
+			errContains: emptyDocumentError,
 		},
 		{
 			description: "namespace for EPP tag is requried",
@@ -42,7 +59,7 @@ func TestValidator_setupSchema(t *testing.T) {
 				  </command>
 				</epp>`),
 			errContains: "schema validation failed",
-			xmlErrors:   []string{"'-INVALID-' is not an element of the set {'ack', 'req'}", "'-INVALID-' is not a valid value of the atomic type"},
+			xmlErrors:   []string{"'-INVALID-' is not an element of the set {'ack', 'req'}"},
 		},
 		{
 			description: "valid XML, including type ns",
@@ -61,39 +78,194 @@ func TestValidator_setupSchema(t *testing.T) {
 		},
 	}
 
-	for _, tc := range cases {
-		t.Run(tc.description, func(t *testing.T) {
-			err := validator.Validate(tc.xml)
+	for name, validator := range validators {
+		for _, tc := range cases {
+			t.Run(name+"/"+tc.description, func(t *testing.T) {
+				err := validator.Validate(tc.xml)
 
-			if tc.errContains == "" {
-				require.Nil(t, err)
+				if tc.errContains == "" {
+					require.Nil(t, err)
 
-				return
-			}
+					return
+				}
 
-			require.NotNil(t, err)
-			assert.Contains(t, err.Error(), tc.errContains)
+				require.NotNil(t, err)
+				assert.Contains(t, err.Error(), tc.errContains)
 
-			xErr, ok := err.(xsd.SchemaValidationError)
-			if !ok {
-				return
-			}
+				xErrors, _ := err.(ValidationErrors)
+				if len(xErrors) != len(tc.xmlErrors) {
+					t.Logf("all errors not caught, got %d errors:\n", len(xErrors))
 
-			xErrors := xErr.Errors()
-			if len(xErrors) != len(tc.xmlErrors) {
-				t.Logf("all errors not caught, got %d errors:\n", len(xErrors))
+					for i, err := range xErrors {
+						t.Logf("%-3d %s\n", i, err.Error())
+					}
 
-				for i, err := range xErrors {
-					t.Logf("%-3d %s\n", i, err.Error())
+					assert.Fail(t, "all errors are not caught")
+					return
 				}
 
-				assert.Fail(t, "all errors are not caught")
-				return
-			}
+				for i, err := range tc.xmlErrors {
+					assert.Contains(t, xErrors[i].Error(), err)
+				}
+			})
+		}
+	}
+}
+
+func TestSchemaSet_Add(t *testing.T) {
+	schemas, err := NewSchemaSet()
+	require.Nil(t, err)
+
+	err = schemas.Add("example-1.0.xsd", []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<schema xmlns:example="urn:example:params:xml:ns:example-1.0" xmlns="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:example:params:xml:ns:example-1.0" elementFormDefault="qualified">
+  <import namespace="urn:ietf:params:xml:ns:eppcom-1.0"/>
+  <element name="create" type="example:createType"/>
+  <complexType name="createType">
+    <sequence>
+      <element name="id" type="eppcom:clIDType" xmlns:eppcom="urn:ietf:params:xml:ns:eppcom-1.0"/>
+    </sequence>
+  </complexType>
+</schema>`))
+	require.Nil(t, err)
+
+	require.NotNil(t, schemas.Add("not-a-schema.xsd", []byte(`<epp/>`)))
+
+	validator, err := schemas.NewValidator()
+	require.Nil(t, err)
+
+	defer validator.Free()
+
+	command := `<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <info>
+      <domain:info xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.se</domain:name>
+      </domain:info>
+    </info>
+    <extension>
+      <example:create xmlns:example="urn:example:params:xml:ns:example-1.0">
+        <example:%s>client-1</example:%s>
+      </example:create>
+    </extension>
+  </command>
+</epp>`
+
+	assert.Nil(t, validator.Validate([]byte(fmt.Sprintf(command, "id", "id"))))
+	assert.NotNil(t, validator.Validate([]byte(fmt.Sprintf(command, "invalid", "invalid"))))
+}
+
+func TestXMLValidator_concurrent(t *testing.T) {
+	validator, err := NewDefaultValidator()
+	require.Nil(t, err)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
 
-			for i, err := range tc.xmlErrors {
-				assert.Contains(t, xErrors[i].Error(), err)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 20; j++ {
+				assert.Nil(t, validator.Validate(testGreeting))
+				assert.NotNil(t, validator.Validate([]byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><command/></epp>`)))
 			}
-		})
+		}()
 	}
+
+	wg.Wait()
+
+	validator.Free()
+	validator.Free()
+
+	assert.NotNil(t, validator.Validate(testGreeting))
+}
+
+func TestXMLValidator_errorLocation(t *testing.T) {
+	validator, err := NewDefaultValidator()
+	require.Nil(t, err)
+
+	defer validator.Free()
+
+	err = validator.Validate([]byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <check>
+      <domain:check xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.se</domain:name>
+        <domain:name></domain:name>
+      </domain:check>
+    </check>
+  </command>
+</epp>`))
+	require.NotNil(t, err)
+
+	xErrors, ok := err.(ValidationErrors)
+	require.True(t, ok)
+	require.Len(t, xErrors, 1)
+
+	assert.Equal(t, 6, xErrors[0].Line)
+	assert.Equal(t, "/epp/command/check/domain:check/domain:name[2]", xErrors[0].Path)
+	assert.Equal(t, xml.Name{Space: types.NameSpaceDomain, Local: "name"}, xErrors[0].Element)
+	assert.Contains(t, xErrors[0].Message, "minLength")
+
+	err = validator.Validate([]byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <poll op="-INVALID-"/>
+  </command>
+</epp>`))
+	require.NotNil(t, err)
+
+	xErrors, ok = err.(ValidationErrors)
+	require.True(t, ok)
+	require.Len(t, xErrors, 1)
+
+	assert.Equal(t, 3, xErrors[0].Line)
+	assert.Equal(t, "/epp/command/poll/@op", xErrors[0].Path)
+	assert.Equal(t, xml.Name{Space: types.NameSpaceEPP10, Local: "poll"}, xErrors[0].Element)
+}
+
+func TestCreateValidationErrorResponse(t *testing.T) {
+	validator, err := NewDefaultValidator()
+	require.Nil(t, err)
+
+	defer validator.Free()
+
+	command := []byte(`<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <command>
+    <check>
+      <domain:check xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>example.se</domain:name>
+        <domain:invalid>example.se</domain:invalid>
+      </domain:check>
+    </check>
+    <clTRID>ABC-12345</clTRID>
+  </command>
+</epp>`)
+
+	response := CreateValidationErrorResponse(command, validator.Validate(command))
+	response.TransactionID.ServerTransactionID = "54321-XYZ"
+
+	require.Len(t, response.Result, 1)
+	assert.Equal(t, EppSyntaxError.Code(), response.Result[0].Code)
+	assert.Equal(t, "ABC-12345", response.TransactionID.ClientTransactionID)
+
+	encoded, err := Encode(response, ServerXMLAttributes())
+	require.Nil(t, err)
+
+	// The response must be valid itself to be sent to the client.
+	require.Nil(t, validator.Validate(encoded))
+
+	decoded, err := DecodeResponse(encoded)
+	require.Nil(t, err)
+
+	assert.Equal(t, EppSyntaxError, decoded.Code())
+	assert.Contains(t, string(encoded), `<domain:invalid`)
+	assert.Contains(t, string(encoded), `<reason>`)
+
+	// Errors not from the validator has no value.
+	response = CreateValidationErrorResponse([]byte(`<epp>`), errors.New("not well formed"))
+
+	require.Len(t, response.Result, 1)
+	assert.Equal(t, EppSyntaxError.Code(), response.Result[0].Code)
+	assert.Nil(t, response.Result[0].ExternalValue)
 }